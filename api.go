@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/graphql-go/graphql"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	serveCommand = kingpin.Command("serve", "Serve grass over HTTP until interrupted, running the daemon too with --daemon")
+	apiEnabled   = serveCommand.Flag("api", "Serve the REST API").Bool()
+	apiAddr      = serveCommand.Flag("addr", "Address to serve on").Envar("GRASS_API_ADDR").Default("127.0.0.1:8080").String()
+	apiToken     = serveCommand.Flag("token", "Bearer token every API request and gRPC call must carry").Envar("GRASS_API_TOKEN").String()
+)
+
+const (
+	// apiMaxBody caps the size of an API request body.
+	apiMaxBody = 1 << 20
+	// apiDefaultLimit is how many results or runs are listed when a request doesn't set a limit.
+	apiDefaultLimit = 50
+)
+
+// apiHandler serves the REST API under /api/v1 for the profiles. Every endpoint but /api/v1/profiles and
+// /api/v1/graphql takes a profile query parameter naming the profile to use, defaulting to the unnamed one.
+// Requests under /slack/ go to the Slack app, if any, which Slack signs instead of sending the token.
+type apiHandler struct {
+	mux      *http.ServeMux
+	profiles map[string]*profile
+	token    string
+	schema   graphql.Schema
+	slack    *slackHandler
+}
+
+// apiError is the body of every failed API response.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// apiResultRef identifies a stored result in API requests.
+type apiResultRef struct {
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+}
+
+// apiStateRequest sets the state of a stored result.
+type apiStateRequest struct {
+	apiResultRef
+	State string `json:"state"`
+}
+
+// apiKeywordRequest names a keyword to add, remove, pause, or resume. For is how long a paused keyword
+// stays paused, e.g. "24h"; it stays paused until resumed if For is empty.
+type apiKeywordRequest struct {
+	Keyword string `json:"keyword"`
+	For     string `json:"for,omitempty"`
+}
+
+// newAPIHandler creates a handler for the profiles. Requests must carry token as a bearer token. GraphQL
+// aggregations by day use days in location.
+func newAPIHandler(profiles []*profile, token string, location *time.Location) (*apiHandler, error) {
+	h := &apiHandler{mux: http.NewServeMux(), profiles: profilesByName(profiles), token: token}
+	var err error
+	if h.schema, err = h.newGraphQLSchema(location); err != nil {
+		return nil, fmt.Errorf("building GraphQL schema: %w", err)
+	}
+
+	h.mux.HandleFunc("GET /api/v1/profiles", h.listProfiles)
+	h.mux.HandleFunc("GET /api/v1/results", h.listResults)
+	h.mux.HandleFunc("POST /api/v1/results/state", h.setState(""))
+	h.mux.HandleFunc("POST /api/v1/results/acknowledge", h.setState(storage.StateAcknowledged))
+	h.mux.HandleFunc("POST /api/v1/search", h.search)
+	h.mux.HandleFunc("GET /api/v1/keywords", h.listKeywords)
+	h.mux.HandleFunc("POST /api/v1/keywords", h.changeKeyword(func(ctx context.Context, set *keywordSet, req apiKeywordRequest) (int, error) {
+		added, err := set.add(ctx, req.Keyword)
+		if err == nil && !added {
+			err = &keywordError{message: fmt.Sprintf("%s is already managed", req.Keyword)}
+		}
+		return http.StatusCreated, err
+	}))
+	h.mux.HandleFunc("DELETE /api/v1/keywords", h.changeKeyword(func(ctx context.Context, set *keywordSet, req apiKeywordRequest) (int, error) {
+		return http.StatusOK, set.remove(ctx, req.Keyword)
+	}))
+	h.mux.HandleFunc("POST /api/v1/keywords/pause", h.changeKeyword(func(ctx context.Context, set *keywordSet, req apiKeywordRequest) (int, error) {
+		var until time.Time
+		if req.For != "" {
+			d, err := time.ParseDuration(req.For)
+			if err != nil || d <= 0 {
+				return http.StatusBadRequest, &keywordError{message: fmt.Sprintf("invalid duration %q", req.For), invalid: true}
+			}
+			until = time.Now().Add(d)
+		}
+		return http.StatusOK, set.setPaused(ctx, req.Keyword, true, until)
+	}))
+	h.mux.HandleFunc("POST /api/v1/keywords/resume", h.changeKeyword(func(ctx context.Context, set *keywordSet, req apiKeywordRequest) (int, error) {
+		return http.StatusOK, set.setPaused(ctx, req.Keyword, false, time.Time{})
+	}))
+	h.mux.HandleFunc("GET /api/v1/runs", h.listRuns)
+	h.mux.HandleFunc("POST /api/v1/graphql", h.graphQL)
+	return h, nil
+}
+
+func (h *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.slack != nil && strings.HasPrefix(r.URL.Path, "/slack/") {
+		h.slack.ServeHTTP(w, r)
+		return
+	}
+	if !hasBearerToken(r, h.token) {
+		writeAPIResponse(w, http.StatusUnauthorized, apiError{Error: "unauthorized"})
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// profile returns the profile named by the request, writing an error response if there is none.
+func (h *apiHandler) profile(w http.ResponseWriter, r *http.Request) (*profile, bool) {
+	name := r.URL.Query().Get("profile")
+	p, ok := h.profiles[name]
+	if !ok {
+		writeAPIResponse(w, http.StatusNotFound, apiError{Error: fmt.Sprintf("unknown profile %q", name)})
+	}
+	return p, ok
+}
+
+// listProfiles lists the profiles, campaigns, and tenant profiles being served.
+func (h *apiHandler) listProfiles(w http.ResponseWriter, r *http.Request) {
+	type profileInfo struct {
+		Name     string `json:"name"`
+		Campaign bool   `json:"campaign"`
+		Active   bool   `json:"active"`
+	}
+	names := profileNames(h.profiles)
+	now := time.Now()
+	profiles := make([]profileInfo, 0, len(names))
+	for _, name := range names {
+		p := h.profiles[name]
+		profiles = append(profiles, profileInfo{Name: name, Campaign: p.campaign != nil, Active: p.active(now)})
+	}
+	writeAPIResponse(w, http.StatusOK, map[string]any{"profiles": profiles})
+}
+
+// profilesByName maps profiles by their names.
+func profilesByName(profiles []*profile) map[string]*profile {
+	byName := make(map[string]*profile, len(profiles))
+	for _, p := range profiles {
+		byName[p.name] = p
+	}
+	return byName
+}
+
+// profileNames returns the names of profiles, sorted.
+func profileNames(profiles map[string]*profile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// listResults lists stored results newest first, filtered like the query command by the platform, keyword,
+// state, since, and until parameters and paged by limit and offset.
+func (h *apiHandler) listResults(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.profile(w, r)
+	if !ok {
+		return
+	}
+	querier, ok := storage.AsQuerier(p.storer)
+	if !ok {
+		writeAPIResponse(w, http.StatusNotImplemented, apiError{Error: "the profile's storage can't be queried"})
+		return
+	}
+
+	params := r.URL.Query()
+	q := storage.Query{Platforms: params["platform"], Keywords: params["keyword"], States: params["state"]}
+	err := storage.CheckStates(q.States...)
+	if err == nil {
+		q.Since, err = apiTime(params.Get("since"))
+	}
+	if err == nil {
+		q.Until, err = apiTime(params.Get("until"))
+	}
+	if err == nil {
+		q.Limit, err = apiInt(params.Get("limit"), apiDefaultLimit)
+	}
+	if err == nil {
+		q.Offset, err = apiInt(params.Get("offset"), 0)
+	}
+	if err != nil {
+		writeAPIResponse(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+
+	results, err := querier.Query(r.Context(), q)
+	if err != nil {
+		writeAPIResponse(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+	if results == nil {
+		results = []search.SearchResult{}
+	}
+	writeAPIResponse(w, http.StatusOK, map[string]any{"results": results, "offset": q.Offset, "limit": q.Limit})
+}
+
+// setState sets the state of a stored result, to state or, if it is empty, to the state in the request.
+func (h *apiHandler) setState(state string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.profile(w, r)
+		if !ok {
+			return
+		}
+		updater, ok := storage.AsUpdater(p.storer)
+		if !ok {
+			writeAPIResponse(w, http.StatusNotImplemented, apiError{Error: "the profile's storage can't keep result states"})
+			return
+		}
+		var req apiStateRequest
+		if err := decodeAPIRequest(w, r, &req); err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, apiError{Error: err.Error()})
+			return
+		}
+		if state != "" {
+			req.State = state
+		}
+		if req.Platform == "" || req.URL == "" {
+			writeAPIResponse(w, http.StatusBadRequest, apiError{Error: "platform and url are required"})
+			return
+		}
+		if err := storage.CheckStates(req.State); err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, apiError{Error: err.Error()})
+			return
+		}
+
+		result, found, err := storage.SetState(r.Context(), updater, req.Platform, req.URL, req.State)
+		if err != nil {
+			writeAPIResponse(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+			return
+		}
+		if !found {
+			writeAPIResponse(w, http.StatusNotFound, apiError{Error: "result not found"})
+			return
+		}
+		writeAPIResponse(w, http.StatusOK, result)
+	}
+}
+
+// search runs an on-demand search of every platform for a keyword, storing and notifying new results as a
+// run would, and responds with the run's report.
+func (h *apiHandler) search(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.profile(w, r)
+	if !ok {
+		return
+	}
+	var req apiKeywordRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		writeAPIResponse(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+	if _, err := search.ParseQuery(req.Keyword); err != nil || req.Keyword == "" {
+		writeAPIResponse(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("invalid keyword %q", req.Keyword)})
+		return
+	}
+	if !p.active(time.Now()) {
+		writeAPIResponse(w, http.StatusConflict, apiError{Error: fmt.Sprintf("campaign %q is not running", p.name)})
+		return
+	}
+
+	log.Info("Searching on request", "profile", p.name, "keyword", req.Keyword)
+	report := p.bot.Run(r.Context(), req.Keyword)
+	recordRun(r.Context(), p, report, "api")
+	writeAPIResponse(w, http.StatusOK, newRunReport(report, p.name, "api"))
+}
+
+// keywordSet loads the profile's keywords, writing an error response if its storage can't keep them.
+func (h *apiHandler) keywordSet(w http.ResponseWriter, r *http.Request, p *profile) (*keywordSet, bool) {
+	if p.keywordStore == nil {
+		writeAPIResponse(w, http.StatusNotImplemented, apiError{Error: "the profile's storage can't keep keywords"})
+		return nil, false
+	}
+	set, err := loadKeywordSet(r.Context(), p.keywordStore, p.configuredKeywords())
+	if err != nil {
+		writeAPIResponse(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return nil, false
+	}
+	return set, true
+}
+
+// listKeywords lists the profile's configured and managed keywords.
+func (h *apiHandler) listKeywords(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.profile(w, r)
+	if !ok {
+		return
+	}
+	set, ok := h.keywordSet(w, r, p)
+	if !ok {
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, map[string]any{"keywords": set.list()})
+}
+
+// changeKeyword returns a handler applying change to the keyword named in the request body, then
+// responding with the profile's keywords. Running daemons pick up the change at their next keyword refresh.
+func (h *apiHandler) changeKeyword(change func(ctx context.Context, set *keywordSet, req apiKeywordRequest) (int, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.profile(w, r)
+		if !ok {
+			return
+		}
+		var req apiKeywordRequest
+		if err := decodeAPIRequest(w, r, &req); err != nil {
+			writeAPIResponse(w, http.StatusBadRequest, apiError{Error: err.Error()})
+			return
+		}
+		if _, err := search.ParseQuery(req.Keyword); err != nil || req.Keyword == "" {
+			writeAPIResponse(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("invalid keyword %q", req.Keyword)})
+			return
+		}
+		set, ok := h.keywordSet(w, r, p)
+		if !ok {
+			return
+		}
+
+		status, err := change(r.Context(), set, req)
+		var refused *keywordError
+		switch {
+		case errors.As(err, &refused) && refused.invalid:
+			writeAPIResponse(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		case errors.As(err, &refused) && refused.notFound:
+			writeAPIResponse(w, http.StatusNotFound, apiError{Error: err.Error()})
+		case errors.As(err, &refused):
+			writeAPIResponse(w, http.StatusConflict, apiError{Error: err.Error()})
+		case err != nil:
+			writeAPIResponse(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		default:
+			log.Info("Keywords changed through the API", "profile", p.name, "method", r.Method, "path", r.URL.Path, "keyword", req.Keyword)
+			writeAPIResponse(w, status, map[string]any{"keywords": set.list()})
+		}
+	}
+}
+
+// listRuns lists the profile's run history, most recent first, up to the limit parameter.
+func (h *apiHandler) listRuns(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.profile(w, r)
+	if !ok {
+		return
+	}
+	recorder, ok := storage.AsRunRecorder(p.storer)
+	if !ok {
+		writeAPIResponse(w, http.StatusNotImplemented, apiError{Error: "the profile's storage can't keep run history"})
+		return
+	}
+	limit, err := apiInt(r.URL.Query().Get("limit"), apiDefaultLimit)
+	if err != nil {
+		writeAPIResponse(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+
+	runs, err := recorder.Runs(r.Context(), limit)
+	if err != nil {
+		writeAPIResponse(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+	if runs == nil {
+		runs = []storage.Run{}
+	}
+	writeAPIResponse(w, http.StatusOK, map[string]any{"runs": runs})
+}
+
+// apiTime parses a date or RFC 3339 time parameter, returning the zero time when it is empty.
+func apiTime(value string) (time.Time, error) {
+	var t time.Time
+	if value == "" {
+		return t, nil
+	}
+	err := (&timeValue{t: &t}).Set(value)
+	return t, err
+}
+
+// apiInt parses a non-negative integer parameter, returning fallback when it is empty.
+func apiInt(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return n, nil
+}
+
+// decodeAPIRequest parses a JSON request body into v, rejecting unknown fields.
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeAPIResponse(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Debug("Failed to write API response", "error", err)
+	}
+}
+
+// serveAPI serves the REST API on addr, or the socket systemd passed as api, until ctx is cancelled, then
+// waits for in-flight requests to finish. The Slack app is served alongside it with --slack-signing-secret.
+func serveAPI(ctx context.Context, addr string, profiles []*profile, token string, location *time.Location) error {
+	handler, err := newAPIHandler(profiles, token, location)
+	if err != nil {
+		return err
+	}
+	if *slackSigningSecret != "" {
+		if handler.slack, err = newSlackHandler(profiles, *slackProfile, *slackSigningSecret, *slackUsers); err != nil {
+			return fmt.Errorf("setting up the Slack app: %w", err)
+		}
+	}
+	listener, err := listen("api", addr)
+	if err != nil {
+		return err
+	}
+	log.Info("Serving API", "addr", listener.Addr())
+	return serve(ctx, &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}, listener)
+}
@@ -0,0 +1,92 @@
+// bot/archive.go
+package bot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/internal/httpclient"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+// maxArchivePage caps how much of a linked page is kept in a snapshot.
+const maxArchivePage = 5 << 20
+
+// Archiver keeps snapshots of saved results, with their full content, so mentions remain reviewable after
+// their posts are edited, deleted, or taken down. It can also keep the HTML of the pages link posts point
+// to.
+type Archiver struct {
+	store storage.Archiver
+	// client fetches linked pages; nil disables page snapshots
+	client *http.Client
+}
+
+// NewArchiver creates an archiver keeping snapshots in store, fetching the pages link posts point to with
+// client when pages is set.
+func NewArchiver(store storage.Archiver, pages bool, client *http.Client) *Archiver {
+	a := &Archiver{store: store}
+	if pages {
+		a.client = httpclient.WithTimeout(client, 30*time.Second)
+	}
+	return a
+}
+
+// Archive snapshots results. A linked page that can't be fetched is logged and the result archived
+// without it.
+func (a *Archiver) Archive(ctx context.Context, results []search.SearchResult) error {
+	now := time.Now().Unix()
+	snapshots := make([]storage.Snapshot, 0, len(results))
+	for _, result := range results {
+		snapshot := storage.Snapshot{Result: result, ArchivedAt: now}
+		if a.client != nil && result.Link != "" {
+			page, err := a.page(ctx, result.Link)
+			if err != nil {
+				log.Debug("Error archiving linked page", "platform", result.Platform, "url", result.URL, "link", result.Link, "error", err)
+			}
+			snapshot.Page = page
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return a.store.Archive(ctx, snapshots)
+}
+
+// page downloads a linked HTML page, up to maxArchivePage bytes.
+func (a *Archiver) page(ctx context.Context, link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("not an http(s) link: %s", link)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "grass-archive/1.0")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", link, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch %s: %s", link, resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return "", fmt.Errorf("%s is not an HTML page: %s", link, contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxArchivePage))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", link, err)
+	}
+	return string(body), nil
+}
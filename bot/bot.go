@@ -2,6 +2,11 @@
 package bot
 
 import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -12,60 +17,703 @@ import (
 type Bot struct {
 	Searchers []search.Searcher
 	Storer    storage.Storer
-	Notifiers []Notifier
+	// Notifiers and Router, like Priorities, Filter, and KeywordVariants, are only set directly before the
+	// bot runs; Reconfigure changes them afterwards.
+	Notifiers map[string]Notifier
+	Router    *Router
+	// Priorities assigns priorities to results before they are routed. A nil prioritizer leaves priorities
+	// to the routing rules.
+	Priorities *Prioritizer
+	// Filter drops excluded results before they are saved or notified. A nil filter keeps everything.
+	Filter *Filter
+	// Spam drops results that look like spam or bot activity. A nil spam filter keeps everything.
+	Spam *SpamFilter
+	// PlatformConcurrency caps how many searches may hit the same platform at once when keywords run
+	// in parallel. Zero or less means one at a time.
+	PlatformConcurrency int
+	// SearchTimeout and NotifyTimeout bound each call to a searcher or notifier. Zero means no timeout.
+	SearchTimeout time.Duration
+	NotifyTimeout time.Duration
+	// DuplicateWindow groups copies of the same story (by content hash) found on several platforms within
+	// this window into one notification. Zero disables grouping.
+	DuplicateWindow time.Duration
+	// Summarizer adds a short summary to long results before they are notified. A nil summarizer
+	// disables summaries.
+	Summarizer Summarizer
+	// Unfurler previews the pages link posts point to before they are notified. A nil unfurler disables
+	// previews.
+	Unfurler Unfurler
+	// Archiver snapshots results as they are saved, including edited versions. Archiving failures are
+	// logged and never stop results being notified. A nil archiver disables archiving.
+	Archiver *Archiver
+	// Metrics records the outcome of every platform search, for example to emit it to a metrics agent. A nil
+	// recorder disables it.
+	Metrics SearchMetrics
+	// Events receives lifecycle events, such as results being found or notified. A nil handler disables
+	// them.
+	Events EventHandler
+	// ThreadContext fetches the post each comment or reply replies to before it is notified, from searchers
+	// that implement search.ThreadFetcher.
+	ThreadContext bool
+	// Backfill makes the first search of each platform and keyword fetch results from this far back instead
+	// of since the stored last search time. Zero disables backfilling.
+	Backfill time.Duration
+	// BackfillMarkSeen saves backfilled results as seen without notifying them.
+	BackfillMarkSeen bool
+	// Since and Until restrict searches to results posted between them, with Since overriding stored last
+	// search times and backfills. Zero values leave that end of the range open. While either is set, last
+	// search times are left unchanged so a one-off range doesn't affect later runs.
+	Since time.Time
+	Until time.Time
+	// KeywordVariants are the patterns of other forms of each keyword to search for on a platform, such as
+	// hashtags, keyed by platform. See search.ExpandVariants.
+	KeywordVariants map[string][]string
+	// EditDetection is one of EditModes: whether results found again are compared with their stored
+	// version to detect edits, and whether edited results are notified again. It needs a storer that can
+	// read back results.
+	EditDetection string
+	// EditThreshold is the fraction of a result's words that must change for it to count as edited. See
+	// search.ChangedFraction.
+	EditThreshold float64
+	// Engagement fetches the current engagement of new results from their platforms before they are saved.
+	Engagement bool
+	// FollowUpThreshold sends a follow-up notification whenever a notified result gains this many replies,
+	// checked by CheckFollowUps. Zero disables follow-ups.
+	FollowUpThreshold int64
+	// FollowUpWindow is how long after notification a result's discussion is followed.
+	FollowUpWindow time.Duration
+	// DeletionWindow is how long after notification CheckDeletions checks a result for deletion. Zero
+	// disables deletion checks.
+	DeletionWindow time.Duration
+	// NotifyDeletions sends a follow-up notification when a notified result is found deleted.
+	NotifyDeletions bool
+	// Outbox queues notifications in storage so failed deliveries are retried by DeliverOutbox instead of
+	// being dropped. A nil outbox delivers notifications directly.
+	Outbox storage.Outbox
+	// SummaryOnly saves new results without notifying them one by one, for when run summaries sent with
+	// NotifySummary are the only notifications wanted.
+	SummaryOnly bool
+
+	// configMu guards the settings Reconfigure changes: Notifiers, Router, Priorities, Filter, and
+	// KeywordVariants.
+	configMu sync.RWMutex
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+	// claims maps the results being processed to the keywords that found them.
+	claims    map[string][]string
+	followUps map[string]*followUp
+	// watched holds the notified results being checked for deletion.
+	watched map[string]*watchedResult
+	// backfilled records the platform and keyword pairs that have already been backfilled.
+	backfilled map[string]bool
+	// processors are the user processors attached after each pipeline stage.
+	processors map[Stage][]Processor
+	// delivering serializes outbox deliveries.
+	delivering sync.Mutex
+	// summary collects the reports of runs since the last TakeSummary.
+	summary *RunReport
 }
 
-func NewBot(searchers []search.Searcher, storer storage.Storer, notifiers []Notifier) *Bot {
+// NewBot creates a bot. Notifiers are keyed by the name routing rules refer to them by; a nil router
+// sends every result to every notifier.
+func NewBot(searchers []search.Searcher, storer storage.Storer, notifiers map[string]Notifier, router *Router) *Bot {
 	return &Bot{
-		Searchers: searchers,
-		Storer:    storer,
-		Notifiers: notifiers,
+		Searchers:  searchers,
+		Storer:     storer,
+		Notifiers:  notifiers,
+		Router:     router,
+		slots:      make(map[string]chan struct{}),
+		claims:     make(map[string][]string),
+		followUps:  make(map[string]*followUp),
+		watched:    make(map[string]*watchedResult),
+		backfilled: make(map[string]bool),
+		summary:    newRunReport(),
+	}
+}
+
+// RunKeywords runs up to concurrency keywords in parallel, each searching every platform. Searches
+// against a single platform are still capped by PlatformConcurrency so parallel keywords don't trip
+// rate limits. A post matching several keywords is saved and notified once, listing every keyword it
+// matched. It returns a report of what every search found.
+func (b *Bot) RunKeywords(ctx context.Context, keywords []string, concurrency int) *RunReport {
+	report := newRunReport()
+	b.runKeywords(ctx, keywords, b.Searchers, concurrency, b.timeRange(), report)
+	b.flushDigests(ctx, false)
+	return b.record(report.finish())
+}
+
+// Run searches every platform for a keyword, storing and notifying new results, and returns a report of
+// what each platform found. Cancelling ctx stops the run between platforms and aborts in-flight storage
+// calls.
+func (b *Bot) Run(ctx context.Context, keyword string) *RunReport {
+	report := newRunReport()
+	b.runKeywords(ctx, []string{keyword}, b.Searchers, 1, b.timeRange(), report)
+	b.flushDigests(ctx, false)
+	return b.record(report.finish())
+}
+
+// RunSearcher searches a single platform for a keyword, storing and notifying new results, and returns a
+// report of what it found. It lets callers such as the daemon scheduler run each searcher and keyword on
+// its own cadence.
+func (b *Bot) RunSearcher(ctx context.Context, provider search.Searcher, keyword string) *RunReport {
+	report := newRunReport()
+	b.runKeywords(ctx, []string{keyword}, []search.Searcher{provider}, 1, b.timeRange(), report)
+	b.flushDigests(ctx, false)
+	return b.record(report.finish())
+}
+
+// runKeywords runs up to concurrency keywords in parallel against providers, searching for results posted
+// within window, then notifies what they saved together. Results stay claimed until every keyword has run,
+// so a result found by several keywords is saved by the first and notified once with all of them.
+func (b *Bot) runKeywords(ctx context.Context, keywords []string, providers []search.Searcher, concurrency int, window timeRange, report *RunReport) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	state := &runState{window: window}
+	defer b.release(ctx, state.claimed)
+
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(keywords); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for keyword := range queue {
+				log.Info("Running search for keyword", "keyword", keyword)
+				b.run(ctx, keyword, providers, report, state)
+			}
+		}()
+	}
+
+	for _, keyword := range keywords {
+		select {
+		case queue <- keyword:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	b.notifyPending(ctx, state.pending)
+
+	for _, advance := range state.advances {
+		if err := b.Storer.SetLastSearchTime(ctx, lastSearchKey(advance.platform, advance.keyword), advance.to); err != nil {
+			log.Error("Error setting last search time", "platform", advance.platform, "keyword", advance.keyword, "error", err)
+		}
 	}
 }
 
-func (b *Bot) Run(keyword string) {
-	for _, provider := range b.Searchers {
-		lastSearchTime, err := b.Storer.GetLastSearchTime(provider.Platform())
+// FlushDigests sends every buffered digest and throttled backlog immediately, whether or not its window has
+// passed. Call it before exiting so batched results are not lost.
+func (b *Bot) FlushDigests(ctx context.Context) {
+	b.flushDigests(ctx, true)
+}
+
+// flushDigests sends buffered digests and throttled backlogs whose window has passed, or all of them when
+// force is set.
+func (b *Bot) flushDigests(ctx context.Context, force bool) {
+	notifiers := b.CurrentNotifiers()
+	names := make([]string, 0, len(notifiers))
+	for name := range notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		notifier, ok := notifiers[name].(flusher)
+		if !ok {
+			continue
+		}
+		flushCtx, cancel := withTimeout(ctx, b.NotifyTimeout)
+		err := notifier.Flush(flushCtx, force)
+		cancel()
 		if err != nil {
-			log.Error("Error retrieving last search time", "platform", provider.Platform(), "error", err)
+			log.Error("Error sending digest", "notifier", name, "error", err)
+		}
+	}
+}
+
+// pendingResult is a saved result waiting to be notified, along with the notifiers routed to it and, if
+// its searcher can count replies or tell whether it was deleted, the checkers used to follow it.
+type pendingResult struct {
+	result    search.SearchResult
+	notifiers []string
+	checker   search.ActivityChecker
+	deletions search.DeletionChecker
+	threads   search.ThreadFetcher
+}
+
+// runState collects what the keywords of a run saved, for notifying them together once they have all
+// run. It is shared by the run's keywords.
+type runState struct {
+	// window is the range of posting times the run searches.
+	window timeRange
+
+	mu       sync.Mutex
+	pending  []pendingResult
+	advances []lastSearchAdvance
+	claimed  []search.SearchResult
+}
+
+// timeRange restricts a run to results posted between since and until. Zero values leave that end of the
+// range open.
+type timeRange struct {
+	since time.Time
+	until time.Time
+}
+
+// timeRange returns the range set by Since and Until.
+func (b *Bot) timeRange() timeRange {
+	return timeRange{since: b.Since, until: b.Until}
+}
+
+// run collects and saves new results from each platform for a keyword, adding them to state so copies of
+// the same story found on several platforms, or by several keywords, are notified once, and recording
+// each platform's search in report. See Stage for the pipeline each platform's results go through.
+func (b *Bot) run(ctx context.Context, keyword string, providers []search.Searcher, report *RunReport, state *runState) {
+	var pending []pendingResult
+	var advances []lastSearchAdvance
+	var claimed []search.SearchResult
+	defer func() {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		state.claimed = append(state.claimed, claimed...)
+	}()
+
+	account, watching := search.ParseAccount(keyword)
+	for _, provider := range providers {
+		if ctx.Err() != nil {
+			log.Warn("Run cancelled", "keyword", keyword, "error", ctx.Err())
+			return
+		}
+		// Accounts are only watched on their own platform
+		if watching && !account.On(provider) {
 			continue
 		}
 
-		results, err := provider.Search(keyword, lastSearchTime)
+		started := time.Now()
+		searched := PlatformReport{Platform: provider.Platform(), Searches: 1}
+		results, advanceTo, err := b.collect(ctx, provider, keyword, state.window, &claimed, &searched)
+		searched.Duration = time.Since(started)
+		if err != nil {
+			searched.Failed = 1
+			searched.Skipped = searched.Found
+			searched.Errors = []error{fmt.Errorf("keyword %q: %w", keyword, err)}
+		}
+		report.add(searched)
+		if b.Metrics != nil {
+			b.Metrics.RecordSearch(keyword, searched)
+		}
 		if err != nil {
-			log.Error("Error searching platform", "platform", provider.Platform(), "error", err)
 			continue
 		}
+		report.addResults(slices.DeleteFunc(slices.Clone(results), func(result search.SearchResult) bool { return result.Edited }))
+		pending = append(pending, b.route(results, provider)...)
+		if advanceTo > 0 {
+			advances = append(advances, lastSearchAdvance{platform: provider.Platform(), keyword: keyword, to: advanceTo})
+		}
+	}
 
-		for _, result := range results {
-			exists, err := b.Storer.Exists(result.Platform, result.URL)
-			if err != nil {
-				log.Error("Error checking existence in storage", "platform", result.Platform, "url", result.URL, "error", err)
-				continue
-			}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.pending = append(state.pending, pending...)
+	state.advances = append(state.advances, advances...)
+}
 
-			if exists {
-				log.Debug("Skipping existing result", "title", result.Title, "url", result.URL, "platform", result.Platform)
-				continue
-			}
+// route pairs saved results with the notifiers routed to them and the checkers of provider, which may be
+// nil. Routing happens after the pipeline so processors can influence it.
+func (b *Bot) route(results []search.SearchResult, provider search.Searcher) []pendingResult {
+	checker, _ := provider.(search.ActivityChecker)
+	deletions, _ := provider.(search.DeletionChecker)
+	threads, _ := provider.(search.ThreadFetcher)
+	router := b.settings().Router
+	pending := make([]pendingResult, 0, len(results))
+	for _, result := range results {
+		notifiers, priority := router.Route(result)
+		result.Priority = priority
+		pending = append(pending, pendingResult{result: result, notifiers: notifiers, checker: checker, deletions: deletions, threads: threads})
+	}
+	return pending
+}
+
+// notifyPending groups, unfurls, adds thread context to, summarizes, and notifies saved results, following their discussions when
+// enabled. Nothing is notified when SummaryOnly is set. The results are already saved and would never be
+// notified later, so this carries on when ctx is cancelled, with each step bounded by NotifyTimeout instead.
+func (b *Bot) notifyPending(ctx context.Context, pending []pendingResult) {
+	if b.SummaryOnly {
+		for _, p := range pending {
+			log.Debug("Not notifying result; only run summaries are sent", "platform", p.result.Platform, "url", p.result.URL)
+		}
+		return
+	}
+
+	notifyCtx := context.WithoutCancel(ctx)
+	groupCtx, cancel := withTimeout(notifyCtx, b.NotifyTimeout)
+	grouped := b.group(groupCtx, pending)
+	cancel()
+	for _, p := range grouped {
+		p.result.Keywords = b.claimedKeywords(p.result.Platform, p.result.URL)
+		p.result.Preview = b.unfurl(notifyCtx, p.result)
+		p.result.Parent = b.parent(notifyCtx, p)
+		p.result.Summary = b.summarize(notifyCtx, p.result)
+		b.notify(notifyCtx, p.result, p.notifiers)
+		if p.notifiers == nil || len(p.notifiers) > 0 {
+			b.follow(notifyCtx, p)
+			b.watch(p)
+		}
+	}
+	// Queued notifications are retried by later deliveries, so these stop with ctx
+	b.DeliverOutbox(ctx)
+}
+
+// lastSearchAdvance is the time a platform and keyword's last search time moves to after a successful
+// search.
+type lastSearchAdvance struct {
+	platform string
+	keyword  string
+	to       int64
+}
+
+// collect runs one platform's results through the pipeline up to and including storage, returning the
+// saved results posted within window for notification and counting them in searched. Results are claimed (and appended to
+// claimed) so concurrent searches skip them until the caller releases them. It also returns the time to
+// advance the last search time to: the newest searched result's timestamp, or zero to leave it unchanged
+// when nothing newer was found, a result could not be checked, or the search dropped results at its
+// result limit. It returns an error if a stage failed.
+func (b *Bot) collect(ctx context.Context, provider search.Searcher, keyword string, window timeRange, claimed *[]search.SearchResult, searched *PlatformReport) ([]search.SearchResult, int64, error) {
+	release, err := b.acquire(ctx, provider.Platform())
+	if err != nil {
+		log.Warn("Run cancelled", "platform", provider.Platform(), "keyword", keyword, "error", err)
+		return nil, 0, err
+	}
+	defer release()
+
+	searchFrom, lastSearchTime, backfill, err := b.searchFrom(ctx, provider.Platform(), keyword, window.since)
+	if err != nil {
+		log.Error("Error retrieving last search time", "platform", provider.Platform(), "keyword", keyword, "error", err)
+		return nil, 0, fmt.Errorf("failed to retrieve last search time: %w", err)
+	}
+	if backfill {
+		log.Info("Backfilling results", "platform", provider.Platform(), "keyword", keyword, "since", time.Unix(searchFrom, 0).Format(time.RFC3339))
+	}
+
+	batch := newPipelineBatch(keyword, claimed)
+	batch.until = window.until
+	results, err := b.searchStage(ctx, provider, keyword, searchFrom, batch)
+	if err == nil {
+		searched.Found = len(results)
+		results, err = b.pipeline(ctx, results, batch)
+	}
+	if err != nil {
+		log.Error("Error processing results", "platform", provider.Platform(), "keyword", keyword, "error", err)
+		return nil, 0, err
+	}
+	for _, result := range results {
+		if !result.Edited {
+			searched.New++
+		}
+	}
+	searched.Skipped = max(searched.Found-searched.New, 0)
+
+	// Never advance past a result that wasn't checked, or into the future on a skewed platform clock
+	var advanceTo int64
+	newest := batch.newest
+	if now := time.Now().Unix(); newest > now {
+		newest = now
+	}
+	if batch.incomplete {
+		log.Warn("Not advancing last search time past unchecked or dropped results", "platform", provider.Platform(), "keyword", keyword)
+	} else if !window.since.IsZero() || !window.until.IsZero() {
+		log.Debug("Not advancing last search time for a time range search", "platform", provider.Platform(), "keyword", keyword)
+	} else if newest > lastSearchTime {
+		advanceTo = newest
+	}
+
+	if backfill && b.BackfillMarkSeen {
+		log.Info("Marked backfilled results as seen", "platform", provider.Platform(), "keyword", keyword, "count", len(results))
+		return nil, advanceTo, nil
+	}
+	return results, advanceTo, nil
+}
+
+// exists checks storage for a result under its canonical URL and, when it differs, the URL the platform
+// returned, since results saved before URLs were normalized are stored as returned.
+func (b *Bot) exists(ctx context.Context, platform, canonicalURL, originalURL string) (bool, error) {
+	exists, err := b.Storer.Exists(ctx, platform, canonicalURL)
+	if err != nil || exists || originalURL == canonicalURL {
+		return exists, err
+	}
+	return b.Storer.Exists(ctx, platform, originalURL)
+}
+
+// group merges results sharing a content hash into a single notification listing every copy, and drops
+// results whose story was already saved from another platform within DuplicateWindow, since that copy
+// was notified by an earlier run. Grouping is disabled when DuplicateWindow is zero.
+func (b *Bot) group(ctx context.Context, pending []pendingResult) []pendingResult {
+	if b.DuplicateWindow <= 0 {
+		return pending
+	}
+
+	current := make(map[string]bool, len(pending))
+	for _, p := range pending {
+		current[p.result.Platform+"\x00"+p.result.URL] = true
+	}
 
-			log.Info("New result", "platform", result.Platform, "title", result.Title, "url", result.URL)
+	var grouped []pendingResult
+	leads := make(map[string]int)
+	for _, p := range pending {
+		hash := p.result.ContentHash
+		if hash == "" {
+			grouped = append(grouped, p)
+			continue
+		}
 
-			err = b.Storer.Save(result)
-			if err != nil {
-				log.Error("Error saving to storage", "platform", result.Platform, "title", result.Title, "url", result.URL, "error", err)
-				continue
+		if i, ok := leads[hash]; ok {
+			if i >= 0 {
+				grouped[i].result.Duplicates = append(grouped[i].result.Duplicates, p.result)
+				log.Info("Grouping duplicate result", "platform", p.result.Platform, "url", p.result.URL, "duplicate_of", grouped[i].result.URL)
 			}
+			continue
+		}
 
-			for _, notifier := range b.Notifiers {
-				if err := notifier.Notify(result); err != nil {
-					log.Error("Error notifying", "platform", result.Platform, "title", result.Title, "url", result.URL, "error", err)
-				}
+		earlier, err := b.Storer.FindByContentHash(ctx, hash, time.Now().Add(-b.DuplicateWindow))
+		if err != nil {
+			log.Error("Error looking up duplicate results", "platform", p.result.Platform, "url", p.result.URL, "error", err)
+		}
+		duplicateOf := ""
+		for _, e := range earlier {
+			if !current[e.Platform+"\x00"+e.URL] {
+				duplicateOf = e.URL
+				break
 			}
 		}
+		if duplicateOf != "" {
+			log.Info("Skipping duplicate of an earlier result", "platform", p.result.Platform, "url", p.result.URL, "duplicate_of", duplicateOf)
+			leads[hash] = -1
+			continue
+		}
+
+		leads[hash] = len(grouped)
+		grouped = append(grouped, p)
+	}
+	return grouped
+}
+
+// acquire waits for a free search slot on a platform, returning a func that releases it.
+func (b *Bot) acquire(ctx context.Context, platform string) (func(), error) {
+	b.mu.Lock()
+	slot, ok := b.slots[platform]
+	if !ok {
+		size := b.PlatformConcurrency
+		if size < 1 {
+			size = 1
+		}
+		slot = make(chan struct{}, size)
+		b.slots[platform] = slot
+	}
+	b.mu.Unlock()
+
+	select {
+	case slot <- struct{}{}:
+		return func() { <-slot }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// claim marks a result found by keyword as being processed so concurrent searches don't store and notify
+// it twice. It reports false if the result is already claimed, adding keyword to the keywords it matched.
+func (b *Bot) claim(platform, url, keyword string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := platform + "\x00" + url
+	keywords, claimed := b.claims[key]
+	if keyword != "" && !slices.Contains(keywords, keyword) {
+		keywords = append(keywords, keyword)
+	}
+	b.claims[key] = keywords
+	return !claimed
+}
+
+// claimedKeywords returns the keywords that have found a claimed result so far.
+func (b *Bot) claimedKeywords(platform, url string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.claims[platform+"\x00"+url])
+}
+
+// release records the keywords that found claimed results, for storers that track them, and releases the
+// claims once the results have been saved or skipped.
+func (b *Bot) release(ctx context.Context, claimed []search.SearchResult) {
+	if len(claimed) == 0 {
+		return
+	}
+	b.mu.Lock()
+	var matched []search.SearchResult
+	for _, result := range claimed {
+		key := result.Platform + "\x00" + result.URL
+		result.Keywords = b.claims[key]
+		delete(b.claims, key)
+		if len(result.Keywords) > 0 {
+			matched = append(matched, result)
+		}
+	}
+	b.mu.Unlock()
+
+	recorder, ok := storage.AsKeywordRecorder(b.Storer)
+	if !ok || len(matched) == 0 {
+		return
+	}
+	if err := recorder.AddKeywords(context.WithoutCancel(ctx), matched); err != nil {
+		log.Error("Error recording matched keywords", "results", len(matched), "error", err)
+	}
+}
+
+// lastSearchKey is the storage key for a platform and keyword's last search time. Tracking keywords
+// separately keeps one keyword's run from hiding results another keyword has not searched for yet.
+func lastSearchKey(platform, keyword string) string {
+	return platform + ":" + keyword
+}
+
+// searchFrom returns the time a platform should be searched from for a keyword, its stored last search
+// time, and whether the search is a backfill. A non-zero since takes precedence over both; otherwise each
+// platform and keyword is backfilled once, on its first search.
+func (b *Bot) searchFrom(ctx context.Context, platform, keyword string, since time.Time) (int64, int64, bool, error) {
+	lastSearchTime, err := b.lastSearchTime(ctx, platform, keyword)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	if !since.IsZero() {
+		return since.Unix(), lastSearchTime, false, nil
+	}
+
+	if b.Backfill > 0 {
+		key := lastSearchKey(platform, keyword)
+		b.mu.Lock()
+		backfill := !b.backfilled[key]
+		b.backfilled[key] = true
+		b.mu.Unlock()
+		if backfill {
+			return time.Now().Add(-b.Backfill).Unix(), lastSearchTime, true, nil
+		}
+	}
+
+	return lastSearchTime, lastSearchTime, false, nil
+}
+
+// lastSearchTime returns when a keyword was last searched on a platform, falling back to the
+// platform-wide time recorded by earlier versions so upgrading does not re-notify old results.
+func (b *Bot) lastSearchTime(ctx context.Context, platform, keyword string) (int64, error) {
+	lastSearchTime, err := b.Storer.GetLastSearchTime(ctx, lastSearchKey(platform, keyword))
+	if err != nil || lastSearchTime != 0 {
+		return lastSearchTime, err
+	}
+	return b.Storer.GetLastSearchTime(ctx, platform)
+}
+
+// notify delivers a result to the named notifiers, or to every notifier when names is nil. With an outbox,
+// the notifications are queued for the next DeliverOutbox instead, falling back to direct delivery if they
+// can't be queued.
+func (b *Bot) notify(ctx context.Context, result search.SearchResult, names []string) {
+	notifiers := b.CurrentNotifiers()
+	if names == nil {
+		for name := range notifiers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	if len(names) == 0 {
+		log.Debug("No notifiers routed for result", "platform", result.Platform, "title", result.Title, "url", result.URL)
+		return
+	}
+
+	if b.Outbox != nil {
+		enqueueCtx, cancel := withTimeout(ctx, b.NotifyTimeout)
+		err := b.enqueue(enqueueCtx, result, names)
+		cancel()
+		if err == nil {
+			return
+		}
+		log.Error("Error queueing notification; delivering directly", "platform", result.Platform, "title", result.Title, "url", result.URL, "error", err)
+	}
 
-		if err := b.Storer.SetLastSearchTime(provider.Platform(), time.Now().Unix()); err != nil {
-			log.Error("Error setting last search time", "platform", provider.Platform(), "error", err)
+	for _, name := range names {
+		notifier, ok := notifiers[name]
+		if !ok {
+			continue
+		}
+		notifyCtx, cancel := withTimeout(ctx, b.NotifyTimeout)
+		err := notifier.Notify(notifyCtx, result)
+		cancel()
+		if err != nil {
+			log.Error("Error notifying", "notifier", name, "platform", result.Platform, "title", result.Title, "url", result.URL, "error", err)
+			b.emit(Event{Type: EventNotificationFailed, Result: &result, Notifier: name, Err: err})
+			continue
 		}
+		b.emit(Event{Type: EventResultNotified, Result: &result, Notifier: name})
+	}
+}
+
+// summarize returns a summary of the result's content, or an empty string when there is no summarizer,
+// the content is too short, or summarization fails. Failures are logged so the result is still notified.
+func (b *Bot) summarize(ctx context.Context, result search.SearchResult) string {
+	if b.Summarizer == nil {
+		return ""
+	}
+
+	summarizeCtx, cancel := withTimeout(ctx, b.NotifyTimeout)
+	defer cancel()
+	summary, err := b.Summarizer.Summarize(summarizeCtx, result)
+	if err != nil {
+		log.Error("Error summarizing result", "platform", result.Platform, "url", result.URL, "error", err)
+		return ""
+	}
+	return summary
+}
+
+// unfurl previews the page a link post points to, or returns nil when unfurling is disabled, the result
+// isn't a link post, or the page can't be unfurled.
+func (b *Bot) unfurl(ctx context.Context, result search.SearchResult) *search.LinkPreview {
+	if b.Unfurler == nil || result.Link == "" {
+		return nil
+	}
+
+	unfurlCtx, cancel := withTimeout(ctx, b.NotifyTimeout)
+	defer cancel()
+	preview, err := b.Unfurler.Unfurl(unfurlCtx, result.Link)
+	if err != nil {
+		log.Debug("Error unfurling link", "platform", result.Platform, "url", result.URL, "link", result.Link, "error", err)
+		return nil
+	}
+	return preview
+}
+
+// parent fetches the post a pending result replies to, or returns nil when thread context is disabled, its
+// searcher can't fetch parents, the result isn't a reply, or the parent can't be fetched.
+func (b *Bot) parent(ctx context.Context, p pendingResult) *search.ParentPost {
+	if !b.ThreadContext || p.threads == nil {
+		return nil
+	}
+
+	parentCtx, cancel := withTimeout(ctx, b.NotifyTimeout)
+	defer cancel()
+	parent, err := p.threads.Parent(parentCtx, p.result)
+	if err != nil {
+		log.Debug("Error fetching parent post", "platform", p.result.Platform, "url", p.result.URL, "error", err)
+		return nil
+	}
+	return parent
+}
+
+// withTimeout derives a context bounded by timeout, or a cancellable copy of ctx when timeout is zero.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, timeout)
 }
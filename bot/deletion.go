@@ -0,0 +1,113 @@
+// bot/deletion.go
+package bot
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+// watchedResult is a notified result being checked for deletion.
+type watchedResult struct {
+	result    search.SearchResult
+	checker   search.DeletionChecker
+	notifiers []string
+	until     time.Time
+}
+
+// watch starts checking a notified result for deletion when deletion checks are enabled and its searcher
+// can tell whether results were deleted.
+func (b *Bot) watch(p pendingResult) {
+	if b.DeletionWindow <= 0 || p.deletions == nil {
+		return
+	}
+
+	result := p.result
+	result.Duplicates = nil
+	b.mu.Lock()
+	b.watched[result.Platform+"\x00"+result.URL] = &watchedResult{
+		result:    result,
+		checker:   p.deletions,
+		notifiers: p.notifiers,
+		until:     time.Now().Add(b.DeletionWindow),
+	}
+	b.mu.Unlock()
+}
+
+// CheckDeletions re-checks every watched result and records those that have been deleted or removed in
+// storage, sending a follow-up notification for each when NotifyDeletions is set. Results are no longer
+// checked once they are found deleted or DeletionWindow has passed since they were notified.
+func (b *Bot) CheckDeletions(ctx context.Context) {
+	b.mu.Lock()
+	now := time.Now()
+	keys := make([]string, 0, len(b.watched))
+	for key, w := range b.watched {
+		if now.After(w.until) {
+			delete(b.watched, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	b.mu.Unlock()
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return
+		}
+
+		b.mu.Lock()
+		w, ok := b.watched[key]
+		b.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		searchCtx, cancel := withTimeout(ctx, b.SearchTimeout)
+		deleted, err := w.checker.Deleted(searchCtx, w.result)
+		cancel()
+		if err != nil {
+			log.Error("Error checking for deletion", "platform", w.result.Platform, "url", w.result.URL, "error", err)
+			continue
+		}
+		if !deleted {
+			continue
+		}
+
+		b.mu.Lock()
+		delete(b.watched, key)
+		b.mu.Unlock()
+
+		result := w.result
+		result.DeletedAt = time.Now().Unix()
+		log.Info("Result deleted", "platform", result.Platform, "title", result.Title, "url", result.URL)
+		b.markDeleted(ctx, result)
+		if b.NotifyDeletions {
+			result.Title = "Deleted: " + result.Title
+			b.notify(ctx, result, w.notifiers)
+		}
+	}
+
+	b.DeliverOutbox(ctx)
+	b.flushDigests(ctx, false)
+}
+
+// markDeleted records a result's deletion time in storage, if the storer can update results.
+func (b *Bot) markDeleted(ctx context.Context, result search.SearchResult) {
+	updater, ok := storage.AsUpdater(b.Storer)
+	if !ok {
+		return
+	}
+	stored, found, err := updater.Get(ctx, result.Platform, result.URL)
+	if err == nil && found {
+		stored.Platform, stored.URL, stored.DeletedAt = result.Platform, result.URL, result.DeletedAt
+		err = updater.Update(ctx, stored)
+	}
+	if err != nil {
+		log.Error("Error recording deleted result", "platform", result.Platform, "url", result.URL, "error", err)
+	}
+}
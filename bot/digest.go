@@ -0,0 +1,153 @@
+// bot/digest.go
+package bot
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Digest is a batch of results sent as a single message. It is the data digest templates render.
+type Digest struct {
+	Results []search.SearchResult
+	// Groups holds the same results grouped by platform and keyword, sorted by platform then keyword.
+	Groups []DigestGroup
+}
+
+// DigestGroup is the results from one platform for one keyword.
+type DigestGroup struct {
+	Platform string
+	Keyword  string
+	Results  []search.SearchResult
+}
+
+// NewDigest groups results by platform and keyword, keeping their order within each group.
+func NewDigest(results []search.SearchResult) Digest {
+	digest := Digest{Results: results}
+	index := make(map[[2]string]int)
+	for _, result := range results {
+		key := [2]string{result.Platform, result.Keyword}
+		i, ok := index[key]
+		if !ok {
+			i = len(digest.Groups)
+			index[key] = i
+			digest.Groups = append(digest.Groups, DigestGroup{Platform: result.Platform, Keyword: result.Keyword})
+		}
+		digest.Groups[i].Results = append(digest.Groups[i].Results, result)
+	}
+
+	sort.SliceStable(digest.Groups, func(i, j int) bool {
+		if digest.Groups[i].Platform != digest.Groups[j].Platform {
+			return digest.Groups[i].Platform < digest.Groups[j].Platform
+		}
+		return digest.Groups[i].Keyword < digest.Groups[j].Keyword
+	})
+	return digest
+}
+
+// Priority returns the highest priority of any result in the digest, which decides its mentions.
+func (d Digest) Priority() search.Priority {
+	var priority search.Priority
+	for _, result := range d.Results {
+		if result.Priority.Rank() > priority.Rank() {
+			priority = result.Priority
+		}
+	}
+	return priority
+}
+
+// DigestSender is implemented by notifiers that can send a whole digest as one message.
+type DigestSender interface {
+	NotifyDigest(ctx context.Context, digest Digest) error
+}
+
+// DigestNotifier buffers results for a notifier and sends them together as a digest when flushed.
+// Notifiers that are not DigestSenders receive the buffered results one at a time instead.
+type DigestNotifier struct {
+	notifier Notifier
+	window   time.Duration
+
+	mu      sync.Mutex
+	pending []search.SearchResult
+	since   time.Time
+}
+
+// NewDigestNotifier wraps a notifier so its results are batched. A zero window sends a digest at the end
+// of every run; otherwise results are collected until window has passed since the first one.
+func NewDigestNotifier(notifier Notifier, window time.Duration) *DigestNotifier {
+	return &DigestNotifier{notifier: notifier, window: window}
+}
+
+// Unwrap returns the wrapped notifier.
+func (d *DigestNotifier) Unwrap() Notifier {
+	return d.notifier
+}
+
+// Notify buffers the result until the next flush.
+func (d *DigestNotifier) Notify(ctx context.Context, result search.SearchResult) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.pending) == 0 {
+		d.since = time.Now()
+	}
+	d.pending = append(d.pending, result)
+	return nil
+}
+
+// Flush sends the buffered results once the window has passed, or immediately when force is set. Results
+// that fail to send are buffered again for the next flush. Flushing also flushes the wrapped notifier if it
+// holds results back itself, such as a ThrottleNotifier.
+func (d *DigestNotifier) Flush(ctx context.Context, force bool) error {
+	err := d.flush(ctx, force)
+	if inner, ok := d.notifier.(flusher); ok {
+		err = errors.Join(err, inner.Flush(ctx, force))
+	}
+	return err
+}
+
+func (d *DigestNotifier) flush(ctx context.Context, force bool) error {
+	d.mu.Lock()
+	if len(d.pending) == 0 || (!force && time.Since(d.since) < d.window) {
+		d.mu.Unlock()
+		return nil
+	}
+	results, since := d.pending, d.since
+	d.pending = nil
+	d.mu.Unlock()
+
+	if sender, ok := d.notifier.(DigestSender); ok {
+		if err := sender.NotifyDigest(ctx, NewDigest(results)); err != nil {
+			d.requeue(results, since)
+			return err
+		}
+		return nil
+	}
+
+	var errs []error
+	var failed []search.SearchResult
+	for _, result := range results {
+		if err := d.notifier.Notify(ctx, result); err != nil {
+			errs = append(errs, err)
+			failed = append(failed, result)
+		}
+	}
+	d.requeue(failed, since)
+	return errors.Join(errs...)
+}
+
+// requeue puts results that failed to send back at the front of the buffer.
+func (d *DigestNotifier) requeue(results []search.SearchResult, since time.Time) {
+	if len(results) == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending = append(results, d.pending...)
+	d.since = since
+}
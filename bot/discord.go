@@ -2,67 +2,297 @@
 package bot
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/charmbracelet/log"
+	"github.com/gorilla/websocket"
+	"github.com/jaxxstorm/grass/internal/httpclient"
+	"github.com/jaxxstorm/grass/internal/proxy"
 	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
 )
 
 type DiscordNotifier struct {
-	session   *discordgo.Session
-	channelID string
+	session    *discordgo.Session
+	channelIDs []string
+	template   *MessageTemplate
+	digest     *MessageTemplate
+	mentions   Mentions
+	triage     Triager
 }
 
-func NewDiscordNotifier() *DiscordNotifier {
+const (
+	// discordMessageLimit is the most characters Discord accepts in one message.
+	discordMessageLimit = 2000
+	// discordButtonURLLimit is the longest URL Discord accepts for a link button. Results with longer URLs
+	// are sent without triage buttons.
+	discordButtonURLLimit = 512
+	// discordTriagePrefix starts the custom ID of each triage button, followed by the state it sets and the
+	// result's platform. The result's URL is taken from the message's link button, since custom IDs are
+	// too short to hold it.
+	discordTriagePrefix = "grass_triage:"
+	// discordTriageNote starts the line saying who last triaged a message.
+	discordTriageNote = "\n-# "
+	// discordTriageTimeout bounds handling a triage button press, from deferring the response to editing the
+	// message.
+	discordTriageTimeout = 10 * time.Second
+)
+
+// NewDiscordNotifier creates a Discord notifier posting as DISCORD_BOT_TOKEN to the channels given with
+// WithChannels, or those in DISCORD_CHANNEL_ID, and opens its gateway connection. Messages use
+// DefaultDiscordTemplate and DefaultDiscordDigestTemplate unless WithTemplate or WithDigestTemplate say
+// otherwise, and mentions given with WithMentions are prepended according to each result's priority. API
+// requests are sent with the client given with WithHTTPClient, or discordgo's default client. With
+// WithTriage, messages of single results have triage buttons, whose presses arrive over the gateway.
+func NewDiscordNotifier(opts ...NotifierOption) (*DiscordNotifier, error) {
+	o := newNotifierOptions(opts)
 	token := os.Getenv("DISCORD_BOT_TOKEN")
-	channelID := os.Getenv("DISCORD_CHANNEL_ID")
+	channelIDs := o.channelIDs
+	if len(channelIDs) == 0 {
+		channelIDs = parseChannelIDs(os.Getenv("DISCORD_CHANNEL_ID"))
+	}
 
 	if token == "" {
-		log.Fatal("Environment variable not set", "variable", "DISCORD_BOT_TOKEN")
+		return nil, errors.New("DISCORD_BOT_TOKEN environment variable is not set")
 	}
-	if channelID == "" {
-		log.Fatal("Environment variable not set", "variable", "DISCORD_CHANNEL_ID")
+	if len(channelIDs) == 0 {
+		return nil, errors.New("DISCORD_CHANNEL_ID environment variable is not set")
 	}
 
 	session, err := discordgo.New("Bot " + token)
 	if err != nil {
-		log.Fatal("Failed to create Discord session", "error", err)
+		return nil, fmt.Errorf("failed to create Discord session: %w", err)
+	}
+	if o.client != nil {
+		session.Client = httpclient.WithTimeout(o.client, session.Client.Timeout)
+	}
+	// The gateway dials its websocket separately from the REST client
+	dialer := *websocket.DefaultDialer
+	dialer.Proxy = proxy.Default()
+	session.Dialer = &dialer
+
+	d := &DiscordNotifier{session: session, channelIDs: channelIDs, mentions: o.mentions, triage: o.triage}
+	if d.triage != nil {
+		session.AddHandler(d.handleInteraction)
+	}
+	if err := session.Open(); err != nil {
+		return nil, fmt.Errorf("failed to open connection to Discord: %w", err)
 	}
 
-	err = session.Open()
+	if o.template == nil {
+		o.template = mustParseTemplate("discord", DefaultDiscordTemplate)
+	}
+	if o.digestTemplate == nil {
+		o.digestTemplate = mustParseTemplate("discord digest", DefaultDiscordDigestTemplate)
+	}
+
+	d.template, d.digest = o.template, o.digestTemplate
+	return d, nil
+}
+
+// Notify sends a formatted message with markdown to each configured Discord channel.
+func (d *DiscordNotifier) Notify(ctx context.Context, result search.SearchResult) error {
+	message, err := d.template.Render(result)
 	if err != nil {
-		log.Fatal("Error opening connection to Discord", "error", err)
+		log.Error("Failed to render Discord message", "title", result.Title, "url", result.URL, "error", err)
+		return err
 	}
+	message = d.mentions.prefix(result.Priority, discordMention) + message
+	send := &discordgo.MessageSend{Content: message, Components: d.triageComponents(result)}
+
+	// Send the markdown-formatted message to every channel, reporting all failures
+	var errs []error
+	for _, channelID := range d.channelIDs {
+		_, err := d.session.ChannelMessageSendComplex(channelID, send, discordgo.WithContext(ctx))
+		if err != nil {
+			log.Error("Failed to send message to Discord", "channel", channelID, "title", result.Title, "url", result.URL, "error", err)
+			errs = append(errs, fmt.Errorf("channel %s: %w", channelID, err))
+			continue
+		}
 
-	return &DiscordNotifier{session: session, channelID: channelID}
+		log.Info("Posted to Discord", "channel", channelID, "title", result.Title, "url", result.URL)
+	}
+
+	return errors.Join(errs...)
 }
 
-// Notify sends a formatted message with markdown to the specified Discord channel.
-func (d *DiscordNotifier) Notify(result search.SearchResult) error {
-	// Convert Unix timestamp to a human-readable format
-	timestamp := time.Unix(result.Timestamp, 0).Format("01/02/2006 03:04 PM")
-
-	// Format the message using markdown
-	message := fmt.Sprintf(
-		"**%s**\n*Platform*: %s\n*Keyword*: %s\n*Posted*: %s\n%s\n%s",
-		result.Title,    // Bold title
-		result.Platform, // Platform name
-		result.Keyword,  // Keyword
-		timestamp,       // Human-readable timestamp
-		result.Content,  // Content of the post
-		result.URL,      // URL (should unfurl automatically)
-	)
-
-	// Send the markdown-formatted message
-	_, err := d.session.ChannelMessageSend(d.channelID, message)
+// NotifyDigest sends a digest of several results to each configured Discord channel, split across as few
+// messages as Discord's length limit allows.
+func (d *DiscordNotifier) NotifyDigest(ctx context.Context, digest Digest) error {
+	message, err := d.digest.RenderDigest(digest)
 	if err != nil {
-		log.Error("Failed to send message to Discord", "title", result.Title, "url", result.URL, "error", err)
+		log.Error("Failed to render Discord digest", "results", len(digest.Results), "error", err)
 		return err
 	}
+	message = d.mentions.prefix(digest.Priority(), discordMention) + message
+
+	var errs []error
+	for _, channelID := range d.channelIDs {
+		var err error
+		for _, part := range splitMessage(message, discordMessageLimit) {
+			if _, err = d.session.ChannelMessageSend(channelID, part, discordgo.WithContext(ctx)); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Error("Failed to send digest to Discord", "channel", channelID, "results", len(digest.Results), "error", err)
+			errs = append(errs, fmt.Errorf("channel %s: %w", channelID, err))
+			continue
+		}
+
+		log.Info("Posted digest to Discord", "channel", channelID, "results", len(digest.Results))
+	}
+
+	return errors.Join(errs...)
+}
 
-	log.Info("Posted to Discord", "title", result.Title, "url", result.URL)
-	return nil
+// NotifyText sends a plain text message to each configured Discord channel.
+func (d *DiscordNotifier) NotifyText(ctx context.Context, text string) error {
+	var errs []error
+	for _, channelID := range d.channelIDs {
+		if _, err := d.session.ChannelMessageSend(channelID, text, discordgo.WithContext(ctx)); err != nil {
+			log.Error("Failed to send message to Discord", "channel", channelID, "error", err)
+			errs = append(errs, fmt.Errorf("channel %s: %w", channelID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close disconnects the notifier from the Discord gateway, over which it receives button presses.
+func (d *DiscordNotifier) Close() error {
+	return d.session.Close()
+}
+
+// triageComponents returns triage buttons for a result, and a link button holding its URL, or nil if the
+// notifier has no triage buttons or the URL doesn't fit in a link button.
+func (d *DiscordNotifier) triageComponents(result search.SearchResult) []discordgo.MessageComponent {
+	if d.triage == nil || result.URL == "" || len(result.URL) > discordButtonURLLimit {
+		return nil
+	}
+	buttons := make([]discordgo.MessageComponent, 0, len(TriageActions)+1)
+	for _, action := range TriageActions {
+		style := discordgo.SecondaryButton
+		switch action.State {
+		case storage.StateAcknowledged:
+			style = discordgo.PrimaryButton
+		case storage.StateEscalated:
+			style = discordgo.DangerButton
+		}
+		buttons = append(buttons, discordgo.Button{
+			Label:    action.Label,
+			Style:    style,
+			CustomID: discordTriagePrefix + action.State + ":" + result.Platform,
+		})
+	}
+	buttons = append(buttons, discordgo.Button{Label: "Open", Style: discordgo.LinkButton, URL: result.URL})
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// handleInteraction sets the state of the result whose triage button was pressed, then notes who pressed it
+// on the message, or tells them privately why it failed. Discord needs a response within three seconds,
+// so the update is deferred before the state is set and the message is edited afterwards.
+func (d *DiscordNotifier) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent || i.Message == nil {
+		return
+	}
+	id, ok := strings.CutPrefix(i.MessageComponentData().CustomID, discordTriagePrefix)
+	if !ok {
+		return
+	}
+	state, platform, ok := strings.Cut(id, ":")
+	if !ok {
+		return
+	}
+	url := discordLinkURL(i.Message.Components)
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), discordTriageTimeout)
+	defer cancel()
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		log.Error("Failed to respond to Discord interaction", "error", err)
+		return
+	}
+
+	found, err := d.triage(ctx, platform, url, state)
+	if err == nil && !found {
+		err = errors.New("the result is no longer stored")
+	}
+	if err != nil {
+		log.Error("Failed to triage result from Discord", "platform", platform, "url", url, "state", state, "error", err)
+		_, err = s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
+			Content: fmt.Sprintf("Couldn't mark the result %s: %v", state, err),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		}, discordgo.WithContext(ctx))
+		if err != nil {
+			log.Error("Failed to respond to Discord interaction", "error", err)
+		}
+		return
+	}
+
+	who := "someone"
+	if user != nil {
+		who = user.Mention()
+	}
+	log.Info("Triaged result from Discord", "platform", platform, "url", url, "state", state)
+	content, _, _ := strings.Cut(i.Message.Content, discordTriageNote)
+	if note := discordTriageNote + TriagedText(state, who); utf8.RuneCountInString(content+note) <= discordMessageLimit {
+		content += note
+	}
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:         &content,
+		Components:      &i.Message.Components,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		log.Error("Failed to update Discord message", "error", err)
+	}
+}
+
+// discordLinkURL returns the URL of the first link button among components.
+func discordLinkURL(components []discordgo.MessageComponent) string {
+	for _, component := range components {
+		switch c := component.(type) {
+		case *discordgo.ActionsRow:
+			if url := discordLinkURL(c.Components); url != "" {
+				return url
+			}
+		case *discordgo.Button:
+			if c.Style == discordgo.LinkButton {
+				return c.URL
+			}
+		}
+	}
+	return ""
+}
+
+// splitMessage breaks a message into parts of at most limit characters, splitting between lines where
+// possible.
+func splitMessage(message string, limit int) []string {
+	var parts []string
+	for utf8.RuneCountInString(message) > limit {
+		runes := []rune(message)
+		cut := strings.LastIndex(string(runes[:limit]), "\n")
+		if cut <= 0 {
+			cut = len(string(runes[:limit]))
+		}
+		parts = append(parts, message[:cut])
+		message = strings.TrimLeft(message[cut:], "\n")
+	}
+	if message != "" {
+		parts = append(parts, message)
+	}
+	return parts
 }
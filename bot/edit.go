@@ -0,0 +1,61 @@
+// bot/edit.go
+package bot
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+// EditModes are the accepted values for edit detection: off, store (save edits to stored results without
+// notifying them), or notify (also notify edited results again, marked as updated).
+var EditModes = []string{"off", "store", "notify"}
+
+// DefaultEditThreshold is the fraction of a result's words that must change for it to count as edited,
+// so typo fixes and formatting changes are ignored.
+const DefaultEditThreshold = 0.2
+
+// edited reports whether a stored result's post has been edited since it was saved, by comparing it with
+// the stored version. Results are never considered edited when edit detection is off or the storer can't
+// read back results.
+func (b *Bot) edited(ctx context.Context, result search.SearchResult) bool {
+	if b.EditDetection == "" || b.EditDetection == "off" {
+		return false
+	}
+	updater, ok := storage.AsUpdater(b.Storer)
+	if !ok {
+		return false
+	}
+
+	stored, found, err := updater.Get(ctx, result.Platform, result.URL)
+	if err != nil {
+		log.Warn("Error reading stored result to check for edits", "platform", result.Platform, "url", result.URL, "error", err)
+		return false
+	}
+	if !found || stored.ContentHash == result.ContentHash || stored.Title+stored.Content == "" {
+		return false
+	}
+
+	changed := search.ChangedFraction(stored, result)
+	if changed < b.EditThreshold {
+		return false
+	}
+	log.Info("Result edited", "platform", result.Platform, "title", result.Title, "url", result.URL, "changed", changed)
+	return true
+}
+
+// updateEdited saves the new version of edited results.
+func (b *Bot) updateEdited(ctx context.Context, results []search.SearchResult) error {
+	updater, ok := storage.AsUpdater(b.Storer)
+	if !ok {
+		return nil
+	}
+	for _, result := range results {
+		if err := updater.Update(ctx, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// bot/elasticsearch.go
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/internal/elastic"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// ElasticsearchNotifier indexes each result into an Elasticsearch or OpenSearch index.
+type ElasticsearchNotifier struct {
+	client *elastic.Client
+	index  string
+}
+
+type elasticsearchDocument struct {
+	Platform  string            `json:"platform"`
+	Keyword   string            `json:"keyword"`
+	Title     string            `json:"title"`
+	URL       string            `json:"url"`
+	Content   string            `json:"content,omitempty"`
+	Author    string            `json:"author,omitempty"`
+	Score     int64             `json:"score"`
+	Comments  int64             `json:"comments"`
+	Reposts   int64             `json:"reposts"`
+	Views     int64             `json:"views"`
+	Tags      []string          `json:"tags,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Priority  string            `json:"priority,omitempty"`
+	Timestamp int64             `json:"timestamp"`
+	IndexedAt int64             `json:"indexed_at"`
+}
+
+// NewElasticsearchNotifier initializes the notifier from the environment and ensures the index exists,
+// sending requests with the client given with WithHTTPClient.
+func NewElasticsearchNotifier(ctx context.Context, opts ...NotifierOption) (*ElasticsearchNotifier, error) {
+	client, err := elastic.NewClientFromEnv(newNotifierOptions(opts).client)
+	if err != nil {
+		return nil, err
+	}
+
+	index := os.Getenv("ELASTICSEARCH_INDEX")
+	if index == "" {
+		index = "grass"
+	}
+
+	created, err := client.EnsureIndex(ctx, index, elastic.ResultMapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare Elasticsearch index %s: %w", index, err)
+	}
+	if created {
+		log.Info("Created Elasticsearch index", "index", index)
+	}
+
+	return &ElasticsearchNotifier{client: client, index: index}, nil
+}
+
+// Notify indexes the result. Documents are keyed by platform and URL so re-indexing a result is idempotent.
+func (e *ElasticsearchNotifier) Notify(ctx context.Context, result search.SearchResult) error {
+	doc := elasticsearchDocument{
+		Platform:  result.Platform,
+		Keyword:   result.Keyword,
+		Title:     result.Title,
+		URL:       result.URL,
+		Content:   result.Content,
+		Author:    result.Author,
+		Score:     result.Score,
+		Comments:  result.Comments,
+		Reposts:   result.Reposts,
+		Views:     result.Views,
+		Tags:      result.Tags,
+		Metadata:  result.Metadata,
+		Priority:  string(result.Priority),
+		Timestamp: result.Timestamp,
+		IndexedAt: time.Now().Unix(),
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	resp, err := e.client.Do(ctx, "PUT", elastic.DocumentPath(e.index, elastic.DocumentID(result.Platform, result.URL)), body)
+	if err != nil {
+		log.Error("Failed to index result in Elasticsearch", "title", result.Title, "url", result.URL, "error", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		log.Error("Elasticsearch index request failed", "status_code", resp.StatusCode, "response", string(respBody))
+		return fmt.Errorf("Elasticsearch index request failed with status code: %d", resp.StatusCode)
+	}
+
+	log.Info("Indexed in Elasticsearch", "index", e.index, "title", result.Title, "url", result.URL)
+	return nil
+}
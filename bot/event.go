@@ -0,0 +1,52 @@
+// bot/event.go
+package bot
+
+import (
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Lifecycle event types, as set in Event.Type.
+const (
+	// EventResultFound is emitted for each new result saved.
+	EventResultFound = "result.found"
+	// EventResultNotified is emitted each time a notifier accepts a result.
+	EventResultNotified = "result.notified"
+	// EventNotificationFailed is emitted each time a notifier fails to send a result, including attempts
+	// retried from the outbox.
+	EventNotificationFailed = "notification.failed"
+	// EventRunCompleted is emitted when a run of one or more keywords finishes.
+	EventRunCompleted = "run.completed"
+)
+
+// EventTypes lists every lifecycle event type.
+var EventTypes = []string{EventResultFound, EventResultNotified, EventNotificationFailed, EventRunCompleted}
+
+// Event is something that happened in a bot's pipeline. Which fields are set depends on Type.
+type Event struct {
+	Type string
+	Time time.Time
+	// Result is the result found, notified, or that failed to be notified.
+	Result *search.SearchResult
+	// Notifier names the notifier a result was or failed to be sent with, and Err is why it failed.
+	Notifier string
+	Err      error
+	// Report is the completed run's report.
+	Report *RunReport
+}
+
+// EventHandler receives a bot's lifecycle events, for example to pass them on to other systems. It is
+// called from the goroutine the event happened on, so it mustn't block.
+type EventHandler interface {
+	HandleEvent(event Event)
+}
+
+// emit passes an event to the bot's event handler, if any, timestamped now.
+func (b *Bot) emit(event Event) {
+	if b.Events == nil {
+		return
+	}
+	event.Time = time.Now()
+	b.Events.HandleEvent(event)
+}
@@ -0,0 +1,94 @@
+// bot/filter.go
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// anyKeyword is the filter key that applies to results for every keyword.
+const anyKeyword = "*"
+
+// Filter drops results matching a keyword's exclusions before they are saved or notified.
+type Filter struct {
+	keywords map[string]keywordFilter
+}
+
+type keywordFilter struct {
+	terms    []string
+	patterns []*regexp.Regexp
+	options  search.MatchOptions
+}
+
+// NewFilter compiles per-keyword exclusions. Keywords are matched case-insensitively.
+func NewFilter(cfg map[string]config.Filter) (*Filter, error) {
+	f := &Filter{keywords: make(map[string]keywordFilter, len(cfg))}
+	for keyword, filterCfg := range cfg {
+		compiled := keywordFilter{options: search.MatchOptions{
+			WholeWord:     filterCfg.WholeWord,
+			CaseSensitive: filterCfg.CaseSensitive,
+			FoldAccents:   filterCfg.FoldAccents,
+		}}
+		for _, term := range filterCfg.Exclude {
+			if term = strings.TrimSpace(term); term != "" {
+				compiled.terms = append(compiled.terms, strings.ToLower(term))
+			}
+		}
+		for _, pattern := range filterCfg.ExcludeRegex {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("filter for keyword %q has an invalid exclude_regex: %w", keyword, err)
+			}
+			compiled.patterns = append(compiled.patterns, re)
+		}
+		f.keywords[strings.ToLower(keyword)] = compiled
+	}
+	return f, nil
+}
+
+// Excluded reports whether a result should be dropped, along with the exclusion it matched.
+func (f *Filter) Excluded(result search.SearchResult) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+
+	text := result.Title + "\n" + result.Content
+	for _, key := range []string{strings.ToLower(result.Keyword), anyKeyword} {
+		if reason, ok := f.keywords[key].excludes(text); ok {
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+// MatchOptions returns the options results for a keyword must satisfy, falling back to the "*" filter.
+// The zero value means results are not post-filtered.
+func (f *Filter) MatchOptions(keyword string) search.MatchOptions {
+	if f == nil {
+		return search.MatchOptions{}
+	}
+	if kf, ok := f.keywords[strings.ToLower(keyword)]; ok {
+		return kf.options
+	}
+	return f.keywords[anyKeyword].options
+}
+
+// excludes checks text against the filter's terms and patterns.
+func (kf keywordFilter) excludes(text string) (string, bool) {
+	lower := strings.ToLower(text)
+	for _, term := range kf.terms {
+		if strings.Contains(lower, term) {
+			return term, true
+		}
+	}
+	for _, re := range kf.patterns {
+		if re.MatchString(text) {
+			return re.String(), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,110 @@
+// bot/followup.go
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// followUp is a notified result whose discussion is being watched for new replies.
+type followUp struct {
+	result    search.SearchResult
+	checker   search.ActivityChecker
+	notifiers []string
+	// replies is the reply count at the last notification, or -1 until it is first read.
+	replies int64
+	until   time.Time
+}
+
+// follow starts watching a notified result for new replies when follow-ups are enabled and its searcher
+// can count replies.
+func (b *Bot) follow(ctx context.Context, p pendingResult) {
+	if b.FollowUpThreshold <= 0 || p.checker == nil {
+		return
+	}
+
+	replies := int64(-1)
+	searchCtx, cancel := withTimeout(ctx, b.SearchTimeout)
+	count, err := p.checker.Replies(searchCtx, p.result)
+	cancel()
+	if err != nil {
+		log.Warn("Failed to read replies; following up from the next check", "platform", p.result.Platform, "url", p.result.URL, "error", err)
+	} else {
+		replies = count
+	}
+
+	result := p.result
+	result.Duplicates = nil
+	b.mu.Lock()
+	b.followUps[result.Platform+"\x00"+result.URL] = &followUp{
+		result:    result,
+		checker:   p.checker,
+		notifiers: p.notifiers,
+		replies:   replies,
+		until:     time.Now().Add(b.FollowUpWindow),
+	}
+	b.mu.Unlock()
+}
+
+// CheckFollowUps re-checks every followed result and sends a follow-up notification for each that has
+// gained at least FollowUpThreshold replies since it was last notified. Results are dropped once
+// FollowUpWindow has passed since they were first notified.
+func (b *Bot) CheckFollowUps(ctx context.Context) {
+	b.mu.Lock()
+	now := time.Now()
+	keys := make([]string, 0, len(b.followUps))
+	for key, f := range b.followUps {
+		if now.After(f.until) {
+			delete(b.followUps, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	b.mu.Unlock()
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return
+		}
+
+		b.mu.Lock()
+		f, ok := b.followUps[key]
+		b.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		searchCtx, cancel := withTimeout(ctx, b.SearchTimeout)
+		replies, err := f.checker.Replies(searchCtx, f.result)
+		cancel()
+		if err != nil {
+			log.Error("Error checking replies", "platform", f.result.Platform, "url", f.result.URL, "error", err)
+			continue
+		}
+
+		if f.replies < 0 {
+			f.replies = replies
+			continue
+		}
+		if replies-f.replies < b.FollowUpThreshold {
+			continue
+		}
+
+		result := f.result
+		result.Replies = replies
+		result.NewReplies = replies - f.replies
+		result.Title = fmt.Sprintf("Follow-up, %d new replies: %s", result.NewReplies, result.Title)
+		log.Info("Discussion follow-up", "platform", result.Platform, "url", result.URL, "replies", replies, "new_replies", result.NewReplies)
+		b.notify(ctx, result, f.notifiers)
+		f.replies = replies
+	}
+
+	b.DeliverOutbox(ctx)
+	b.flushDigests(ctx, false)
+}
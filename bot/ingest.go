@@ -0,0 +1,57 @@
+// bot/ingest.go
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Ingest runs results pushed by an external source, such as a webhook, through the same pipeline as
+// search results, from canonicalization through to notification. Processors attached after StageSearch
+// see them too. Results are handled per keyword, so their query is applied as it would be for a search;
+// results without a keyword skip the query match. It returns the number of new results, and an error if
+// any keyword's results could not be processed.
+func (b *Bot) Ingest(ctx context.Context, results []search.SearchResult) (int, error) {
+	return b.ingest(ctx, results, nil)
+}
+
+// ingest runs results through the pipeline and notifies them, following them with provider's checkers
+// when provider is set.
+func (b *Bot) ingest(ctx context.Context, results []search.SearchResult, provider search.Searcher) (int, error) {
+	var claimed []search.SearchResult
+	defer func() { b.release(ctx, claimed) }()
+
+	var keywords []string
+	byKeyword := make(map[string][]search.SearchResult)
+	for _, result := range results {
+		if _, ok := byKeyword[result.Keyword]; !ok {
+			keywords = append(keywords, result.Keyword)
+		}
+		byKeyword[result.Keyword] = append(byKeyword[result.Keyword], result)
+	}
+
+	var pending []pendingResult
+	var errs []error
+	for _, keyword := range keywords {
+		batch := newPipelineBatch(keyword, &claimed)
+		saved, err := b.pipeline(ctx, b.canonicalize(ctx, byKeyword[keyword], batch), batch)
+		if err != nil {
+			log.Error("Error processing ingested results", "keyword", keyword, "error", err)
+			errs = append(errs, fmt.Errorf("keyword %q: %w", keyword, err))
+			continue
+		}
+		if batch.incomplete {
+			// Unlike a search, nothing would look for the skipped results again, so the sender should retry
+			errs = append(errs, fmt.Errorf("keyword %q: some results could not be checked against storage", keyword))
+		}
+		pending = append(pending, b.route(saved, provider)...)
+	}
+
+	b.notifyPending(ctx, pending)
+	b.flushDigests(ctx, false)
+	return len(pending), errors.Join(errs...)
+}
@@ -0,0 +1,75 @@
+// bot/job.go
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Job is a search requested from outside the bot, such as one taken from a queue by a worker: keywords to
+// search for on some of the bot's platforms, optionally within a time range.
+type Job struct {
+	Keywords []string
+	// Platforms names the platforms to search, such as HackerNews, matched ignoring case. Empty searches
+	// every platform.
+	Platforms []string
+	// Since and Until restrict the job to results posted between them, as the Bot fields of the same name
+	// do, which apply when they are zero.
+	Since time.Time
+	Until time.Time
+}
+
+// RunJob runs up to concurrency of a job's keywords in parallel on its platforms, storing and notifying new
+// results as Run does, and returns a report of what each platform found. It returns an error, without
+// searching, if the job has no keywords, its range ends before it starts, or it names a platform the bot
+// doesn't search.
+func (b *Bot) RunJob(ctx context.Context, job Job, concurrency int) (*RunReport, error) {
+	if len(job.Keywords) == 0 {
+		return nil, errors.New("job has no keywords")
+	}
+	window := b.timeRange()
+	if !job.Since.IsZero() {
+		window.since = job.Since
+	}
+	if !job.Until.IsZero() {
+		window.until = job.Until
+	}
+	if !window.since.IsZero() && !window.until.IsZero() && window.until.Before(window.since) {
+		return nil, fmt.Errorf("job range ends at %s, before it starts", window.until.Format(time.RFC3339))
+	}
+
+	providers := b.Searchers
+	if len(job.Platforms) > 0 {
+		providers = nil
+		for _, platform := range job.Platforms {
+			provider, ok := b.searcher(platform)
+			if !ok {
+				return nil, fmt.Errorf("platform %q isn't searched", platform)
+			}
+			if !slices.ContainsFunc(providers, func(added search.Searcher) bool { return added.Platform() == provider.Platform() }) {
+				providers = append(providers, provider)
+			}
+		}
+	}
+
+	report := newRunReport()
+	b.runKeywords(ctx, job.Keywords, providers, concurrency, window, report)
+	b.flushDigests(ctx, false)
+	return b.record(report.finish()), nil
+}
+
+// searcher returns the searcher of the named platform.
+func (b *Bot) searcher(platform string) (search.Searcher, bool) {
+	for _, provider := range b.Searchers {
+		if strings.EqualFold(provider.Platform(), platform) {
+			return provider, true
+		}
+	}
+	return nil, false
+}
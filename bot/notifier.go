@@ -1,9 +1,61 @@
 // bot/notifier.go
 package bot
 
-import "github.com/jaxxstorm/grass/search"
+import (
+	"context"
+	"errors"
+	"strings"
 
-// Notifier defines the interface for output mechanisms.
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Notifier defines the interface for output mechanisms. Notify should abandon its requests when ctx is
+// cancelled or its deadline passes.
 type Notifier interface {
-	Notify(result search.SearchResult) error
+	Notify(ctx context.Context, result search.SearchResult) error
+}
+
+// errUndeliverable marks notification errors that retrying can't fix, such as a channel that doesn't exist.
+var errUndeliverable = errors.New("undeliverable")
+
+// undeliverable reports whether err only holds errors that retrying can't fix, so a notification sent to
+// several channels is still retried if any of them may succeed later.
+func undeliverable(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		for _, err := range errs {
+			if !undeliverable(err) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	}
+	return errors.Is(err, errUndeliverable)
+}
+
+// AsTextSender returns the notifier that sends plain text messages for n, looking through wrappers such as
+// DigestNotifier and ThrottleNotifier. Text sent this way bypasses digests and rate limits.
+func AsTextSender(n Notifier) (TextSender, bool) {
+	for {
+		if sender, ok := n.(TextSender); ok {
+			return sender, true
+		}
+		wrapper, ok := n.(interface{ Unwrap() Notifier })
+		if !ok {
+			return nil, false
+		}
+		n = wrapper.Unwrap()
+	}
+}
+
+// parseChannelIDs splits a comma-separated list of channel IDs, dropping empty entries.
+func parseChannelIDs(value string) []string {
+	var channelIDs []string
+	for _, channelID := range strings.Split(value, ",") {
+		channelID = strings.TrimSpace(channelID)
+		if channelID != "" {
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+	return channelIDs
 }
@@ -0,0 +1,136 @@
+// bot/outbox.go
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+const (
+	// outboxBatchSize is how many due notifications are read from storage at a time.
+	outboxBatchSize = 100
+	// outboxMaxAttempts is how many times a notification is tried before it is abandoned.
+	outboxMaxAttempts = 10
+	// outboxBaseDelay and outboxMaxDelay bound the backoff between delivery attempts.
+	outboxBaseDelay = time.Minute
+	outboxMaxDelay  = time.Hour
+)
+
+// enqueue queues a notification of the result for each named notifier.
+func (b *Bot) enqueue(ctx context.Context, result search.SearchResult, names []string) error {
+	payload, err := json.Marshal(search.NewNotification(result))
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	now := time.Now().Unix()
+	entries := make([]storage.OutboxEntry, 0, len(names))
+	for _, name := range names {
+		id, err := outboxID()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, storage.OutboxEntry{
+			ID:          id,
+			Notifier:    name,
+			Payload:     payload,
+			NextAttempt: now,
+			CreatedAt:   now,
+		})
+	}
+	return b.Outbox.Enqueue(ctx, entries)
+}
+
+// DeliverOutbox sends every queued notification that is due. Failed deliveries are retried with
+// exponential backoff on later calls, and abandoned after outboxMaxAttempts attempts. Notifications are
+// removed only once delivered, so a crash between sending and removing one sends it again.
+func (b *Bot) DeliverOutbox(ctx context.Context) {
+	if b.Outbox == nil {
+		return
+	}
+
+	// Serialize deliveries so parallel keyword runs don't send the same entry twice
+	b.delivering.Lock()
+	defer b.delivering.Unlock()
+
+	for ctx.Err() == nil {
+		entries, err := b.Outbox.DueOutbox(ctx, time.Now(), outboxBatchSize)
+		if err != nil {
+			log.Error("Error reading notification outbox", "error", err)
+			return
+		}
+		if len(entries) == 0 {
+			return
+		}
+
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := b.deliver(ctx, entry); err != nil {
+				log.Error("Error updating notification outbox", "id", entry.ID, "notifier", entry.Notifier, "error", err)
+				return
+			}
+		}
+	}
+}
+
+// deliver sends one queued notification, removing it from the outbox on success or when it can't be sent,
+// and scheduling a retry otherwise. It returns an error only when the outbox itself can't be updated.
+func (b *Bot) deliver(ctx context.Context, entry storage.OutboxEntry) error {
+	notifier, ok := b.CurrentNotifiers()[entry.Notifier]
+	if !ok {
+		log.Warn("Dropping queued notification for unknown notifier", "id", entry.ID, "notifier", entry.Notifier)
+		return b.Outbox.DeleteOutbox(ctx, entry.ID)
+	}
+
+	var notification search.Notification
+	if err := json.Unmarshal(entry.Payload, &notification); err != nil {
+		log.Error("Dropping unreadable queued notification", "id", entry.ID, "notifier", entry.Notifier, "error", err)
+		return b.Outbox.DeleteOutbox(ctx, entry.ID)
+	}
+	result := notification.SearchResult()
+
+	notifyCtx, cancel := withTimeout(ctx, b.NotifyTimeout)
+	err := notifier.Notify(notifyCtx, result)
+	cancel()
+	if err == nil {
+		b.emit(Event{Type: EventResultNotified, Result: &result, Notifier: entry.Notifier})
+		return b.Outbox.DeleteOutbox(ctx, entry.ID)
+	}
+	b.emit(Event{Type: EventNotificationFailed, Result: &result, Notifier: entry.Notifier, Err: err})
+
+	if undeliverable(err) {
+		log.Error("Dropping undeliverable notification", "notifier", entry.Notifier, "platform", result.Platform, "title", result.Title, "url", result.URL, "error", err)
+		return b.Outbox.DeleteOutbox(ctx, entry.ID)
+	}
+
+	entry.Attempts++
+	entry.LastError = err.Error()
+	if entry.Attempts >= outboxMaxAttempts {
+		log.Error("Giving up on notification", "notifier", entry.Notifier, "platform", result.Platform, "title", result.Title, "url", result.URL, "attempts", entry.Attempts, "error", err)
+		return b.Outbox.DeleteOutbox(ctx, entry.ID)
+	}
+
+	delay := min(outboxBaseDelay<<(entry.Attempts-1), outboxMaxDelay)
+	entry.NextAttempt = time.Now().Add(delay).Unix()
+	log.Warn("Error notifying; will retry", "notifier", entry.Notifier, "platform", result.Platform, "title", result.Title, "url", result.URL, "attempt", entry.Attempts, "retry_in", delay, "error", err)
+	return b.Outbox.UpdateOutbox(ctx, entry)
+}
+
+// outboxID returns a random identifier for a queued notification.
+func outboxID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate outbox ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,409 @@
+// bot/pipeline.go
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Stage names a step of the pipeline each platform's results go through on every run:
+//
+//	search → filter → enrich → dedupe → engage → store → notify
+//
+// Processors added with Use run after the stage they are attached to, on that stage's output.
+type Stage string
+
+const (
+	// StageSearch fetches results, drops those posted after the run's time range, canonicalizes their
+	// URLs, and drops repeats within the batch.
+	StageSearch Stage = "search"
+	// StageFilter applies boolean queries, match options, exclusions, and the spam filter.
+	StageFilter Stage = "filter"
+	// StageEnrich sets each result's priority from the priority and routing rules, and its content hash.
+	StageEnrich Stage = "enrich"
+	// StageDedupe drops results that are already stored or being handled by a concurrent search, except
+	// stored results that were edited, when edit detection is enabled. Those are passed on with Edited set.
+	StageDedupe Stage = "dedupe"
+	// StageEngage fetches the current engagement of new results from their platforms, when enabled.
+	StageEngage Stage = "engage"
+	// StageStore saves the new results and the new versions of edited ones, archiving them when enabled.
+	// Processors attached to it run on saved results, just before they are grouped, routed, and notified.
+	StageStore Stage = "store"
+)
+
+// Stages lists the stages processors can be attached to, in pipeline order.
+var Stages = []Stage{StageSearch, StageFilter, StageEnrich, StageDedupe, StageEngage, StageStore}
+
+// Processor is a pipeline step added by users embedding grass, for example to score, classify, or filter
+// results. It returns the results to pass on, so it can modify, drop, or reorder them. An error stops the
+// platform's run before anything is saved and leaves its last search time unchanged, except after
+// StageStore, where the results are already saved and are notified unprocessed.
+type Processor interface {
+	Process(ctx context.Context, results []search.SearchResult) ([]search.SearchResult, error)
+}
+
+// ProcessorFunc adapts a function to a Processor.
+type ProcessorFunc func(ctx context.Context, results []search.SearchResult) ([]search.SearchResult, error)
+
+// Process calls f.
+func (f ProcessorFunc) Process(ctx context.Context, results []search.SearchResult) ([]search.SearchResult, error) {
+	return f(ctx, results)
+}
+
+// Use attaches a processor after a stage. Processors attached to the same stage run in the order they were
+// added. Use must not be called while the bot is running.
+func (b *Bot) Use(after Stage, processor Processor) error {
+	for _, stage := range Stages {
+		if stage == after {
+			if b.processors == nil {
+				b.processors = make(map[Stage][]Processor)
+			}
+			b.processors[after] = append(b.processors[after], processor)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown pipeline stage %q", after)
+}
+
+// process runs the processors attached after a stage.
+func (b *Bot) process(ctx context.Context, stage Stage, results []search.SearchResult) ([]search.SearchResult, error) {
+	for _, processor := range b.processors[stage] {
+		if len(results) == 0 {
+			return results, nil
+		}
+		var err error
+		results, err = processor.Process(ctx, results)
+		if err != nil {
+			return nil, fmt.Errorf("processor after %s stage: %w", stage, err)
+		}
+	}
+	return results, nil
+}
+
+// pipeline runs searched results through the processors after the search stage and every later stage,
+// returning the saved results for notification.
+func (b *Bot) pipeline(ctx context.Context, results []search.SearchResult, batch *pipelineBatch) ([]search.SearchResult, error) {
+	results, err := b.process(ctx, StageSearch, results)
+	if err == nil {
+		results, err = b.filterStage(batch.keyword, results)
+	}
+	if err == nil {
+		results, err = b.process(ctx, StageFilter, results)
+	}
+	if err == nil {
+		results, err = b.process(ctx, StageEnrich, b.enrichStage(results))
+	}
+	if err == nil {
+		results, err = b.process(ctx, StageDedupe, b.dedupeStage(ctx, results, batch))
+	}
+	if err == nil {
+		results, err = b.process(ctx, StageEngage, b.engageStage(ctx, results))
+	}
+	if err == nil {
+		err = b.storeStage(ctx, results)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Results are saved, so a failing processor can no longer stop them being notified
+	processed, err := b.process(ctx, StageStore, results)
+	if err != nil {
+		log.Error("Error processing saved results; notifying them unprocessed", "keyword", batch.keyword, "error", err)
+		processed = results
+	}
+	if b.EditDetection == "notify" {
+		return processed, nil
+	}
+	var notified []search.SearchResult
+	for _, result := range processed {
+		if !result.Edited {
+			notified = append(notified, result)
+		}
+	}
+	return notified, nil
+}
+
+// pipelineBatch is the state of one platform's results as they move through the pipeline.
+type pipelineBatch struct {
+	keyword string
+	// claimed collects the results claimed by the dedupe stage, for the caller to release.
+	claimed *[]search.SearchResult
+	// originalURLs maps canonical URLs to the URLs the platform returned.
+	originalURLs map[string]string
+	// until, unless zero, is the latest posting time kept.
+	until time.Time
+	// newest is the newest searched result's timestamp.
+	newest int64
+	// incomplete is set when a result could not be checked against storage, or the search dropped results
+	// past its result limit.
+	incomplete bool
+}
+
+func newPipelineBatch(keyword string, claimed *[]search.SearchResult) *pipelineBatch {
+	return &pipelineBatch{keyword: keyword, claimed: claimed, originalURLs: make(map[string]string)}
+}
+
+// searchStage searches the platform for the keyword and its variants, canonicalizing URLs so tracking
+// parameters and shorteners don't make the same link look new. Results found by a variant are reported
+// under the keyword. Account keywords list the account's posts instead. A search that was truncated at its
+// result limit marks the batch incomplete, so the last search time isn't advanced past the dropped results.
+func (b *Bot) searchStage(ctx context.Context, provider search.Searcher, keyword string, from int64, batch *pipelineBatch) ([]search.SearchResult, error) {
+	if account, ok := search.ParseAccount(keyword); ok {
+		return b.accountStage(ctx, provider, keyword, account, from, batch)
+	}
+
+	var results []search.SearchResult
+	for _, form := range search.ExpandVariants(keyword, b.settings().KeywordVariants[provider.Platform()]) {
+		searchCtx, cancel := withTimeout(ctx, b.SearchTimeout)
+		found, err := provider.Search(searchCtx, form, from)
+		cancel()
+		if errors.Is(err, search.ErrTruncated) {
+			batch.incomplete = true
+		} else if err != nil {
+			if form != keyword {
+				return nil, fmt.Errorf("search for variant %q failed: %w", form, err)
+			}
+			return nil, fmt.Errorf("search failed: %w", err)
+		}
+		for i := range found {
+			found[i].Keyword = keyword
+		}
+		results = append(results, found...)
+	}
+	return b.canonicalize(ctx, beforeUntil(results, batch.until), batch), nil
+}
+
+// accountStage lists the posts of a watched account in place of a keyword search.
+func (b *Bot) accountStage(ctx context.Context, provider search.Searcher, keyword string, account search.Account, from int64, batch *pipelineBatch) ([]search.SearchResult, error) {
+	searcher, ok := provider.(search.AccountSearcher)
+	if !ok {
+		return nil, fmt.Errorf("%s searcher can't watch accounts", provider.Platform())
+	}
+	searchCtx, cancel := withTimeout(ctx, b.SearchTimeout)
+	results, err := searcher.SearchAccount(searchCtx, account.Name, from)
+	cancel()
+	if errors.Is(err, search.ErrTruncated) {
+		batch.incomplete = true
+	} else if err != nil {
+		return nil, fmt.Errorf("search for account %s failed: %w", account.Name, err)
+	}
+	for i := range results {
+		results[i].Keyword = keyword
+	}
+	return b.canonicalize(ctx, beforeUntil(results, batch.until), batch), nil
+}
+
+// beforeUntil drops results posted after until, unless it is zero, since searchers only take a start time.
+func beforeUntil(results []search.SearchResult, until time.Time) []search.SearchResult {
+	if until.IsZero() {
+		return results
+	}
+	var kept []search.SearchResult
+	for _, result := range results {
+		if result.Timestamp > until.Unix() {
+			continue
+		}
+		kept = append(kept, result)
+	}
+	return kept
+}
+
+// canonicalize rewrites result URLs to their canonical form and drops repeats within the batch.
+func (b *Bot) canonicalize(ctx context.Context, results []search.SearchResult, batch *pipelineBatch) []search.SearchResult {
+	var kept []search.SearchResult
+	for _, result := range results {
+		if result.Timestamp > batch.newest {
+			batch.newest = result.Timestamp
+		}
+
+		originalURL := result.URL
+		result.URL = search.CanonicalURL(ctx, result.URL)
+		if _, ok := batch.originalURLs[result.URL]; ok {
+			continue
+		}
+		batch.originalURLs[result.URL] = originalURL
+		kept = append(kept, result)
+	}
+	return kept
+}
+
+// filterStage drops results that don't match the keyword's query exactly, since boolean queries and match
+// options are only approximated by platforms, along with excluded results and likely spam. Results
+// ingested without a keyword skip the query match.
+func (b *Bot) filterStage(keyword string, results []search.SearchResult) ([]search.SearchResult, error) {
+	filter := b.settings().Filter
+	var query *search.Query
+	postFilter := false
+	matchOptions := filter.MatchOptions(keyword)
+	// Every post by a watched account matches its keyword
+	if _, watching := search.ParseAccount(keyword); keyword != "" && !watching {
+		parsed, err := search.ParseQuery(keyword)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keyword query: %w", err)
+		}
+		query = parsed
+		postFilter = query.IsBoolean() || matchOptions != (search.MatchOptions{})
+	}
+
+	var kept []search.SearchResult
+	for _, result := range results {
+		if postFilter && !query.MatchWith(result.Title+"\n"+result.Content, matchOptions) {
+			log.Debug("Skipping result not matching query", "title", result.Title, "url", result.URL, "platform", result.Platform, "query", keyword)
+			continue
+		}
+		if exclusion, excluded := filter.Excluded(result); excluded {
+			log.Debug("Skipping excluded result", "title", result.Title, "url", result.URL, "platform", result.Platform, "exclusion", exclusion)
+			continue
+		}
+		if reasons, spam := b.Spam.Spam(result); spam {
+			log.Debug("Skipping likely spam", "title", result.Title, "url", result.URL, "platform", result.Platform, "author", result.Author, "reasons", reasons)
+			continue
+		}
+		kept = append(kept, result)
+	}
+	return kept, nil
+}
+
+// enrichStage sets each result's priority from the priority and routing rules, and its content hash.
+func (b *Bot) enrichStage(results []search.SearchResult) []search.SearchResult {
+	settings := b.settings()
+	for i := range results {
+		results[i].Priority = settings.Priorities.Prioritize(results[i])
+		_, results[i].Priority = settings.Router.Route(results[i])
+		results[i].ContentHash = search.ContentHash(results[i])
+	}
+	return results
+}
+
+// dedupeStage claims each result and drops those already stored or claimed by a concurrent search, whose
+// keywords the result's keyword is added to. Results that can't be checked are dropped and mark the batch
+// incomplete.
+func (b *Bot) dedupeStage(ctx context.Context, results []search.SearchResult, batch *pipelineBatch) []search.SearchResult {
+	var kept []search.SearchResult
+	for _, result := range results {
+		// Another keyword may have found the same result and not notified it yet
+		if !b.claim(result.Platform, result.URL, batch.keyword) {
+			log.Debug("Skipping result claimed by another search", "title", result.Title, "url", result.URL, "platform", result.Platform, "keyword", batch.keyword)
+			continue
+		}
+		*batch.claimed = append(*batch.claimed, result)
+
+		originalURL, ok := batch.originalURLs[result.URL]
+		if !ok {
+			originalURL = result.URL
+		}
+		exists, err := b.exists(ctx, result.Platform, result.URL, originalURL)
+		if err != nil {
+			log.Error("Error checking existence in storage", "platform", result.Platform, "url", result.URL, "error", err)
+			batch.incomplete = true
+			continue
+		}
+		if exists {
+			if b.edited(ctx, result) {
+				result.Edited = true
+				kept = append(kept, result)
+				continue
+			}
+			log.Debug("Skipping existing result", "title", result.Title, "url", result.URL, "platform", result.Platform)
+			continue
+		}
+
+		log.Info("New result", "platform", result.Platform, "title", result.Title, "url", result.URL, "priority", result.Priority)
+		kept = append(kept, result)
+	}
+	return kept
+}
+
+// engageStage refreshes the engagement counts of new results from searchers that can fetch them, so they
+// are stored and notified with current numbers. Failures are logged and leave the counts found by the search.
+func (b *Bot) engageStage(ctx context.Context, results []search.SearchResult) []search.SearchResult {
+	if !b.Engagement || len(results) == 0 {
+		return results
+	}
+
+	byPlatform := make(map[string][]int)
+	var platforms []string
+	for i, result := range results {
+		if _, ok := byPlatform[result.Platform]; !ok {
+			platforms = append(platforms, result.Platform)
+		}
+		byPlatform[result.Platform] = append(byPlatform[result.Platform], i)
+	}
+
+	for _, platform := range platforms {
+		fetcher := b.engagementFetcher(platform)
+		if fetcher == nil {
+			continue
+		}
+		indexes := byPlatform[platform]
+		fetched := make([]search.SearchResult, len(indexes))
+		for j, i := range indexes {
+			fetched[j] = results[i]
+		}
+
+		fetchCtx, cancel := withTimeout(ctx, b.SearchTimeout)
+		err := fetcher.Engagement(fetchCtx, fetched)
+		cancel()
+		if err != nil {
+			log.Warn("Error fetching engagement", "platform", platform, "results", len(fetched), "error", err)
+		}
+		for j, i := range indexes {
+			results[i] = fetched[j]
+		}
+	}
+	return results
+}
+
+// engagementFetcher returns the searcher for a platform if it can fetch engagement.
+func (b *Bot) engagementFetcher(platform string) search.EngagementFetcher {
+	for _, provider := range b.Searchers {
+		if provider.Platform() != platform {
+			continue
+		}
+		if fetcher, ok := provider.(search.EngagementFetcher); ok {
+			return fetcher
+		}
+	}
+	return nil
+}
+
+// storeStage saves new results in one batch, and the new versions of edited results, then archives them.
+func (b *Bot) storeStage(ctx context.Context, results []search.SearchResult) error {
+	var saved, edited []search.SearchResult
+	for _, result := range results {
+		if result.Edited {
+			edited = append(edited, result)
+		} else {
+			saved = append(saved, result)
+		}
+	}
+	if len(saved) > 0 {
+		if err := b.Storer.SaveBatch(ctx, saved); err != nil {
+			return fmt.Errorf("failed to save %d results: %w", len(saved), err)
+		}
+		for i := range saved {
+			b.emit(Event{Type: EventResultFound, Result: &saved[i]})
+		}
+	}
+	if err := b.updateEdited(ctx, edited); err != nil {
+		return fmt.Errorf("failed to update %d edited results: %w", len(edited), err)
+	}
+	b.archive(ctx, results)
+	return nil
+}
+
+// archive snapshots saved results when archiving is enabled, logging failures.
+func (b *Bot) archive(ctx context.Context, results []search.SearchResult) {
+	if b.Archiver == nil || len(results) == 0 {
+		return
+	}
+	if err := b.Archiver.Archive(ctx, results); err != nil {
+		log.Error("Error archiving results", "results", len(results), "error", err)
+	}
+}
@@ -0,0 +1,61 @@
+// bot/preview.go
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// PreviewNotifier writes the message another notifier would send for each result to an output instead of
+// sending it, for checking filters and templates without notifying anyone.
+type PreviewNotifier struct {
+	name     string
+	template *MessageTemplate
+	mentions Mentions
+	out      io.Writer
+}
+
+// NewPreviewNotifier creates a notifier previewing the messages of the named notifier, rendered with its
+// template and prefixed with its mentions in Slack or Discord syntax. A nil template previews each result
+// as the JSON sent to notifiers without one, such as Elasticsearch and plugins.
+func NewPreviewNotifier(name string, tmpl *MessageTemplate, mentions Mentions, out io.Writer) *PreviewNotifier {
+	return &PreviewNotifier{name: name, template: tmpl, mentions: mentions, out: out}
+}
+
+func (p *PreviewNotifier) Notify(ctx context.Context, result search.SearchResult) error {
+	if p.template == nil {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		return p.write(string(data))
+	}
+
+	message, err := p.template.Render(result)
+	if err != nil {
+		return err
+	}
+	switch p.name {
+	case "slack":
+		message = p.mentions.prefix(result.Priority, slackMention) + message
+	case "discord":
+		message = p.mentions.prefix(result.Priority, discordMention) + message
+	}
+	return p.write(message)
+}
+
+// NotifyText previews a plain text message.
+func (p *PreviewNotifier) NotifyText(ctx context.Context, text string) error {
+	return p.write(text)
+}
+
+// write writes a message under a header naming the notifier.
+func (p *PreviewNotifier) write(message string) error {
+	_, err := fmt.Fprintf(p.out, "── %s ──\n%s\n\n", p.name, strings.TrimRight(message, "\n"))
+	return err
+}
@@ -2,19 +2,63 @@
 package bot
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/jaxxstorm/grass/search"
 )
 
-type PrintNotifier struct{}
+type PrintNotifier struct {
+	template       *MessageTemplate
+	digestTemplate *MessageTemplate
+	out            io.Writer
+}
+
+// NewPrintNotifier creates a notifier that writes results to stdout, or the writer given with WithOutput.
+// Messages use DefaultPrintTemplate and DefaultPrintDigestTemplate unless WithTemplate or
+// WithDigestTemplate say otherwise.
+func NewPrintNotifier(opts ...NotifierOption) *PrintNotifier {
+	o := newNotifierOptions(opts)
+	if o.template == nil {
+		o.template = mustParseTemplate("print", DefaultPrintTemplate)
+	}
+	if o.digestTemplate == nil {
+		o.digestTemplate = mustParseTemplate("print digest", DefaultPrintDigestTemplate)
+	}
+	if o.out == nil {
+		o.out = os.Stdout
+	}
+	return &PrintNotifier{template: o.template, digestTemplate: o.digestTemplate, out: o.out}
+}
+
+// SetOutput makes the notifier write to w instead of stdout, such as stderr when stdout is for a report.
+func (p *PrintNotifier) SetOutput(w io.Writer) {
+	p.out = w
+}
 
-func NewPrintNotifier() *PrintNotifier {
-	return &PrintNotifier{}
+func (p *PrintNotifier) Notify(ctx context.Context, result search.SearchResult) error {
+	message, err := p.template.Render(result)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(p.out, message)
+	return nil
+}
+
+// NotifyText writes a plain text message to the notifier's output.
+func (p *PrintNotifier) NotifyText(ctx context.Context, text string) error {
+	fmt.Fprintln(p.out, text)
+	return nil
 }
 
-func (p *PrintNotifier) Notify(result search.SearchResult) error {
-	fmt.Printf("Platform: %s\nKeyword: %s\nTitle: %s\nURL: %s\nTimestamp: %d\n\n",
-		result.Platform, result.Keyword, result.Title, result.URL, result.Timestamp)
+// NotifyDigest writes a digest of several results to the notifier's output.
+func (p *PrintNotifier) NotifyDigest(ctx context.Context, digest Digest) error {
+	message, err := p.digestTemplate.RenderDigest(digest)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(p.out, message)
 	return nil
 }
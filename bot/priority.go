@@ -0,0 +1,124 @@
+// bot/priority.go
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Mentions maps a result priority to the users, groups, or roles a notifier should ping.
+type Mentions map[search.Priority][]string
+
+// ParseMentions validates the priority keys of a mentions configuration. When no critical mentions are
+// configured, critical results ping everyone currently active in the channel.
+func ParseMentions(raw map[string][]string) (Mentions, error) {
+	mentions := make(Mentions, len(raw))
+	for key, targets := range raw {
+		priority, err := search.ParsePriority(key)
+		if err != nil {
+			return nil, err
+		}
+		mentions[priority] = targets
+	}
+
+	if _, ok := mentions[search.PriorityCritical]; !ok {
+		mentions[search.PriorityCritical] = []string{"here"}
+	}
+
+	return mentions, nil
+}
+
+// prefix renders the mentions for a priority using a platform-specific formatter.
+func (m Mentions) prefix(priority search.Priority, format func(string) string) string {
+	if priority == "" {
+		priority = search.PriorityInfo
+	}
+
+	var rendered []string
+	for _, target := range m[priority] {
+		rendered = append(rendered, format(target))
+	}
+
+	if len(rendered) == 0 {
+		return ""
+	}
+	return strings.Join(rendered, " ") + " "
+}
+
+// slackMention formats a mention target using Slack's message syntax. Targets are "here", "channel",
+// "everyone", user IDs (U.../W...), or user group IDs (S...).
+func slackMention(target string) string {
+	switch {
+	case target == "here" || target == "channel" || target == "everyone":
+		return fmt.Sprintf("<!%s>", target)
+	case strings.HasPrefix(target, "S"):
+		return fmt.Sprintf("<!subteam^%s>", target)
+	default:
+		return fmt.Sprintf("<@%s>", target)
+	}
+}
+
+// discordMention formats a mention target using Discord's message syntax. Targets are "here", "everyone",
+// role IDs prefixed with "role:", or user IDs.
+func discordMention(target string) string {
+	switch {
+	case target == "here" || target == "everyone":
+		return "@" + target
+	case strings.HasPrefix(target, "role:"):
+		return fmt.Sprintf("<@&%s>", strings.TrimPrefix(target, "role:"))
+	default:
+		return fmt.Sprintf("<@%s>", target)
+	}
+}
+
+// Prioritizer assigns priorities to results from rules that, unlike routing rules, don't affect which
+// notifiers receive them.
+type Prioritizer struct {
+	rules []priorityRule
+}
+
+type priorityRule struct {
+	condition
+	priority search.Priority
+}
+
+// NewPrioritizer compiles priority rules. Every rule must name a priority.
+func NewPrioritizer(rules []config.PriorityRule) (*Prioritizer, error) {
+	p := &Prioritizer{}
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("priority rule %d", i+1)
+		}
+		if rule.Priority == "" {
+			return nil, fmt.Errorf("%s has no priority", name)
+		}
+		priority, err := search.ParsePriority(rule.Priority)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		cond, err := newCondition(name, rule.Platforms, rule.Keywords, rule.ContentRegex, rule.MinScore)
+		if err != nil {
+			return nil, err
+		}
+		p.rules = append(p.rules, priorityRule{condition: cond, priority: priority})
+	}
+	return p, nil
+}
+
+// Prioritize returns the highest of the result's priority and the priorities of every matching rule.
+func (p *Prioritizer) Prioritize(result search.SearchResult) search.Priority {
+	priority := result.Priority
+	if p == nil {
+		return priority
+	}
+	for _, rule := range p.rules {
+		if rule.matches(result) && rule.priority.Rank() > priority.Rank() {
+			priority = rule.priority
+		}
+	}
+	return priority
+}
@@ -0,0 +1,85 @@
+// bot/reconfigure.go
+package bot
+
+import (
+	"context"
+	"io"
+
+	"github.com/charmbracelet/log"
+)
+
+// Settings are the parts of a bot's configuration that Reconfigure can change while it runs.
+type Settings struct {
+	Notifiers       map[string]Notifier
+	Router          *Router
+	Priorities      *Prioritizer
+	Filter          *Filter
+	KeywordVariants map[string][]string
+}
+
+// Reconfigure replaces the bot's notifiers, routing and priority rules, filters, and keyword variants, for
+// example when its configuration file is reloaded. Searches and notifications already under way finish with
+// the settings they started with. Notifiers that are no longer used send their buffered digests and
+// backlogs, and are closed if they hold a connection.
+func (b *Bot) Reconfigure(ctx context.Context, settings Settings) {
+	b.configMu.Lock()
+	old := b.Notifiers
+	b.Notifiers = settings.Notifiers
+	b.Router = settings.Router
+	b.Priorities = settings.Priorities
+	b.Filter = settings.Filter
+	b.KeywordVariants = settings.KeywordVariants
+	b.configMu.Unlock()
+
+	for name, notifier := range old {
+		if kept, ok := settings.Notifiers[name]; ok && kept == notifier {
+			continue
+		}
+		if f, ok := notifier.(flusher); ok {
+			flushCtx, cancel := withTimeout(ctx, b.NotifyTimeout)
+			err := f.Flush(flushCtx, true)
+			cancel()
+			if err != nil {
+				log.Error("Error sending digest", "notifier", name, "error", err)
+			}
+		}
+		if err := CloseNotifier(notifier); err != nil {
+			log.Warn("Error closing notifier", "notifier", name, "error", err)
+		}
+	}
+}
+
+// CurrentNotifiers returns the bot's notifiers by name. Use it rather than Notifiers once the bot may be
+// reconfigured.
+func (b *Bot) CurrentNotifiers() map[string]Notifier {
+	b.configMu.RLock()
+	defer b.configMu.RUnlock()
+	return b.Notifiers
+}
+
+// settings returns the bot's current reconfigurable settings.
+func (b *Bot) settings() Settings {
+	b.configMu.RLock()
+	defer b.configMu.RUnlock()
+	return Settings{
+		Notifiers:       b.Notifiers,
+		Router:          b.Router,
+		Priorities:      b.Priorities,
+		Filter:          b.Filter,
+		KeywordVariants: b.KeywordVariants,
+	}
+}
+
+// CloseNotifier closes n, looking through wrappers such as DigestNotifier, if it holds a connection.
+func CloseNotifier(n Notifier) error {
+	for {
+		if closer, ok := n.(io.Closer); ok {
+			return closer.Close()
+		}
+		wrapper, ok := n.(interface{ Unwrap() Notifier })
+		if !ok {
+			return nil
+		}
+		n = wrapper.Unwrap()
+	}
+}
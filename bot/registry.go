@@ -0,0 +1,144 @@
+// bot/registry.go
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownNotifier is returned by NewNotifier for a name no notifier is registered under.
+var ErrUnknownNotifier = errors.New("unknown notifier")
+
+// NotifierOption configures a built-in notifier. Notifiers ignore options that don't apply to them.
+type NotifierOption func(*notifierOptions)
+
+type notifierOptions struct {
+	template       *MessageTemplate
+	digestTemplate *MessageTemplate
+	mentions       Mentions
+	channelIDs     []string
+	client         *http.Client
+	out            io.Writer
+	triageProfile  string
+	triage         Triager
+}
+
+// WithTemplate renders each result's message with tmpl instead of the notifier's default template.
+func WithTemplate(tmpl *MessageTemplate) NotifierOption {
+	return func(o *notifierOptions) {
+		o.template = tmpl
+	}
+}
+
+// WithDigestTemplate renders digests with tmpl instead of the notifier's default digest template.
+func WithDigestTemplate(tmpl *MessageTemplate) NotifierOption {
+	return func(o *notifierOptions) {
+		o.digestTemplate = tmpl
+	}
+}
+
+// WithMentions prepends mentions to Slack and Discord messages according to each result's priority.
+func WithMentions(mentions Mentions) NotifierOption {
+	return func(o *notifierOptions) {
+		o.mentions = mentions
+	}
+}
+
+// WithChannels posts Slack and Discord messages to channelIDs instead of the channels in the environment.
+func WithChannels(channelIDs []string) NotifierOption {
+	return func(o *notifierOptions) {
+		o.channelIDs = channelIDs
+	}
+}
+
+// WithHTTPClient sends the notifier's requests with client instead of a default client.
+func WithHTTPClient(client *http.Client) NotifierOption {
+	return func(o *notifierOptions) {
+		o.client = client
+	}
+}
+
+// WithOutput makes the print notifier write to w instead of stdout.
+func WithOutput(w io.Writer) NotifierOption {
+	return func(o *notifierOptions) {
+		o.out = w
+	}
+}
+
+// newNotifierOptions applies opts over the defaults.
+func newNotifierOptions(opts []NotifierOption) notifierOptions {
+	var o notifierOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// NotifierFactory creates a notifier configured by opts. Notifiers that connect to a service at startup do
+// so within ctx.
+type NotifierFactory func(ctx context.Context, opts ...NotifierOption) (Notifier, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]NotifierFactory)
+)
+
+func init() {
+	RegisterNotifier("print", func(ctx context.Context, opts ...NotifierOption) (Notifier, error) {
+		return NewPrintNotifier(opts...), nil
+	})
+	RegisterNotifier("slack", func(ctx context.Context, opts ...NotifierOption) (Notifier, error) {
+		return asNotifier(NewSlackNotifier(opts...))
+	})
+	RegisterNotifier("discord", func(ctx context.Context, opts ...NotifierOption) (Notifier, error) {
+		return asNotifier(NewDiscordNotifier(opts...))
+	})
+	RegisterNotifier("elasticsearch", func(ctx context.Context, opts ...NotifierOption) (Notifier, error) {
+		return asNotifier(NewElasticsearchNotifier(ctx, opts...))
+	})
+}
+
+// RegisterNotifier makes a notifier available to NewNotifier under name, replacing any registered under
+// the same name. Programs embedding grass register their own notifiers this way.
+func RegisterNotifier(name string, factory NotifierFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// RegisteredNotifiers returns the names notifiers are registered under, sorted.
+func RegisteredNotifiers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewNotifier creates the notifier registered under name, returning an error wrapping ErrUnknownNotifier
+// if there is none.
+func NewNotifier(ctx context.Context, name string, opts ...NotifierOption) (Notifier, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownNotifier, name)
+	}
+	return factory(ctx, opts...)
+}
+
+// asNotifier returns a constructor's notifier as a Notifier, or a nil Notifier if it failed.
+func asNotifier[N Notifier](notifier N, err error) (Notifier, error) {
+	if err != nil {
+		return nil, err
+	}
+	return notifier, nil
+}
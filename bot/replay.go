@@ -0,0 +1,43 @@
+// bot/replay.go
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Replay notifies stored results again, such as to fill a new notifier with recent history. Each result is
+// routed as a new result would be, or sent to just the named notifiers when notifiers is non-empty.
+// Results are unfurled and summarized as usual but not saved, followed, or watched for deletion. Results
+// are sent in the order given, and the number sent is returned.
+func (b *Bot) Replay(ctx context.Context, results []search.SearchResult, notifiers []string) (int, error) {
+	settings := b.settings()
+	for _, name := range notifiers {
+		if _, ok := settings.Notifiers[name]; !ok {
+			return 0, fmt.Errorf("unknown notifier %q", name)
+		}
+	}
+
+	sent := 0
+	for _, result := range results {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		names := notifiers
+		if len(names) == 0 {
+			names, result.Priority = settings.Router.Route(result)
+			if names != nil && len(names) == 0 {
+				continue
+			}
+		}
+		result.Preview = b.unfurl(ctx, result)
+		result.Summary = b.summarize(ctx, result)
+		b.notify(ctx, result, names)
+		sent++
+	}
+	b.flushDigests(ctx, false)
+	b.DeliverOutbox(ctx)
+	return sent, nil
+}
@@ -0,0 +1,170 @@
+// bot/report.go
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// RunReport summarizes what a run found on each platform, so callers can tell whether it worked without
+// reading the logs. It is safe to merge into from several goroutines.
+type RunReport struct {
+	// Started is when the run began and Duration how long it took.
+	Started  time.Time
+	Duration time.Duration
+
+	mu        sync.Mutex
+	platforms map[string]*PlatformReport
+	results   []search.SearchResult
+}
+
+// PlatformReport counts the searches of one platform during a run.
+type PlatformReport struct {
+	Platform string
+	// Searches is how many keyword searches ran, and Failed how many of them failed before their results
+	// could be saved.
+	Searches int
+	Failed   int
+	// Found is how many results the searches returned, New how many of them were saved as new, and Skipped
+	// how many were dropped because they were filtered out, already stored, or their search failed.
+	Found   int
+	New     int
+	Skipped int
+	// Duration is the total time spent searching and processing results.
+	Duration time.Duration
+	// Errors holds the error of each failed search.
+	Errors []error
+}
+
+// SearchMetrics records the outcome of each search a bot runs as it finishes, rather than once the run is
+// over like a RunReport.
+type SearchMetrics interface {
+	// RecordSearch records one search of keyword on a platform, with search counting what it found.
+	RecordSearch(keyword string, search PlatformReport)
+}
+
+func newRunReport() *RunReport {
+	return &RunReport{Started: time.Now()}
+}
+
+// finish records the run's duration and returns r.
+func (r *RunReport) finish() *RunReport {
+	r.Duration = time.Since(r.Started)
+	return r
+}
+
+// platform returns the counts for a platform, adding them if needed. r.mu must be held.
+func (r *RunReport) platform(name string) *PlatformReport {
+	if r.platforms == nil {
+		r.platforms = make(map[string]*PlatformReport)
+	}
+	p, ok := r.platforms[name]
+	if !ok {
+		p = &PlatformReport{Platform: name}
+		r.platforms[name] = p
+	}
+	return p
+}
+
+// add records one search of a platform.
+func (r *RunReport) add(search PlatformReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.platform(search.Platform)
+	p.Searches += search.Searches
+	p.Failed += search.Failed
+	p.Found += search.Found
+	p.New += search.New
+	p.Skipped += search.Skipped
+	p.Duration += search.Duration
+	p.Errors = append(p.Errors, search.Errors...)
+}
+
+// addResults records new results saved by a search.
+func (r *RunReport) addResults(results []search.SearchResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, results...)
+}
+
+// Results returns the new results the run saved, in the order they were saved. Edited versions of stored
+// results aren't included.
+func (r *RunReport) Results() []search.SearchResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]search.SearchResult(nil), r.results...)
+}
+
+// Merge adds the counts and new results of other to r, for example to total the runs of several
+// profiles. r's start time becomes the earlier of the two, and its duration covers both.
+func (r *RunReport) Merge(other *RunReport) {
+	if other == nil {
+		return
+	}
+	r.mergeCounts(other)
+	r.addResults(other.Results())
+}
+
+// mergeCounts is Merge without the results, for reports that accumulate across runs.
+func (r *RunReport) mergeCounts(other *RunReport) {
+	for _, p := range other.Platforms() {
+		r.add(p)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	end := r.Started.Add(r.Duration)
+	if otherEnd := other.Started.Add(other.Duration); otherEnd.After(end) {
+		end = otherEnd
+	}
+	if r.Started.IsZero() || other.Started.Before(r.Started) {
+		r.Started = other.Started
+	}
+	r.Duration = end.Sub(r.Started)
+}
+
+// Platforms returns the counts for each platform searched, sorted by platform.
+func (r *RunReport) Platforms() []PlatformReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	platforms := make([]PlatformReport, 0, len(r.platforms))
+	for _, p := range r.platforms {
+		copied := *p
+		copied.Errors = append([]error(nil), p.Errors...)
+		platforms = append(platforms, copied)
+	}
+	sort.Slice(platforms, func(i, j int) bool { return platforms[i].Platform < platforms[j].Platform })
+	return platforms
+}
+
+// Totals returns the counts summed across platforms, with an empty Platform.
+func (r *RunReport) Totals() PlatformReport {
+	var total PlatformReport
+	for _, p := range r.Platforms() {
+		total.Searches += p.Searches
+		total.Failed += p.Failed
+		total.Found += p.Found
+		total.New += p.New
+		total.Skipped += p.Skipped
+		total.Duration += p.Duration
+		total.Errors = append(total.Errors, p.Errors...)
+	}
+	return total
+}
+
+// AllFailed reports whether searches ran and every one of them failed.
+func (r *RunReport) AllFailed() bool {
+	total := r.Totals()
+	return total.Searches > 0 && total.Failed == total.Searches
+}
+
+// String summarizes the report on one line.
+func (r *RunReport) String() string {
+	total := r.Totals()
+	return fmt.Sprintf("%d new results from %d found across %d platforms, %d of %d searches failed, in %s",
+		total.New, total.Found, len(r.Platforms()), total.Failed, total.Searches, r.Duration.Round(time.Millisecond))
+}
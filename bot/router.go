@@ -0,0 +1,187 @@
+// bot/router.go
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Router decides which notifiers receive a given result based on configured rules.
+type Router struct {
+	rules            []route
+	defaultNotifiers []string
+}
+
+type route struct {
+	condition
+	name      string
+	notifiers []string
+	priority  search.Priority
+	cont      bool
+}
+
+// condition is the part of a routing or priority rule that decides which results it applies to.
+type condition struct {
+	platforms map[string]bool
+	keywords  map[string]bool
+	content   *regexp.Regexp
+	minScore  *int64
+}
+
+// newCondition compiles a rule's conditions.
+func newCondition(name string, platforms, keywords []string, contentRegex string, minScore *int64) (condition, error) {
+	c := condition{
+		platforms: lowerSet(platforms),
+		keywords:  lowerSet(keywords),
+		minScore:  minScore,
+	}
+	if contentRegex != "" {
+		re, err := regexp.Compile(contentRegex)
+		if err != nil {
+			return condition{}, fmt.Errorf("%s has an invalid content_regex: %w", name, err)
+		}
+		c.content = re
+	}
+	return c, nil
+}
+
+// NewRouter compiles routing rules and checks that every referenced notifier is enabled.
+func NewRouter(cfg config.Routing, enabled []string) (*Router, error) {
+	known := make(map[string]bool, len(enabled))
+	for _, name := range enabled {
+		known[name] = true
+	}
+
+	checkNotifiers := func(names []string, where string) error {
+		for _, name := range names {
+			if !known[name] {
+				return fmt.Errorf("%s references notifier %q which is not enabled", where, name)
+			}
+		}
+		return nil
+	}
+
+	r := &Router{defaultNotifiers: cfg.DefaultNotifiers}
+	if err := checkNotifiers(cfg.DefaultNotifiers, "default_notifiers"); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range cfg.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		if err := checkNotifiers(rule.Notifiers, name); err != nil {
+			return nil, err
+		}
+
+		priority, err := search.ParsePriority(rule.Priority)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		cond, err := newCondition(name, rule.Platforms, rule.Keywords, rule.ContentRegex, rule.MinScore)
+		if err != nil {
+			return nil, err
+		}
+
+		r.rules = append(r.rules, route{
+			condition: cond,
+			name:      name,
+			notifiers: rule.Notifiers,
+			priority:  priority,
+			cont:      rule.Continue,
+		})
+	}
+
+	return r, nil
+}
+
+// Route returns the names of the notifiers that should receive the result, along with the highest priority
+// assigned by any matching rule. A nil slice means every notifier.
+func (r *Router) Route(result search.SearchResult) ([]string, search.Priority) {
+	if r == nil {
+		return nil, result.Priority
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	matched := false
+	priority := result.Priority
+
+	for _, rule := range r.rules {
+		if !rule.matches(result) {
+			continue
+		}
+
+		matched = true
+		if rule.priority.Rank() > priority.Rank() {
+			priority = rule.priority
+		}
+		for _, name := range rule.notifiers {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+
+		if !rule.cont {
+			break
+		}
+	}
+
+	if !matched {
+		return r.defaultNotifiers, priority
+	}
+
+	// A matching rule with no notifiers deliberately drops the result, so never return nil here.
+	if names == nil {
+		names = []string{}
+	}
+	return names, priority
+}
+
+// matches reports whether a result satisfies every condition set on the rule.
+func (c condition) matches(result search.SearchResult) bool {
+	if len(c.platforms) > 0 && !c.platforms[strings.ToLower(result.Platform)] {
+		return false
+	}
+	if len(c.keywords) > 0 && !c.matchesKeyword(result) {
+		return false
+	}
+	if c.minScore != nil && result.Score < *c.minScore {
+		return false
+	}
+	if c.content != nil && !c.content.MatchString(result.Title+"\n"+result.Content) {
+		return false
+	}
+	return true
+}
+
+// matchesKeyword reports whether the result's keyword, or any other keyword it matched, is one of the
+// condition's keywords.
+func (c condition) matchesKeyword(result search.SearchResult) bool {
+	if c.keywords[strings.ToLower(result.Keyword)] {
+		return true
+	}
+	for _, keyword := range result.Keywords {
+		if c.keywords[strings.ToLower(keyword)] {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerSet builds a case-insensitive lookup set.
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = true
+	}
+	return set
+}
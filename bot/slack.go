@@ -3,56 +3,198 @@ package bot
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/internal/httpclient"
 	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
 )
 
+// slackRetryableErrors are the Slack API errors a later attempt may not get. Any other error, such as
+// channel_not_found or invalid_auth, makes the message undeliverable.
+var slackRetryableErrors = map[string]bool{
+	"ratelimited":         true,
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+}
+
 type SlackNotifier struct {
-	token     string
-	channelID string
+	token      string
+	channelIDs []string
+	template   *MessageTemplate
+	digest     *MessageTemplate
+	mentions   Mentions
+	client     *http.Client
+	// triage adds triage buttons naming triageProfile to the messages of single results.
+	triage        bool
+	triageProfile string
 }
 
-func NewSlackNotifier() *SlackNotifier {
+const (
+	// SlackTriageActionPrefix starts the action ID of each triage button on Slack messages, followed by the
+	// state the button sets.
+	SlackTriageActionPrefix = "grass_triage_"
+	// SlackTriageStatusBlock is the block ID of the note on a Slack message saying who last triaged it.
+	SlackTriageStatusBlock = "grass_triage_status"
+
+	// slackSectionLimit and slackButtonValueLimit are the most characters Slack accepts in a section's text
+	// and a button's value. Longer messages are sent without buttons.
+	slackSectionLimit     = 3000
+	slackButtonValueLimit = 2000
+)
+
+// SlackTriageValue is the value of a triage button on a Slack message, naming the result it triages.
+type SlackTriageValue struct {
+	Profile  string `json:"profile,omitempty"`
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+}
+
+// NewSlackNotifier creates a Slack notifier posting as SLACK_BOT_TOKEN to the channels given with
+// WithChannels, or those in SLACK_CHANNEL_ID. Messages use DefaultSlackTemplate and
+// DefaultSlackDigestTemplate unless WithTemplate or WithDigestTemplate say otherwise, and mentions given
+// with WithMentions are prepended according to each result's priority. Messages are posted with the client
+// given with WithHTTPClient, or a default client, and have triage buttons with WithTriage.
+func NewSlackNotifier(opts ...NotifierOption) (*SlackNotifier, error) {
+	o := newNotifierOptions(opts)
 	token := os.Getenv("SLACK_BOT_TOKEN")
-	channelID := os.Getenv("SLACK_CHANNEL_ID")
+	channelIDs := o.channelIDs
+	if len(channelIDs) == 0 {
+		channelIDs = parseChannelIDs(os.Getenv("SLACK_CHANNEL_ID"))
+	}
 
 	if token == "" {
-		log.Fatal("SLACK_BOT_TOKEN environment variable is not set")
+		return nil, errors.New("SLACK_BOT_TOKEN environment variable is not set")
+	}
+	if len(channelIDs) == 0 {
+		return nil, errors.New("SLACK_CHANNEL_ID environment variable is not set")
+	}
+
+	if o.template == nil {
+		o.template = mustParseTemplate("slack", DefaultSlackTemplate)
 	}
-	if channelID == "" {
-		log.Fatal("SLACK_CHANNEL_ID environment variable is not set")
+	if o.digestTemplate == nil {
+		o.digestTemplate = mustParseTemplate("slack digest", DefaultSlackDigestTemplate)
 	}
 
-	return &SlackNotifier{token: token, channelID: channelID}
+	return &SlackNotifier{
+		token:         token,
+		channelIDs:    channelIDs,
+		template:      o.template,
+		digest:        o.digestTemplate,
+		mentions:      o.mentions,
+		client:        httpclient.WithTimeout(o.client, 0),
+		triage:        o.triage != nil,
+		triageProfile: o.triageProfile,
+	}, nil
 }
 
-// Notify sends a formatted message to the specified Slack channel.
-func (s *SlackNotifier) Notify(result search.SearchResult) error {
-	// Convert Unix timestamp to a human-readable format
-	timestamp := time.Unix(result.Timestamp, 0).Format("01/02/2006 03:04 PM")
-
-	// Format the message with markdown-like styling for Slack
-	message := fmt.Sprintf(
-		"*%s*\n*Platform*: %s\n*Keyword*: %s\n*Posted*: %s\n%s\n<%s|Link>",
-		result.Title,    // Bold title
-		result.Platform, // Platform name
-		result.Keyword,  // Keyword
-		timestamp,       // Human-readable timestamp
-		result.Content,  // Content of the post
-		result.URL,      // URL as a clickable link
-	)
+// Notify sends a formatted message to each configured Slack channel.
+func (s *SlackNotifier) Notify(ctx context.Context, result search.SearchResult) error {
+	message, err := s.template.Render(result)
+	if err != nil {
+		log.Error("Failed to render Slack message", "title", result.Title, "url", result.URL, "error", err)
+		return err
+	}
+	message = s.mentions.prefix(result.Priority, slackMention) + message
+	blocks := s.triageBlocks(message, result)
+
+	// Post to every channel, reporting all failures
+	var errs []error
+	for _, channelID := range s.channelIDs {
+		if err := s.post(ctx, channelID, message, blocks); err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", channelID, err))
+			continue
+		}
+
+		log.Info("Posted to Slack", "channel", channelID, "title", result.Title, "url", result.URL)
+	}
 
+	return errors.Join(errs...)
+}
+
+// NotifyDigest sends a digest of several results as one message to each configured Slack channel.
+func (s *SlackNotifier) NotifyDigest(ctx context.Context, digest Digest) error {
+	message, err := s.digest.RenderDigest(digest)
+	if err != nil {
+		log.Error("Failed to render Slack digest", "results", len(digest.Results), "error", err)
+		return err
+	}
+	message = s.mentions.prefix(digest.Priority(), slackMention) + message
+
+	var errs []error
+	for _, channelID := range s.channelIDs {
+		if err := s.post(ctx, channelID, message, nil); err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", channelID, err))
+			continue
+		}
+
+		log.Info("Posted digest to Slack", "channel", channelID, "results", len(digest.Results))
+	}
+
+	return errors.Join(errs...)
+}
+
+// NotifyText sends a plain text message to each configured Slack channel.
+func (s *SlackNotifier) NotifyText(ctx context.Context, text string) error {
+	var errs []error
+	for _, channelID := range s.channelIDs {
+		if err := s.post(ctx, channelID, text, nil); err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", channelID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// triageBlocks lays a result's message out as blocks followed by triage buttons, or returns nil if the
+// notifier has no triage buttons or the message is too long for them.
+func (s *SlackNotifier) triageBlocks(message string, result search.SearchResult) []map[string]any {
+	if !s.triage || len(message) > slackSectionLimit {
+		return nil
+	}
+	value, err := json.Marshal(SlackTriageValue{Profile: s.triageProfile, Platform: result.Platform, URL: result.URL})
+	if err != nil || len(value) > slackButtonValueLimit {
+		return nil
+	}
+
+	buttons := make([]map[string]any, 0, len(TriageActions))
+	for _, action := range TriageActions {
+		button := map[string]any{
+			"type":      "button",
+			"action_id": SlackTriageActionPrefix + action.State,
+			"text":      map[string]string{"type": "plain_text", "text": action.Label},
+			"value":     string(value),
+		}
+		if action.State == storage.StateEscalated {
+			button["style"] = "danger"
+		}
+		buttons = append(buttons, button)
+	}
+	return []map[string]any{
+		{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": message}},
+		{"type": "actions", "elements": buttons},
+	}
+}
+
+// post sends a single message to a Slack channel via chat.postMessage, laid out as blocks if there are
+// any. The message is still sent as text for notifications.
+func (s *SlackNotifier) post(ctx context.Context, channelID, message string, blocks []map[string]any) error {
 	// Build the JSON payload for the Slack API request
 	payload := map[string]interface{}{
-		"channel": s.channelID,
+		"channel": channelID,
 		"text":    message,
 	}
+	if blocks != nil {
+		payload["blocks"] = blocks
+	}
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
@@ -60,7 +202,7 @@ func (s *SlackNotifier) Notify(result search.SearchResult) error {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", "https://slack.com/api/chat.postMessage", bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/chat.postMessage", bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		log.Error("Failed to create Slack request", "error", err)
 		return err
@@ -69,20 +211,35 @@ func (s *SlackNotifier) Notify(result search.SearchResult) error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+s.token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
-		log.Error("Failed to send message to Slack", "error", err)
+		log.Error("Failed to send message to Slack", "channel", channelID, "error", err)
 		return err
 	}
 	defer resp.Body.Close()
 
 	// Check if the request was successful
 	if resp.StatusCode != http.StatusOK {
-		log.Error("Slack API request failed", "status_code", resp.StatusCode)
+		log.Error("Slack API request failed", "channel", channelID, "status_code", resp.StatusCode)
 		return fmt.Errorf("Slack API request failed with status code: %d", resp.StatusCode)
 	}
 
-	log.Info("Posted to Slack", "title", result.Title, "url", result.URL)
+	// Slack reports most failures with a 200 and ok set to false
+	var body struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		log.Error("Failed to decode Slack response", "channel", channelID, "error", err)
+		return fmt.Errorf("failed to decode Slack response: %w", err)
+	}
+	if !body.OK {
+		log.Error("Slack API request failed", "channel", channelID, "error", body.Error)
+		if slackRetryableErrors[body.Error] {
+			return fmt.Errorf("Slack API error: %s", body.Error)
+		}
+		return fmt.Errorf("%w: Slack API error: %s", errUndeliverable, body.Error)
+	}
+
 	return nil
 }
@@ -0,0 +1,137 @@
+// bot/spam.go
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// SpamLevels are the accepted strictness levels, from least to most aggressive.
+var SpamLevels = []string{"off", "low", "medium", "high"}
+
+// spamThresholds is the score a result needs to be dropped at each level.
+var spamThresholds = map[string]int{"low": 3, "medium": 2, "high": 1}
+
+const (
+	// spamFloodWindow is how long results are remembered when looking for duplicate-content floods.
+	spamFloodWindow = time.Hour
+	// spamFloodCount is how many distinct posts with the same content make a flood.
+	spamFloodCount = 3
+	// spamNewAccountAge is how young an account must be to count as brand new.
+	spamNewAccountAge = 7 * 24 * time.Hour
+)
+
+var (
+	cryptoSpamPattern = regexp.MustCompile(`(?i)\b(airdrops?|presale|giveaway|free (crypto|tokens?|nfts?)|claim (your|now)|100x|1000x|to the moon|memecoin|whitelist spots?|connect (your )?wallet|dm me)\b|\b0x[0-9a-fA-F]{40}\b`)
+	followBotPattern  = regexp.MustCompile(`(?i)\b(follow (me|back|for follow)|f4f|follow4follow|check (out )?my (profile|bio)|link in (my )?bio|dm (me )?for (promo|promotion|collab)|promote your|gain followers)\b`)
+	linkPattern       = regexp.MustCompile(`(?i)https?://`)
+	hashtagPattern    = regexp.MustCompile(`#\w+`)
+)
+
+// SpamFilter scores results with heuristics for crypto spam, follow-bot phrasing, duplicate-content floods,
+// and brand-new accounts posting links, dropping those that score at or above the level's threshold.
+type SpamFilter struct {
+	threshold int
+
+	mu     sync.Mutex
+	floods map[string]map[string]time.Time
+}
+
+// NewSpamFilter creates a filter for one of SpamLevels. The "off" level returns a nil filter, which keeps
+// everything.
+func NewSpamFilter(level string) (*SpamFilter, error) {
+	if level == "" || level == "off" {
+		return nil, nil
+	}
+	threshold, ok := spamThresholds[level]
+	if !ok {
+		return nil, fmt.Errorf("unknown spam filter level %q: must be one of %s", level, strings.Join(SpamLevels, ", "))
+	}
+	return &SpamFilter{threshold: threshold, floods: make(map[string]map[string]time.Time)}, nil
+}
+
+// Spam reports whether a result looks like spam, along with the heuristics it tripped.
+func (s *SpamFilter) Spam(result search.SearchResult) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+
+	text := result.Title + "\n" + result.Content
+	score := 0
+	var reasons []string
+	add := func(points int, reason string) {
+		score += points
+		reasons = append(reasons, reason)
+	}
+
+	if matches := distinctMatches(cryptoSpamPattern, text); matches >= 2 {
+		add(3, "crypto spam")
+	} else if matches == 1 {
+		add(1, "crypto spam")
+	}
+	if distinctMatches(followBotPattern, text) > 0 {
+		add(2, "follow-bot phrasing")
+	}
+	if len(hashtagPattern.FindAllString(result.Content, -1)) > 5 {
+		add(1, "hashtag stuffing")
+	}
+	if result.AuthorCreatedAt > 0 && time.Since(time.Unix(result.AuthorCreatedAt, 0)) < spamNewAccountAge {
+		if linkPattern.MatchString(result.Content) {
+			add(2, "new account posting links")
+		} else {
+			add(1, "new account")
+		}
+	}
+	if s.flood(result) {
+		add(3, "duplicate-content flood")
+	}
+
+	return strings.Join(reasons, ", "), score >= s.threshold
+}
+
+// flood records the result and reports whether several distinct posts with the same content have been
+// seen on its platform recently.
+func (s *SpamFilter) flood(result search.SearchResult) bool {
+	content := strings.Join(strings.Fields(strings.ToLower(result.Content)), " ")
+	if len(content) < 20 {
+		return false
+	}
+	key := result.Platform + "\x00" + content
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, posts := range s.floods {
+		for url, seen := range posts {
+			if now.Sub(seen) > spamFloodWindow {
+				delete(posts, url)
+			}
+		}
+		if len(posts) == 0 {
+			delete(s.floods, k)
+		}
+	}
+
+	posts, ok := s.floods[key]
+	if !ok {
+		posts = make(map[string]time.Time)
+		s.floods[key] = posts
+	}
+	posts[result.URL] = now
+	return len(posts) >= spamFloodCount
+}
+
+// distinctMatches counts the different phrases a pattern matches, ignoring case.
+func distinctMatches(pattern *regexp.Regexp, text string) int {
+	seen := make(map[string]bool)
+	for _, match := range pattern.FindAllString(text, -1) {
+		seen[strings.ToLower(match)] = true
+	}
+	return len(seen)
+}
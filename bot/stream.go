@@ -0,0 +1,124 @@
+// bot/stream.go
+package bot
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+)
+
+const (
+	// streamBaseDelay and streamMaxDelay bound the backoff between reconnection attempts.
+	streamBaseDelay = time.Second
+	streamMaxDelay  = 5 * time.Minute
+	// streamStable is how long a connection must last for the backoff to reset when it drops.
+	streamStable = time.Minute
+	// streamBatchSize and streamBatchWait bound how many streamed results are collected, and for how
+	// long, before they go through the pipeline together.
+	streamBatchSize = 100
+	streamBatchWait = 2 * time.Second
+)
+
+// Stream runs a streaming searcher for keywords until ctx is cancelled, reconnecting with exponential
+// backoff whenever the stream ends. Before each connection it searches every keyword once, so results
+// posted while disconnected are not missed. Streamed results go through the same pipeline as pushed
+// results; see Ingest. Account keywords are only searched, never streamed.
+func (b *Bot) Stream(ctx context.Context, provider search.StreamingSearcher, keywords []string) {
+	failures := 0
+	for {
+		// Catch up from the last search time before listening for new results
+		b.runKeywords(ctx, keywords, []search.Searcher{provider}, 1, b.timeRange(), newRunReport())
+		b.flushDigests(ctx, false)
+		if ctx.Err() != nil {
+			return
+		}
+
+		connected := time.Now()
+		err := b.stream(ctx, provider, keywords)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(connected) >= streamStable {
+			failures = 0
+		}
+		failures++
+		delay := min(streamBaseDelay<<(failures-1), streamMaxDelay)
+		log.Warn("Stream disconnected; reconnecting", "platform", provider.Platform(), "error", err, "retry_in", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// stream runs one connection, batching its results through the pipeline until it ends.
+func (b *Bot) stream(ctx context.Context, provider search.StreamingSearcher, keywords []string) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var streamed []string
+	for _, keyword := range keywords {
+		if _, watching := search.ParseAccount(keyword); !watching {
+			streamed = append(streamed, keyword)
+		}
+	}
+	keywords = streamed
+
+	results := make(chan search.SearchResult, streamBatchSize)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- provider.Stream(streamCtx, keywords, results)
+	}()
+	log.Info("Streaming results", "platform", provider.Platform(), "keywords", len(keywords))
+
+	var batch []search.SearchResult
+	var wait <-chan time.Time
+	flush := func() {
+		if len(batch) > 0 {
+			// Errors are logged by ingest, and there is no sender to report them to
+			_, _ = b.ingest(ctx, batch, provider)
+		}
+		batch, wait = nil, nil
+	}
+	add := func(result search.SearchResult) {
+		if result.Platform == "" {
+			result.Platform = provider.Platform()
+		}
+		if len(batch) == 0 {
+			wait = time.After(streamBatchWait)
+		}
+		batch = append(batch, result)
+		if len(batch) >= streamBatchSize {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case result := <-results:
+			add(result)
+		case <-wait:
+			flush()
+		case err := <-errc:
+			// Keep whatever the stream sent before it ended
+			for drained := false; !drained; {
+				select {
+				case result := <-results:
+					add(result)
+				default:
+					drained = true
+				}
+			}
+			flush()
+			if err == nil {
+				err = errors.New("stream ended")
+			}
+			return err
+		}
+	}
+}
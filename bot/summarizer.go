@@ -0,0 +1,118 @@
+// bot/summarizer.go
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jaxxstorm/grass/internal/httpclient"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Summarizer condenses a result's content into a short summary for notifications. It returns an empty
+// summary for results that don't need one.
+type Summarizer interface {
+	Summarize(ctx context.Context, result search.SearchResult) (string, error)
+}
+
+// summarizerPrompt instructs the model to produce the summary shown in notifications.
+const summarizerPrompt = "Summarize the following social media post or discussion in one or two plain sentences for a notification. Reply with the summary only."
+
+// maxSummarizerInput caps how many characters of content are sent to the model.
+const maxSummarizerInput = 8000
+
+// OpenAISummarizer summarizes results with any OpenAI-compatible chat completions API, such as OpenAI,
+// Azure OpenAI, or a local Ollama server.
+type OpenAISummarizer struct {
+	baseURL   string
+	apiKey    string
+	model     string
+	minLength int
+	client    *http.Client
+}
+
+// NewOpenAISummarizer configures a summarizer from OPENAI_API_KEY and OPENAI_BASE_URL (defaulting to the
+// OpenAI API), sending requests with client. Results whose content is shorter than minLength characters are
+// not summarized.
+func NewOpenAISummarizer(model string, minLength int, client *http.Client) (*OpenAISummarizer, error) {
+	baseURL := os.Getenv("OPENAI_BASE_URL")
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if baseURL == "" {
+		if apiKey == "" {
+			return nil, errors.New("missing summarizer configuration: OPENAI_API_KEY is required unless OPENAI_BASE_URL is set")
+		}
+		baseURL = "https://api.openai.com/v1"
+	}
+
+	return &OpenAISummarizer{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		apiKey:    apiKey,
+		model:     model,
+		minLength: minLength,
+		client:    httpclient.WithTimeout(client, 60*time.Second),
+	}, nil
+}
+
+// Summarize asks the model for a one or two sentence summary of the result's title and content.
+func (o *OpenAISummarizer) Summarize(ctx context.Context, result search.SearchResult) (string, error) {
+	if utf8.RuneCountInString(result.Content) < o.minLength {
+		return "", nil
+	}
+
+	payload := map[string]interface{}{
+		"model": o.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": summarizerPrompt},
+			{"role": "user", "content": result.Title + "\n\n" + truncate(maxSummarizerInput, result.Content)},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal summarizer request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create summarizer request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("summarizer request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("summarizer request failed with status code %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", fmt.Errorf("failed to parse summarizer response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", errors.New("summarizer returned no choices")
+	}
+
+	return strings.TrimSpace(completion.Choices[0].Message.Content), nil
+}
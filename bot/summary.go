@@ -0,0 +1,102 @@
+// bot/summary.go
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// SummaryModes are the accepted values for how run summaries are sent: not at all, as well as each result,
+// or instead of each result.
+var SummaryModes = []string{"off", "also", "only"}
+
+// SummaryMessage formats a report as a run summary notification: a headline with the totals, then a line
+// per platform.
+func SummaryMessage(report *RunReport) string {
+	total := report.Totals()
+	platforms := report.Platforms()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "grass run summary: %s across %s", plural(total.New, "new result"), plural(len(platforms), "platform"))
+	if total.Failed > 0 {
+		fmt.Fprintf(&b, ", %s", plural(total.Failed, "error"))
+	}
+	fmt.Fprintf(&b, " in %s", report.Duration.Round(time.Second))
+	for _, p := range platforms {
+		fmt.Fprintf(&b, "\n• %s: %d new of %d found in %s, %s", p.Platform, p.New, p.Found, plural(p.Searches, "search"), p.Duration.Round(time.Millisecond))
+		if p.Failed > 0 {
+			fmt.Fprintf(&b, ", %d failed", p.Failed)
+		}
+	}
+	return b.String()
+}
+
+// plural formats a count with its noun, adding "s" or "es" when the count isn't one.
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	if strings.HasSuffix(noun, "ch") {
+		return fmt.Sprintf("%d %ses", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// NotifySummary sends a run summary to every notifier that can send plain text, bypassing digests and
+// rate limits. It returns an error if any notifier failed.
+func (b *Bot) NotifySummary(ctx context.Context, report *RunReport) error {
+	notifiers := b.CurrentNotifiers()
+	names := make([]string, 0, len(notifiers))
+	for name := range notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	message := SummaryMessage(report)
+	var errs []error
+	for _, name := range names {
+		sender, ok := AsTextSender(notifiers[name])
+		if !ok {
+			continue
+		}
+		notifyCtx, cancel := withTimeout(ctx, b.NotifyTimeout)
+		err := sender.NotifyText(notifyCtx, message)
+		cancel()
+		if err != nil {
+			log.Error("Error sending run summary", "notifier", name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TakeSummary returns a report combining the counts of every run since the last call, or since the bot was
+// created, and starts collecting a new one. Its Results are always empty. The daemon uses it to send periodic summaries.
+func (b *Bot) TakeSummary() *RunReport {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	summary := b.summary
+	if summary == nil {
+		summary = newRunReport()
+	}
+	b.summary = newRunReport()
+	return summary.finish()
+}
+
+// record adds a finished run to the report returned by TakeSummary, and emits its completion.
+func (b *Bot) record(report *RunReport) *RunReport {
+	b.mu.Lock()
+	if b.summary == nil {
+		b.summary = newRunReport()
+	}
+	b.summary.mergeCounts(report)
+	b.mu.Unlock()
+	b.emit(Event{Type: EventRunCompleted, Report: report})
+	return report
+}
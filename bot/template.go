@@ -0,0 +1,167 @@
+// bot/template.go
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Default message templates. Slack and Discord show timestamps natively, in each reader's own timezone.
+const (
+	DefaultPrintTemplate   = "{{ if .Edited }}Updated\n{{ end }}Platform: {{ .Platform }}\nKeyword: {{ keywords . }}\nTitle: {{ .Title }}\nURL: {{ .URL }}\nTimestamp: {{ .Timestamp }}\n{{ with .Parent }}In reply to: {{ or .Title (truncate 140 .Content) }} ({{ .URL }})\n{{ end }}{{ with .Preview }}Links to: {{ or .Title .URL }} ({{ .URL }})\n{{ end }}{{ range .Duplicates }}Also on {{ .Platform }}: {{ .URL }}\n{{ end }}\n"
+	DefaultSlackTemplate   = "{{ if .Edited }}*Updated:* {{ end }}*{{ .Title }}*\n*Platform*: {{ .Platform }}\n*Keyword*: {{ keywords . }}\n*Posted*: {{ slackDate .Timestamp }}\n{{ with .Parent }}In reply to <{{ .URL }}|{{ or .Title .Author \"a post\" }}>{{ with .Content }}\n> {{ truncate 280 . }}{{ end }}\n{{ end }}{{ or .Summary .Content }}\n{{ with .Preview }}Links to: <{{ .URL }}|{{ or .Title .URL }}>{{ with .Description }}\n> {{ truncate 280 . }}{{ end }}\n{{ end }}<{{ .URL }}|Link>{{ range .Duplicates }}\nAlso on {{ .Platform }}: <{{ .URL }}|Link>{{ end }}"
+	DefaultDiscordTemplate = "{{ if .Edited }}**Updated:** {{ end }}**{{ .Title }}**\n*Platform*: {{ .Platform }}\n*Keyword*: {{ keywords . }}\n*Posted*: {{ discordTime \"f\" .Timestamp }} ({{ discordTime \"R\" .Timestamp }})\n{{ with .Parent }}In reply to [{{ or .Title .Author \"a post\" }}](<{{ .URL }}>){{ with .Content }}\n> {{ truncate 280 . }}{{ end }}\n{{ end }}{{ or .Summary .Content }}\n{{ with .Preview }}Links to: **{{ or .Title .URL }}**{{ with .Description }}\n> {{ truncate 280 . }}{{ end }}\n{{ .URL }}\n{{ end }}{{ .URL }}{{ range .Duplicates }}\nAlso on {{ .Platform }}: {{ .URL }}{{ end }}"
+)
+
+// Default digest templates, rendered against a Digest when results are batched into one message.
+const (
+	DefaultPrintDigestTemplate   = "Digest: {{ len .Results }} new result(s)\n{{ range .Groups }}\n{{ .Platform }} / {{ .Keyword }}:\n{{ range .Results }}- {{ .Title }}: {{ .URL }}\n{{ with .Summary }}  {{ . }}\n{{ end }}{{ end }}{{ end }}\n"
+	DefaultSlackDigestTemplate   = "*{{ len .Results }} new result(s)*{{ range .Groups }}\n\n*{{ .Platform }}* / *{{ .Keyword }}*{{ range .Results }}\n• <{{ .URL }}|{{ .Title }}>{{ with .Summary }}\n  {{ . }}{{ end }}{{ end }}{{ end }}"
+	DefaultDiscordDigestTemplate = "**{{ len .Results }} new result(s)**{{ range .Groups }}\n\n**{{ .Platform }}** / **{{ .Keyword }}**{{ range .Results }}\n- [{{ .Title }}](<{{ .URL }}>){{ with .Summary }}\n  {{ . }}{{ end }}{{ end }}{{ end }}"
+)
+
+// MessageTemplate renders a search result into a notifier message.
+type MessageTemplate struct {
+	tmpl *template.Template
+}
+
+// templateFuncs are available to every message template.
+var templateFuncs = template.FuncMap{
+	"truncate":    truncate,
+	"humanize":    humanize,
+	"formatTime":  formatTime,
+	"slackDate":   slackDate,
+	"discordTime": discordTime,
+	"keywords":    keywords,
+	"upper":       strings.ToUpper,
+	"lower":       strings.ToLower,
+	"trim":        strings.TrimSpace,
+}
+
+// ParseTemplate compiles a message template, falling back to defaultText when text is empty.
+func ParseTemplate(name, text, defaultText string) (*MessageTemplate, error) {
+	if text == "" {
+		text = defaultText
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+
+	return &MessageTemplate{tmpl: tmpl}, nil
+}
+
+// mustParseTemplate compiles one of the built-in templates.
+func mustParseTemplate(name, text string) *MessageTemplate {
+	return &MessageTemplate{tmpl: template.Must(template.New(name).Funcs(templateFuncs).Parse(text))}
+}
+
+// Render executes the template against a result.
+func (m *MessageTemplate) Render(result search.SearchResult) (string, error) {
+	return m.execute(result)
+}
+
+// RenderDigest executes the template against a digest of several results.
+func (m *MessageTemplate) RenderDigest(digest Digest) (string, error) {
+	return m.execute(digest)
+}
+
+func (m *MessageTemplate) execute(data any) (string, error) {
+	var buf bytes.Buffer
+	if err := m.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", m.tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// truncate shortens s to at most n characters, adding an ellipsis when it cuts.
+func truncate(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// keywords lists every keyword a result matched, falling back to its Keyword.
+func keywords(result search.SearchResult) string {
+	if len(result.Keywords) == 0 {
+		return result.Keyword
+	}
+	return strings.Join(result.Keywords, ", ")
+}
+
+// DefaultTimeLayout is the traditional grass timestamp format.
+const DefaultTimeLayout = "01/02/2006 03:04 PM"
+
+// timeLocation and timeLayout control how formatTime renders timestamps.
+var (
+	timeLocation = time.Local
+	timeLayout   = DefaultTimeLayout
+)
+
+// SetTimeFormat sets the timezone and Go time layout timestamps are rendered in by formatTime, including
+// the fallback text of Slack dates. Call it before any messages are rendered.
+func SetTimeFormat(location *time.Location, layout string) {
+	timeLocation = location
+	timeLayout = layout
+}
+
+// formatTime renders a Unix timestamp in the configured timezone and layout.
+func formatTime(epochSecs int64) string {
+	return time.Unix(epochSecs, 0).In(timeLocation).Format(timeLayout)
+}
+
+// slackDate renders a Unix timestamp as a Slack date, which Slack shows in each reader's timezone, falling
+// back to formatTime in clients that can't.
+func slackDate(epochSecs int64) string {
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s>", epochSecs, formatTime(epochSecs))
+}
+
+// discordTime renders a Unix timestamp as a Discord timestamp, which Discord shows in each reader's
+// timezone. style is one of Discord's formats: t, T, d, D, f, F, or R (relative, e.g. "5 minutes ago").
+func discordTime(style string, epochSecs int64) string {
+	return fmt.Sprintf("<t:%d:%s>", epochSecs, style)
+}
+
+// humanize renders a Unix timestamp relative to now, e.g. "5 minutes ago".
+func humanize(epochSecs int64) string {
+	d := time.Since(time.Unix(epochSecs, 0))
+	suffix := "ago"
+	if d < 0 {
+		d = -d
+		suffix = "from now"
+	}
+
+	unit := func(n int64, name string) string {
+		if n != 1 {
+			name += "s"
+		}
+		return fmt.Sprintf("%d %s %s", n, name, suffix)
+	}
+
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return unit(int64(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return unit(int64(d/time.Hour), "hour")
+	case d < 30*24*time.Hour:
+		return unit(int64(d/(24*time.Hour)), "day")
+	case d < 365*24*time.Hour:
+		return unit(int64(d/(30*24*time.Hour)), "month")
+	default:
+		return unit(int64(d/(365*24*time.Hour)), "year")
+	}
+}
@@ -0,0 +1,202 @@
+// bot/throttle.go
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// ThrottleOverflows are the ways a throttled notifier handles results over its cap: "digest" sends them
+// together once the cap allows, and "drop" discards them, sending only a count.
+var ThrottleOverflows = []string{"digest", "drop"}
+
+// throttleDigestMax is the most suppressed results included in an overflow digest; the rest are counted
+// in its trailer.
+const throttleDigestMax = 50
+
+// TextSender is implemented by notifiers that can send a plain text message, such as the trailer saying
+// how many results a throttled notifier suppressed.
+type TextSender interface {
+	NotifyText(ctx context.Context, text string) error
+}
+
+// flusher is implemented by notifiers that hold results back until they are flushed.
+type flusher interface {
+	Flush(ctx context.Context, force bool) error
+}
+
+// ThrottleNotifier caps how many messages a notifier sends per window so a keyword going viral doesn't hit
+// API rate limits or flood channels. Results over the cap are held back, and once a message is allowed
+// again they are sent as a digest (or, when dropping, counted) with an "N more results suppressed" trailer.
+type ThrottleNotifier struct {
+	notifier Notifier
+	limit    int
+	window   time.Duration
+	drop     bool
+
+	mu         sync.Mutex
+	sent       []time.Time
+	suppressed []search.SearchResult
+	dropped    int
+}
+
+// NewThrottleNotifier wraps a notifier so it sends at most limit messages per window. overflow is one of
+// ThrottleOverflows.
+func NewThrottleNotifier(notifier Notifier, limit int, window time.Duration, overflow string) (*ThrottleNotifier, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("rate limit must be positive, got %d", limit)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("rate window must be positive, got %s", window)
+	}
+	switch overflow {
+	case "", "digest", "drop":
+	default:
+		return nil, fmt.Errorf("unknown rate limit overflow %q: must be one of %s", overflow, strings.Join(ThrottleOverflows, ", "))
+	}
+	return &ThrottleNotifier{notifier: notifier, limit: limit, window: window, drop: overflow == "drop"}, nil
+}
+
+// Unwrap returns the wrapped notifier.
+func (t *ThrottleNotifier) Unwrap() Notifier {
+	return t.notifier
+}
+
+// Notify sends the result if the cap allows, otherwise holds it back until the next flush. Once results
+// are being held back, later ones are held back too so they are reported in order.
+func (t *ThrottleNotifier) Notify(ctx context.Context, result search.SearchResult) error {
+	if !t.allow(func() { t.suppress([]search.SearchResult{result}) }) {
+		return nil
+	}
+	return t.notifier.Notify(ctx, result)
+}
+
+// NotifyDigest sends a digest as a single message if the cap allows, otherwise holds its results back.
+func (t *ThrottleNotifier) NotifyDigest(ctx context.Context, digest Digest) error {
+	if !t.allow(func() { t.suppress(digest.Results) }) {
+		return nil
+	}
+	return t.sendDigest(ctx, digest)
+}
+
+// allow records a message if one may be sent now. Otherwise it calls hold, with the lock held, and reports
+// false.
+func (t *ThrottleNotifier) allow(hold func()) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.suppressed) > 0 || t.dropped > 0 || !t.available(time.Now()) {
+		hold()
+		return false
+	}
+	t.sent = append(t.sent, time.Now())
+	return true
+}
+
+// available forgets messages older than the window and reports whether another may be sent. Callers must
+// hold the lock.
+func (t *ThrottleNotifier) available(now time.Time) bool {
+	kept := t.sent[:0]
+	for _, sent := range t.sent {
+		if now.Sub(sent) < t.window {
+			kept = append(kept, sent)
+		}
+	}
+	t.sent = kept
+	return len(t.sent) < t.limit
+}
+
+// suppress holds results back, or only counts them when dropping. Callers must hold the lock.
+func (t *ThrottleNotifier) suppress(results []search.SearchResult) {
+	if t.drop {
+		t.dropped += len(results)
+		return
+	}
+	t.suppressed = append(t.suppressed, results...)
+}
+
+// Flush sends the held back results once the cap allows another message, or immediately when force is set.
+// Results that fail to send are held back again. Flushing also flushes the wrapped notifier if it buffers
+// results itself.
+func (t *ThrottleNotifier) Flush(ctx context.Context, force bool) error {
+	err := t.flush(ctx, force)
+	if inner, ok := t.notifier.(flusher); ok {
+		err = errors.Join(err, inner.Flush(ctx, force))
+	}
+	return err
+}
+
+func (t *ThrottleNotifier) flush(ctx context.Context, force bool) error {
+	t.mu.Lock()
+	if (len(t.suppressed) == 0 && t.dropped == 0) || (!force && !t.available(time.Now())) {
+		t.mu.Unlock()
+		return nil
+	}
+	results, dropped := t.suppressed, t.dropped
+	t.suppressed, t.dropped = nil, 0
+	t.sent = append(t.sent, time.Now())
+	t.mu.Unlock()
+
+	if len(results) > 0 {
+		if _, ok := t.notifier.(DigestSender); ok {
+			included := results[:min(len(results), throttleDigestMax)]
+			if err := t.sendDigest(ctx, NewDigest(included)); err != nil {
+				t.restore(results, dropped)
+				return err
+			}
+			dropped += len(results) - len(included)
+		} else {
+			dropped += len(results)
+		}
+	}
+	if dropped > 0 {
+		if err := t.sendTrailer(ctx, dropped); err != nil {
+			t.restore(nil, dropped)
+			return err
+		}
+	}
+	return nil
+}
+
+// restore holds back results and counts that failed to send so the next flush retries them.
+func (t *ThrottleNotifier) restore(results []search.SearchResult, dropped int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.suppressed = append(results, t.suppressed...)
+	t.dropped += dropped
+}
+
+// sendDigest sends a digest through the wrapped notifier, one result at a time if it can't send digests.
+func (t *ThrottleNotifier) sendDigest(ctx context.Context, digest Digest) error {
+	if sender, ok := t.notifier.(DigestSender); ok {
+		return sender.NotifyDigest(ctx, digest)
+	}
+
+	var errs []error
+	for _, result := range digest.Results {
+		if err := t.notifier.Notify(ctx, result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendTrailer reports how many results were suppressed without being sent.
+func (t *ThrottleNotifier) sendTrailer(ctx context.Context, count int) error {
+	sender, ok := t.notifier.(TextSender)
+	if !ok {
+		return nil
+	}
+
+	noun := "results"
+	if count == 1 {
+		noun = "result"
+	}
+	return sender.NotifyText(ctx, fmt.Sprintf("%d more %s suppressed by the rate limit of %d messages per %s", count, noun, t.limit, t.window))
+}
@@ -0,0 +1,49 @@
+// bot/triage.go
+package bot
+
+import (
+	"context"
+
+	"github.com/jaxxstorm/grass/storage"
+)
+
+// TriageAction is a button on a notification that sets the state of the notified result.
+type TriageAction struct {
+	Label string
+	State string
+}
+
+// TriageActions are the buttons on notifications sent by notifiers given WithTriage.
+var TriageActions = []TriageAction{
+	{Label: "Acknowledge", State: storage.StateAcknowledged},
+	{Label: "Dismiss", State: storage.StateDismissed},
+	{Label: "Escalate", State: storage.StateEscalated},
+}
+
+// Triager sets the state of a stored result when a button on its notification is pressed, reporting false
+// if the result isn't stored.
+type Triager func(ctx context.Context, platform, url, state string) (bool, error)
+
+// WithTriage adds TriageActions buttons to the Slack and Discord notifications of single results, which
+// digests don't get. Discord delivers presses over the notifier's gateway connection, where they are passed
+// to triage. Slack delivers them to the Slack app's interactivity URL instead, so its buttons name profile
+// for whatever serves that URL to find the result in.
+func WithTriage(profile string, triage Triager) NotifierOption {
+	return func(o *notifierOptions) {
+		o.triageProfile = profile
+		o.triage = triage
+	}
+}
+
+// TriagedText describes a result's state being set by who, for the note added to its notification.
+func TriagedText(state, who string) string {
+	switch state {
+	case storage.StateAcknowledged:
+		return "Acknowledged by " + who
+	case storage.StateDismissed:
+		return "Dismissed by " + who
+	case storage.StateEscalated:
+		return "Escalated by " + who
+	}
+	return "Marked " + state + " by " + who
+}
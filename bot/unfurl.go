@@ -0,0 +1,208 @@
+// bot/unfurl.go
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaxxstorm/grass/internal/httpclient"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Unfurler fetches a preview of the page a link post points to, for notifications.
+type Unfurler interface {
+	Unfurl(ctx context.Context, link string) (*search.LinkPreview, error)
+}
+
+const (
+	// maxUnfurlBody caps how much of a page is read looking for its metadata.
+	maxUnfurlBody = 512 << 10
+	// unfurlCacheSize is the most previews kept in memory.
+	unfurlCacheSize = 1000
+	// unfurlFailureTTL is how long a failed fetch is remembered, shorter than successes so transient
+	// failures are retried.
+	unfurlFailureTTL = 10 * time.Minute
+)
+
+var (
+	htmlMetaTag    = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	htmlTitleTag   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlAttribute  = regexp.MustCompile(`(?is)([a-z][a-z0-9:_-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	htmlWhitespace = regexp.MustCompile(`\s+`)
+)
+
+// OpenGraphUnfurler builds previews from a page's OpenGraph and Twitter card metadata, falling back to its
+// HTML title and meta description. Previews, and failures, are cached so a link found by several searches
+// or runs is fetched once.
+type OpenGraphUnfurler struct {
+	client *http.Client
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]unfurlEntry
+}
+
+// unfurlEntry is a cached preview, or a nil preview for a page that couldn't be unfurled.
+type unfurlEntry struct {
+	preview *search.LinkPreview
+	err     error
+	expires time.Time
+}
+
+// NewOpenGraphUnfurler creates an unfurler that fetches pages with client and caches previews for ttl.
+func NewOpenGraphUnfurler(ttl time.Duration, client *http.Client) *OpenGraphUnfurler {
+	return &OpenGraphUnfurler{
+		client: httpclient.WithTimeout(client, 15*time.Second),
+		ttl:    ttl,
+		cache:  make(map[string]unfurlEntry),
+	}
+}
+
+// Unfurl returns the preview of a page, fetching it unless a cached preview is still fresh.
+func (o *OpenGraphUnfurler) Unfurl(ctx context.Context, link string) (*search.LinkPreview, error) {
+	now := time.Now()
+	o.mu.Lock()
+	entry, ok := o.cache[link]
+	o.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.preview, entry.err
+	}
+
+	preview, err := o.fetch(ctx, link)
+	// Don't remember fetches abandoned because the caller gave up
+	if ctx.Err() != nil {
+		return nil, err
+	}
+	entry = unfurlEntry{preview: preview, err: err, expires: now.Add(o.ttl)}
+	if err != nil {
+		entry.expires = now.Add(min(o.ttl, unfurlFailureTTL))
+	}
+	o.store(link, entry, now)
+	return preview, err
+}
+
+// store caches an entry, evicting expired entries, and then the oldest, when the cache is full.
+func (o *OpenGraphUnfurler) store(link string, entry unfurlEntry, now time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.cache) >= unfurlCacheSize {
+		oldest := ""
+		for cached, e := range o.cache {
+			if !now.Before(e.expires) {
+				delete(o.cache, cached)
+			} else if oldest == "" || e.expires.Before(o.cache[oldest].expires) {
+				oldest = cached
+			}
+		}
+		if len(o.cache) >= unfurlCacheSize {
+			delete(o.cache, oldest)
+		}
+	}
+	o.cache[link] = entry
+}
+
+// fetch downloads the start of a page and reads its metadata.
+func (o *OpenGraphUnfurler) fetch(ctx context.Context, link string) (*search.LinkPreview, error) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("not an http(s) link: %s", link)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "grass-unfurl/1.0")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", link, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", link, resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("%s is not an HTML page: %s", link, contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxUnfurlBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", link, err)
+	}
+
+	// Redirects are followed, so relative image URLs resolve against the final page
+	preview := parsePreview(string(body), resp.Request.URL)
+	if preview.Title == "" && preview.Description == "" {
+		return nil, errors.New("page has no title or description")
+	}
+	return preview, nil
+}
+
+// parsePreview reads a page's metadata. OpenGraph properties win over Twitter card names, which win over
+// the HTML title and description.
+func parsePreview(page string, pageURL *url.URL) *search.LinkPreview {
+	if end := strings.Index(strings.ToLower(page), "</head>"); end >= 0 {
+		page = page[:end]
+	}
+
+	meta := make(map[string]string)
+	for _, tag := range htmlMetaTag.FindAllString(page, -1) {
+		attrs := make(map[string]string)
+		for _, attr := range htmlAttribute.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(attr[1])] = attr[2] + attr[3] + attr[4]
+		}
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		if _, seen := meta[key]; key != "" && !seen {
+			meta[key] = cleanMeta(attrs["content"])
+		}
+	}
+
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := meta[key]; value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	preview := &search.LinkPreview{
+		URL:         pageURL.String(),
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		SiteName:    first("og:site_name"),
+	}
+	if preview.Title == "" {
+		if match := htmlTitleTag.FindStringSubmatch(page); match != nil {
+			preview.Title = cleanMeta(match[1])
+		}
+	}
+	if image := first("og:image", "og:image:url", "twitter:image"); image != "" {
+		if resolved, err := pageURL.Parse(image); err == nil {
+			preview.Image = resolved.String()
+		}
+	}
+	return preview
+}
+
+// cleanMeta decodes entities and collapses whitespace in a metadata value.
+func cleanMeta(value string) string {
+	return strings.TrimSpace(htmlWhitespace.ReplaceAllString(html.UnescapeString(value), " "))
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+// campaignReportKey is the storage key recording when a campaign's end report was sent, so it is sent once.
+const campaignReportKey = "__campaign_report"
+
+// campaign is the time box of a campaign profile. A zero start or end leaves that side open.
+type campaign struct {
+	start time.Time
+	end   time.Time
+}
+
+// active reports whether the campaign is running at now.
+func (c *campaign) active(now time.Time) bool {
+	return (c.start.IsZero() || !now.Before(c.start)) && !c.ended(now)
+}
+
+// ended reports whether the campaign is over at now.
+func (c *campaign) ended(now time.Time) bool {
+	return !c.end.IsZero() && !now.Before(c.end)
+}
+
+// campaignNames returns the campaigns defined in the config file, sorted by name.
+func campaignNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Campaigns))
+	for name := range cfg.Campaigns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newCampaign initializes a campaign's profile, exiting on invalid configuration.
+func newCampaign(ctx context.Context, cfg *config.Config, name string, c config.Campaign, searcherCache map[string]search.Searcher) *profile {
+	logger := log.With("campaign", name)
+	if _, ok := cfg.Profiles[name]; ok {
+		logger.Fatalf("Campaign %q has the same name as a profile", name)
+	}
+	if len(c.Keywords) == 0 {
+		logger.Fatalf("Campaign %q has no keywords", name)
+	}
+
+	start, err := parseCampaignTime(c.Start, false)
+	if err != nil {
+		logger.Fatalf("Invalid campaign start: %v", err)
+	}
+	end, err := parseCampaignTime(c.End, true)
+	if err != nil {
+		logger.Fatalf("Invalid campaign end: %v", err)
+	}
+	if !start.IsZero() && !end.IsZero() && !end.After(start) {
+		logger.Fatalf("Campaign %q ends before it starts", name)
+	}
+
+	if c.TableName == "" {
+		c.TableName = "campaign-" + name
+	}
+	p := newProfile(ctx, cfg, name, c.Profile, searcherCache)
+	p.campaign = &campaign{start: start, end: end}
+	return p
+}
+
+// parseCampaignTime parses a campaign boundary. Dates cover the whole day, so an end date is the start of
+// the following day.
+func parseCampaignTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if end {
+			date = date.AddDate(0, 0, 1)
+		}
+		return date, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (2006-01-02) nor an RFC 3339 time", value)
+	}
+	return parsed, nil
+}
+
+// active reports whether the profile should search at now. Only campaigns are ever inactive.
+func (p *profile) active(now time.Time) bool {
+	return p.campaign == nil || p.campaign.active(now)
+}
+
+// campaignReport summarizes the mentions a campaign found, in total and by platform and keyword.
+func (p *profile) campaignReport(ctx context.Context) (string, error) {
+	counter, ok := storage.AsCounter(p.storer)
+	if !ok {
+		return "", errors.New("storage backend can't count results for campaign reports")
+	}
+	counts, err := counter.CountResults(ctx, time.Time{}, time.Time{})
+	if err != nil {
+		return "", fmt.Errorf("failed to count campaign results: %w", err)
+	}
+
+	var total int64
+	platforms := make(map[string]int64)
+	keywords := make(map[string]int64)
+	for _, count := range counts {
+		total += count.Count
+		platforms[count.Platform] += count.Count
+		keywords[count.Keyword] += count.Count
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Campaign %q (%s): %d mentions\n", p.name, p.campaign.describe(), total)
+	writeBreakdown(&report, "By platform", platforms)
+	writeBreakdown(&report, "By keyword", keywords)
+	return report.String(), nil
+}
+
+// describe formats the campaign's time box.
+func (c *campaign) describe() string {
+	format := func(t time.Time, open string) string {
+		if t.IsZero() {
+			return open
+		}
+		return t.Format(time.RFC3339)
+	}
+	return format(c.start, "open start") + " to " + format(c.end, "open end")
+}
+
+// writeBreakdown lists counts from highest to lowest, breaking ties by name.
+func writeBreakdown(report *strings.Builder, title string, counts map[string]int64) {
+	if len(counts) == 0 {
+		return
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Fprintf(report, "%s:\n", title)
+	for _, name := range names {
+		fmt.Fprintf(report, "  %s: %d\n", name, counts[name])
+	}
+}
+
+// sendCampaignReport sends the campaign's report to its notifiers once it has ended. The time it was sent
+// is stored so later runs don't send it again.
+func (p *profile) sendCampaignReport(ctx context.Context) {
+	if p.campaign == nil || !p.campaign.ended(time.Now()) {
+		return
+	}
+	logger := log.With("campaign", p.name)
+
+	sent, err := p.storer.GetLastSearchTime(ctx, campaignReportKey)
+	if err != nil {
+		logger.Error("Failed to check whether the campaign report was sent", "error", err)
+		return
+	}
+	if sent != 0 {
+		return
+	}
+
+	report, err := p.campaignReport(ctx)
+	if err != nil {
+		logger.Error("Failed to build campaign report", "error", err)
+		return
+	}
+
+	notifiers := p.bot.CurrentNotifiers()
+	names := make([]string, 0, len(notifiers))
+	for name := range notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := false
+	for _, name := range names {
+		sender, ok := bot.AsTextSender(notifiers[name])
+		if !ok {
+			continue
+		}
+		notifyCtx, cancel := withTimeout(ctx, *notifyTimeout)
+		err := sender.NotifyText(notifyCtx, strings.TrimSpace(report))
+		cancel()
+		if err != nil {
+			logger.Error("Failed to send campaign report", "notifier", name, "error", err)
+			failed = true
+		}
+	}
+	if failed {
+		return
+	}
+
+	logger.Info("Sent campaign report")
+	if err := p.storer.SetLastSearchTime(ctx, campaignReportKey, time.Now().Unix()); err != nil {
+		logger.Error("Failed to record that the campaign report was sent", "error", err)
+	}
+}
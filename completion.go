@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+var (
+	completionCommand = kingpin.Command("completion", "Print a shell completion script; e.g. add `source <(grass completion bash)` to ~/.bashrc")
+	completionShell   = completionCommand.Arg("shell", "Shell to complete in: bash, zsh, or fish").Required().Enum("bash", "zsh", "fish")
+)
+
+// fishCompletionTemplate completes with the candidates kingpin lists for --completion-bash, which the bash
+// and zsh scripts use too.
+const fishCompletionTemplate = `function __{{.App.Name}}_complete
+    {{.App.Name}} --completion-bash (commandline -opc)[2..-1] (commandline -ct)
+end
+complete -c {{.App.Name}} -a '(__{{.App.Name}}_complete)'
+`
+
+// runCompletion writes the completion script for --shell to w.
+func runCompletion(w io.Writer) error {
+	text := map[string]string{
+		"bash": kingpin.BashCompletionTemplate,
+		"zsh":  kingpin.ZshCompletionTemplate,
+		"fish": fishCompletionTemplate,
+	}[*completionShell]
+	tmpl, err := template.New(*completionShell).Parse(text)
+	if err != nil {
+		return fmt.Errorf("parsing %s completion script: %w", *completionShell, err)
+	}
+	data := struct{ App struct{ Name string } }{}
+	data.App.Name = kingpin.CommandLine.Name
+	return tmpl.Execute(w, data)
+}
+
+// completeCommands adds command names to kingpin's --completion-bash candidates when no command has been
+// typed yet. kingpin completes the arguments of the default run command instead, so commands would
+// otherwise never be offered.
+func completeCommands(args []string, w io.Writer) {
+	i := slices.Index(args, "--completion-bash")
+	if i < 0 {
+		return
+	}
+	words := args[i+1:]
+	current, previous := "", ""
+	if len(words) > 0 {
+		current = words[len(words)-1]
+	}
+	if len(words) > 1 {
+		previous = words[len(words)-2]
+	}
+	// Flags and their values are completed by kingpin
+	if strings.HasPrefix(current, "-") || strings.HasPrefix(previous, "--") && !strings.Contains(previous, "=") {
+		return
+	}
+	var names []string
+	for _, command := range kingpin.CommandLine.Model().Commands {
+		if command.Hidden {
+			continue
+		}
+		if slices.Contains(words[:max(len(words)-1, 0)], command.Name) {
+			return
+		}
+		names = append(names, command.Name)
+	}
+	fmt.Fprintln(w, strings.Join(names, "\n"))
+}
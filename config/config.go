@@ -0,0 +1,191 @@
+// config/config.go
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the optional file-based configuration for grass. Anything not set here falls back to
+// command line flags and environment variables.
+type Config struct {
+	Routing   Routing             `yaml:"routing"`
+	Notifiers map[string]Notifier `yaml:"notifiers"`
+	Schedule  Schedule            `yaml:"schedule"`
+	// Filters maps a keyword to the exclusions applied to its results. The "*" entry applies to every keyword.
+	Filters map[string]Filter `yaml:"filters"`
+	// Priorities are rules assigning priorities to results before they are routed.
+	Priorities []PriorityRule `yaml:"priorities"`
+	// KeywordVariants maps a searcher name to patterns of other forms of each keyword to search it for. See
+	// Profile.KeywordVariants.
+	KeywordVariants map[string][]string `yaml:"keyword_variants"`
+	// Profiles run independent sets of keywords from one instance. When empty, a single profile is built
+	// from command line flags and the settings above.
+	Profiles map[string]Profile `yaml:"profiles"`
+	// Campaigns are time-boxed profiles, such as a product launch, whose state and reports are kept apart
+	// from evergreen monitoring. They run alongside the profiles above.
+	Campaigns map[string]Campaign `yaml:"campaigns"`
+	// Env sets environment variables, such as platform credentials, while this configuration's searchers,
+	// notifiers, and storage are created. It lets each tenant config file carry its own credentials.
+	Env map[string]string `yaml:"env"`
+	// Log configures logging. The --log-* flags take precedence, and it is ignored in tenant config files.
+	Log Log `yaml:"log"`
+}
+
+// Log sets the level, format, and destination of log output.
+type Log struct {
+	// Level is debug, info, warn, or error.
+	Level string `yaml:"level"`
+	// Format is text, json, or logfmt.
+	Format string `yaml:"format"`
+	// File writes logs to this file instead of stderr, rotating it once it reaches MaxSizeMB and keeping
+	// MaxBackups older files.
+	File       string `yaml:"file"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+}
+
+// Profile is a named set of keywords with its own searchers, notifiers, and storage. Unset fields fall back
+// to the corresponding command line flag or top-level setting.
+type Profile struct {
+	Keywords []string `yaml:"keywords"`
+	// Accounts are watched for new posts regardless of keyword, written <searcher>/<account> such as
+	// hackernews/pg. Each is searched as the keyword "from:<searcher>/<account>".
+	Accounts     []string `yaml:"accounts"`
+	Searchers    []string `yaml:"searchers"`
+	Bots         []string `yaml:"bots"`
+	DB           string   `yaml:"db"`
+	SecondaryDBs []string `yaml:"secondary_dbs"`
+	// TableName is the storage table, file, or key prefix. It defaults to the profile name so each
+	// profile keeps its own results and last search times.
+	TableName string              `yaml:"table_name"`
+	Routing   Routing             `yaml:"routing"`
+	Notifiers map[string]Notifier `yaml:"notifiers"`
+	Schedule  Schedule            `yaml:"schedule"`
+	Filters   map[string]Filter   `yaml:"filters"`
+	// Priorities are rules assigning priorities to results. Every matching rule applies, and the highest
+	// priority wins.
+	Priorities []PriorityRule `yaml:"priorities"`
+	// KeywordVariants maps a searcher name (e.g. fediverse, bluesky) to patterns of other forms of each
+	// keyword to search it for, such as "#{tag}" for hashtags, reported under the keyword itself.
+	KeywordVariants map[string][]string `yaml:"keyword_variants"`
+}
+
+// Campaign is a profile that only searches between Start and End. Its storage table defaults to
+// "campaign-<name>", so it has its own results, last search times, and duplicate detection, and a summary
+// report is sent to its notifiers once it ends.
+type Campaign struct {
+	Profile `yaml:",inline"`
+	// Start and End bound the campaign as dates (2006-01-02), which cover the whole day, or RFC 3339 times.
+	// Either may be left open.
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// Filter drops a keyword's results before they are saved or notified.
+type Filter struct {
+	// Exclude drops results whose title or content contains any of these terms, ignoring case.
+	Exclude []string `yaml:"exclude"`
+	// ExcludeRegex drops results whose title or content matches any of these regular expressions.
+	ExcludeRegex []string `yaml:"exclude_regex"`
+	// WholeWord, CaseSensitive, and FoldAccents require results to contain the keyword under these rules,
+	// filtering out loose platform matches (e.g. "AGE" matching "page" or "age").
+	WholeWord     bool `yaml:"whole_word"`
+	CaseSensitive bool `yaml:"case_sensitive"`
+	FoldAccents   bool `yaml:"fold_accents"`
+}
+
+// IsZero reports whether the filter has no exclusions or matching rules.
+func (f Filter) IsZero() bool {
+	return len(f.Exclude) == 0 && len(f.ExcludeRegex) == 0 && !f.WholeWord && !f.CaseSensitive && !f.FoldAccents
+}
+
+// Schedule sets how often each searcher and keyword runs in daemon mode. Values are cron expressions
+// (e.g. "*/10 * * * *"), descriptors such as "@hourly", or "@every 10m".
+type Schedule struct {
+	// Default applies when neither the keyword nor the searcher has a schedule. When empty, --interval is used.
+	Default string `yaml:"default"`
+	// Searchers maps a searcher name (e.g. hackernews, youtube) to its schedule.
+	Searchers map[string]string `yaml:"searchers"`
+	// Keywords maps a keyword to its schedule, taking precedence over the searcher's schedule.
+	Keywords map[string]string `yaml:"keywords"`
+}
+
+// Notifier holds per-notifier settings, keyed by bot type (e.g. slack, discord, print).
+type Notifier struct {
+	// Template is a Go text/template rendered against each search result.
+	Template string `yaml:"template"`
+	// Mentions maps a priority (info, warn, critical) to the users, groups, or roles to ping.
+	Mentions map[string][]string `yaml:"mentions"`
+	// Channels overrides the Slack or Discord channel IDs set in the environment.
+	Channels []string `yaml:"channels"`
+	// Digest combines new results into one message per run instead of one message per result.
+	Digest bool `yaml:"digest"`
+	// DigestWindow collects results for this long (e.g. "1h") before sending a digest. It implies Digest.
+	DigestWindow string `yaml:"digest_window"`
+	// DigestTemplate is a Go text/template rendered against each digest.
+	DigestTemplate string `yaml:"digest_template"`
+	// RateLimit caps how many messages the notifier sends per RateWindow. Zero disables the cap.
+	RateLimit int `yaml:"rate_limit"`
+	// RateWindow is the period RateLimit applies to (e.g. "1h").
+	RateWindow string `yaml:"rate_window"`
+	// RateOverflow is what happens to results over the cap: "digest" (the default) or "drop".
+	RateOverflow string `yaml:"rate_overflow"`
+}
+
+// Routing controls which notifiers receive which results.
+type Routing struct {
+	// Rules are evaluated in order. The first matching rule wins unless it sets Continue.
+	Rules []Route `yaml:"rules"`
+	// DefaultNotifiers receive results that match no rule. When empty, every notifier receives them.
+	DefaultNotifiers []string `yaml:"default_notifiers"`
+}
+
+// Route maps results matching all of its conditions to a set of notifiers. Empty conditions match everything.
+type Route struct {
+	Name         string   `yaml:"name"`
+	Platforms    []string `yaml:"platforms"`
+	Keywords     []string `yaml:"keywords"`
+	ContentRegex string   `yaml:"content_regex"`
+	MinScore     *int64   `yaml:"min_score"`
+	Notifiers    []string `yaml:"notifiers"`
+	// Priority is assigned to matching results: info, warn, or critical.
+	Priority string `yaml:"priority"`
+	Continue bool   `yaml:"continue"`
+}
+
+// PriorityRule assigns a priority to results matching all of its conditions, without affecting which
+// notifiers receive them. Empty conditions match everything.
+type PriorityRule struct {
+	Name         string   `yaml:"name"`
+	Platforms    []string `yaml:"platforms"`
+	Keywords     []string `yaml:"keywords"`
+	ContentRegex string   `yaml:"content_regex"`
+	MinScore     *int64   `yaml:"min_score"`
+	// Priority is assigned to matching results: info, warn, or critical.
+	Priority string `yaml:"priority"`
+}
+
+// Load reads a YAML configuration file. An empty path returns an empty configuration.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
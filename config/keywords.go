@@ -0,0 +1,134 @@
+// config/keywords.go
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keyword is an entry in a keywords file: a keyword with its optional schedule and filter.
+type Keyword struct {
+	Keyword string `yaml:"keyword"`
+	// Schedule is the keyword's daemon schedule, as in Schedule.Keywords.
+	Schedule string `yaml:"schedule"`
+	Filter   `yaml:",inline"`
+}
+
+// UnmarshalYAML accepts a bare keyword as well as a mapping.
+func (k *Keyword) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		k.Keyword = value.Value
+		return nil
+	}
+	type plain Keyword
+	return value.Decode((*plain)(k))
+}
+
+// LoadKeywords reads a keywords file. Files ending in .yaml or .yml hold a list of keywords, each a string or
+// a mapping with the fields of Keyword. Other files hold a keyword per line, see parseKeywordLine.
+func LoadKeywords(path string) ([]Keyword, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keywords file: %w", err)
+	}
+
+	var keywords []Keyword
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&keywords); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse keywords file %s: %w", path, err)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			keyword, ok, err := parseKeywordLine(scanner.Text())
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			if ok {
+				keywords = append(keywords, keyword)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read keywords file: %w", err)
+		}
+	}
+
+	for i, keyword := range keywords {
+		if strings.TrimSpace(keyword.Keyword) == "" {
+			return nil, fmt.Errorf("keywords file %s: entry %d has no keyword", path, i+1)
+		}
+	}
+	return keywords, nil
+}
+
+// keywordOptions are the options a line of a plain keywords file can set.
+var keywordOptions = []string{"schedule", "exclude", "exclude_regex", "whole_word", "case_sensitive", "fold_accents"}
+
+// parseKeywordLine parses a line of a plain keywords file, reporting false for blank and comment lines.
+// A comment is "#" followed by a space or the end of the line, at the start of the line or after
+// whitespace, so hashtags can still be keywords. Options follow the keyword after "|", separated by ";":
+//
+//	tailscale | whole_word; exclude=lawn, salad; schedule=*/10 * * * *
+func parseKeywordLine(line string) (Keyword, bool, error) {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') && (i+1 == len(line) || line[i+1] == ' ' || line[i+1] == '\t') {
+			line = line[:i]
+			break
+		}
+	}
+	text, options, _ := strings.Cut(line, "|")
+	keyword := Keyword{Keyword: strings.TrimSpace(text)}
+	if keyword.Keyword == "" {
+		if strings.TrimSpace(options) != "" {
+			return keyword, false, fmt.Errorf("options without a keyword")
+		}
+		return keyword, false, nil
+	}
+
+	for _, option := range strings.Split(options, ";") {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(option), "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name != "" && !slices.Contains(keywordOptions, name) {
+			return keyword, false, fmt.Errorf("unknown option %q", name)
+		}
+		flag := name == "whole_word" || name == "case_sensitive" || name == "fold_accents"
+		if flag && hasValue {
+			return keyword, false, fmt.Errorf("option %s takes no value", name)
+		}
+		if !flag && name != "" && value == "" {
+			return keyword, false, fmt.Errorf("option %s needs a value", name)
+		}
+		switch name {
+		case "":
+		case "whole_word":
+			keyword.WholeWord = true
+		case "case_sensitive":
+			keyword.CaseSensitive = true
+		case "fold_accents":
+			keyword.FoldAccents = true
+		case "schedule":
+			keyword.Schedule = value
+		case "exclude":
+			for _, term := range strings.Split(value, ",") {
+				if term = strings.TrimSpace(term); term != "" {
+					keyword.Exclude = append(keyword.Exclude, term)
+				}
+			}
+		case "exclude_regex":
+			keyword.ExcludeRegex = append(keyword.ExcludeRegex, value)
+		}
+	}
+	return keyword, true, nil
+}
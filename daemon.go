@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/internal/scheduler"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// newScheduler creates a job for every profile's polled searcher and keyword pairs using their configured
+// schedules, plus a job per profile keeping those jobs in step with its managed keywords, a follow-up job per profile when follow-ups are enabled, a deletion check job per profile
+// when deletion checks are enabled, a run summary job per profile when summaries are enabled, a report job per campaign, an hourly prune job when retention is set,
+// and a job reloading the config file when there is one.
+func newScheduler(ctx context.Context, profiles []*profile) (*scheduler.Scheduler, error) {
+	sched := scheduler.New()
+
+	// Reloads run first, so a replica taking over as leader applies changes before it searches
+	if reloads != nil {
+		sched.Add("config", scheduler.Every(configCheckInterval), func(ctx context.Context) {
+			reloads.check(ctx, sched, profiles)
+		})
+	}
+
+	for _, p := range profiles {
+		jobPrefix := p.jobPrefix()
+
+		p.scheduled = p.keywordsOrConfigured(ctx)
+		for _, keyword := range p.scheduled {
+			if err := scheduleSearches(sched, p, keyword); err != nil {
+				return nil, err
+			}
+		}
+
+		if p.keywordStore != nil {
+			if *keywordRefresh <= 0 {
+				return nil, fmt.Errorf("%smanaged keywords: refresh interval must be positive", jobPrefix)
+			}
+			p := p
+			sched.Add(jobPrefix+"keywords", scheduler.Every(*keywordRefresh), func(ctx context.Context) {
+				p.scheduled = syncKeywords(ctx, sched, p, p.scheduled)
+			})
+		}
+
+		if *followUpThreshold > 0 {
+			if *followUpInterval <= 0 {
+				return nil, fmt.Errorf("%sfollow-ups: interval must be positive", jobPrefix)
+			}
+			b := p.bot
+			sched.Add(jobPrefix+"follow-ups", scheduler.Every(*followUpInterval), func(ctx context.Context) {
+				b.CheckFollowUps(ctx)
+			})
+		}
+
+		if *deletionWindow > 0 {
+			if *deletionInterval <= 0 {
+				return nil, fmt.Errorf("%sdeletion checks: interval must be positive", jobPrefix)
+			}
+			b := p.bot
+			sched.Add(jobPrefix+"deletion-checks", scheduler.Every(*deletionInterval), func(ctx context.Context) {
+				b.CheckDeletions(ctx)
+			})
+		}
+
+		if *runSummary != "off" {
+			if *summaryInterval <= 0 {
+				return nil, fmt.Errorf("%srun summaries: interval must be positive", jobPrefix)
+			}
+			b := p.bot
+			sched.Add(jobPrefix+"run-summary", scheduler.Every(*summaryInterval), func(ctx context.Context) {
+				b.NotifySummary(ctx, b.TakeSummary())
+			})
+		}
+
+		if p.bot.Outbox != nil {
+			b := p.bot
+			sched.Add(jobPrefix+"outbox", scheduler.Every(time.Minute), func(ctx context.Context) {
+				b.DeliverOutbox(ctx)
+			})
+		}
+
+		if p.campaign != nil {
+			p := p
+			sched.Add(jobPrefix+"campaign-report", scheduler.Every(5*time.Minute), func(ctx context.Context) {
+				p.sendCampaignReport(ctx)
+			})
+		}
+
+		if *retention > 0 {
+			storer := p.storer
+			sched.Add(jobPrefix+"prune", scheduler.Every(time.Hour), func(ctx context.Context) {
+				prune(ctx, storer)
+			})
+		}
+	}
+
+	return sched, nil
+}
+
+// jobPrefix prefixes the names of the profile's jobs, keeping them apart from other profiles' jobs.
+func (p *profile) jobPrefix() string {
+	if p.name == "" {
+		return ""
+	}
+	return p.name + "/"
+}
+
+// scheduleSearches adds a job searching each of the profile's polled searchers for keyword.
+func scheduleSearches(sched *scheduler.Scheduler, p *profile, keyword string) error {
+	for _, provider := range p.searchers {
+		if _, ok := provider.(search.StreamingSearcher); ok {
+			continue
+		}
+		if err := scheduleSearch(sched, p, provider, keyword); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduleSearch adds a job searching provider for keyword on the profile's schedule for them.
+func scheduleSearch(sched *scheduler.Scheduler, p *profile, provider search.Searcher, keyword string) error {
+	name := p.searcherNames[provider]
+	expr := scheduleFor(p.scheduleConfig(), name, keyword)
+	schedule, err := scheduler.Parse(expr)
+	if err != nil {
+		return fmt.Errorf("%ssearcher %s, keyword %q: %w", p.jobPrefix(), name, keyword, err)
+	}
+
+	log.Info("Scheduled search", "profile", p.name, "searcher", name, "keyword", keyword, "schedule", expr)
+	sched.Add(p.jobPrefix()+name+":"+keyword, schedule, func(ctx context.Context) {
+		if !p.active(time.Now()) {
+			log.Debug("Skipping search outside campaign", "campaign", p.name, "keyword", keyword)
+			return
+		}
+		p.bot.RunSearcher(ctx, provider, keyword)
+	})
+	return nil
+}
+
+// syncKeywords schedules searches for keywords added or resumed with the keywords command since the
+// scheduled keywords were read, and removes those for keywords since removed or paused. It returns the
+// keywords now scheduled, leaving them unchanged if the managed keywords can't be read.
+func syncKeywords(ctx context.Context, sched *scheduler.Scheduler, p *profile, scheduled []string) []string {
+	keywords, err := p.activeKeywords(ctx)
+	if err != nil {
+		log.Warn("Keeping the scheduled keywords", "profile", p.name, "error", err)
+		return scheduled
+	}
+
+	var now []string
+	for _, keyword := range keywords {
+		if !slices.Contains(scheduled, keyword) {
+			if err := scheduleSearches(sched, p, keyword); err != nil {
+				log.Error("Failed to schedule keyword", "profile", p.name, "keyword", keyword, "error", err)
+				continue
+			}
+			log.Info("Keyword added", "profile", p.name, "keyword", keyword)
+		}
+		now = append(now, keyword)
+	}
+	for _, keyword := range scheduled {
+		if slices.Contains(keywords, keyword) {
+			continue
+		}
+		unscheduleSearches(sched, p, keyword)
+		log.Info("Keyword removed", "profile", p.name, "keyword", keyword)
+	}
+	return now
+}
+
+// unscheduleSearches removes the jobs searching the profile's searchers for keyword.
+func unscheduleSearches(sched *scheduler.Scheduler, p *profile, keyword string) {
+	for _, provider := range p.searchers {
+		sched.Remove(p.jobPrefix() + p.searcherNames[provider] + ":" + keyword)
+	}
+}
+
+// startStreams streams every profile's streaming searchers in the background, in place of polling them on a
+// schedule. Campaign profiles only stream while their campaign runs. The returned function waits for the
+// streams to stop once ctx is cancelled.
+func startStreams(ctx context.Context, profiles []*profile) func() {
+	var wg sync.WaitGroup
+	for _, p := range profiles {
+		keywords := p.keywordsOrConfigured(ctx)
+		if len(keywords) == 0 {
+			continue
+		}
+		for _, provider := range p.searchers {
+			streamer, ok := provider.(search.StreamingSearcher)
+			if !ok {
+				continue
+			}
+			if p.campaign != nil && p.campaign.ended(time.Now()) {
+				continue
+			}
+
+			log.Info("Streaming searcher", "profile", p.name, "searcher", p.searcherNames[provider], "keywords", len(keywords))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				streamCtx, cancel := campaignContext(ctx, p.campaign)
+				defer cancel()
+				if streamCtx.Err() == nil {
+					p.bot.Stream(streamCtx, streamer, keywords)
+				}
+			}()
+		}
+	}
+	return wg.Wait
+}
+
+// campaignContext waits for a campaign to start and returns a context that is cancelled when it ends. It
+// returns a cancelled context if ctx is cancelled first.
+func campaignContext(ctx context.Context, c *campaign) (context.Context, context.CancelFunc) {
+	if c == nil {
+		return context.WithCancel(ctx)
+	}
+	if wait := time.Until(c.start); !c.start.IsZero() && wait > 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+	}
+	if c.end.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, c.end)
+}
+
+// scheduleFor picks the schedule for a searcher and keyword: the keyword's schedule, then the searcher's,
+// then the configured default, and finally --interval.
+func scheduleFor(cfg config.Schedule, searcher, keyword string) string {
+	if expr, ok := cfg.Keywords[keyword]; ok {
+		return expr
+	}
+	if expr, ok := cfg.Searchers[searcher]; ok {
+		return expr
+	}
+	if cfg.Default != "" {
+		return cfg.Default
+	}
+	return "@every " + interval.String()
+}
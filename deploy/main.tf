@@ -34,6 +34,20 @@ resource "aws_dynamodb_table" "social_search" {
     write_capacity     = var.write_capacity
   }
 
+  global_secondary_index {
+    name               = "KeywordTimestampIndex"
+    hash_key           = "Keyword"
+    range_key          = "Timestamp"
+    projection_type    = "ALL"
+    read_capacity      = var.read_capacity
+    write_capacity     = var.write_capacity
+  }
+
+  ttl {
+    attribute_name = "ExpiresAt"
+    enabled        = true
+  }
+
   global_secondary_index {
     name               = "TimestampIndex"
     hash_key           = "Timestamp"
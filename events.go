@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	eventWebhooks      = kingpin.Flag("event-webhook", "Post lifecycle events, such as results being found or notified, to this URL (repeatable)").Envar("GRASS_EVENT_WEBHOOKS").Strings()
+	eventWebhookSecret = kingpin.Flag("event-webhook-secret", "Sign lifecycle event webhooks with this secret in their X-Grass-Signature header").Envar("GRASS_EVENT_WEBHOOK_SECRET").String()
+	eventTypes         = kingpin.Flag("event-type", "Only post these lifecycle events: result.found, result.notified, notification.failed, or run.completed (repeatable)").Envar("GRASS_EVENT_TYPES").Enums(bot.EventTypes...)
+)
+
+const (
+	// eventQueueSize is how many events wait to be posted before further events are dropped.
+	eventQueueSize = 1000
+	// eventAttempts is how many times an event is posted to an endpoint before it is given up on.
+	eventAttempts = 3
+	// eventRetryDelay is the delay before the first retry, doubling with each further one.
+	eventRetryDelay = time.Second
+	// eventTimeout bounds each post of an event.
+	eventTimeout = 10 * time.Second
+	// eventDrainTimeout bounds posting the queued events when grass exits.
+	eventDrainTimeout = 30 * time.Second
+)
+
+// eventPayload is the JSON body of a lifecycle event webhook. Which fields are set depends on Type.
+type eventPayload struct {
+	ID       string               `json:"id"`
+	Type     string               `json:"type"`
+	Time     time.Time            `json:"time"`
+	Profile  string               `json:"profile"`
+	Result   *search.SearchResult `json:"result,omitempty"`
+	Notifier string               `json:"notifier,omitempty"`
+	Error    string               `json:"error,omitempty"`
+	Run      *storage.Run         `json:"run,omitempty"`
+}
+
+// queuedEvent is an event waiting to be posted, already encoded so later changes to what it describes
+// don't race with posting it.
+type queuedEvent struct {
+	id, kind string
+	body     []byte
+}
+
+// eventSender posts lifecycle events to the --event-webhook endpoints from a queue, so slow endpoints never
+// hold up searches. Events are posted one at a time, in the order they happened.
+type eventSender struct {
+	urls   []string
+	secret string
+	types  []string
+	client *http.Client
+
+	mu      sync.Mutex
+	closed  bool
+	queue   chan queuedEvent
+	pending sync.WaitGroup
+	done    chan struct{}
+}
+
+// events posts lifecycle events to the --event-webhook endpoints, or is nil when there are none.
+var events *eventSender
+
+// startEvents starts posting lifecycle events to the --event-webhook endpoints, if any.
+func startEvents() {
+	if len(*eventWebhooks) == 0 {
+		return
+	}
+	events = &eventSender{
+		urls:   *eventWebhooks,
+		secret: *eventWebhookSecret,
+		types:  *eventTypes,
+		client: sharedHTTPClient,
+		queue:  make(chan queuedEvent, eventQueueSize),
+		done:   make(chan struct{}),
+	}
+	go events.loop()
+	log.Debug("Posting lifecycle events", "endpoints", len(events.urls))
+}
+
+// flushEvents waits for the queued lifecycle events to be posted, for example before a Lambda invocation
+// returns and the function is frozen.
+func flushEvents() {
+	if events != nil {
+		events.flush()
+	}
+}
+
+// closeEvents posts the queued lifecycle events and stops posting.
+func closeEvents() {
+	if events == nil {
+		return
+	}
+	events.close()
+	events = nil
+}
+
+// profileEvents returns the handler of a profile's lifecycle events, or nil when no endpoints are set.
+func profileEvents(profile string) bot.EventHandler {
+	if events == nil {
+		return nil
+	}
+	return &eventHandler{sender: events, profile: profile}
+}
+
+// eventHandler queues the lifecycle events of a profile's bot.
+type eventHandler struct {
+	sender  *eventSender
+	profile string
+}
+
+func (h *eventHandler) HandleEvent(event bot.Event) {
+	if len(h.sender.types) > 0 && !slices.Contains(h.sender.types, event.Type) {
+		return
+	}
+	id, err := eventID()
+	if err != nil {
+		log.Warn("Dropping lifecycle event", "type", event.Type, "error", err)
+		return
+	}
+	payload := eventPayload{ID: id, Type: event.Type, Time: event.Time.UTC(), Profile: h.profile, Result: event.Result, Notifier: event.Notifier}
+	if event.Err != nil {
+		payload.Error = event.Err.Error()
+	}
+	if event.Report != nil {
+		run := newRun(event.Report, h.profile, "")
+		payload.Run = &run
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Warn("Dropping lifecycle event", "type", event.Type, "error", err)
+		return
+	}
+	h.sender.enqueue(queuedEvent{id: id, kind: event.Type, body: body})
+}
+
+// enqueue queues an event to be posted, dropping it if the queue is full or the sender is closed.
+func (s *eventSender) enqueue(event queuedEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.pending.Add(1)
+	select {
+	case s.queue <- event:
+	default:
+		s.pending.Done()
+		log.Warn("Lifecycle event queue is full; dropping event", "type", event.kind, "id", event.id)
+	}
+}
+
+// loop posts queued events until the queue is closed.
+func (s *eventSender) loop() {
+	defer close(s.done)
+	for event := range s.queue {
+		for _, url := range s.urls {
+			if err := s.post(url, event); err != nil {
+				log.Error("Failed to post lifecycle event", "url", url, "type", event.kind, "id", event.id, "error", err)
+			}
+		}
+		s.pending.Done()
+	}
+}
+
+// post posts an event to url, retrying network errors, rate limits, and server errors with backoff.
+func (s *eventSender) post(url string, event queuedEvent) error {
+	var err error
+	for attempt := 0; attempt < eventAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(eventRetryDelay << (attempt - 1))
+		}
+		var retry bool
+		if retry, err = s.send(url, event); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// send makes one attempt at posting an event to url, reporting whether a failure is worth retrying.
+func (s *eventSender) send(url string, event queuedEvent) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(event.body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Grass-Event", event.kind)
+	req.Header.Set("X-Grass-Delivery", event.id)
+	req.Header.Set("X-Grass-Timestamp", timestamp)
+	if s.secret != "" {
+		req.Header.Set("X-Grass-Signature", "sha256="+signEvent(s.secret, timestamp, event.body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return false, nil
+}
+
+// signEvent returns the hex HMAC-SHA256, keyed by secret, of an event's timestamp and body joined by a dot.
+// Receivers recompute it to check the event came from grass, and reject old timestamps to stop replays.
+func signEvent(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// flush waits up to eventDrainTimeout for the queued events to be posted.
+func (s *eventSender) flush() {
+	drained := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(eventDrainTimeout):
+		log.Warn("Gave up waiting for lifecycle events to be posted")
+	}
+}
+
+// close stops accepting events and waits up to eventDrainTimeout for the queued ones to be posted.
+func (s *eventSender) close() {
+	s.mu.Lock()
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+	select {
+	case <-s.done:
+	case <-time.After(eventDrainTimeout):
+		log.Warn("Gave up waiting for lifecycle events to be posted")
+	}
+}
+
+// eventID returns a random identifier for an event, so receivers can drop duplicate deliveries.
+func eventID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate event ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
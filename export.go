@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+	"github.com/parquet-go/parquet-go"
+)
+
+var (
+	exportCommand   = kingpin.Command("export", "Export stored results, filtered by --keyword, --since, and --until, as JSON, CSV, or Parquet")
+	exportFormat    = exportCommand.Flag("format", "Export format: json, csv, or parquet").Default("json").Enum("json", "csv", "parquet")
+	exportFile      = exportCommand.Flag("file", "File to write the export to instead of stdout").Short('o').String()
+	exportPlatforms = exportCommand.Flag("platform", "Only export results from this platform, e.g. HackerNews (repeatable)").Strings()
+	exportProfile   = exportCommand.Flag("profile", "Export the storage of this profile from the config file instead of --db and --table-name").String()
+)
+
+// exportRow is a stored result as a flat row, for CSV and Parquet exports.
+type exportRow struct {
+	Platform    string            `parquet:"platform"`
+	Keyword     string            `parquet:"keyword"`
+	Keywords    []string          `parquet:"keywords,list"`
+	Title       string            `parquet:"title"`
+	URL         string            `parquet:"url"`
+	Timestamp   int64             `parquet:"timestamp"`
+	Content     string            `parquet:"content"`
+	Author      string            `parquet:"author"`
+	Score       int64             `parquet:"score"`
+	Comments    int64             `parquet:"comments"`
+	Reposts     int64             `parquet:"reposts"`
+	Views       int64             `parquet:"views"`
+	Tags        []string          `parquet:"tags,list"`
+	Metadata    map[string]string `parquet:"metadata"`
+	Priority    string            `parquet:"priority"`
+	ContentHash string            `parquet:"content_hash"`
+}
+
+// exportColumns are the CSV header, in the order of exportRow's fields.
+var exportColumns = []string{
+	"platform", "keyword", "keywords", "title", "url", "timestamp", "content", "author", "score",
+	"comments", "reposts", "views", "tags", "metadata", "priority", "content_hash",
+}
+
+// runExport writes every stored result selected by the export flags, newest first, to --file or to stdout,
+// reading them from the profile's primary storage.
+func runExport(ctx context.Context, cfg *config.Config) error {
+	storer, db, err := openStorage(ctx, cfg, *exportProfile)
+	if err != nil {
+		return err
+	}
+	if closer, ok := storer.(io.Closer); ok {
+		defer closer.Close()
+	}
+	querier, ok := storage.AsQuerier(storer)
+	if !ok {
+		return fmt.Errorf("%s storage can't be exported", db)
+	}
+
+	results, err := querier.Query(ctx, storage.Query{
+		Platforms: *exportPlatforms,
+		Keywords:  *keywords,
+		Since:     *since,
+		Until:     *until,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *exportFile == "" {
+		return writeExport(os.Stdout, *exportFormat, results)
+	}
+	f, err := os.Create(*exportFile)
+	if err != nil {
+		return err
+	}
+	if err := writeExport(f, *exportFormat, results); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d results to %s\n", len(results), *exportFile)
+	return nil
+}
+
+// writeExport writes results to w in format.
+func writeExport(w io.Writer, format string, results []search.SearchResult) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportColumns); err != nil {
+			return err
+		}
+		for _, result := range results {
+			row := newExportRow(result)
+			var metadata []byte
+			if len(row.Metadata) > 0 {
+				var err error
+				if metadata, err = json.Marshal(row.Metadata); err != nil {
+					return err
+				}
+			}
+			if err := cw.Write([]string{
+				row.Platform, row.Keyword, strings.Join(row.Keywords, ","), row.Title, row.URL,
+				strconv.FormatInt(row.Timestamp, 10), row.Content, row.Author, strconv.FormatInt(row.Score, 10),
+				strconv.FormatInt(row.Comments, 10), strconv.FormatInt(row.Reposts, 10),
+				strconv.FormatInt(row.Views, 10), strings.Join(row.Tags, ","), string(metadata), row.Priority,
+				row.ContentHash,
+			}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "parquet":
+		pw := parquet.NewGenericWriter[exportRow](w)
+		rows := make([]exportRow, len(results))
+		for i, result := range results {
+			rows[i] = newExportRow(result)
+		}
+		if _, err := pw.Write(rows); err != nil {
+			return err
+		}
+		return pw.Close()
+	default:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if results == nil {
+			results = []search.SearchResult{}
+		}
+		return encoder.Encode(results)
+	}
+}
+
+// newExportRow flattens a stored result into an exportRow.
+func newExportRow(result search.SearchResult) exportRow {
+	return exportRow{
+		Platform:    result.Platform,
+		Keyword:     result.Keyword,
+		Keywords:    result.Keywords,
+		Title:       result.Title,
+		URL:         result.URL,
+		Timestamp:   result.Timestamp,
+		Content:     result.Content,
+		Author:      result.Author,
+		Score:       result.Score,
+		Comments:    result.Comments,
+		Reposts:     result.Reposts,
+		Views:       result.Views,
+		Tags:        result.Tags,
+		Metadata:    result.Metadata,
+		Priority:    string(result.Priority),
+		ContentHash: result.ContentHash,
+	}
+}
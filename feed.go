@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	feedAddr  = kingpin.Flag("feed-addr", "In daemon mode, serve RSS, Atom, and JSON feeds of stored results on this address, e.g. :8081").Envar("GRASS_FEED_ADDR").String()
+	feedToken = kingpin.Flag("feed-token", "Token feed requests must carry, as a bearer token or a token query parameter").Envar("GRASS_FEED_TOKEN").String()
+	feedLimit = kingpin.Flag("feed-items", "Number of results in each feed unless a request sets limit").Envar("GRASS_FEED_ITEMS").Default("50").Int()
+)
+
+const (
+	// feedMaxLimit caps how many results a feed request may ask for.
+	feedMaxLimit = 500
+	// feedTitleWidth caps the length of item titles, which are taken from the content of results without a
+	// title.
+	feedTitleWidth = 80
+)
+
+// feedFormats maps the extension of each feed URL to its content type.
+var feedFormats = map[string]string{
+	"rss":  "application/rss+xml; charset=utf-8",
+	"atom": "application/atom+xml; charset=utf-8",
+	"json": "application/feed+json; charset=utf-8",
+}
+
+// feedHandler serves feeds of the stored results of the profiles: every keyword's at /feed.<format>, and
+// one keyword's at /feed/<keyword>.<format>, where format is rss, atom, or json. Feeds take the profile,
+// platform, state, and limit query parameters of the REST API's results endpoint.
+type feedHandler struct {
+	mux      *http.ServeMux
+	profiles map[string]*profile
+	token    string
+}
+
+// newFeedHandler creates a handler for the profiles. Requests must carry token unless it is empty.
+func newFeedHandler(profiles []*profile, token string) *feedHandler {
+	h := &feedHandler{mux: http.NewServeMux(), profiles: make(map[string]*profile, len(profiles)), token: token}
+	for _, p := range profiles {
+		h.profiles[p.name] = p
+	}
+	for format := range feedFormats {
+		h.mux.HandleFunc("GET /feed."+format, h.serveFeed)
+	}
+	h.mux.HandleFunc("GET /feed/{file...}", h.serveFeed)
+	return h
+}
+
+func (h *feedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" && !hasBearerToken(r, h.token) &&
+		subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(h.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// serveFeed serves the feed named by the request's path.
+func (h *feedHandler) serveFeed(w http.ResponseWriter, r *http.Request) {
+	keyword, format := "", strings.TrimPrefix(r.URL.Path, "/feed.")
+	if file := r.PathValue("file"); file != "" {
+		i := strings.LastIndex(file, ".")
+		if i <= 0 {
+			http.NotFound(w, r)
+			return
+		}
+		keyword, format = file[:i], file[i+1:]
+	}
+	contentType, ok := feedFormats[format]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	params := r.URL.Query()
+	p, ok := h.profiles[params.Get("profile")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown profile %q", params.Get("profile")), http.StatusNotFound)
+		return
+	}
+	querier, ok := storage.AsQuerier(p.storer)
+	if !ok {
+		http.Error(w, "the profile's storage can't be queried", http.StatusNotImplemented)
+		return
+	}
+	q := storage.Query{Platforms: params["platform"], States: params["state"]}
+	if keyword != "" {
+		q.Keywords = []string{keyword}
+	}
+	err := storage.CheckStates(q.States...)
+	if err == nil {
+		q.Limit, err = apiInt(params.Get("limit"), *feedLimit)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Limit = min(q.Limit, feedMaxLimit)
+
+	results, err := querier.Query(r.Context(), q)
+	if err != nil {
+		log.Error("Failed to query results for a feed", "profile", p.name, "keyword", keyword, "error", err)
+		http.Error(w, "failed to query results", http.StatusInternalServerError)
+		return
+	}
+
+	title := "grass"
+	if p.name != "" {
+		title += " (" + p.name + ")"
+	}
+	if keyword != "" {
+		title += ": " + keyword
+	} else {
+		title += ": every keyword"
+	}
+	self := feedURL(r)
+
+	var body []byte
+	switch format {
+	case "rss":
+		body, err = rssFeed(title, self, results)
+	case "atom":
+		body, err = atomFeed(title, self, results)
+	case "json":
+		body, err = jsonFeed(title, self, results)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// feedURL returns the URL a feed was requested at, for feeds to link to themselves.
+func feedURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// feedTitle returns a result's title, or the start of its content for results without one.
+func feedTitle(result search.SearchResult) string {
+	title := strings.Join(strings.Fields(result.Title), " ")
+	if title == "" {
+		title = strings.Join(strings.Fields(result.Content), " ")
+	}
+	if runes := []rune(title); len(runes) > feedTitleWidth {
+		title = string(runes[:feedTitleWidth-1]) + "…"
+	}
+	if title == "" {
+		title = result.Platform + " post"
+	}
+	return title
+}
+
+// feedTags returns the categories of a result: its platform, the keywords it matched, and its tags.
+func feedTags(result search.SearchResult) []string {
+	tags := []string{result.Platform}
+	tags = append(tags, resultKeywordList(result)...)
+	return append(tags, result.Tags...)
+}
+
+// feedUpdated returns when the newest of results was posted, or now if there are none.
+func feedUpdated(results []search.SearchResult) time.Time {
+	if len(results) == 0 {
+		return time.Now()
+	}
+	return time.Unix(results[0].Timestamp, 0)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// rssFeed renders results as an RSS 2.0 feed.
+func rssFeed(title, self string, results []search.SearchResult) ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         title,
+			Link:          self,
+			Description:   "Results found by grass",
+			LastBuildDate: feedUpdated(results).UTC().Format(time.RFC1123Z),
+			Generator:     "grass/" + Version,
+		},
+	}
+	for _, result := range results {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       feedTitle(result),
+			Link:        result.URL,
+			GUID:        rssGUID{IsPermaLink: true, Value: result.URL},
+			PubDate:     time.Unix(result.Timestamp, 0).UTC().Format(time.RFC1123Z),
+			Creator:     result.Author,
+			Categories:  feedTags(result),
+			Description: result.Content,
+		})
+	}
+	return marshalFeedXML(doc)
+}
+
+type atomDocument struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Link      atomLink    `xml:"link"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomAuthor     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// atomFeed renders results as an Atom feed.
+func atomFeed(title, self string, results []search.SearchResult) ([]byte, error) {
+	doc := atomDocument{
+		ID:        self,
+		Title:     title,
+		Updated:   feedUpdated(results).UTC().Format(time.RFC3339),
+		Link:      atomLink{Rel: "self", Href: self},
+		Generator: "grass/" + Version,
+	}
+	for _, result := range results {
+		posted := time.Unix(result.Timestamp, 0).UTC().Format(time.RFC3339)
+		// Atom requires an author, so anonymous results are credited to their platform
+		author := result.Author
+		if author == "" {
+			author = result.Platform
+		}
+		entry := atomEntry{
+			ID:        result.URL,
+			Title:     feedTitle(result),
+			Link:      atomLink{Href: result.URL},
+			Published: posted,
+			Updated:   posted,
+			Author:    atomAuthor{Name: author},
+			Summary:   result.Content,
+		}
+		for _, tag := range feedTags(result) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return marshalFeedXML(doc)
+}
+
+// marshalFeedXML renders an XML feed document with its declaration.
+func marshalFeedXML(doc any) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
+
+type jsonFeedDocument struct {
+	Version string         `json:"version"`
+	Title   string         `json:"title"`
+	FeedURL string         `json:"feed_url"`
+	Items   []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentText   string           `json:"content_text"`
+	DatePublished string           `json:"date_published"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	// Grass is an extension carrying the fields feed items have no place for.
+	Grass jsonFeedExtension `json:"_grass"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedExtension struct {
+	Platform string          `json:"platform"`
+	Keywords []string        `json:"keywords"`
+	Score    int64           `json:"score"`
+	Comments int64           `json:"comments"`
+	Priority search.Priority `json:"priority,omitempty"`
+}
+
+// jsonFeed renders results as a JSON Feed 1.1.
+func jsonFeed(title, self string, results []search.SearchResult) ([]byte, error) {
+	doc := jsonFeedDocument{
+		Version: "https://jsonfeed.org/version/1.1",
+		Title:   title,
+		FeedURL: self,
+		Items:   []jsonFeedItem{},
+	}
+	for _, result := range results {
+		item := jsonFeedItem{
+			ID:            result.URL,
+			URL:           result.URL,
+			Title:         feedTitle(result),
+			ContentText:   result.Content,
+			DatePublished: time.Unix(result.Timestamp, 0).UTC().Format(time.RFC3339),
+			Tags:          feedTags(result),
+			Grass: jsonFeedExtension{
+				Platform: result.Platform,
+				Keywords: resultKeywordList(result),
+				Score:    result.Score,
+				Comments: result.Comments,
+				Priority: result.Priority,
+			},
+		}
+		// Items need content, so results without any use their title
+		if item.ContentText == "" {
+			item.ContentText = item.Title
+		}
+		if result.Author != "" {
+			item.Authors = []jsonFeedAuthor{{Name: result.Author}}
+		}
+		doc.Items = append(doc.Items, item)
+	}
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(body, '\n'), nil
+}
+
+// serveFeeds serves feeds of the profiles' stored results on addr until ctx is cancelled, then waits for
+// in-flight requests to finish. Requests must carry token unless it is empty.
+func serveFeeds(ctx context.Context, addr string, profiles []*profile, token string) error {
+	log.Info("Serving feeds", "addr", addr)
+	return listenAndServe(ctx, &http.Server{
+		Addr:              addr,
+		Handler:           newFeedHandler(profiles, token),
+		ReadHeaderTimeout: 10 * time.Second,
+	})
+}
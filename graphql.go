@@ -0,0 +1,470 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+// graphQLRequest is the body of a GraphQL request.
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+}
+
+// graphQLBucket is a group of results sharing the values of the dimensions they were aggregated by.
+// Dimensions that weren't grouped by are empty, apart from those of the bucket it was nested in.
+type graphQLBucket struct {
+	platform, keyword, day, state string
+	results                       []search.SearchResult
+}
+
+// graphQL executes a GraphQL query. As is usual for GraphQL, query errors are reported in the response
+// body with a 200 status.
+func (h *apiHandler) graphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		writeAPIResponse(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        r.Context(),
+	})
+	writeAPIResponse(w, http.StatusOK, result)
+}
+
+// longType is a 64-bit integer, for timestamps and engagement counts that can exceed GraphQL's 32-bit Int.
+var longType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Long",
+	Description: "A 64-bit integer.",
+	Serialize:   func(value any) any { return value },
+})
+
+// newGraphQLSchema builds the schema of the GraphQL endpoint over the profiles' stored results and run
+// history. Days are calendar days in location.
+func (h *apiHandler) newGraphQLSchema(location *time.Location) (graphql.Schema, error) {
+	stateValues := graphql.EnumValueConfigMap{}
+	for _, state := range storage.States {
+		stateValues[strings.ToUpper(state)] = &graphql.EnumValueConfig{Value: state}
+	}
+	stateType := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "State",
+		Description: "How a result has been handled.",
+		Values:      stateValues,
+	})
+	metadataType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MetadataEntry",
+		Fields: graphql.Fields{
+			"key":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	resultType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Result",
+		Description: "A stored result.",
+		Fields: graphql.Fields{
+			"platform":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"keyword":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"keywords":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), Description: "Every keyword the result matched, for storage that records them."},
+			"title":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"url":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"timestamp":      &graphql.Field{Type: graphql.NewNonNull(longType), Description: "When the result was posted, in Unix seconds."},
+			"posted":         &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "When the result was posted, as an RFC 3339 time."},
+			"content":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"author":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"score":          &graphql.Field{Type: graphql.NewNonNull(longType)},
+			"comments":       &graphql.Field{Type: graphql.NewNonNull(longType)},
+			"reposts":        &graphql.Field{Type: graphql.NewNonNull(longType)},
+			"views":          &graphql.Field{Type: graphql.NewNonNull(longType)},
+			"tags":           &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"metadata":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(metadataType)))},
+			"priority":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"contentHash":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"deletedAt":      &graphql.Field{Type: longType, Description: "When the post was found to be deleted, in Unix seconds."},
+			"state":          &graphql.Field{Type: graphql.NewNonNull(stateType)},
+			"stateChangedAt": &graphql.Field{Type: graphql.String, Description: "When the result's state was last set, as an RFC 3339 time."},
+		},
+	})
+	runPlatformType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "RunPlatform",
+		Description: "The searches of one platform during a run, or of every platform in its totals.",
+		Fields: graphql.Fields{
+			"platform": &graphql.Field{Type: graphql.String},
+			"searches": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"failed":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"found":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"new":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"skipped":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"duration": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "Seconds spent searching."},
+			"errors":   &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+		},
+	})
+	runType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Run",
+		Description: "A run from the run history.",
+		Fields: graphql.Fields{
+			"profile":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"trigger":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"started":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"duration":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "Seconds the run took."},
+			"failed":    &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"totals":    &graphql.Field{Type: graphql.NewNonNull(runPlatformType)},
+			"platforms": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(runPlatformType)))},
+		},
+	})
+	profileType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Profile",
+		Fields: graphql.Fields{
+			"name":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"campaign": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"active":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+	dimensionType := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "Dimension",
+		Description: "What results are aggregated by.",
+		Values: graphql.EnumValueConfigMap{
+			"PLATFORM": &graphql.EnumValueConfig{Value: "platform"},
+			"KEYWORD":  &graphql.EnumValueConfig{Value: "keyword"},
+			"DAY":      &graphql.EnumValueConfig{Value: "day"},
+			"STATE":    &graphql.EnumValueConfig{Value: "state"},
+		},
+	})
+
+	groupByArgs := graphql.FieldConfigArgument{
+		"groupBy": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(dimensionType)))},
+	}
+	var bucketType *graphql.Object
+	bucketType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Bucket",
+		Description: "Results sharing the values of the dimensions they were aggregated by, including those of the buckets it is nested in.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"platform": &graphql.Field{Type: graphql.String, Resolve: bucketField(func(b *graphQLBucket) string { return b.platform })},
+				"keyword":  &graphql.Field{Type: graphql.String, Resolve: bucketField(func(b *graphQLBucket) string { return b.keyword })},
+				"day":      &graphql.Field{Type: graphql.String, Description: "A date like 2024-01-31.", Resolve: bucketField(func(b *graphQLBucket) string { return b.day })},
+				"state":    &graphql.Field{Type: stateType, Resolve: bucketField(func(b *graphQLBucket) string { return b.state })},
+				"count": &graphql.Field{
+					Type:    graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (any, error) { return len(p.Source.(*graphQLBucket).results), nil },
+				},
+				"results": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(resultType))),
+					Description: "The bucket's results, newest first.",
+					Args: graphql.FieldConfigArgument{
+						"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: apiDefaultLimit},
+					},
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						results := p.Source.(*graphQLBucket).results
+						if limit, _ := p.Args["limit"].(int); limit > 0 && len(results) > limit {
+							results = results[:limit]
+						}
+						return graphQLResults(results, location), nil
+					},
+				},
+				"aggregate": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bucketType))),
+					Description: "Aggregates the bucket's results further.",
+					Args:        groupByArgs,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return aggregate(p.Source.(*graphQLBucket), graphQLStrings(p.Args["groupBy"]), location), nil
+					},
+				},
+			}
+		}),
+	})
+
+	filterArgs := func(extra graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		args := graphql.FieldConfigArgument{
+			"profile":   &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: ""},
+			"platforms": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"keywords":  &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"states":    &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(stateType))},
+			"since":     &graphql.ArgumentConfig{Type: graphql.String, Description: "A date like 2024-01-31 or an RFC 3339 time."},
+			"until":     &graphql.ArgumentConfig{Type: graphql.String, Description: "A date like 2024-01-31 or an RFC 3339 time."},
+		}
+		for name, arg := range extra {
+			args[name] = arg
+		}
+		return args
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"profiles": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(profileType))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					now := time.Now()
+					profiles := make([]map[string]any, 0, len(h.profiles))
+					for _, name := range profileNames(h.profiles) {
+						profile := h.profiles[name]
+						profiles = append(profiles, map[string]any{"name": name, "campaign": profile.campaign != nil, "active": profile.active(now)})
+					}
+					return profiles, nil
+				},
+			},
+			"results": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(resultType))),
+				Description: "Stored results, newest first.",
+				Args: filterArgs(graphql.FieldConfigArgument{
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: apiDefaultLimit, Description: "How many results to return, or 0 for all."},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				}),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					limit, _ := p.Args["limit"].(int)
+					offset, _ := p.Args["offset"].(int)
+					results, err := h.graphQLQuery(p, max(limit, 0), max(offset, 0))
+					if err != nil {
+						return nil, err
+					}
+					return graphQLResults(results, location), nil
+				},
+			},
+			"aggregate": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bucketType))),
+				Description: "Counts the stored results matching the filters, grouped by the given dimensions.",
+				Args:        filterArgs(groupByArgs),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					results, err := h.graphQLQuery(p, 0, 0)
+					if err != nil {
+						return nil, err
+					}
+					return aggregate(&graphQLBucket{results: results}, graphQLStrings(p.Args["groupBy"]), location), nil
+				},
+			},
+			"runs": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(runType))),
+				Description: "Run history, most recent first.",
+				Args: graphql.FieldConfigArgument{
+					"profile": &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: ""},
+					"limit":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: apiDefaultLimit},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					profile, err := h.graphQLProfile(p)
+					if err != nil {
+						return nil, err
+					}
+					recorder, ok := storage.AsRunRecorder(profile.storer)
+					if !ok {
+						return nil, fmt.Errorf("the profile's storage can't keep run history")
+					}
+					limit, _ := p.Args["limit"].(int)
+					runs, err := recorder.Runs(p.Context, max(limit, 0))
+					if err != nil {
+						return nil, err
+					}
+					out := make([]map[string]any, 0, len(runs))
+					for _, run := range runs {
+						out = append(out, graphQLRun(run))
+					}
+					return out, nil
+				},
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// graphQLProfile returns the profile named by a field's profile argument.
+func (h *apiHandler) graphQLProfile(p graphql.ResolveParams) (*profile, error) {
+	name, _ := p.Args["profile"].(string)
+	profile, ok := h.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	return profile, nil
+}
+
+// graphQLQuery queries the stored results selected by a field's filter arguments.
+func (h *apiHandler) graphQLQuery(p graphql.ResolveParams, limit, offset int) ([]search.SearchResult, error) {
+	profile, err := h.graphQLProfile(p)
+	if err != nil {
+		return nil, err
+	}
+	querier, ok := storage.AsQuerier(profile.storer)
+	if !ok {
+		return nil, fmt.Errorf("the profile's storage can't be queried")
+	}
+	q := storage.Query{
+		Platforms: graphQLStrings(p.Args["platforms"]),
+		Keywords:  graphQLStrings(p.Args["keywords"]),
+		States:    graphQLStrings(p.Args["states"]),
+		Limit:     limit,
+		Offset:    offset,
+	}
+	since, _ := p.Args["since"].(string)
+	until, _ := p.Args["until"].(string)
+	if q.Since, err = apiTime(since); err != nil {
+		return nil, err
+	}
+	if q.Until, err = apiTime(until); err != nil {
+		return nil, err
+	}
+	return querier.Query(p.Context, q)
+}
+
+// aggregate groups a bucket's results by the values of the dimensions, keeping the bucket's own values for
+// the others. Buckets are sorted by their values in the order of dimensions.
+func aggregate(parent *graphQLBucket, dimensions []string, location *time.Location) []*graphQLBucket {
+	buckets := make(map[[4]string]*graphQLBucket)
+	for _, result := range parent.results {
+		bucket := graphQLBucket{platform: parent.platform, keyword: parent.keyword, day: parent.day, state: parent.state}
+		for _, dimension := range dimensions {
+			switch dimension {
+			case "platform":
+				bucket.platform = result.Platform
+			case "keyword":
+				bucket.keyword = result.Keyword
+			case "day":
+				bucket.day = time.Unix(result.Timestamp, 0).In(location).Format(time.DateOnly)
+			case "state":
+				bucket.state = storage.ResultState(result)
+			}
+		}
+		key := [4]string{bucket.platform, bucket.keyword, bucket.day, bucket.state}
+		if buckets[key] == nil {
+			buckets[key] = &bucket
+		}
+		buckets[key].results = append(buckets[key].results, result)
+	}
+
+	sorted := make([]*graphQLBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		sorted = append(sorted, bucket)
+	}
+	slices.SortFunc(sorted, func(a, b *graphQLBucket) int {
+		for _, dimension := range dimensions {
+			var c int
+			switch dimension {
+			case "platform":
+				c = strings.Compare(a.platform, b.platform)
+			case "keyword":
+				c = strings.Compare(a.keyword, b.keyword)
+			case "day":
+				c = strings.Compare(a.day, b.day)
+			case "state":
+				c = slices.Index(storage.States, a.state) - slices.Index(storage.States, b.state)
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return sorted
+}
+
+// bucketField resolves a bucket dimension, which is null when the results weren't grouped by it.
+func bucketField(value func(*graphQLBucket) string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		if v := value(p.Source.(*graphQLBucket)); v != "" {
+			return v, nil
+		}
+		return nil, nil
+	}
+}
+
+// graphQLStrings converts a list argument to strings.
+func graphQLStrings(value any) []string {
+	list, _ := value.([]any)
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// graphQLResults converts results to the fields of the Result type.
+func graphQLResults(results []search.SearchResult, location *time.Location) []map[string]any {
+	out := make([]map[string]any, 0, len(results))
+	for _, result := range results {
+		metadata := make([]map[string]any, 0, len(result.Metadata))
+		keys := make([]string, 0, len(result.Metadata))
+		for key := range result.Metadata {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			metadata = append(metadata, map[string]any{"key": key, "value": result.Metadata[key]})
+		}
+		fields := map[string]any{
+			"platform":    result.Platform,
+			"keyword":     result.Keyword,
+			"keywords":    resultKeywordList(result),
+			"title":       result.Title,
+			"url":         result.URL,
+			"timestamp":   result.Timestamp,
+			"posted":      time.Unix(result.Timestamp, 0).In(location).Format(time.RFC3339),
+			"content":     result.Content,
+			"author":      result.Author,
+			"score":       result.Score,
+			"comments":    result.Comments,
+			"reposts":     result.Reposts,
+			"views":       result.Views,
+			"tags":        append([]string{}, result.Tags...),
+			"metadata":    metadata,
+			"priority":    string(result.Priority),
+			"contentHash": result.ContentHash,
+			"state":       storage.ResultState(result),
+		}
+		if result.DeletedAt != 0 {
+			fields["deletedAt"] = result.DeletedAt
+		}
+		if changed, ok := result.Metadata[storage.StateChangedMetadata]; ok {
+			fields["stateChangedAt"] = changed
+		}
+		out = append(out, fields)
+	}
+	return out
+}
+
+// resultKeywordList returns every keyword a result matched, or just its keyword when none are recorded.
+func resultKeywordList(result search.SearchResult) []string {
+	if len(result.Keywords) > 0 {
+		return result.Keywords
+	}
+	return []string{result.Keyword}
+}
+
+// graphQLRun converts a run to the fields of the Run type.
+func graphQLRun(run storage.Run) map[string]any {
+	platform := func(p storage.RunPlatform) map[string]any {
+		return map[string]any{
+			"platform": p.Platform,
+			"searches": p.Searches,
+			"failed":   p.Failed,
+			"found":    p.Found,
+			"new":      p.New,
+			"skipped":  p.Skipped,
+			"duration": p.Duration,
+			"errors":   append([]string{}, p.Errors...),
+		}
+	}
+	platforms := make([]map[string]any, 0, len(run.Platforms))
+	for _, p := range run.Platforms {
+		platforms = append(platforms, platform(p))
+	}
+	return map[string]any{
+		"profile":   run.Profile,
+		"trigger":   run.Trigger,
+		"started":   run.Started.Format(time.RFC3339),
+		"duration":  run.Duration,
+		"failed":    run.Failed,
+		"totals":    platform(run.Totals),
+		"platforms": platforms,
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/bot"
+	grassv1 "github.com/jaxxstorm/grass/proto/grass/v1"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcEnabled = serveCommand.Flag("grpc", "Serve the gRPC API").Bool()
+	grpcAddr    = serveCommand.Flag("grpc-addr", "Address to serve the gRPC API on").Envar("GRASS_GRPC_ADDR").Default("127.0.0.1:9090").String()
+)
+
+// grpcStreamBuffer is how many saved results a StreamResults call may fall behind by before results are
+// dropped for it.
+const grpcStreamBuffer = 256
+
+// savedResult is a result saved by a profile.
+type savedResult struct {
+	profile string
+	result  search.SearchResult
+}
+
+// resultFeed fans out the results saved by the profiles it is attached to, to subscribers such as
+// StreamResults calls.
+type resultFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan savedResult]struct{}
+}
+
+func newResultFeed() *resultFeed {
+	return &resultFeed{subscribers: make(map[chan savedResult]struct{})}
+}
+
+// attach publishes the new results, and new versions of edited ones, that each profile saves. It must be
+// called before the profiles run.
+func (f *resultFeed) attach(profiles []*profile) error {
+	for _, p := range profiles {
+		name := p.name
+		err := p.bot.Use(bot.StageStore, bot.ProcessorFunc(func(ctx context.Context, results []search.SearchResult) ([]search.SearchResult, error) {
+			f.publish(name, results)
+			return results, nil
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish sends results to every subscriber, dropping them for subscribers that have fallen behind rather
+// than holding up the profile's run.
+func (f *resultFeed) publish(profile string, results []search.SearchResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, result := range results {
+		for subscriber := range f.subscribers {
+			select {
+			case subscriber <- savedResult{profile: profile, result: result}:
+			default:
+				log.Warn("Subscriber falling behind; dropping result", "profile", profile, "url", result.URL)
+			}
+		}
+	}
+}
+
+// subscribe returns a channel receiving every result published from now on, and a function that stops
+// the subscription.
+func (f *resultFeed) subscribe() (<-chan savedResult, func()) {
+	ch := make(chan savedResult, grpcStreamBuffer)
+	f.mu.Lock()
+	f.subscribers[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch, func() {
+		f.mu.Lock()
+		delete(f.subscribers, ch)
+		f.mu.Unlock()
+	}
+}
+
+// grpcServer implements the gRPC API for the profiles.
+type grpcServer struct {
+	grassv1.UnimplementedGrassServiceServer
+
+	profiles map[string]*profile
+	feed     *resultFeed
+	// done is closed when the server shuts down, ending streams that would otherwise run until cancelled.
+	done <-chan struct{}
+}
+
+// profile returns the named profile.
+func (s *grpcServer) profile(name string) (*profile, error) {
+	p, ok := s.profiles[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown profile %q", name)
+	}
+	return p, nil
+}
+
+func (s *grpcServer) ListProfiles(ctx context.Context, req *grassv1.ListProfilesRequest) (*grassv1.ListProfilesResponse, error) {
+	now := time.Now()
+	resp := &grassv1.ListProfilesResponse{}
+	for _, name := range profileNames(s.profiles) {
+		p := s.profiles[name]
+		resp.Profiles = append(resp.Profiles, &grassv1.Profile{Name: name, Campaign: p.campaign != nil, Active: p.active(now)})
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) QueryResults(ctx context.Context, req *grassv1.QueryResultsRequest) (*grassv1.QueryResultsResponse, error) {
+	p, err := s.profile(req.GetProfile())
+	if err != nil {
+		return nil, err
+	}
+	querier, ok := storage.AsQuerier(p.storer)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the profile's storage can't be queried")
+	}
+
+	if err := storage.CheckStates(req.GetStates()...); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	q := storage.Query{
+		Platforms: req.GetPlatforms(),
+		Keywords:  req.GetKeywords(),
+		States:    req.GetStates(),
+		Limit:     int(req.GetLimit()),
+		Offset:    max(int(req.GetOffset()), 0),
+	}
+	switch {
+	case q.Limit == 0:
+		q.Limit = apiDefaultLimit
+	case q.Limit < 0:
+		q.Limit = 0
+	}
+	if req.GetSince() != 0 {
+		q.Since = time.Unix(req.GetSince(), 0)
+	}
+	if req.GetUntil() != 0 {
+		q.Until = time.Unix(req.GetUntil(), 0)
+	}
+	results, err := querier.Query(ctx, q)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &grassv1.QueryResultsResponse{Results: protoResults(results)}, nil
+}
+
+func (s *grpcServer) StreamResults(req *grassv1.StreamResultsRequest, stream grassv1.GrassService_StreamResultsServer) error {
+	for _, name := range req.GetProfiles() {
+		if _, err := s.profile(name); err != nil {
+			return err
+		}
+	}
+	saved, unsubscribe := s.feed.subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server shutting down")
+		case r := <-saved:
+			if !streamed(req, r) {
+				continue
+			}
+			resp := &grassv1.StreamResultsResponse{Profile: r.profile, Result: protoResult(r.result)}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamed reports whether a saved result matches a StreamResults call's filters.
+func streamed(req *grassv1.StreamResultsRequest, r savedResult) bool {
+	if len(req.GetProfiles()) > 0 && !slices.Contains(req.GetProfiles(), r.profile) {
+		return false
+	}
+	if len(req.GetPlatforms()) > 0 && !slices.Contains(req.GetPlatforms(), r.result.Platform) {
+		return false
+	}
+	if len(req.GetKeywords()) > 0 && !slices.ContainsFunc(resultKeywordList(r.result), func(keyword string) bool {
+		return slices.Contains(req.GetKeywords(), keyword)
+	}) {
+		return false
+	}
+	return true
+}
+
+func (s *grpcServer) Search(ctx context.Context, req *grassv1.SearchRequest) (*grassv1.SearchResponse, error) {
+	p, err := s.profile(req.GetProfile())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := search.ParseQuery(req.GetKeyword()); err != nil || req.GetKeyword() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid keyword %q", req.GetKeyword())
+	}
+	if !p.active(time.Now()) {
+		return nil, status.Errorf(codes.FailedPrecondition, "campaign %q is not running", p.name)
+	}
+
+	log.Info("Searching on request", "profile", p.name, "keyword", req.GetKeyword())
+	report := p.bot.Run(ctx, req.GetKeyword())
+	recordRun(ctx, p, report, "grpc")
+	return &grassv1.SearchResponse{
+		Run:     protoRun(newRun(report, p.name, "grpc")),
+		Results: protoResults(report.Results()),
+	}, nil
+}
+
+func (s *grpcServer) SetResultState(ctx context.Context, req *grassv1.SetResultStateRequest) (*grassv1.SetResultStateResponse, error) {
+	p, err := s.profile(req.GetProfile())
+	if err != nil {
+		return nil, err
+	}
+	updater, ok := storage.AsUpdater(p.storer)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the profile's storage can't keep result states")
+	}
+	if err := storage.CheckStates(req.GetState()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	result, found, err := storage.SetState(ctx, updater, req.GetPlatform(), req.GetUrl(), req.GetState())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !found {
+		return nil, status.Error(codes.NotFound, "result not found")
+	}
+	return &grassv1.SetResultStateResponse{Result: protoResult(result)}, nil
+}
+
+// grpcAuthorize checks that a call carries token as a bearer token in its authorization metadata.
+func grpcAuthorize(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range md.Get("authorization") {
+		if isBearerToken(authorization, token) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+// serveGRPC serves the gRPC API on addr, or the socket systemd passed as grpc, until ctx is cancelled, then
+// waits for in-flight calls to finish. Results saved by the profiles are streamed through feed.
+func serveGRPC(ctx context.Context, addr string, profiles []*profile, token string, feed *resultFeed) error {
+	listener, err := listen("grpc", addr)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := grpcAuthorize(ctx, token); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := grpcAuthorize(stream.Context(), token); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	)
+	grassv1.RegisterGrassServiceServer(server, &grpcServer{profiles: profilesByName(profiles), feed: feed, done: ctx.Done()})
+
+	log.Info("Serving gRPC API", "addr", listener.Addr())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- server.Serve(listener)
+	}()
+	select {
+	case err := <-errc:
+		return fmt.Errorf("serving gRPC: %w", err)
+	case <-ctx.Done():
+	}
+	server.GracefulStop()
+	return <-errc
+}
+
+// protoResults converts results for the gRPC API.
+func protoResults(results []search.SearchResult) []*grassv1.SearchResult {
+	out := make([]*grassv1.SearchResult, 0, len(results))
+	for _, result := range results {
+		out = append(out, protoResult(result))
+	}
+	return out
+}
+
+// protoResult converts a result for the gRPC API.
+func protoResult(result search.SearchResult) *grassv1.SearchResult {
+	return &grassv1.SearchResult{
+		Platform:    result.Platform,
+		Keyword:     result.Keyword,
+		Keywords:    result.Keywords,
+		Title:       result.Title,
+		Url:         result.URL,
+		Timestamp:   result.Timestamp,
+		Content:     result.Content,
+		Author:      result.Author,
+		Score:       result.Score,
+		Comments:    result.Comments,
+		Reposts:     result.Reposts,
+		Views:       result.Views,
+		Tags:        result.Tags,
+		Metadata:    result.Metadata,
+		Priority:    string(result.Priority),
+		ContentHash: result.ContentHash,
+		DeletedAt:   result.DeletedAt,
+		Edited:      result.Edited,
+		State:       storage.ResultState(result),
+	}
+}
+
+// protoRun converts a run for the gRPC API.
+func protoRun(run storage.Run) *grassv1.Run {
+	platform := func(p storage.RunPlatform) *grassv1.RunPlatform {
+		return &grassv1.RunPlatform{
+			Platform:        p.Platform,
+			Searches:        int32(p.Searches),
+			Failed:          int32(p.Failed),
+			Found:           int32(p.Found),
+			New:             int32(p.New),
+			Skipped:         int32(p.Skipped),
+			DurationSeconds: p.Duration,
+			Errors:          p.Errors,
+		}
+	}
+	out := &grassv1.Run{
+		Profile:         run.Profile,
+		Trigger:         run.Trigger,
+		Started:         run.Started.Unix(),
+		DurationSeconds: run.Duration,
+		Failed:          run.Failed,
+		Totals:          platform(run.Totals),
+	}
+	for _, p := range run.Platforms {
+		out.Platforms = append(out.Platforms, platform(p))
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/grass/internal/httpclient"
+	"github.com/jaxxstorm/grass/internal/proxy"
+)
+
+// sharedHTTPClient sends the requests of every searcher, notifier, and HTTP-based storage backend that
+// doesn't have its own proxy. It is created in main from the --http-*, --tls-*, and --proxy flags.
+var sharedHTTPClient *http.Client
+
+// httpConfig returns the HTTP client settings from the command line, sending requests through proxy.
+func httpConfig(proxy proxy.Func) httpclient.Config {
+	agent := *userAgent
+	if agent == "" {
+		agent = "grass/" + Version
+	}
+	return httpclient.Config{
+		Timeout:             *httpTimeout,
+		DialTimeout:         *httpDialTimeout,
+		MaxIdleConnsPerHost: *httpIdleConns,
+		MaxConnsPerHost:     *httpMaxConns,
+		IdleConnTimeout:     *httpIdleTimeout,
+		UserAgent:           agent,
+		CAFile:              *tlsCAFile,
+		MinTLSVersion:       *tlsMinVersion,
+		InsecureSkipVerify:  *tlsSkipVerify,
+		Proxy:               proxy,
+	}
+}
+
+// hasBearerToken reports whether the request carries token as a bearer token, comparing in constant time.
+func hasBearerToken(r *http.Request, token string) bool {
+	return isBearerToken(r.Header.Get("Authorization"), token)
+}
+
+// isBearerToken reports whether an Authorization header value carries token as a bearer token, comparing
+// in constant time.
+func isBearerToken(authorization, token string) bool {
+	given, ok := strings.CutPrefix(authorization, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// listenAndServe runs server until ctx is cancelled, then waits for in-flight requests to finish.
+func listenAndServe(ctx context.Context, server *http.Server) error {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	return serve(ctx, server, listener)
+}
+
+// serve runs server on listener until ctx is cancelled, then waits for in-flight requests to finish.
+func serve(ctx context.Context, server *http.Server, listener net.Listener) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
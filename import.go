@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	importCommand   = kingpin.Command("import", "Store results from an export or another grass database as already seen, without notifying them")
+	importFile      = importCommand.Arg("file", "JSON or CSV export to import (default: stdin)").String()
+	importFormat    = importCommand.Flag("format", "Format of the file: json or csv (default: from the file extension, else json)").Enum("json", "csv")
+	importFromDB    = importCommand.Flag("from-db", "Import every result from this database type instead of a file").Enum(storageBackends...)
+	importFromTable = importCommand.Flag("from-table-name", "Table, file, or bucket of the --from-db database").String()
+	importProfile   = importCommand.Flag("profile", "Import into the storage of this profile from the config file instead of --db and --table-name").String()
+)
+
+// importBatchSize is how many results are saved at a time.
+const importBatchSize = 100
+
+// runImport saves the results of an export file, or of the database selected by --from-db, to the profile's
+// primary storage. Results that are already stored are kept as they are.
+func runImport(ctx context.Context, cfg *config.Config) error {
+	var (
+		results []search.SearchResult
+		err     error
+	)
+	switch {
+	case *importFromDB != "" && *importFile != "":
+		return errors.New("import either a file or --from-db, not both")
+	case *importFromDB != "":
+		results, err = importDatabase(ctx, *importFromDB, *importFromTable)
+	default:
+		results, err = importExport(*importFile, *importFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	storer, _, err := openStorage(ctx, cfg, *importProfile)
+	if err != nil {
+		return err
+	}
+	if closer, ok := storer.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	imported, existing, err := storeResults(ctx, storer, results, nil)
+	if err != nil {
+		return err
+	}
+	log.Info("Imported results", "imported", imported, "already_stored", existing)
+	return nil
+}
+
+// storeResults saves results to storer in batches, skipping those that are already stored. Skipped results
+// still add their keywords to the stored ones, in storers that record them. progress, if set, is called
+// with the number of results handled so far after each batch. It returns how many results were saved and
+// how many were already stored.
+func storeResults(ctx context.Context, storer storage.Storer, results []search.SearchResult, progress func(done int)) (int, int, error) {
+	recorder, recordsKeywords := storage.AsKeywordRecorder(storer)
+	saved, existing := 0, 0
+	for start := 0; start < len(results); start += importBatchSize {
+		var batch, stored []search.SearchResult
+		for i, result := range results[start:min(start+importBatchSize, len(results))] {
+			if result.Platform == "" || result.URL == "" {
+				return saved, existing, fmt.Errorf("result %d has no platform or URL", start+i+1)
+			}
+			exists, err := storer.Exists(ctx, result.Platform, result.URL)
+			if err != nil {
+				return saved, existing, err
+			}
+			if exists {
+				if len(result.Keywords) == 0 && result.Keyword != "" {
+					result.Keywords = []string{result.Keyword}
+				}
+				stored = append(stored, result)
+				continue
+			}
+			batch = append(batch, result)
+		}
+
+		if len(batch) > 0 {
+			if err := storer.SaveBatch(ctx, batch); err != nil {
+				return saved, existing, err
+			}
+		}
+		if recordsKeywords && len(stored) > 0 {
+			if err := recorder.AddKeywords(ctx, stored); err != nil {
+				return saved, existing, err
+			}
+		}
+		saved += len(batch)
+		existing += len(stored)
+		if progress != nil {
+			progress(saved + existing)
+		}
+	}
+	return saved, existing, nil
+}
+
+// importDatabase returns every result stored in another grass database.
+func importDatabase(ctx context.Context, db, table string) ([]search.SearchResult, error) {
+	source, err := newStorer(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := source.(io.Closer); ok {
+		defer closer.Close()
+	}
+	querier, ok := storage.AsQuerier(source)
+	if !ok {
+		return nil, fmt.Errorf("%s storage can't be imported from", db)
+	}
+	return querier.Query(ctx, storage.Query{})
+}
+
+// importExport reads the results of a file written by the export command, or of stdin when path is empty,
+// in format, or the format named by the file's extension when format is empty.
+func importExport(path, format string) ([]search.SearchResult, error) {
+	if format == "" {
+		format = "json"
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			format = "csv"
+		}
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if format == "csv" {
+		return readCSVExport(r)
+	}
+	var results []search.SearchResult
+	if err := json.NewDecoder(r).Decode(&results); err != nil {
+		return nil, fmt.Errorf("decoding JSON export: %w", err)
+	}
+	return results, nil
+}
+
+// readCSVExport reads results from CSV with a header row naming exportColumns, in any order. Missing
+// columns are left empty.
+func readCSVExport(r io.Reader) ([]search.SearchResult, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	var results []search.SearchResult
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return results, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		number := func(name string) int64 {
+			if err != nil || field(name) == "" {
+				return 0
+			}
+			var n int64
+			if n, err = strconv.ParseInt(field(name), 10, 64); err != nil {
+				err = fmt.Errorf("line %d: %s: %w", line, name, err)
+			}
+			return n
+		}
+		list := func(name string) []string {
+			if field(name) == "" {
+				return nil
+			}
+			return strings.Split(field(name), ",")
+		}
+
+		result := search.SearchResult{
+			Platform:    field("platform"),
+			Keyword:     field("keyword"),
+			Keywords:    list("keywords"),
+			Title:       field("title"),
+			URL:         field("url"),
+			Timestamp:   number("timestamp"),
+			Content:     field("content"),
+			Author:      field("author"),
+			Score:       number("score"),
+			Comments:    number("comments"),
+			Reposts:     number("reposts"),
+			Views:       number("views"),
+			Tags:        list("tags"),
+			Priority:    search.Priority(field("priority")),
+			ContentHash: field("content_hash"),
+		}
+		if err != nil {
+			return nil, err
+		}
+		if metadata := field("metadata"); metadata != "" {
+			if err := json.Unmarshal([]byte(metadata), &result.Metadata); err != nil {
+				return nil, fmt.Errorf("line %d: metadata: %w", line, err)
+			}
+		}
+		results = append(results, result)
+	}
+}
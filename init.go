@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/mattn/go-isatty"
+)
+
+var (
+	initCommand = kingpin.Command("init", "Write a starter config file and .env template for the searchers, notifiers, and storage you choose")
+	initConfig  = initCommand.Flag("file", "Path to write the config file to").Default("grass.yaml").String()
+	initEnvFile = initCommand.Flag("env-file", "Path to write the .env template to").Default(".env").String()
+	initForce   = initCommand.Flag("force", "Overwrite files that already exist").Bool()
+)
+
+// initSearchers and initNotifiers are the built-in searchers and notifiers offered by init.
+var (
+	initSearchers = search.Registered()
+	initNotifiers = bot.RegisteredNotifiers()
+)
+
+// initEnvVar is an environment variable a searcher, notifier, or storage backend reads.
+type initEnvVar struct {
+	name string
+	help string
+}
+
+// initEnvVars are the environment variables each searcher, notifier, and storage backend reads, by name.
+var initEnvVars = map[string][]initEnvVar{
+	"reddit": {
+		{"REDDIT_CLIENT_ID", "Client ID of a Reddit script app, from https://www.reddit.com/prefs/apps"},
+		{"REDDIT_CLIENT_SECRET", "Secret of the Reddit script app"},
+		{"REDDIT_USERNAME", "Reddit account the app belongs to"},
+		{"REDDIT_PASSWORD", "Password of the Reddit account; or leave the username and password empty and run grass login reddit with an installed app"},
+	},
+	"bluesky": {
+		{"BSKY_USERNAME", "Bluesky handle, e.g. you.bsky.social"},
+		{"BSKY_PASSWORD", "Bluesky app password, from Settings > App Passwords"},
+	},
+	"fediverse": {
+		{"FEDIVERSE_INSTANCES", "Instance URLs to search, comma separated, e.g. https://mastodon.social"},
+		{"MASTODON_SOCIAL_ACCESS_TOKEN", "Access token for each instance, named <HOST>_ACCESS_TOKEN after its host; or set <HOST>_CLIENT_ID and <HOST>_CLIENT_SECRET, or run grass login fediverse <instance>"},
+	},
+	"youtube": {
+		{"YOUTUBE_API_KEY", "YouTube Data API v3 key; or set YOUTUBE_CLIENT_ID and YOUTUBE_CLIENT_SECRET and run grass login youtube"},
+	},
+	"discord": {
+		{"DISCORD_BOT_TOKEN", "Token of the Discord bot that posts results"},
+		{"DISCORD_CHANNEL_ID", "Channel IDs to post results to, comma separated"},
+	},
+	"slack": {
+		{"SLACK_BOT_TOKEN", "Bot token of the Slack app that posts results, starting xoxb-"},
+		{"SLACK_CHANNEL_ID", "Channel IDs to post results to, comma separated"},
+	},
+	"elasticsearch": {
+		{"ELASTICSEARCH_URL", "Elasticsearch URL, e.g. https://localhost:9200"},
+		{"ELASTICSEARCH_API_KEY", "API key; or set ELASTICSEARCH_USERNAME and ELASTICSEARCH_PASSWORD"},
+		{"ELASTICSEARCH_INDEX", "Index to write results to"},
+	},
+	"dynamodb": {
+		{"AWS_REGION", "AWS region of the DynamoDB table; credentials come from the usual AWS sources"},
+	},
+	"redis": {
+		{"REDIS_URL", "Redis URL (default: redis://localhost:6379/0)"},
+	},
+	"s3": {
+		{"S3_BUCKET", "Bucket to store results in; credentials come from the usual AWS sources"},
+		{"AWS_REGION", "AWS region of the bucket"},
+	},
+	"gcs": {
+		{"GCS_BUCKET", "Bucket to store results in; credentials come from Application Default Credentials"},
+	},
+	"clickhouse": {
+		{"CLICKHOUSE_URL", "ClickHouse HTTP URL, e.g. http://localhost:8123"},
+		{"CLICKHOUSE_USER", "ClickHouse user"},
+		{"CLICKHOUSE_PASSWORD", "ClickHouse password"},
+		{"CLICKHOUSE_DATABASE", "ClickHouse database"},
+	},
+}
+
+// runInit writes a config file and .env template for the chosen keywords, searchers, notifiers, and
+// storage. Choices not made with --keyword, --searchers, --bot, and --db are asked for when stdin is a
+// terminal, and otherwise default to a Hacker News search printed to stdout and stored in SQLite.
+func runInit(in io.Reader, out io.Writer) error {
+	for _, path := range []string{*initConfig, *initEnvFile} {
+		if _, err := os.Stat(path); err == nil && !*initForce {
+			return fmt.Errorf("%s already exists; use --force to overwrite it", path)
+		}
+	}
+
+	interactive := false
+	if f, ok := in.(*os.File); ok {
+		interactive = isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+	}
+	reader := bufio.NewReader(in)
+	ask := func(question string, chosen []string, fallback string, options []string) ([]string, error) {
+		if len(chosen) > 0 || !interactive {
+			return orDefault(chosen, fallback), nil
+		}
+		prompt := question
+		if len(options) > 0 {
+			prompt += " (" + strings.Join(options, ", ") + ")"
+		}
+		fmt.Fprintf(out, "%s [%s]: ", prompt, fallback)
+		answer, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		var values []string
+		for _, value := range strings.Split(answer, ",") {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			// Options are single words, so they can also be separated by spaces
+			if len(options) > 0 {
+				for _, option := range strings.Fields(value) {
+					if !slices.Contains(options, option) {
+						fmt.Fprintf(out, "Using %s, which isn't built in; it must be a plugin\n", option)
+					}
+					values = append(values, option)
+				}
+				continue
+			}
+			values = append(values, value)
+		}
+		return orDefault(values, fallback), nil
+	}
+
+	keywordList, err := ask("Keywords to search for, comma separated", *keywords, "grass", nil)
+	if err != nil {
+		return err
+	}
+	searcherList, err := ask("Searchers", *searchers, "hackernews", initSearchers)
+	if err != nil {
+		return err
+	}
+	notifierList, err := ask("Notifiers", *botTypes, "print", initNotifiers)
+	if err != nil {
+		return err
+	}
+	dbList, err := ask("Storage", nil, *dbType, storageBackends)
+	if err != nil {
+		return err
+	}
+	db := dbList[0]
+	if !slices.Contains(storageBackends, db) {
+		return fmt.Errorf("unknown storage %q", db)
+	}
+
+	var config strings.Builder
+	fmt.Fprintf(&config, "# Generated by grass init. Run grass with --config=%s; credentials are read from %s.\n", *initConfig, *initEnvFile)
+	config.WriteString("profiles:\n  default:\n")
+	writeList := func(name string, values []string) {
+		fmt.Fprintf(&config, "    %s:\n", name)
+		for _, value := range values {
+			fmt.Fprintf(&config, "      - %q\n", value)
+		}
+	}
+	writeList("keywords", keywordList)
+	writeList("searchers", searcherList)
+	writeList("bots", notifierList)
+	fmt.Fprintf(&config, "    db: %s\n    table_name: grass\n", db)
+
+	var env strings.Builder
+	env.WriteString("# Generated by grass init. grass reads .env from its working directory on start; fill in the\n# values below, or set them in the environment instead.\n")
+	written := make(map[string]bool)
+	for _, name := range slices.Concat(searcherList, notifierList, []string{db}) {
+		for _, variable := range initEnvVars[name] {
+			if written[variable.name] {
+				continue
+			}
+			written[variable.name] = true
+			fmt.Fprintf(&env, "\n# %s: %s\n%s=\n", name, variable.help, variable.name)
+		}
+	}
+
+	if err := os.WriteFile(*initConfig, []byte(config.String()), 0o644); err != nil {
+		return err
+	}
+	// The template will hold credentials, so only the owner can read it
+	if err := os.WriteFile(*initEnvFile, []byte(env.String()), 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Wrote %s and %s. Fill in %s, then run: grass --config=%s\n", *initConfig, *initEnvFile, *initEnvFile, *initConfig)
+	return nil
+}
+
+// orDefault returns values, or fallback alone when values is empty.
+func orDefault(values []string, fallback string) []string {
+	if len(values) == 0 {
+		return []string{fallback}
+	}
+	return values
+}
@@ -0,0 +1,129 @@
+// internal/elastic/elastic.go
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/grass/internal/httpclient"
+)
+
+// ResultMapping is the index mapping for search results. Platform, keyword and URL are exact-match keyword
+// fields for aggregations, while title and content are analyzed for full-text search.
+const ResultMapping = `{
+  "mappings": {
+    "properties": {
+      "platform":   { "type": "keyword" },
+      "keyword":    { "type": "keyword" },
+      "url":        { "type": "keyword" },
+      "title":      { "type": "text", "analyzer": "english", "fields": { "raw": { "type": "keyword", "ignore_above": 512 } } },
+      "content":    { "type": "text", "analyzer": "english" },
+      "author":     { "type": "keyword" },
+      "score":      { "type": "long" },
+      "comments":   { "type": "long" },
+      "reposts":    { "type": "long" },
+      "views":      { "type": "long" },
+      "tags":       { "type": "keyword" },
+      "metadata":   { "type": "object" },
+      "priority":   { "type": "keyword" },
+      "content_hash": { "type": "keyword" },
+      "timestamp":  { "type": "date", "format": "epoch_second" },
+      "indexed_at": { "type": "date", "format": "epoch_second" }
+    }
+  }
+}`
+
+// Client is a minimal Elasticsearch/OpenSearch REST client shared by the notifier and storer.
+type Client struct {
+	baseURL  string
+	apiKey   string
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewClientFromEnv configures a client from ELASTICSEARCH_URL and either ELASTICSEARCH_API_KEY or
+// ELASTICSEARCH_USERNAME/ELASTICSEARCH_PASSWORD, sending requests with client (nil uses a default client).
+func NewClientFromEnv(client *http.Client) (*Client, error) {
+	baseURL := os.Getenv("ELASTICSEARCH_URL")
+	if baseURL == "" {
+		return nil, errors.New("missing Elasticsearch configuration: ELASTICSEARCH_URL is required")
+	}
+
+	return &Client{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   os.Getenv("ELASTICSEARCH_API_KEY"),
+		username: os.Getenv("ELASTICSEARCH_USERNAME"),
+		password: os.Getenv("ELASTICSEARCH_PASSWORD"),
+		http:     httpclient.WithTimeout(client, 30*time.Second),
+	}, nil
+}
+
+// Do sends an authenticated request to the Elasticsearch API.
+func (c *Client) Do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	switch {
+	case c.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	return c.http.Do(req)
+}
+
+// EnsureIndex creates the index with the given mapping if it does not already exist. It reports whether
+// the index was created.
+func (c *Client) EnsureIndex(ctx context.Context, index, mapping string) (bool, error) {
+	resp, err := c.Do(ctx, "HEAD", "/"+url.PathEscape(index), nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return false, fmt.Errorf("unexpected status code checking index: %d", resp.StatusCode)
+	}
+
+	resp, err = c.Do(ctx, "PUT", "/"+url.PathEscape(index), []byte(mapping))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("failed to create index with status code %d: %s", resp.StatusCode, body)
+	}
+
+	return true, nil
+}
+
+// DocumentPath returns the REST path for a document in an index.
+func DocumentPath(index, id string) string {
+	return fmt.Sprintf("/%s/_doc/%s", url.PathEscape(index), url.PathEscape(id))
+}
+
+// DocumentID derives a stable document ID from a result's platform and URL.
+func DocumentID(platform, resultURL string) string {
+	sum := sha256.Sum256([]byte(platform + "\x00" + resultURL))
+	return hex.EncodeToString(sum[:])
+}
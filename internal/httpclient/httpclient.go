@@ -0,0 +1,148 @@
+// internal/httpclient/httpclient.go
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jaxxstorm/grass/internal/proxy"
+)
+
+// TLSVersions maps the accepted minimum TLS versions to their crypto/tls constants.
+var TLSVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Middleware wraps a transport, for example to add rate limiting or caching.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// Config configures the HTTP client shared by searchers, notifiers, and HTTP-based storage.
+type Config struct {
+	// Timeout bounds each request, including reading the body. Zero leaves it to each user of the client.
+	Timeout time.Duration
+	// DialTimeout bounds opening a connection.
+	DialTimeout time.Duration
+	// MaxIdleConnsPerHost is how many kept-alive connections are pooled per host, MaxConnsPerHost caps all
+	// connections per host (0 is unlimited), and IdleConnTimeout closes pooled connections left unused.
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	// UserAgent is sent with requests that don't set their own.
+	UserAgent string
+	// CAFile adds PEM certificates to the system roots, for proxies and servers with a private CA.
+	CAFile string
+	// MinTLSVersion is a key of TLSVersions. Empty uses the crypto/tls default.
+	MinTLSVersion      string
+	InsecureSkipVerify bool
+	// Proxy picks the proxy for each request. Nil sends requests directly.
+	Proxy proxy.Func
+	// Middleware wraps the transport in order, so the last middleware sees each request first.
+	Middleware []Middleware
+}
+
+// New creates a client from cfg.
+func New(cfg Config) (*http.Client, error) {
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: cfg.Timeout}, nil
+}
+
+// NewTransport creates the transport behind a client from cfg, with its middleware applied.
+func NewTransport(cfg Config) (http.RoundTripper, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.MinTLSVersion != "" {
+		version, ok := TLSVersions[cfg.MinTLSVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS version %q", cfg.MinTLSVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("CA file contains no PEM certificates")
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = cfg.Proxy
+	transport.TLSClientConfig = tlsConfig
+	if cfg.DialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+
+	var rt http.RoundTripper = transport
+	if cfg.UserAgent != "" {
+		rt = &userAgentTransport{base: rt, userAgent: cfg.UserAgent}
+	}
+	for _, middleware := range cfg.Middleware {
+		rt = middleware(rt)
+	}
+	return rt, nil
+}
+
+// WithTimeout returns a copy of client that gives up on requests after timeout, unless the client already
+// has a timeout. A nil client gets a default transport, so components can be used without a shared client.
+func WithTimeout(client *http.Client, timeout time.Duration) *http.Client {
+	if client == nil {
+		return &http.Client{Timeout: timeout}
+	}
+	derived := *client
+	if derived.Timeout == 0 {
+		derived.Timeout = timeout
+	}
+	return &derived
+}
+
+// WithUserAgent returns a copy of client that sends userAgent with requests that don't set their own.
+func WithUserAgent(client *http.Client, userAgent string) *http.Client {
+	derived := *client
+	base := derived.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	derived.Transport = &userAgentTransport{base: base, userAgent: userAgent}
+	return &derived
+}
+
+// userAgentTransport sets the User-Agent of requests that don't set their own.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") != "" {
+		return t.base.RoundTrip(req)
+	}
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,67 @@
+// internal/proxy/proxy.go
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// Direct is the proxy setting that sends requests straight to their destination, ignoring the proxy
+// environment variables.
+const Direct = "direct"
+
+// Func picks the proxy for a request, as http.Transport.Proxy does. A nil URL means no proxy.
+type Func func(*http.Request) (*url.URL, error)
+
+// Parse reads a proxy setting: a comma-separated list of http, https, socks5, or socks5h proxy URLs, used
+// in turn for each request so a pool of rotating proxies shares the load. An empty setting falls back to
+// the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables, and "direct" disables proxying.
+func Parse(setting string) (Func, error) {
+	setting = strings.TrimSpace(setting)
+	switch setting {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case Direct:
+		return nil, nil
+	}
+
+	var proxies []*url.URL
+	for _, raw := range strings.Split(setting, ",") {
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", raw, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme in %q: use http, https, socks5, or socks5h", u.Redacted())
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("proxy URL %q has no host", u.Redacted())
+		}
+		proxies = append(proxies, u)
+	}
+	if len(proxies) == 1 {
+		return http.ProxyURL(proxies[0]), nil
+	}
+
+	var next atomic.Uint64
+	return func(*http.Request) (*url.URL, error) {
+		return proxies[(next.Add(1)-1)%uint64(len(proxies))], nil
+	}, nil
+}
+
+// SetDefault makes every client that doesn't set its own transport, including third-party clients such as
+// Discord's, send requests through proxy. It must be called before any requests are made.
+func SetDefault(proxy Func) {
+	http.DefaultTransport.(*http.Transport).Proxy = proxy
+}
+
+// Default returns the proxy used by the default transport, for clients such as websocket dialers that
+// don't use it but should go through the same proxy.
+func Default() Func {
+	return http.DefaultTransport.(*http.Transport).Proxy
+}
@@ -0,0 +1,87 @@
+// internal/queue/nats.go
+package queue
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsDefaultGroup is the queue group consumers join when the URL doesn't name one.
+const natsDefaultGroup = "grass"
+
+// natsQueue receives messages published to a NATS subject, sharing them with the other consumers in its
+// queue group. Core NATS doesn't redeliver, so messages are lost if a consumer fails to handle them.
+type natsQueue struct {
+	conn *nats.Conn
+	sub  *nats.Subscription
+}
+
+func newNATS(u *url.URL) (*natsQueue, error) {
+	subject := strings.TrimPrefix(u.Path, "/")
+	if subject == "" {
+		return nil, fmt.Errorf("NATS queue URL %q names no subject: use nats://<host>/<subject>", u.Redacted())
+	}
+	group := u.Query().Get("group")
+	if group == "" {
+		group = natsDefaultGroup
+	}
+
+	server := url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host}
+	conn, err := nats.Connect(server.String(), nats.Name("grass"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to NATS: %w", err)
+	}
+	sub, err := conn.QueueSubscribeSync(subject, group)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("subscribing to %s: %w", subject, err)
+	}
+	return &natsQueue{conn: conn, sub: sub}, nil
+}
+
+func (q *natsQueue) Receive(ctx context.Context) (Message, error) {
+	msg, err := q.sub.NextMsgWithContext(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("receiving from NATS: %w", err)
+	}
+	return &natsMessage{msg: msg}, nil
+}
+
+// Close stops receiving, then closes the connection once replies to handled messages have been sent.
+func (q *natsQueue) Close() error {
+	return q.conn.Drain()
+}
+
+// natsMessage is a message received from NATS. Messages sent as requests are answered with the reply.
+type natsMessage struct {
+	msg *nats.Msg
+}
+
+func (m *natsMessage) Body() []byte {
+	return m.msg.Data
+}
+
+func (m *natsMessage) Ack(ctx context.Context, reply []byte) error {
+	return m.respond(reply)
+}
+
+func (m *natsMessage) Nack(ctx context.Context, reply []byte) error {
+	return m.respond(reply)
+}
+
+func (m *natsMessage) respond(reply []byte) error {
+	if m.msg.Reply == "" {
+		return nil
+	}
+	if err := m.msg.Respond(reply); err != nil {
+		return fmt.Errorf("replying to NATS message: %w", err)
+	}
+	return nil
+}
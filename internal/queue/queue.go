@@ -0,0 +1,74 @@
+// internal/queue/queue.go
+package queue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Message is a message taken from a queue.
+type Message interface {
+	Body() []byte
+	// Ack removes a handled message from the queue, sending reply to the sender when it is waiting for one.
+	Ack(ctx context.Context, reply []byte) error
+	// Nack gives up on a message, leaving it to be delivered again where the queue redelivers messages, and
+	// sends reply to the sender when it is waiting for one.
+	Nack(ctx context.Context, reply []byte) error
+}
+
+// Queue is a source of messages shared by a fleet of consumers, each message going to one of them.
+type Queue interface {
+	// Receive waits for the next message until ctx is cancelled.
+	Receive(ctx context.Context) (Message, error)
+	Close() error
+}
+
+// Option configures a queue opened with Open.
+type Option func(*options)
+
+type options struct {
+	httpClient *http.Client
+	visibility time.Duration
+}
+
+// WithHTTPClient sends requests to HTTP-based queues, such as SQS, with client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithVisibilityTimeout hides received SQS messages from other consumers for this long, after which
+// messages that weren't acked are delivered again. Zero leaves the queue's own setting.
+func WithVisibilityTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.visibility = timeout
+	}
+}
+
+// Open connects to the queue at rawURL: an SQS queue URL, such as
+// https://sqs.us-east-1.amazonaws.com/123456789012/grass-jobs, or a NATS subject, such as
+// nats://localhost:4222/grass.jobs, which is consumed as the queue group named by the group query
+// parameter (default grass).
+func Open(ctx context.Context, rawURL string, opts ...Option) (Queue, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid queue URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return newSQS(ctx, rawURL, o)
+	case "nats", "tls":
+		return newNATS(u)
+	default:
+		return nil, fmt.Errorf("unsupported queue URL %q: use an SQS queue URL or nats://<host>/<subject>", rawURL)
+	}
+}
@@ -0,0 +1,113 @@
+// internal/queue/sqs.go
+package queue
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// sqsWaitSeconds is how long each receive long-polls for messages.
+const sqsWaitSeconds = 20
+
+// sqsQueue receives messages from an SQS queue. Messages that aren't acked are delivered again once their
+// visibility timeout passes, and moved to the queue's dead-letter queue, if it has one, after its redrive
+// policy's maximum receives.
+type sqsQueue struct {
+	client     *sqs.Client
+	url        string
+	visibility int32
+}
+
+func newSQS(ctx context.Context, queueURL string, o options) (*sqsQueue, error) {
+	var loadOpts []func(*config.LoadOptions) error
+	if o.httpClient != nil {
+		loadOpts = append(loadOpts, config.WithHTTPClient(o.httpClient))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	// Queue URLs name their region, so it needn't be configured too
+	if cfg.Region == "" {
+		cfg.Region = sqsRegion(queueURL)
+	}
+
+	return &sqsQueue{
+		client:     sqs.NewFromConfig(cfg),
+		url:        queueURL,
+		visibility: int32(o.visibility.Seconds()),
+	}, nil
+}
+
+// sqsRegion returns the region of a queue URL such as https://sqs.us-east-1.amazonaws.com/..., or "" if it
+// doesn't name one.
+func sqsRegion(queueURL string) string {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(u.Hostname(), ".")
+	if len(parts) < 3 || parts[0] != "sqs" {
+		return ""
+	}
+	return parts[1]
+}
+
+func (q *sqsQueue) Receive(ctx context.Context) (Message, error) {
+	for {
+		out, err := q.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(q.url),
+			MaxNumberOfMessages: 1,
+			WaitTimeSeconds:     sqsWaitSeconds,
+			VisibilityTimeout:   q.visibility,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("receiving from SQS: %w", err)
+		}
+		if len(out.Messages) > 0 {
+			msg := out.Messages[0]
+			return &sqsMessage{queue: q, body: []byte(aws.ToString(msg.Body)), receipt: msg.ReceiptHandle}, nil
+		}
+	}
+}
+
+func (q *sqsQueue) Close() error {
+	return nil
+}
+
+// sqsMessage is a message received from SQS. SQS has no replies, so they are dropped.
+type sqsMessage struct {
+	queue   *sqsQueue
+	body    []byte
+	receipt *string
+}
+
+func (m *sqsMessage) Body() []byte {
+	return m.body
+}
+
+func (m *sqsMessage) Ack(ctx context.Context, reply []byte) error {
+	_, err := m.queue.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(m.queue.url),
+		ReceiptHandle: m.receipt,
+	})
+	if err != nil {
+		return fmt.Errorf("deleting SQS message: %w", err)
+	}
+	return nil
+}
+
+// Nack leaves the message hidden until its visibility timeout passes, so failing jobs are retried no more
+// often than that.
+func (m *sqsMessage) Nack(ctx context.Context, reply []byte) error {
+	return nil
+}
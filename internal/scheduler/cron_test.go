@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNext(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{name: "every minute", expr: "* * * * *", after: at(time.January, 1, 0, 0), want: at(time.January, 1, 0, 1)},
+		{name: "seconds are truncated", expr: "* * * * *", after: at(time.January, 1, 0, 0).Add(30 * time.Second), want: at(time.January, 1, 0, 1)},
+		{name: "minute step", expr: "*/15 * * * *", after: at(time.January, 1, 0, 0), want: at(time.January, 1, 0, 15)},
+		{name: "step from a value", expr: "5/15 * * * *", after: at(time.January, 1, 0, 6), want: at(time.January, 1, 0, 20)},
+		{name: "minute list", expr: "10,40 * * * *", after: at(time.January, 1, 0, 10), want: at(time.January, 1, 0, 40)},
+		{name: "weekday range", expr: "0 9 * * 1-5", after: at(time.January, 1, 0, 0), want: at(time.January, 1, 9, 0)},
+		{name: "weekday range skips the weekend", expr: "0 9 * * 1-5", after: at(time.January, 5, 10, 0), want: at(time.January, 8, 9, 0)},
+		{name: "Sunday as 0", expr: "0 0 * * 0", after: at(time.January, 1, 0, 0), want: at(time.January, 7, 0, 0)},
+		{name: "Sunday as 7", expr: "0 0 * * 7", after: at(time.January, 1, 0, 0), want: at(time.January, 7, 0, 0)},
+		{name: "weekend range ending at 7", expr: "0 0 * * 6-7", after: at(time.January, 1, 0, 0), want: at(time.January, 6, 0, 0)},
+		{name: "day of month list", expr: "0 0 1,15 * *", after: at(time.January, 1, 0, 0), want: at(time.January, 15, 0, 0)},
+		{name: "day of month range", expr: "0 0 10-12 * *", after: at(time.January, 11, 5, 0), want: at(time.January, 12, 0, 0)},
+		{name: "day of month range into next month", expr: "0 0 10-12 * *", after: at(time.January, 12, 0, 0), want: at(time.February, 10, 0, 0)},
+		{name: "day missing from short months", expr: "0 0 31 * *", after: at(time.January, 31, 0, 0), want: at(time.March, 31, 0, 0)},
+		{name: "leap day", expr: "0 0 29 2 *", after: at(time.March, 1, 0, 0), want: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "either restricted day field matches", expr: "0 0 1 * 1", after: at(time.January, 1, 0, 0), want: at(time.January, 8, 0, 0)},
+		{name: "day of month matches when day of week doesn't", expr: "0 0 3 * 1", after: at(time.January, 1, 0, 0), want: at(time.January, 3, 0, 0)},
+		{name: "question mark day of month", expr: "0 0 ? * 1", after: at(time.January, 1, 0, 0), want: at(time.January, 8, 0, 0)},
+		{name: "month range", expr: "0 0 1 6-8 *", after: at(time.January, 1, 0, 0), want: at(time.June, 1, 0, 0)},
+		{name: "weekly descriptor", expr: "@weekly", after: at(time.January, 1, 0, 0), want: at(time.January, 7, 0, 0)},
+		{name: "monthly descriptor", expr: "@monthly", after: at(time.January, 1, 0, 0), want: at(time.February, 1, 0, 0)},
+		{name: "every interval", expr: "@every 10m", after: at(time.January, 1, 0, 3), want: at(time.January, 1, 0, 13)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.expr, err)
+			}
+			if got := schedule.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{name: "too few fields", expr: "* * * *"},
+		{name: "too many fields", expr: "* * * * * *"},
+		{name: "minute out of range", expr: "60 * * * *"},
+		{name: "hour out of range", expr: "* 24 * * *"},
+		{name: "day of month zero", expr: "* * 0 * *"},
+		{name: "day of month out of range", expr: "* * 32 * *"},
+		{name: "month out of range", expr: "* * * 13 *"},
+		{name: "day of week out of range", expr: "* * * * 8"},
+		{name: "reversed day of month range", expr: "* * 5-1 * *"},
+		{name: "reversed day of week range", expr: "* * * * 5-1"},
+		{name: "day of week range past 7", expr: "* * * * 5-8"},
+		{name: "zero step", expr: "*/0 * * * *"},
+		{name: "non-numeric value", expr: "a * * * *"},
+		{name: "non-numeric range bound", expr: "* * * * 1-x"},
+		{name: "unknown descriptor", expr: "@fortnightly"},
+		{name: "interval below a second", expr: "@every 500ms"},
+		{name: "invalid interval", expr: "@every soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.expr); err == nil {
+				t.Errorf("Parse(%q) returned no error", tt.expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// internal/scheduler/scheduler.go
+package scheduler
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Job is a named unit of work run on a schedule.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Run      func(ctx context.Context)
+
+	next    time.Time
+	removed bool
+}
+
+// Scheduler runs jobs on their schedules. Jobs run one at a time, in the order they become due, so they
+// can share resources that are not safe for concurrent use. Jobs may add and remove other jobs while they
+// run; any other changes must be made before Run.
+type Scheduler struct {
+	jobs    []*Job
+	now     func() time.Time
+	running bool
+
+	// mu guards the job running now, which Current reads from other goroutines.
+	mu      sync.Mutex
+	current *Job
+	started time.Time
+}
+
+// New creates an empty scheduler.
+func New() *Scheduler {
+	return &Scheduler{now: time.Now}
+}
+
+// Add registers a job. A job added while the scheduler is running runs as soon as the current job
+// finishes, and then on its schedule.
+func (s *Scheduler) Add(name string, schedule Schedule, run func(ctx context.Context)) {
+	job := &Job{Name: name, Schedule: schedule, Run: run}
+	if s.running {
+		job.next = s.now()
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Remove unregisters the job with the given name, reporting whether there was one.
+func (s *Scheduler) Remove(name string) bool {
+	for i, job := range s.jobs {
+		if job.Name == name {
+			job.removed = true
+			s.jobs = slices.Delete(s.jobs, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes every job once immediately and then on its schedule until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.running = true
+	defer func() { s.running = false }()
+
+	// Jobs added by the jobs below are already due, so they are left to the loop after
+	for _, job := range slices.Clone(s.jobs) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !job.removed {
+			s.runJob(ctx, job)
+		}
+	}
+
+	for {
+		next := s.nextDue()
+		if next == nil {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		timer := time.NewTimer(time.Until(next.next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		s.runJob(ctx, next)
+	}
+}
+
+// runJob runs a job and schedules its next run relative to when it finished.
+func (s *Scheduler) runJob(ctx context.Context, job *Job) {
+	started := s.now()
+	s.mu.Lock()
+	s.current, s.started = job, started
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.current = nil
+		s.mu.Unlock()
+	}()
+
+	log.Debug("Running scheduled job", "job", job.Name)
+	job.Run(ctx)
+
+	job.next = job.Schedule.Next(s.now())
+	log.Debug("Scheduled job finished", "job", job.Name, "duration", s.now().Sub(started), "next_run", job.next)
+}
+
+// Current returns the name of the job running now and when it started, reporting false between jobs. It is
+// safe to call while the scheduler runs.
+func (s *Scheduler) Current() (string, time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return "", time.Time{}, false
+	}
+	return s.current.Name, s.started, true
+}
+
+// nextDue returns the job with the earliest next run, ignoring schedules that never fire again.
+func (s *Scheduler) nextDue() *Job {
+	var next *Job
+	for _, job := range s.jobs {
+		if job.next.IsZero() {
+			continue
+		}
+		if next == nil || job.next.Before(next.next) {
+			next = job
+		}
+	}
+	return next
+}
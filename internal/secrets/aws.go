@@ -0,0 +1,72 @@
+// internal/secrets/aws.go
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// awsClients are the clients of the AWS secret stores, sharing the default AWS configuration.
+type awsClients struct {
+	secretsManager *secretsmanager.Client
+	ssm            *ssm.Client
+}
+
+// awsClients returns the AWS clients, loading the default AWS configuration the first time. r.mu must be
+// held.
+func (r *Resolver) awsClients(ctx context.Context) (*awsClients, error) {
+	if r.aws != nil {
+		return r.aws, nil
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	r.aws = &awsClients{
+		secretsManager: secretsmanager.NewFromConfig(cfg),
+		ssm:            ssm.NewFromConfig(cfg),
+	}
+	return r.aws, nil
+}
+
+// secretsManager returns the current value of a Secrets Manager secret.
+func (r *Resolver) secretsManager(ctx context.Context, id string) (string, error) {
+	clients, err := r.awsClients(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := clients.secretsManager.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString == nil {
+		if out.SecretBinary != nil {
+			return string(out.SecretBinary), nil
+		}
+		return "", errors.New("secret has no value")
+	}
+	return *out.SecretString, nil
+}
+
+// parameter returns the decrypted value of an SSM parameter.
+func (r *Resolver) parameter(ctx context.Context, name string) (string, error) {
+	if strings.Contains(name, "/") && !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	clients, err := r.awsClients(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := clients.ssm.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(name), WithDecryption: aws.Bool(true)})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.Parameter.Value), nil
+}
@@ -0,0 +1,112 @@
+// internal/secrets/secrets.go
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schemes of secret references, each naming where the secret is kept.
+const (
+	SchemeSecretsManager = "aws-sm://"
+	SchemeParameterStore = "aws-ssm://"
+	SchemeVault          = "vault://"
+)
+
+// vaultTimeout bounds each request to Vault.
+const vaultTimeout = 30 * time.Second
+
+// IsReference reports whether value refers to a secret rather than holding one.
+func IsReference(value string) bool {
+	for _, scheme := range []string{SchemeSecretsManager, SchemeParameterStore, SchemeVault} {
+		if strings.HasPrefix(value, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolver looks up the secrets that references refer to. References have the form <scheme><name>#<key>:
+//
+//   - aws-sm://<secret> is an AWS Secrets Manager secret, by name or ARN.
+//   - aws-ssm://<parameter> is an SSM Parameter Store parameter, decrypted. Names containing a slash are
+//     hierarchical, so aws-ssm://grass/reddit is the parameter /grass/reddit.
+//   - vault://<path> is a secret read from HashiCorp Vault at VAULT_ADDR with VAULT_TOKEN, such as
+//     vault://secret/data/grass/reddit for a KV version 2 secret.
+//
+// The key, which is optional for secrets holding a single value, picks a field of a JSON secret or a Vault
+// secret's data. Each secret is fetched once, however many references use it. A Resolver is safe for
+// concurrent use.
+type Resolver struct {
+	mu      sync.Mutex
+	secrets map[string]string
+	aws     *awsClients
+	http    *http.Client
+}
+
+// NewResolver creates a resolver. Clients for each secret store are created when first needed.
+func NewResolver() *Resolver {
+	return &Resolver{
+		secrets: make(map[string]string),
+		http:    &http.Client{Timeout: vaultTimeout},
+	}
+}
+
+// Resolve returns the secret ref refers to.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	name, key, _ := strings.Cut(ref, "#")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	secret, ok := r.secrets[name]
+	if !ok {
+		var err error
+		switch {
+		case strings.HasPrefix(name, SchemeSecretsManager):
+			secret, err = r.secretsManager(ctx, strings.TrimPrefix(name, SchemeSecretsManager))
+		case strings.HasPrefix(name, SchemeParameterStore):
+			secret, err = r.parameter(ctx, strings.TrimPrefix(name, SchemeParameterStore))
+		case strings.HasPrefix(name, SchemeVault):
+			secret, err = r.vault(ctx, strings.TrimPrefix(name, SchemeVault))
+		default:
+			err = errors.New("unsupported secret reference")
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		r.secrets[name] = secret
+	}
+
+	// Vault secrets are always sets of fields, so one without a key must have a single field
+	if key == "" && strings.HasPrefix(name, SchemeVault) {
+		fields := map[string]any{}
+		if err := json.Unmarshal([]byte(secret), &fields); err != nil || len(fields) != 1 {
+			return "", fmt.Errorf("%s: secret has %d keys; choose one with #<key>", name, len(fields))
+		}
+		for field := range fields {
+			key = field
+		}
+	}
+	if key == "" {
+		return secret, nil
+	}
+	fields := map[string]any{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("%s: secret isn't a JSON object, so it has no key %q", name, key)
+	}
+	value, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("%s: secret has no key %q", name, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
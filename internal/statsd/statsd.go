@@ -0,0 +1,212 @@
+// internal/statsd/statsd.go
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxUDPPacket keeps packets under the usual 1500 byte MTU once IP and UDP headers are added, so they
+	// aren't fragmented and dropped.
+	maxUDPPacket = 1432
+	// maxUnixPacket is the packet size DogStatsD agents read from their Unix socket.
+	maxUnixPacket = 8192
+	// maxBuffered is how many timings are buffered before they are flushed early.
+	maxBuffered = 1000
+)
+
+// tagEscaper replaces the characters that separate metrics, fields, and tags in the DogStatsD format.
+var tagEscaper = strings.NewReplacer(",", "_", "|", "_", "#", "_", "@", "_", "\n", " ", "\r", " ")
+
+// Config configures a Client.
+type Config struct {
+	// Addr is the agent's address, as host:port for UDP or unix:///path/to/dsd.socket for a Unix socket.
+	Addr string
+	// Prefix is prepended to every metric name, e.g. "grass.".
+	Prefix string
+	// Tags are added to every metric, as <name>:<value>.
+	Tags []string
+	// SampleRate is the fraction of timings sent, between 0 and 1. Counts are aggregated before they are
+	// sent, so they are never sampled. Zero or less sends every timing.
+	SampleRate float64
+	// FlushInterval is how often buffered metrics are sent. Zero or less only sends them when Flush is called.
+	FlushInterval time.Duration
+}
+
+// Client sends metrics to a StatsD agent, with tags in the DogStatsD format. Counts are summed in memory
+// and timings buffered until the next flush, so a busy run sends a few packets rather than one per metric.
+// It is safe for concurrent use.
+type Client struct {
+	conn       net.Conn
+	maxPacket  int
+	prefix     string
+	tags       []string
+	sampleRate float64
+
+	mu      sync.Mutex
+	counts  map[string]int64
+	timings []string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New connects to the agent at cfg.Addr and starts flushing every cfg.FlushInterval. Sending over UDP
+// never blocks on the agent, so a missing agent only loses metrics.
+func New(cfg Config) (*Client, error) {
+	network, addr, maxPacket := "udp", cfg.Addr, maxUDPPacket
+	if path, ok := strings.CutPrefix(cfg.Addr, "unix://"); ok {
+		network, addr, maxPacket = "unixgram", path, maxUnixPacket
+	}
+	if addr == "" {
+		return nil, errors.New("no StatsD address")
+	}
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to StatsD at %s: %w", cfg.Addr, err)
+	}
+
+	c := &Client{
+		conn:       conn,
+		maxPacket:  maxPacket,
+		prefix:     cfg.Prefix,
+		sampleRate: cfg.SampleRate,
+		counts:     make(map[string]int64),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	for _, tag := range cfg.Tags {
+		c.tags = append(c.tags, tagEscaper.Replace(tag))
+	}
+	if c.sampleRate <= 0 || c.sampleRate > 1 {
+		c.sampleRate = 1
+	}
+
+	go c.loop(cfg.FlushInterval)
+	return c, nil
+}
+
+// Tag returns a DogStatsD tag, replacing the characters of value the format reserves.
+func Tag(name, value string) string {
+	return name + ":" + tagEscaper.Replace(value)
+}
+
+// Count adds value to the counter name with tags.
+func (c *Client) Count(name string, value int64, tags ...string) {
+	key := c.metric(name, tags, "c", "")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[key] += value
+}
+
+// Timing records a duration of the timer name with tags, subject to the sample rate.
+func (c *Client) Timing(name string, d time.Duration, tags ...string) {
+	if c.sampleRate < 1 && rand.Float64() >= c.sampleRate {
+		return
+	}
+	line := c.metric(name, tags, "ms", strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64))
+
+	c.mu.Lock()
+	c.timings = append(c.timings, line)
+	full := len(c.timings) >= maxBuffered
+	c.mu.Unlock()
+	if full {
+		c.Flush()
+	}
+}
+
+// metric formats a metric line. Counts are formatted without their value, which is only known at flush.
+func (c *Client) metric(name string, tags []string, kind, value string) string {
+	var b strings.Builder
+	b.WriteString(c.prefix)
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(kind)
+	if kind == "ms" && c.sampleRate < 1 {
+		b.WriteString("|@")
+		b.WriteString(strconv.FormatFloat(c.sampleRate, 'f', -1, 64))
+	}
+	if all := append(append([]string(nil), c.tags...), tags...); len(all) > 0 {
+		b.WriteString("|#")
+		b.WriteString(strings.Join(all, ","))
+	}
+	return b.String()
+}
+
+// Flush sends every buffered metric, packing as many lines into each packet as fit.
+func (c *Client) Flush() error {
+	c.mu.Lock()
+	lines := c.timings
+	c.timings = nil
+	keys := make([]string, 0, len(c.counts))
+	for key := range c.counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, rest, _ := strings.Cut(key, ":")
+		lines = append(lines, name+":"+strconv.FormatInt(c.counts[key], 10)+rest)
+	}
+	clear(c.counts)
+	c.mu.Unlock()
+
+	var errs []error
+	var packet []byte
+	send := func() {
+		if len(packet) == 0 {
+			return
+		}
+		if _, err := c.conn.Write(packet); err != nil {
+			errs = append(errs, err)
+		}
+		packet = packet[:0]
+	}
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > c.maxPacket {
+			send()
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	send()
+	return errors.Join(errs...)
+}
+
+// loop flushes every interval until the client is closed.
+func (c *Client) loop(interval time.Duration) {
+	defer close(c.done)
+	if interval <= 0 {
+		<-c.stop
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.Flush()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Close sends every buffered metric and closes the connection.
+func (c *Client) Close() error {
+	close(c.stop)
+	<-c.done
+	err := c.Flush()
+	return errors.Join(err, c.conn.Close())
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+// internal/systemd/systemd_other.go
+package systemd
+
+import (
+	"net"
+	"time"
+)
+
+// Notify does nothing outside Linux, where there is no systemd.
+func Notify(state string) (bool, error) {
+	return false, nil
+}
+
+// WatchdogInterval reports that there is no watchdog outside Linux.
+func WatchdogInterval() (time.Duration, bool) {
+	return 0, false
+}
+
+// Listeners returns no sockets outside Linux.
+func Listeners() (map[string]net.Listener, error) {
+	return nil, nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	keywordsCommand  = kingpin.Command("keywords", "Manage keywords kept in storage, which running instances pick up without a restart")
+	keywordsProfile  = keywordsCommand.Flag("profile", "Manage the keywords of this profile from the config file instead of those in --db and --table-name").String()
+	keywordsList     = keywordsCommand.Command("list", "List managed keywords, and those given with --keyword or in the config file").Default()
+	keywordsAdd      = keywordsCommand.Command("add", "Add keywords to search for")
+	keywordsAdded    = keywordsAdd.Arg("keyword", "Keywords to add").Required().Strings()
+	keywordsRemove   = keywordsCommand.Command("remove", "Remove managed keywords")
+	keywordsRemoved  = keywordsRemove.Arg("keyword", "Keywords to remove").Required().Strings()
+	keywordsPause    = keywordsCommand.Command("pause", "Stop searching for keywords until they are resumed, including keywords from --keyword or the config file")
+	keywordsPaused   = keywordsPause.Arg("keyword", "Keywords to pause").Required().Strings()
+	keywordsPauseFor = keywordsPause.Flag("for", "Resume the keywords automatically after this long, e.g. 24h").Duration()
+	keywordsResume   = keywordsCommand.Command("resume", "Search for paused keywords again")
+	keywordsResumed  = keywordsResume.Arg("keyword", "Keywords to resume").Required().Strings()
+)
+
+// runKeywords runs the keywords subcommand named by command against the profile's primary storage,
+// writing what it did to w.
+func runKeywords(ctx context.Context, cfg *config.Config, command string, w io.Writer) error {
+	storer, db, err := openStorage(ctx, cfg, *keywordsProfile)
+	if err != nil {
+		return err
+	}
+	if closer, ok := storer.(io.Closer); ok {
+		defer closer.Close()
+	}
+	store, ok := storage.AsKeywordStore(storer)
+	if !ok {
+		return fmt.Errorf("%s storage can't keep keywords; use sqlite, dynamodb, redis, bolt, or ndjson", db)
+	}
+	set, err := loadKeywordSet(ctx, store, configuredKeywords(cfg, *keywordsProfile))
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case keywordsAdd.FullCommand():
+		for _, keyword := range *keywordsAdded {
+			added, err := set.add(ctx, keyword)
+			if err != nil {
+				return err
+			}
+			if !added {
+				fmt.Fprintf(w, "%s is already managed\n", keyword)
+				continue
+			}
+			fmt.Fprintf(w, "Added %s\n", keyword)
+		}
+	case keywordsRemove.FullCommand():
+		for _, keyword := range *keywordsRemoved {
+			if err := set.remove(ctx, keyword); err != nil {
+				return err
+			}
+			if slices.Contains(set.configured, keyword) {
+				fmt.Fprintf(w, "Removed %s from storage; it is still given with --keyword or in the config file\n", keyword)
+				continue
+			}
+			fmt.Fprintf(w, "Removed %s\n", keyword)
+		}
+	case keywordsPause.FullCommand(), keywordsResume.FullCommand():
+		pause := command == keywordsPause.FullCommand()
+		names := *keywordsResumed
+		var until time.Time
+		if pause {
+			names = *keywordsPaused
+			if *keywordsPauseFor > 0 {
+				until = time.Now().Add(*keywordsPauseFor)
+			}
+		}
+		for _, keyword := range names {
+			if err := set.setPaused(ctx, keyword, pause, until); err != nil {
+				return err
+			}
+			switch {
+			case pause && !until.IsZero():
+				fmt.Fprintf(w, "Paused %s until %s\n", keyword, until.Format(time.DateTime))
+			case pause:
+				fmt.Fprintf(w, "Paused %s\n", keyword)
+			default:
+				fmt.Fprintf(w, "Resumed %s\n", keyword)
+			}
+		}
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEYWORD\tSOURCE\tSTATUS\tADDED")
+		for _, entry := range set.list() {
+			status, added := "active", ""
+			if entry.Paused {
+				status = "paused"
+			}
+			if entry.PausedUntil != 0 {
+				status += " until " + time.Unix(entry.PausedUntil, 0).Format(time.DateTime)
+			}
+			if entry.Source == "managed" {
+				added = time.Unix(entry.AddedAt, 0).Format(time.DateOnly)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", entry.Keyword, entry.Source, status, added)
+		}
+		return tw.Flush()
+	}
+	return nil
+}
+
+// keywordError is a keyword change refused because of the keyword's state rather than a storage failure.
+// notFound is set when the keyword isn't known at all, and invalid when the change itself is malformed.
+type keywordError struct {
+	message  string
+	notFound bool
+	invalid  bool
+}
+
+func (e *keywordError) Error() string {
+	return e.message
+}
+
+// keywordSet is a profile's configured keywords and the managed keywords in its storage, shared by the
+// keywords command and the API.
+type keywordSet struct {
+	store      storage.KeywordStore
+	managed    []storage.ManagedKeyword
+	configured []string
+}
+
+// keywordEntry describes one of a profile's keywords. Source is "config" for keywords given with --keyword
+// or in the config file and "managed" for those added with the keywords command or the API. PausedUntil is
+// set when a paused keyword resumes by itself.
+type keywordEntry struct {
+	Keyword     string `json:"keyword"`
+	Source      string `json:"source"`
+	Paused      bool   `json:"paused"`
+	PausedUntil int64  `json:"paused_until,omitempty"`
+	AddedAt     int64  `json:"added_at,omitempty"`
+}
+
+// loadKeywordSet reads the managed keywords from store.
+func loadKeywordSet(ctx context.Context, store storage.KeywordStore, configured []string) (*keywordSet, error) {
+	managed, err := store.ManagedKeywords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &keywordSet{store: store, managed: managed, configured: configured}, nil
+}
+
+// find returns the managed keyword, reporting false if there is none.
+func (s *keywordSet) find(keyword string) (storage.ManagedKeyword, bool) {
+	i := slices.IndexFunc(s.managed, func(m storage.ManagedKeyword) bool { return m.Keyword == keyword })
+	if i < 0 {
+		return storage.ManagedKeyword{}, false
+	}
+	return s.managed[i], true
+}
+
+// put stores a managed keyword and updates the set to match.
+func (s *keywordSet) put(ctx context.Context, entry storage.ManagedKeyword) error {
+	if err := s.store.PutKeyword(ctx, entry); err != nil {
+		return err
+	}
+	s.managed = slices.DeleteFunc(s.managed, func(m storage.ManagedKeyword) bool { return m.Keyword == entry.Keyword })
+	s.managed = append(s.managed, entry)
+	return nil
+}
+
+// add stores a new managed keyword, reporting false if it is already managed.
+func (s *keywordSet) add(ctx context.Context, keyword string) (bool, error) {
+	if _, err := search.ParseQuery(keyword); err != nil {
+		return false, fmt.Errorf("invalid keyword: %w", err)
+	}
+	if _, ok := s.find(keyword); ok {
+		return false, nil
+	}
+	return true, s.put(ctx, storage.ManagedKeyword{Keyword: keyword, AddedAt: time.Now().Unix()})
+}
+
+// remove deletes a managed keyword. Configured keywords can only be paused.
+func (s *keywordSet) remove(ctx context.Context, keyword string) error {
+	if _, ok := s.find(keyword); !ok {
+		if slices.Contains(s.configured, keyword) {
+			return &keywordError{message: fmt.Sprintf("%s is given with --keyword or in the config file; pause it instead", keyword)}
+		}
+		return &keywordError{message: fmt.Sprintf("%s isn't a managed keyword", keyword), notFound: true}
+	}
+	if err := s.store.DeleteKeyword(ctx, keyword); err != nil {
+		return err
+	}
+	s.managed = slices.DeleteFunc(s.managed, func(m storage.ManagedKeyword) bool { return m.Keyword == keyword })
+	return nil
+}
+
+// setPaused pauses or resumes a managed or configured keyword. A keyword paused with a non-zero until is
+// searched again from then.
+func (s *keywordSet) setPaused(ctx context.Context, keyword string, paused bool, until time.Time) error {
+	entry, ok := s.find(keyword)
+	if !ok && !slices.Contains(s.configured, keyword) {
+		return &keywordError{message: fmt.Sprintf("%s isn't a managed or configured keyword", keyword), notFound: true}
+	}
+	if !ok {
+		entry = storage.ManagedKeyword{Keyword: keyword, AddedAt: time.Now().Unix()}
+	}
+	entry.Paused, entry.PausedUntil = paused, 0
+	if paused && !until.IsZero() {
+		entry.PausedUntil = until.Unix()
+	}
+	return s.put(ctx, entry)
+}
+
+// list returns the configured keywords, then the managed keywords that aren't configured.
+func (s *keywordSet) list() []keywordEntry {
+	now := time.Now()
+	entries := make([]keywordEntry, 0, len(s.configured)+len(s.managed))
+	for _, keyword := range s.configured {
+		entry, _ := s.find(keyword)
+		entry.Keyword = keyword
+		entries = append(entries, newKeywordEntry(entry, "config", now))
+	}
+	managed := slices.Clone(s.managed)
+	slices.SortFunc(managed, func(a, b storage.ManagedKeyword) int { return strings.Compare(a.Keyword, b.Keyword) })
+	for _, entry := range managed {
+		if slices.Contains(s.configured, entry.Keyword) {
+			continue
+		}
+		managedEntry := newKeywordEntry(entry, "managed", now)
+		managedEntry.AddedAt = entry.AddedAt
+		entries = append(entries, managedEntry)
+	}
+	return entries
+}
+
+// newKeywordEntry describes a keyword from source as of now, so keywords whose pause has run out are listed
+// as active.
+func newKeywordEntry(keyword storage.ManagedKeyword, source string, now time.Time) keywordEntry {
+	entry := keywordEntry{Keyword: keyword.Keyword, Source: source, Paused: keyword.PausedAt(now)}
+	if entry.Paused {
+		entry.PausedUntil = keyword.PausedUntil
+	}
+	return entry
+}
+
+// configuredKeywords returns the keywords a profile is given in the config file, or with --keyword and
+// --account when it has none or no profile is named.
+func configuredKeywords(cfg *config.Config, profile string) []string {
+	p := cfg.Profiles[profile]
+	given, watched := p.Keywords, p.Accounts
+	if len(given) == 0 {
+		given = *keywords
+	}
+	if len(watched) == 0 {
+		watched = *accounts
+	}
+	configured := slices.Clone(given)
+	for _, account := range watched {
+		configured = append(configured, search.AccountPrefix+account)
+	}
+	return configured
+}
+
+// loadKeywordsFile adds the keywords in path to those given with --keyword, and their options to the
+// top-level filters and schedule unless the config file already sets them for that keyword.
+func loadKeywordsFile(cfg *config.Config, path string) error {
+	if path == "" {
+		return nil
+	}
+	entries, err := config.LoadKeywords(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !slices.Contains(*keywords, entry.Keyword) {
+			*keywords = append(*keywords, entry.Keyword)
+		}
+		if _, ok := cfg.Filters[entry.Keyword]; !ok && !entry.Filter.IsZero() {
+			if cfg.Filters == nil {
+				cfg.Filters = make(map[string]config.Filter)
+			}
+			cfg.Filters[entry.Keyword] = entry.Filter
+		}
+		if _, ok := cfg.Schedule.Keywords[entry.Keyword]; !ok && entry.Schedule != "" {
+			if cfg.Schedule.Keywords == nil {
+				cfg.Schedule.Keywords = make(map[string]string)
+			}
+			cfg.Schedule.Keywords[entry.Keyword] = entry.Schedule
+		}
+	}
+	return nil
+}
+
+// isKeywordsCommand reports whether command is one of the keywords subcommands.
+func isKeywordsCommand(command string) bool {
+	return strings.HasPrefix(command, keywordsCommand.FullCommand()+" ")
+}
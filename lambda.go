@@ -0,0 +1,124 @@
+//go:build lambda
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/charmbracelet/log"
+)
+
+// lambdaDeadlineMargin is how long before an invocation's deadline grass stops searching, leaving time to
+// report what it found before Lambda ends the invocation.
+const lambdaDeadlineMargin = 10 * time.Second
+
+// inLambda reports whether grass is running as an AWS Lambda function.
+func inLambda() bool {
+	return os.Getenv("AWS_LAMBDA_RUNTIME_API") != ""
+}
+
+// loadLambdaEnv prepares a Lambda function's environment before flags are parsed. Results are stored in
+// DynamoDB unless GRASS_DB is set. When GRASS_SSM_PATH is set, every SSM parameter under that path is set
+// as an environment variable named after the rest of its path, upper-cased, unless the variable is already
+// set. A parameter named config holds the YAML configuration file.
+func loadLambdaEnv() error {
+	if !inLambda() {
+		return nil
+	}
+	if _, ok := os.LookupEnv("GRASS_DB"); !ok {
+		os.Setenv("GRASS_DB", "dynamodb")
+	}
+	path := os.Getenv("GRASS_SSM_PATH")
+	if path == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	pages := ssm.NewGetParametersByPathPaginator(ssm.NewFromConfig(awsCfg), &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+	names := strings.NewReplacer("/", "_", "-", "_", ".", "_")
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("reading SSM parameters under %s: %w", path, err)
+		}
+		for _, param := range page.Parameters {
+			name := strings.TrimPrefix(strings.TrimPrefix(aws.ToString(param.Name), strings.TrimSuffix(path, "/")), "/")
+			if name == "config" {
+				if err := setLambdaConfig(aws.ToString(param.Value)); err != nil {
+					return err
+				}
+				continue
+			}
+			key := strings.ToUpper(names.Replace(name))
+			if _, ok := os.LookupEnv(key); !ok {
+				os.Setenv(key, aws.ToString(param.Value))
+			}
+		}
+	}
+	return nil
+}
+
+// setLambdaConfig writes a configuration file read from SSM to the function's temporary storage and points
+// GRASS_CONFIG at it, unless GRASS_CONFIG is already set.
+func setLambdaConfig(text string) error {
+	if _, ok := os.LookupEnv("GRASS_CONFIG"); ok {
+		return nil
+	}
+	path := filepath.Join(os.TempDir(), "grass.yaml")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		return fmt.Errorf("writing configuration from SSM: %w", err)
+	}
+	return os.Setenv("GRASS_CONFIG", path)
+}
+
+// serveLambda handles Lambda invocations until the function is shut down, running every profile once per
+// invocation and returning the run's counts and new results as the --output=json report. The event is
+// ignored, so any trigger, such as an EventBridge schedule, works. An invocation fails when every search
+// failed or its deadline was reached.
+func serveLambda(profiles []*profile) {
+	log.Info("Serving Lambda invocations", "profiles", len(profiles))
+	lambda.Start(func(ctx context.Context) (runReport, error) {
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline.Add(-lambdaDeadlineMargin))
+			defer cancel()
+		}
+
+		report := runProfiles(ctx, profiles, "lambda")
+		// The function may be frozen or shut down between invocations, so nothing is held back for the next
+		for _, p := range profiles {
+			p.bot.FlushDigests(ctx)
+		}
+		flushStatsd()
+		flushEvents()
+		logReport(report)
+
+		out := newRunReport(report, "", "lambda")
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return out, fmt.Errorf("stopped searching at the invocation deadline")
+		case report.AllFailed():
+			return out, fmt.Errorf("every search failed")
+		}
+		return out, nil
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !lambda
+
+package main
+
+// Builds without the lambda tag never run as an AWS Lambda function.
+
+func inLambda() bool {
+	return false
+}
+
+func loadLambdaEnv() error {
+	return nil
+}
+
+func serveLambda(profiles []*profile) {}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/internal/scheduler"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	leaderElection = kingpin.Flag("leader-election", "In daemon mode, only search and notify while holding a lease in storage, so several replicas can run with one active at a time").Envar("GRASS_LEADER_ELECTION").Bool()
+	leaderLease    = kingpin.Flag("leader-lease", "How long a replica's lease lasts without being renewed before another replica takes over").Envar("GRASS_LEADER_LEASE").Default("30s").Duration()
+	replicaID      = kingpin.Flag("replica-id", "Name this replica holds the lease under (default: hostname and process ID)").Envar("GRASS_REPLICA_ID").String()
+)
+
+// leaderLeaseName names the lease held by the replica running the daemon's jobs.
+const leaderLeaseName = "daemon"
+
+// leader runs the daemon's jobs only while holding the leader lease, renewing it every third of
+// --leader-lease and starting over as a standby if it is lost.
+type leader struct {
+	locker storage.Locker
+	holder string
+	lease  time.Duration
+
+	// mu guards the scheduler running while this replica leads, which Current reads from other goroutines.
+	mu    sync.Mutex
+	sched *scheduler.Scheduler
+}
+
+// newLeader elects the daemon's leader through the storage of the first profile, which must be able to
+// hold leases.
+func newLeader(profiles []*profile) (*leader, error) {
+	if *leaderLease <= 0 {
+		return nil, fmt.Errorf("lease must be positive")
+	}
+	locker, ok := storage.AsLocker(profiles[0].storer)
+	if !ok {
+		return nil, fmt.Errorf("storage can't hold leases; use SQLite, DynamoDB, or Redis")
+	}
+	holder := *replicaID
+	if holder == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to name replica: %w", err)
+		}
+		holder = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	return &leader{locker: locker, holder: holder, lease: *leaderLease}, nil
+}
+
+// run waits to take the lease and then runs lead until ctx is cancelled or the lease is lost, over and over
+// until ctx is cancelled. The lease is released on the way out so a standby takes over at once.
+func (l *leader) run(ctx context.Context, lead func(ctx context.Context)) {
+	log.Info("Waiting to lead", "replica", l.holder)
+	renew := time.NewTicker(l.lease / 3)
+	defer renew.Stop()
+	for ctx.Err() == nil {
+		if l.acquire(ctx) {
+			log.Info("Leading", "replica", l.holder)
+			l.lead(ctx, renew.C, lead)
+			if ctx.Err() == nil {
+				log.Warn("Lost the lease; standing by", "replica", l.holder)
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-renew.C:
+		}
+	}
+
+	releaseCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := l.locker.ReleaseLease(releaseCtx, leaderLeaseName, l.holder); err != nil {
+		log.Warn("Failed to release the lease", "replica", l.holder, "error", err)
+	}
+}
+
+// lead runs lead while renewing the lease on each tick. If the lease is taken by another replica, or can't
+// be renewed before it would expire, lead's context is cancelled and lead returns once its job finishes.
+func (l *leader) lead(ctx context.Context, tick <-chan time.Time, lead func(ctx context.Context)) {
+	leadCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-tick:
+		}
+		acquired, err := l.locker.AcquireLease(ctx, leaderLeaseName, l.holder, l.lease)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil && time.Since(renewed) < l.lease-l.lease/3:
+			log.Warn("Failed to renew the lease; retrying", "replica", l.holder, "error", err)
+		case err != nil:
+			log.Error("Failed to renew the lease before it expires", "replica", l.holder, "error", err)
+			return
+		case !acquired:
+			return
+		default:
+			renewed = time.Now()
+		}
+	}
+}
+
+// acquire tries once to take the lease, reporting whether this replica now holds it.
+func (l *leader) acquire(ctx context.Context) bool {
+	acquired, err := l.locker.AcquireLease(ctx, leaderLeaseName, l.holder, l.lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warn("Failed to take the lease", "replica", l.holder, "error", err)
+		}
+		return false
+	}
+	return acquired
+}
+
+// setScheduler records the scheduler running while this replica leads, or nil when it stops.
+func (l *leader) setScheduler(sched *scheduler.Scheduler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sched = sched
+}
+
+// Current returns the job the leading replica's scheduler is running, reporting false between jobs and
+// while standing by.
+func (l *leader) Current() (string, time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sched == nil {
+		return "", time.Time{}, false
+	}
+	return l.sched.Current()
+}
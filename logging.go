@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/internal/logfile"
+)
+
+// logFormats maps the accepted --log-format values to their formatters.
+var logFormats = map[string]log.Formatter{
+	"text":   log.TextFormatter,
+	"json":   log.JSONFormatter,
+	"logfmt": log.LogfmtFormatter,
+}
+
+// setupLogging configures the global logger from the --log-* flags, falling back to cfg and then to text
+// logs at defaultLevel on stderr. Messages from libraries using the standard log package are sent through
+// it too. It returns the log file, if logs are written to one, for the caller to close.
+func setupLogging(cfg config.Log, defaultLevel string) (*logfile.File, error) {
+	level, err := log.ParseLevel(firstNonEmpty(*logLevel, cfg.Level, defaultLevel))
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
+	}
+	format := firstNonEmpty(*logFormat, cfg.Format, "text")
+	formatter, ok := logFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("invalid log format %q: use text, json, or logfmt", format)
+	}
+
+	var output io.Writer = os.Stderr
+	var file *logfile.File
+	if path := firstNonEmpty(*logFile, cfg.File); path != "" {
+		maxSize := firstPositive(*logMaxSize, cfg.MaxSizeMB, 100)
+		backups := firstPositive(*logMaxBackups, cfg.MaxBackups, 5)
+		file, err = logfile.Open(path, int64(maxSize)<<20, backups)
+		if err != nil {
+			return nil, err
+		}
+		output = file
+	}
+
+	log.SetOutput(output)
+	log.SetFormatter(formatter)
+	log.SetLevel(level)
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(log.StandardLog().Writer())
+	return file, nil
+}
+
+// firstNonEmpty returns the first of values that isn't empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// firstPositive returns the first of values that is greater than zero.
+func firstPositive(values ...int) int {
+	for _, value := range values {
+		if value > 0 {
+			return value
+		}
+	}
+	return 0
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/joho/godotenv"
+	"golang.org/x/oauth2"
+
+	"github.com/jaxxstorm/grass/internal/httpclient"
+	"github.com/jaxxstorm/grass/search"
+)
+
+var (
+	loginCommand   = kingpin.Command("login", "Authorize grass with a platform through OAuth and store the tokens it's issued")
+	loginPlatform  = loginCommand.Arg("platform", "Platform to log in to: reddit, youtube, or fediverse").Required().Enum("reddit", "youtube", "fediverse")
+	loginInstance  = loginCommand.Arg("instance", "Instance to log in to with fediverse, e.g. https://mastodon.social").String()
+	loginPort      = loginCommand.Flag("port", "Local port the browser is redirected to once authorized; Reddit apps must list http://127.0.0.1:<port>/callback as their redirect URI").Default("8976").Int()
+	loginNoBrowser = loginCommand.Flag("no-browser", "Only print the authorization URL rather than opening it in a browser").Bool()
+	loginFile      = loginCommand.Flag("file", "File to store tokens in (default: grass/credentials.env in the user's config directory)").Envar("GRASS_CREDENTIALS_FILE").String()
+)
+
+// loginTimeout bounds waiting for the user to authorize grass.
+const loginTimeout = 10 * time.Minute
+
+// loginWebsite is the website shown for the app grass registers with Mastodon instances.
+const loginWebsite = "https://github.com/jaxxstorm/grass"
+
+// credentialsPath returns the file grass login stores tokens in: $GRASS_CREDENTIALS_FILE, or
+// grass/credentials.env in the user's config directory.
+func credentialsPath() (string, error) {
+	if path := os.Getenv("GRASS_CREDENTIALS_FILE"); path != "" {
+		return path, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "grass", "credentials.env"), nil
+}
+
+// loadCredentials sets the tokens stored by grass login as environment variables. Variables that are
+// already set, including those from .env, take precedence.
+func loadCredentials() {
+	path, err := credentialsPath()
+	if err != nil {
+		return
+	}
+	if err := godotenv.Load(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn("Failed to read stored credentials", "file", path, "error", err)
+	}
+}
+
+// storeCredentials adds values to the credentials file, replacing those already stored under the same
+// names. The file holds tokens, so only its owner can read it.
+func storeCredentials(path string, values map[string]string) error {
+	stored, err := godotenv.Read(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		stored = make(map[string]string)
+	}
+	for name, value := range values {
+		stored[name] = value
+	}
+	text, err := godotenv.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(text+"\n"), 0o600); err != nil {
+		return err
+	}
+	// WriteFile keeps the permissions of a file that already exists
+	return os.Chmod(path, 0o600)
+}
+
+// runLogin authorizes grass with the platform chosen on the command line and stores the tokens it's issued
+// where grass reads them on start.
+func runLogin(ctx context.Context, out io.Writer) error {
+	path := *loginFile
+	if path == "" {
+		var err error
+		if path, err = credentialsPath(); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, loginTimeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, sharedHTTPClient)
+
+	var values map[string]string
+	var err error
+	switch *loginPlatform {
+	case "reddit":
+		values, err = loginReddit(ctx, out)
+	case "youtube":
+		values, err = loginYouTube(ctx, out)
+	case "fediverse":
+		values, err = loginFediverse(ctx, out)
+	}
+	if err != nil {
+		return err
+	}
+	if err := storeCredentials(path, values); err != nil {
+		return fmt.Errorf("storing tokens: %w", err)
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	fmt.Fprintf(out, "Logged in. Stored %s in %s, which grass reads on start.\n", strings.Join(names, ", "), path)
+	return nil
+}
+
+// loginReddit authorizes an installed or web Reddit app with the authorization code flow, returning a
+// refresh token that doesn't expire.
+func loginReddit(ctx context.Context, out io.Writer) (map[string]string, error) {
+	clientID := os.Getenv("REDDIT_CLIENT_ID")
+	if clientID == "" {
+		return nil, errors.New("REDDIT_CLIENT_ID isn't set; create an installed app at https://www.reddit.com/prefs/apps first")
+	}
+	config := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: os.Getenv("REDDIT_CLIENT_SECRET"),
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   search.RedditAuthURL,
+			TokenURL:  search.RedditTokenURL,
+			AuthStyle: oauth2.AuthStyleInHeader,
+		},
+		Scopes: []string{"read", "history"},
+	}
+
+	// Reddit rejects token requests without a descriptive user agent
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpclient.WithUserAgent(sharedHTTPClient, search.RedditUserAgent))
+
+	token, err := authorize(ctx, out, config, oauth2.SetAuthURLParam("duration", "permanent"))
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		return nil, errors.New("Reddit didn't issue a refresh token")
+	}
+	return map[string]string{"REDDIT_REFRESH_TOKEN": token.RefreshToken}, nil
+}
+
+// loginYouTube authorizes a Google "TVs and Limited Input devices" client with the device flow, returning
+// a refresh token for reading YouTube.
+func loginYouTube(ctx context.Context, out io.Writer) (map[string]string, error) {
+	clientID := os.Getenv("YOUTUBE_CLIENT_ID")
+	clientSecret := os.Getenv("YOUTUBE_CLIENT_SECRET")
+	if clientID == "" || clientSecret == "" {
+		return nil, errors.New("YOUTUBE_CLIENT_ID and YOUTUBE_CLIENT_SECRET aren't set; create an OAuth client for TVs and Limited Input devices in the Google Cloud console first")
+	}
+	config := search.YouTubeOAuthConfig(clientID, clientSecret)
+
+	device, err := config.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("requesting a device code: %w", err)
+	}
+	verificationURL := device.VerificationURI
+	if device.VerificationURIComplete != "" {
+		verificationURL = device.VerificationURIComplete
+	}
+	fmt.Fprintf(out, "Visit %s and enter the code %s to authorize grass.\n", verificationURL, device.UserCode)
+	if !*loginNoBrowser {
+		if err := openBrowser(verificationURL); err != nil {
+			log.Debug("Failed to open browser", "error", err)
+		}
+	}
+
+	token, err := config.DeviceAccessToken(ctx, device)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		return nil, errors.New("Google didn't issue a refresh token")
+	}
+	return map[string]string{"YOUTUBE_REFRESH_TOKEN": token.RefreshToken}, nil
+}
+
+// loginFediverse registers grass as an app with a Mastodon instance and authorizes it with the
+// authorization code flow, returning an access token for the instance.
+func loginFediverse(ctx context.Context, out io.Writer) (map[string]string, error) {
+	if *loginInstance == "" {
+		return nil, errors.New("choose an instance to log in to, e.g. grass login fediverse https://mastodon.social")
+	}
+	instanceURL := strings.TrimSuffix(*loginInstance, "/")
+	if !strings.Contains(instanceURL, "://") {
+		instanceURL = "https://" + instanceURL
+	}
+	if _, err := url.Parse(instanceURL); err != nil {
+		return nil, fmt.Errorf("invalid instance: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("client_name", "grass")
+	form.Set("redirect_uris", loginRedirectURL())
+	form.Set("scopes", "read")
+	form.Set("website", loginWebsite)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, instanceURL+"/api/v1/apps", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := sharedHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("registering with %s: %w", instanceURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registering with %s: %s", instanceURL, resp.Status)
+	}
+	var app struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&app); err != nil {
+		return nil, fmt.Errorf("registering with %s: %w", instanceURL, err)
+	}
+
+	config := &oauth2.Config{
+		ClientID:     app.ClientID,
+		ClientSecret: app.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   instanceURL + "/oauth/authorize",
+			TokenURL:  instanceURL + "/oauth/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		Scopes: []string{"read"},
+	}
+	token, err := authorize(ctx, out, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(strings.Split(strings.ReplaceAll(os.Getenv("FEDIVERSE_INSTANCES"), " ", ""), ","), instanceURL) {
+		fmt.Fprintf(out, "Add %s to FEDIVERSE_INSTANCES to search it.\n", instanceURL)
+	}
+	return map[string]string{search.FediverseEnvPrefix(instanceURL) + "_ACCESS_TOKEN": token.AccessToken}, nil
+}
+
+// loginRedirectURL is the URL the browser is redirected to once the user authorizes grass.
+func loginRedirectURL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d/callback", *loginPort)
+}
+
+// authorize runs the authorization code flow: it sends the user to config's authorization URL, receives
+// the code the browser is redirected back with on --port, and exchanges it for a token.
+func authorize(ctx context.Context, out io.Writer, config *oauth2.Config, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", *loginPort))
+	if err != nil {
+		return nil, fmt.Errorf("listening for the authorization redirect: %w", err)
+	}
+	config.RedirectURL = loginRedirectURL()
+
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	state := base64.RawURLEncoding.EncodeToString(stateBytes)
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "Unexpected authorization; run grass login again.", http.StatusBadRequest)
+			return
+		}
+		var res result
+		if authErr := query.Get("error"); authErr != "" {
+			res.err = fmt.Errorf("authorization failed: %s", authErr)
+			fmt.Fprintln(w, "grass wasn't authorized; you can close this tab.")
+		} else {
+			res.code = query.Get("code")
+			fmt.Fprintln(w, "grass is authorized; you can close this tab.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authURL := config.AuthCodeURL(state, opts...)
+	fmt.Fprintf(out, "Visit %s to authorize grass.\n", authURL)
+	if !*loginNoBrowser {
+		if err := openBrowser(authURL); err != nil {
+			log.Debug("Failed to open browser", "error", err)
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for authorization: %w", ctx.Err())
+	case res := <-results:
+		if res.err != nil {
+			return nil, res.err
+		}
+		token, err := config.Exchange(ctx, res.code)
+		if err != nil {
+			return nil, fmt.Errorf("exchanging the authorization code: %w", err)
+		}
+		return token, nil
+	}
+}
@@ -1,25 +1,107 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"github.com/charmbracelet/log"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
 	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/internal/httpclient"
+	"github.com/jaxxstorm/grass/internal/proxy"
 	"github.com/jaxxstorm/grass/search"
 	"github.com/jaxxstorm/grass/storage"
 	"github.com/joho/godotenv"
 )
 
 var (
-	Version     = "dev"
-	dbType      = kingpin.Flag("db", "Specify the database type to use: dynamodb or sqlite").Default("sqlite").Enum("dynamodb", "sqlite")
-	keywords    = kingpin.Flag("keyword", "Specify keywords to search for").Strings()
-	botTypes    = kingpin.Flag("bot", "Specify bot types to use: print, discord").Strings()
-	searchers   = kingpin.Flag("searchers", "Specify searchers to use: hackernews, reddit, bluesky").Strings()
-	tableName   = kingpin.Flag("table-name", "Specify the table name to use for SQLite storage").Envar("SOCIAL_SEARCH_TABLE_NAME").Default("grass").String()
-	showVersion = kingpin.Flag("version", "Show the version and exit").Bool()
+	Version           = "dev"
+	dbType            = kingpin.Flag("db", "Specify the database type to use: dynamodb, sqlite, redis, bolt, ndjson, s3, gcs, clickhouse, or elasticsearch").Default("sqlite").Envar("GRASS_DB").Enum(storageBackends...)
+	secondaryDBs      = kingpin.Flag("secondary-db", "Additional database types to write results to; deduplication state is read from --db").Enums(storageBackends...)
+	keywords          = kingpin.Flag("keyword", "Specify keywords to search for").Envar("GRASS_KEYWORD").Strings()
+	keywordsFile      = kingpin.Flag("keywords-file", "Read more keywords from a file, one per line with optional options, or a YAML list").Envar("GRASS_KEYWORDS_FILE").String()
+	accounts          = kingpin.Flag("account", "Watch an account and notify all of its new posts, as <searcher>/<account>, e.g. hackernews/pg or bluesky/jay.bsky.team").Strings()
+	botTypes          = kingpin.Flag("bot", "Specify bot types to use: print, discord, slack, elasticsearch, or a notifier plugin").Envar("GRASS_BOT").Strings()
+	searchers         = kingpin.Flag("searchers", "Specify searchers to use: hackernews, reddit, bluesky, fediverse, youtube, or a searcher plugin").Envar("GRASS_SEARCHERS").Strings()
+	httpTimeout       = kingpin.Flag("http-timeout", "Abandon any single HTTP request that takes longer than this (0 leaves it to each searcher and notifier)").Envar("GRASS_HTTP_TIMEOUT").Default("0s").Duration()
+	httpDialTimeout   = kingpin.Flag("http-dial-timeout", "Give up opening an HTTP connection after this long").Envar("GRASS_HTTP_DIAL_TIMEOUT").Default("30s").Duration()
+	httpIdleConns     = kingpin.Flag("http-max-idle-conns-per-host", "Number of idle HTTP connections kept open per host for reuse").Envar("GRASS_HTTP_MAX_IDLE_CONNS_PER_HOST").Default("10").Int()
+	httpMaxConns      = kingpin.Flag("http-max-conns-per-host", "Maximum number of HTTP connections per host (0 is unlimited)").Envar("GRASS_HTTP_MAX_CONNS_PER_HOST").Default("0").Int()
+	httpIdleTimeout   = kingpin.Flag("http-idle-timeout", "Close idle HTTP connections after this long").Envar("GRASS_HTTP_IDLE_TIMEOUT").Default("90s").Duration()
+	userAgent         = kingpin.Flag("user-agent", "User-Agent sent with HTTP requests that don't set a platform-specific one (default: grass/<version>)").Envar("GRASS_USER_AGENT").String()
+	tlsCAFile         = kingpin.Flag("tls-ca-file", "PEM file of extra certificate authorities to trust, e.g. for a TLS-intercepting proxy").Envar("GRASS_TLS_CA_FILE").String()
+	tlsMinVersion     = kingpin.Flag("tls-min-version", "Minimum TLS version for HTTPS requests: 1.0, 1.1, 1.2, or 1.3").Envar("GRASS_TLS_MIN_VERSION").Default("1.2").Enum("1.0", "1.1", "1.2", "1.3")
+	tlsSkipVerify     = kingpin.Flag("tls-insecure-skip-verify", "Don't verify HTTPS certificates (insecure; for testing only)").Envar("GRASS_TLS_INSECURE_SKIP_VERIFY").Bool()
+	proxyURL          = kingpin.Flag("proxy", "Send every searcher and notifier request through this http, https, socks5, or socks5h proxy; a comma-separated list rotates between proxies (default: HTTP_PROXY, HTTPS_PROXY, and NO_PROXY)").Envar("GRASS_PROXY").String()
+	searcherProxies   = kingpin.Flag("searcher-proxy", "Proxy for one searcher, overriding --proxy, as <searcher>=<proxy>[,<proxy>...]; use <searcher>=direct to bypass the proxy").StringMap()
+	searchCache       = kingpin.Flag("search-cache-entries", "Number of searcher responses kept to revalidate with ETag and Last-Modified, so unchanged responses aren't downloaded again (0 disables)").Envar("GRASS_SEARCH_CACHE_ENTRIES").Default("1000").Int()
+	pluginDir         = kingpin.Flag("plugin-dir", "Directory containing grass-searcher-<name> and grass-notifier-<name> plugin executables (default: grass/plugins in the user config directory)").Envar("GRASS_PLUGIN_DIR").String()
+	tableName         = kingpin.Flag("table-name", "Specify the table name to use for SQLite storage").Envar("SOCIAL_SEARCH_TABLE_NAME").Default("grass").String()
+	retention         = kingpin.Flag("retention", "Delete stored results older than this duration after each run (0 keeps them forever)").Envar("GRASS_RETENTION").Default("0s").Duration()
+	dynamoCreateTable = kingpin.Flag("dynamodb-create-table", "Create the DynamoDB table (on-demand billing, TTL, keyword index) if it does not exist").Envar("DYNAMODB_CREATE_TABLE").Bool()
+	redisTTL          = kingpin.Flag("redis-ttl", "Expire stored results after this duration when using Redis storage (0 keeps them forever)").Envar("REDIS_TTL").Default("0s").Duration()
+	concurrency       = kingpin.Flag("concurrency", "Number of keywords to search in parallel").Envar("GRASS_CONCURRENCY").Default("4").Int()
+	platformLimit     = kingpin.Flag("platform-concurrency", "Maximum number of concurrent searches against a single platform").Envar("GRASS_PLATFORM_CONCURRENCY").Default("1").Int()
+	maxResults        = kingpin.Flag("max-results-per-search", "Stop paging through a platform's results once one search has collected this many (0 is unlimited)").Envar("GRASS_MAX_RESULTS_PER_SEARCH").Default(strconv.Itoa(search.DefaultMaxResults)).Int()
+	searchTimeout     = kingpin.Flag("search-timeout", "Abandon a search (including searcher authentication) that takes longer than this (0 disables)").Envar("GRASS_SEARCH_TIMEOUT").Default("30s").Duration()
+	notifyTimeout     = kingpin.Flag("notify-timeout", "Abandon a notification that takes longer than this (0 disables)").Envar("GRASS_NOTIFY_TIMEOUT").Default("15s").Duration()
+	duplicateWindow   = kingpin.Flag("duplicate-window", "Group copies of the same story found on several platforms within this window into one notification (0 disables)").Envar("GRASS_DUPLICATE_WINDOW").Default("0s").Duration()
+	digest            = kingpin.Flag("digest", "Combine the new results from each run into one message per notifier").Envar("GRASS_DIGEST").Bool()
+	digestWindow      = kingpin.Flag("digest-window", "Collect results for this long before sending a digest, instead of once per run (implies --digest)").Envar("GRASS_DIGEST_WINDOW").Default("0s").Duration()
+	rateLimit         = kingpin.Flag("rate-limit", "Send at most this many messages per notifier per --rate-window, holding back the rest (0 disables)").Envar("GRASS_RATE_LIMIT").Default("0").Int()
+	rateWindow        = kingpin.Flag("rate-window", "Period the --rate-limit applies to").Envar("GRASS_RATE_WINDOW").Default("1h").Duration()
+	rateOverflow      = kingpin.Flag("rate-overflow", "What to do with results over the rate limit: digest sends them together once allowed, drop only reports how many there were").Envar("GRASS_RATE_OVERFLOW").Default("digest").Enum(bot.ThrottleOverflows...)
+	runSummary        = kingpin.Flag("run-summary", "Send each notifier a summary of new results, errors, and durations: off, also (alongside each result), or only (instead of each result)").Envar("GRASS_RUN_SUMMARY").Default("off").Enum(bot.SummaryModes...)
+	summaryInterval   = kingpin.Flag("run-summary-interval", "In daemon mode, how often to send a run summary covering every search since the last one").Envar("GRASS_RUN_SUMMARY_INTERVAL").Default("24h").Duration()
+	followUpThreshold = kingpin.Flag("follow-up-threshold", "In daemon mode, notify again whenever a notified result gains this many replies (0 disables)").Envar("GRASS_FOLLOW_UP_THRESHOLD").Default("0").Int64()
+	followUpWindow    = kingpin.Flag("follow-up-window", "How long after notification a result's replies are followed").Envar("GRASS_FOLLOW_UP_WINDOW").Default("24h").Duration()
+	followUpInterval  = kingpin.Flag("follow-up-interval", "Time between checks of followed results for new replies").Envar("GRASS_FOLLOW_UP_INTERVAL").Default("15m").Duration()
+	deletionWindow    = kingpin.Flag("deletion-window", "In daemon mode, check notified results for deletion or removal by moderators for this long after notifying them (0 disables)").Envar("GRASS_DELETION_WINDOW").Default("0s").Duration()
+	deletionInterval  = kingpin.Flag("deletion-interval", "Time between checks of notified results for deletion").Envar("GRASS_DELETION_INTERVAL").Default("30m").Duration()
+	notifyDeletions   = kingpin.Flag("notify-deletions", "Send a follow-up notification when a notified result is deleted or removed").Envar("GRASS_NOTIFY_DELETIONS").Bool()
+	spamFilter        = kingpin.Flag("spam-filter", "Drop likely spam and bot posts: off, low, medium, or high strictness").Envar("GRASS_SPAM_FILTER").Default("off").Enum(bot.SpamLevels...)
+	summarize         = kingpin.Flag("summarize", "Summarize long results with an OpenAI-compatible API (see OPENAI_API_KEY and OPENAI_BASE_URL) and notify the summary instead of the content").Envar("GRASS_SUMMARIZE").Bool()
+	summarizeModel    = kingpin.Flag("summarize-model", "Model used to summarize results").Envar("GRASS_SUMMARIZE_MODEL").Default("gpt-4o-mini").String()
+	summarizeMin      = kingpin.Flag("summarize-min-length", "Only summarize results whose content is at least this many characters").Envar("GRASS_SUMMARIZE_MIN_LENGTH").Default("500").Int()
+	backfill          = kingpin.Flag("backfill", "Ignore stored last search times on the first search of each keyword and fetch results from this far back, e.g. 720h").Envar("GRASS_BACKFILL").Default("0s").Duration()
+	backfillMarkSeen  = kingpin.Flag("backfill-mark-seen", "Save backfilled results as seen without notifying them").Envar("GRASS_BACKFILL_MARK_SEEN").Bool()
+	since             = timeFlag(kingpin.Flag("since", "Search for results posted after this date or RFC 3339 time instead of since the stored last search time, without updating it").Envar("GRASS_SINCE"))
+	until             = timeFlag(kingpin.Flag("until", "Only keep results posted before this date or RFC 3339 time, without updating the stored last search time").Envar("GRASS_UNTIL"))
+	unfurl            = kingpin.Flag("unfurl", "Fetch the title, description, and image of the pages link posts point to and include them in notifications").Envar("GRASS_UNFURL").Bool()
+	archive           = kingpin.Flag("archive", "Keep a snapshot of every saved result, with its full content, that is never pruned, so mentions stay reviewable after deletion").Envar("GRASS_ARCHIVE").Bool()
+	archiveDBType     = kingpin.Flag("archive-db", "Database type to keep snapshots in: sqlite, s3, or gcs (default: --db)").Envar("GRASS_ARCHIVE_DB").Enum("sqlite", "s3", "gcs")
+	archivePages      = kingpin.Flag("archive-pages", "Also snapshot the HTML of the pages link posts point to").Envar("GRASS_ARCHIVE_PAGES").Bool()
+	triageButtons     = kingpin.Flag("triage-buttons", "Add Acknowledge, Dismiss, and Escalate buttons to Slack and Discord notifications, setting the state of the result in storage").Envar("GRASS_TRIAGE_BUTTONS").Bool()
+	threadContext     = kingpin.Flag("thread-context", "Fetch the post each comment or reply responds to and include a snippet of it in notifications").Envar("GRASS_THREAD_CONTEXT").Bool()
+	unfurlCacheTTL    = kingpin.Flag("unfurl-cache-ttl", "How long fetched link previews are reused").Envar("GRASS_UNFURL_CACHE_TTL").Default("24h").Duration()
+	keywordVariants   = kingpin.Flag("keyword-variants", "Also search platforms for their usual forms of each keyword, such as #keyword and @keyword on the Fediverse, unless keyword_variants configures them").Envar("GRASS_KEYWORD_VARIANTS").Bool()
+	editDetection     = kingpin.Flag("edit-detection", "Compare results found again with their stored version to detect edits: off, store (save the new version), or notify (also notify it again, marked as updated)").Envar("GRASS_EDIT_DETECTION").Default("off").Enum(bot.EditModes...)
+	editThreshold     = kingpin.Flag("edit-threshold", "Fraction of a result's words that must change for it to count as edited").Envar("GRASS_EDIT_THRESHOLD").Default(strconv.FormatFloat(bot.DefaultEditThreshold, 'f', -1, 64)).Float64()
+	engagement        = kingpin.Flag("engagement", "Fetch the current engagement (points, comments, reposts, views) of new results before saving them").Envar("GRASS_ENGAGEMENT").Bool()
+	outbox            = kingpin.Flag("outbox", "Queue notifications in storage and retry failed deliveries on later runs (sqlite, bolt, ndjson, and redis storage)").Envar("GRASS_OUTBOX").Bool()
+	daemon            = kingpin.Flag("daemon", "Keep running, searching each searcher and keyword on its configured schedule").Envar("GRASS_DAEMON").Bool()
+	keywordRefresh    = kingpin.Flag("keyword-refresh", "In daemon mode, how often to check storage for keywords added, removed, paused, or resumed with the keywords command").Envar("GRASS_KEYWORD_REFRESH").Default("1m").Duration()
+	interval          = kingpin.Flag("interval", "Default time between searches in daemon mode when the config file sets no schedule").Envar("GRASS_INTERVAL").Default("15m").Duration()
+	webhookAddr       = kingpin.Flag("webhook-addr", "Accept results pushed to /webhook/<profile> on this address, e.g. :8080").Envar("GRASS_WEBHOOK_ADDR").String()
+	webhookToken      = kingpin.Flag("webhook-token", "Bearer token webhook requests must carry").Envar("GRASS_WEBHOOK_TOKEN").String()
+	reportCampaigns   = kingpin.Flag("campaign-report", "Print a summary report for every campaign in the config file and exit").Bool()
+	tenantsDir        = kingpin.Flag("tenants-dir", "Directory of tenant config files (<tenant>.yaml), each run as isolated profiles alongside --config").Envar("GRASS_TENANTS_DIR").String()
+	timezone          = kingpin.Flag("timezone", "IANA timezone, e.g. Europe/London, that message timestamps are shown in (default: local time)").Envar("GRASS_TIMEZONE").String()
+	timeFormat        = kingpin.Flag("time-format", "Go time layout message timestamps are shown in").Envar("GRASS_TIME_FORMAT").Default(bot.DefaultTimeLayout).String()
+	logLevel          = kingpin.Flag("log-level", "Log level: debug, info, warn, or error (default: info)").Envar("GRASS_LOG_LEVEL").Enum("debug", "info", "warn", "error")
+	logFormat         = kingpin.Flag("log-format", "Log format: text, json, or logfmt (default: text)").Envar("GRASS_LOG_FORMAT").Enum("text", "json", "logfmt")
+	logFile           = kingpin.Flag("log-file", "Write logs to this file instead of stderr, rotating it as it grows").Envar("GRASS_LOG_FILE").String()
+	logMaxSize        = kingpin.Flag("log-max-size", "Rotate the log file once it reaches this many megabytes (default: 100)").Envar("GRASS_LOG_MAX_SIZE").Int()
+	logMaxBackups     = kingpin.Flag("log-max-backups", "Number of rotated log files to keep (default: 5)").Envar("GRASS_LOG_MAX_BACKUPS").Int()
+	configFile        = kingpin.Flag("config", "Path to a YAML configuration file").Envar("GRASS_CONFIG").String()
+	showVersion       = kingpin.Flag("version", "Show the version and exit").Bool()
 )
 
 func init() {
@@ -28,94 +110,501 @@ func init() {
 	if err != nil {
 		log.Debug("No .env file found or error reading it; make sure environment variables are set.")
 	}
+	loadCredentials()
 }
 
 func main() {
-	kingpin.Parse()
+	if err := loadLambdaEnv(); err != nil {
+		log.Fatalf("Failed to load Lambda configuration: %v", err)
+	}
+	if err := resolveSecretEnv(); err != nil {
+		log.Fatalf("Failed to resolve secret: %v", err)
+	}
+	completeCommands(os.Args[1:], os.Stdout)
+	command := kingpin.Parse()
+	if command == watchCommand.FullCommand() {
+		*daemon = true
+	}
 
 	if *showVersion {
 		fmt.Println("Version:", Version)
 		os.Exit(0)
 	}
+	if command == initCommand.FullCommand() {
+		if err := runInit(os.Stdin, os.Stdout); err != nil {
+			log.Fatalf("Init failed: %v", err)
+		}
+		return
+	}
+	if command == completionCommand.FullCommand() {
+		if err := runCompletion(os.Stdout); err != nil {
+			log.Fatalf("Completion failed: %v", err)
+		}
+		return
+	}
 
-	// Initialize searchers
-	var searchersList []search.Searcher
-	for _, searcher := range *searchers {
-		switch searcher {
-		case "hackernews":
-			searchersList = append(searchersList, search.NewHackerNewsSearcher())
-		case "reddit":
-			redditSearcher, err := search.NewRedditSearcher()
-			if err != nil {
-				log.Fatalf("Failed to initialize Reddit searcher: %v", err)
+	if !since.IsZero() && !until.IsZero() && !until.After(*since) {
+		log.Fatal("--until must be after --since")
+	}
+	if *daemon && (!since.IsZero() || !until.IsZero()) {
+		log.Fatal("--since and --until apply to a single run and can't be used with --daemon")
+	}
+	if *leaderElection && !*daemon {
+		log.Fatal("Leader election only applies to the daemon; add --daemon")
+	}
+
+	if command == validateCommand.FullCommand() {
+		if err := runValidate(os.Stdout); err != nil {
+			log.Fatalf("Validation failed: %v", err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := resolveSecrets(cfg.Env); err != nil {
+		log.Fatalf("Failed to resolve secret in configuration: %v", err)
+	}
+	if err := loadKeywordsFile(cfg, *keywordsFile); err != nil {
+		log.Fatalf("Invalid --keywords-file: %v", err)
+	}
+	// The live feed shows new results, so watching only logs problems unless asked for more
+	defaultLogLevel := "info"
+	if command == watchCommand.FullCommand() {
+		defaultLogLevel = "warn"
+	}
+	logs, err := setupLogging(cfg.Log, defaultLogLevel)
+	if err != nil {
+		log.Fatalf("Invalid logging settings: %v", err)
+	}
+	if logs != nil {
+		defer logs.Close()
+	}
+
+	location := time.Local
+	if *timezone != "" {
+		location, err = time.LoadLocation(*timezone)
+		if err != nil {
+			log.Fatalf("Invalid --timezone: %v", err)
+		}
+	}
+	bot.SetTimeFormat(location, *timeFormat)
+
+	// Cancel in-flight work on interrupt so storage calls can wind down cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	defaultProxy, err := proxy.Parse(*proxyURL)
+	if err != nil {
+		log.Fatalf("Invalid --proxy: %v", err)
+	}
+	proxy.SetDefault(defaultProxy)
+	sharedHTTPClient, err = httpclient.New(httpConfig(defaultProxy))
+	if err != nil {
+		log.Fatalf("Invalid HTTP client settings: %v", err)
+	}
+	search.SetHTTPClient(sharedHTTPClient)
+	search.SetCacheSize(*searchCache)
+	// Plugins make their own requests, so they read the proxy variables
+	env, err := proxyEnv(*proxyURL)
+	if err != nil {
+		log.Warn("Plugins won't use --proxy", "error", err)
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+
+	if command == selfUpdateCommand.FullCommand() {
+		if err := runSelfUpdate(ctx, os.Stdout); err != nil {
+			log.Fatalf("Self-update failed: %v", err)
+		}
+		return
+	}
+	if command == loginCommand.FullCommand() {
+		if err := runLogin(ctx, os.Stdout); err != nil {
+			log.Fatalf("Login failed: %v", err)
+		}
+		return
+	}
+	if isKeywordsCommand(command) {
+		if err := runKeywords(ctx, cfg, command, os.Stdout); err != nil {
+			log.Fatalf("Keywords failed: %v", err)
+		}
+		return
+	}
+	if command == queryCommand.FullCommand() {
+		if err := runQuery(ctx, cfg, location, os.Stdout); err != nil {
+			log.Fatalf("Query failed: %v", err)
+		}
+		return
+	}
+	if command == triageCommand.FullCommand() {
+		if err := runTriage(ctx, cfg, os.Stdout); err != nil {
+			log.Fatalf("Triage failed: %v", err)
+		}
+		return
+	}
+	if command == tuiCommand.FullCommand() {
+		if err := runTUI(ctx, cfg, location); err != nil {
+			log.Fatalf("Dashboard failed: %v", err)
+		}
+		return
+	}
+	if command == searchCommand.FullCommand() {
+		if err := runSearch(ctx, location, os.Stdout); err != nil {
+			log.Fatalf("Search failed: %v", err)
+		}
+		return
+	}
+	if command == migrateCommand.FullCommand() {
+		if err := runMigrate(ctx, cfg); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if command == importCommand.FullCommand() {
+		if err := runImport(ctx, cfg); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		return
+	}
+	if command == exportCommand.FullCommand() {
+		if err := runExport(ctx, cfg); err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		return
+	}
+	if command == statsCommand.FullCommand() {
+		if err := runStats(ctx, cfg, os.Stdout); err != nil {
+			log.Fatalf("Stats failed: %v", err)
+		}
+		return
+	}
+
+	logPlugins()
+
+	if command == replayCommand.FullCommand() {
+		if err := runReplay(ctx, cfg, location, os.Stdout); err != nil {
+			log.Fatalf("Replay failed: %v", err)
+		}
+		return
+	}
+
+	if command == previewCommand.FullCommand() {
+		if err := runPreview(ctx, cfg, os.Stdout); err != nil {
+			log.Fatalf("Preview failed: %v", err)
+		}
+		return
+	}
+
+	if command == serveCommand.FullCommand() {
+		if !*apiEnabled && !*grpcEnabled {
+			log.Fatal("Nothing to serve; pass --api or --grpc")
+		}
+		if *apiToken == "" {
+			log.Fatal("The API requires a token; set --token or GRASS_API_TOKEN")
+		}
+		if *slackSigningSecret != "" && !*apiEnabled {
+			log.Fatal("The Slack app is served by the API; add --api")
+		}
+	}
+
+	if *feedAddr != "" && !*daemon && command != serveCommand.FullCommand() {
+		log.Fatal("Feeds are only served in daemon mode; add --daemon")
+	}
+
+	startStatsd()
+	defer closeStatsd()
+	startEvents()
+	defer closeEvents()
+
+	// Initialize every profile up front so configuration errors surface before any searching
+	names, profileCfgs := profileConfigs(cfg)
+	var profiles []*profile
+	withEnv(cfg.Env, func() {
+		searcherCache := make(map[string]search.Searcher)
+		for _, name := range names {
+			profiles = append(profiles, newProfile(ctx, cfg, name, profileCfgs[name], searcherCache))
+		}
+		for _, name := range campaignNames(cfg) {
+			profiles = append(profiles, newCampaign(ctx, cfg, name, cfg.Campaigns[name], searcherCache))
+		}
+	})
+	if *tenantsDir != "" {
+		paths, tenants, err := tenantNames(*tenantsDir)
+		if err != nil {
+			log.Fatalf("Failed to read tenants directory: %v", err)
+		}
+		for _, tenant := range tenants {
+			profiles = append(profiles, newTenantProfiles(ctx, tenant, paths[tenant])...)
+		}
+	}
+	for _, p := range profiles {
+		defer p.close()
+	}
+
+	if *reportCampaigns {
+		for _, p := range profiles {
+			if p.campaign == nil {
+				continue
 			}
-			searchersList = append(searchersList, redditSearcher)
-		case "bluesky":
-			blueskySearcher, err := search.NewBlueskySearcher()
+			report, err := p.campaignReport(ctx)
 			if err != nil {
-				log.Fatalf("Failed to initialize Bluesky searcher: %v", err)
+				log.Fatalf("Failed to report on campaign %q: %v", p.name, err)
 			}
-			searchersList = append(searchersList, blueskySearcher)
-		case "fediverse":
-			fediverseSearcher, err := search.NewFediverseSearcher()
-			if err != nil {
-				log.Fatalf("Failed to initialize Fediverse searcher: %v", err)
+			fmt.Print(report)
+		}
+		return
+	}
+
+	if inLambda() {
+		serveLambda(profiles)
+		return
+	}
+
+	// The webhook server shuts down before the profiles it feeds are closed
+	if *webhookAddr != "" {
+		webhookCtx, stopWebhooks := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := serveWebhooks(webhookCtx, *webhookAddr, profiles, *webhookToken); err != nil {
+				log.Fatalf("Webhook server failed: %v", err)
 			}
-			searchersList = append(searchersList, fediverseSearcher)
-		case "youtube":
-			youtubeSearcher, err := search.NewYouTubeSearcher()
-			if err != nil {
-				log.Fatalf("Failed to initialize YouTube searcher: %v", err)
+		}()
+		defer func() {
+			stopWebhooks()
+			<-done
+		}()
+	}
+
+	// So does the feed server, which reads from the profiles' storage
+	if *feedAddr != "" {
+		feedCtx, stopFeeds := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := serveFeeds(feedCtx, *feedAddr, profiles, *feedToken); err != nil {
+				log.Fatalf("Feed server failed: %v", err)
 			}
-			searchersList = append(searchersList, youtubeSearcher)
-		default:
-			log.Fatalf("Unknown searcher specified: %s", searcher)
-		}
+		}()
+		defer func() {
+			stopFeeds()
+			<-done
+		}()
 	}
 
-	// Initialize the storage backend
-	var storer storage.Storer
-	var err error
+	if command == watchCommand.FullCommand() {
+		feed := newResultFeed()
+		if err := feed.attach(profiles); err != nil {
+			log.Fatalf("Failed to watch results: %v", err)
+		}
+		waitWatch := watchResults(ctx, feed, os.Stdout, location)
+		defer waitWatch()
+	}
 
-	switch *dbType {
-	case "dynamodb":
-		storer, err = storage.NewDynamoDBStorer(*tableName)
+	if command == workerCommand.FullCommand() {
+		q, err := openQueue(ctx)
 		if err != nil {
-			log.Fatalf("Failed to initialize DynamoDB storage: %v", err)
+			log.Fatalf("Failed to open the job queue: %v", err)
 		}
-	case "sqlite":
-		storer, err = storage.NewSQLiteStorer(*tableName)
-		if err != nil {
-			log.Fatalf("Failed to initialize SQLite storage: %v", err)
+		defer q.Close()
+		log.Info("Starting worker", "profiles", len(profiles), "jobs", *workerJobs)
+		stopping := notifySystemd(ctx, nil)
+		runWorker(ctx, q, profiles)
+		stopping()
+		log.Info("Worker stopped")
+		return
+	}
+
+	// The API servers also shut down before the profiles they serve are closed
+	if command == serveCommand.FullCommand() {
+		apiCtx, stopAPI := context.WithCancel(ctx)
+		var servers sync.WaitGroup
+		if *apiEnabled {
+			servers.Add(1)
+			go func() {
+				defer servers.Done()
+				if err := serveAPI(apiCtx, *apiAddr, profiles, *apiToken, location); err != nil {
+					log.Fatalf("API server failed: %v", err)
+				}
+			}()
 		}
-		defer func() {
-			if err := storer.(*storage.SQLiteStorer).Close(); err != nil {
-				log.Printf("Failed to close SQLite storage: %v", err)
+		if *grpcEnabled {
+			feed := newResultFeed()
+			if err := feed.attach(profiles); err != nil {
+				log.Fatalf("Failed to stream results: %v", err)
 			}
+			servers.Add(1)
+			go func() {
+				defer servers.Done()
+				if err := serveGRPC(apiCtx, *grpcAddr, profiles, *apiToken, feed); err != nil {
+					log.Fatalf("gRPC server failed: %v", err)
+				}
+			}()
+		}
+		defer func() {
+			stopAPI()
+			servers.Wait()
 		}()
-	default:
-		log.Fatalf("Unknown database type: %s", *dbType)
+		if !*daemon {
+			stopping := notifySystemd(ctx, nil)
+			<-ctx.Done()
+			stopping()
+			return
+		}
+	}
+
+	if *daemon && *configFile != "" {
+		if err := startReloads(ctx, *configFile, cfg); err != nil {
+			log.Fatalf("Failed to watch the config file: %v", err)
+		}
+	}
+
+	if *daemon && *leaderElection {
+		l, err := newLeader(profiles)
+		if err != nil {
+			log.Fatalf("Invalid leader election: %v", err)
+		}
+		log.Info("Starting daemon", "profiles", len(profiles), "replica", l.holder)
+		stopping := notifySystemd(ctx, l)
+		l.run(ctx, func(ctx context.Context) {
+			sched, err := newScheduler(ctx, profiles)
+			if err != nil {
+				log.Fatalf("Invalid schedule: %v", err)
+			}
+			waitStreams := startStreams(ctx, profiles)
+			l.setScheduler(sched)
+			if err := sched.Run(ctx); err != nil && ctx.Err() == nil {
+				log.Errorf("Scheduler stopped: %v", err)
+			}
+			l.setScheduler(nil)
+			waitStreams()
+		})
+		stopping()
+		log.Info("Daemon stopped")
+		return
+	}
+
+	if *daemon {
+		sched, err := newScheduler(ctx, profiles)
+		if err != nil {
+			log.Fatalf("Invalid schedule: %v", err)
+		}
+		log.Info("Starting daemon", "profiles", len(profiles))
+		waitStreams := startStreams(ctx, profiles)
+		stopping := notifySystemd(ctx, sched)
+		if err := sched.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("Scheduler stopped: %v", err)
+		}
+		stopping()
+		waitStreams()
+		log.Info("Daemon stopped")
+		return
+	}
+
+	report := runProfiles(ctx, profiles, "run")
+	logReport(report)
+	if *runOutput == "json" {
+		if err := writeRunReport(os.Stdout, report); err != nil {
+			log.Error("Failed to write the run report", "error", err)
+		}
+	}
+	if *pushgatewayURL != "" {
+		if err := pushMetrics(ctx, report); err != nil {
+			log.Error("Failed to push metrics to the Pushgateway", "error", err)
+		}
+	}
+
+	if *webhookAddr != "" {
+		log.Info("Searches finished; serving webhooks until interrupted")
+		<-ctx.Done()
 	}
 
-	// Initialize notifiers
-	var notifiers []bot.Notifier
-	for _, botType := range *botTypes {
-		switch botType {
-		case "print":
-			notifiers = append(notifiers, bot.NewPrintNotifier())
-		case "discord":
-			notifiers = append(notifiers, bot.NewDiscordNotifier())
-		case "slack":
-			notifiers = append(notifiers, bot.NewSlackNotifier())
-		default:
-			log.Fatalf("Unknown bot type: %s", botType)
+	// Let automation tell a run where nothing could be searched from one that found nothing new
+	if report.AllFailed() {
+		log.Error("Every search failed")
+		for _, p := range profiles {
+			p.close()
 		}
+		closeStatsd()
+		closeEvents()
+		os.Exit(1)
 	}
+}
+
+// runProfiles runs every active profile once, searching each of its keywords, and returns what they found
+// together. Each profile's run is recorded in its run history under trigger.
+func runProfiles(ctx context.Context, profiles []*profile, trigger string) *bot.RunReport {
+	report := &bot.RunReport{}
+	for _, p := range profiles {
+		if !p.active(time.Now()) {
+			log.Info("Skipping inactive campaign", "campaign", p.name)
+			p.sendCampaignReport(ctx)
+			continue
+		}
+		keywords := p.keywordsOrConfigured(ctx)
+		if p.name != "" {
+			log.Info("Running profile", "profile", p.name, "keywords", len(keywords))
+		}
+		profileReport := p.bot.RunKeywords(ctx, keywords, *concurrency)
+		report.Merge(profileReport)
+		recordRun(ctx, p, profileReport, trigger)
+		if *runSummary != "off" {
+			p.bot.NotifySummary(ctx, profileReport)
+		}
 
-	// Run the bot
-	b := bot.NewBot(searchersList, storer, notifiers)
-	for _, keyword := range *keywords {
-		log.Printf("Running search for keyword: %s", keyword)
-		b.Run(keyword)
+		if *retention > 0 {
+			prune(ctx, p.storer)
+		}
+	}
+	return report
+}
+
+// logReport logs the outcome of a run for each platform and in total.
+func logReport(report *bot.RunReport) {
+	for _, p := range report.Platforms() {
+		log.Info("Platform searched", "platform", p.Platform, "searches", p.Searches, "failed", p.Failed, "found", p.Found, "new", p.New, "skipped", p.Skipped, "duration", p.Duration.Round(time.Millisecond))
+		for _, err := range p.Errors {
+			log.Debug("Search failed", "platform", p.Platform, "error", err)
+		}
+	}
+	log.Info("Run finished", "summary", report.String())
+}
+
+// prune deletes stored results older than the retention period.
+func prune(ctx context.Context, storer storage.Storer) {
+	cutoff := time.Now().Add(-*retention)
+	log.Info("Pruning stored results", "older_than", cutoff.Format(time.RFC3339))
+	if err := storer.Prune(ctx, cutoff); err != nil {
+		log.Error("Failed to prune stored results", "error", err)
+	}
+}
+
+// withTimeout derives a context bounded by timeout, or a cancellable copy of ctx when timeout is zero.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// mustTemplate parses a notifier's message template, exiting on invalid templates.
+func mustTemplate(name, text, defaultText string) *bot.MessageTemplate {
+	tmpl, err := bot.ParseTemplate(name, text, defaultText)
+	if err != nil {
+		log.Fatalf("Invalid message template: %v", err)
+	}
+	return tmpl
+}
+
+// mustMentions parses a notifier's priority mentions, exiting on unknown priorities.
+func mustMentions(name string, raw map[string][]string) bot.Mentions {
+	mentions, err := bot.ParseMentions(raw)
+	if err != nil {
+		log.Fatalf("Invalid mentions for %s notifier: %v", name, err)
 	}
+	return mentions
 }
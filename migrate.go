@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	migrateCommand = kingpin.Command("migrate", "Copy every stored result and last search time from --db to another storage backend")
+	migrateToDB    = migrateCommand.Flag("to-db", "Database type to copy to").Required().Enum(storageBackends...)
+	migrateToTable = migrateCommand.Flag("to-table-name", "Table, file, or bucket of the --to-db database").String()
+	migrateProfile = migrateCommand.Flag("profile", "Copy from the storage of this profile from the config file instead of --db and --table-name").String()
+)
+
+// runMigrate copies the results and last search times of the profile's primary storage to the storage
+// selected by --to-db. Results already in the destination are skipped and later last search times kept, so
+// an interrupted migration can be run again.
+func runMigrate(ctx context.Context, cfg *config.Config) error {
+	source, db, err := openStorage(ctx, cfg, *migrateProfile)
+	if err != nil {
+		return err
+	}
+	if closer, ok := source.(io.Closer); ok {
+		defer closer.Close()
+	}
+	querier, ok := storage.AsQuerier(source)
+	if !ok {
+		return fmt.Errorf("%s storage can't be migrated from", db)
+	}
+	lister, ok := storage.AsSearchTimeLister(source)
+	if !ok {
+		return fmt.Errorf("%s storage can't be migrated from", db)
+	}
+
+	destination, err := newStorer(ctx, *migrateToDB, *migrateToTable)
+	if err != nil {
+		return err
+	}
+	if closer, ok := destination.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	results, err := querier.Query(ctx, storage.Query{})
+	if err != nil {
+		return err
+	}
+	log.Info("Migrating results", "from", db, "to", *migrateToDB, "results", len(results))
+	copied, existing, err := storeResults(ctx, destination, results, func(done int) {
+		log.Info("Migrating results", "done", done, "total", len(results))
+	})
+	if err != nil {
+		return err
+	}
+
+	times, err := lister.LastSearchTimes(ctx)
+	if err != nil {
+		return err
+	}
+	updated := 0
+	for key, lastSearchTime := range times {
+		current, err := destination.GetLastSearchTime(ctx, key)
+		if err != nil {
+			return err
+		}
+		if current >= lastSearchTime {
+			continue
+		}
+		if err := destination.SetLastSearchTime(ctx, key, lastSearchTime); err != nil {
+			return err
+		}
+		updated++
+	}
+
+	log.Info("Migrated storage", "results", copied, "already_stored", existing, "last_search_times", updated)
+	return nil
+}
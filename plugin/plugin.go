@@ -0,0 +1,195 @@
+// plugin/plugin.go
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Plugins are executables named grass-searcher-<name> or grass-notifier-<name> in the plugin directory.
+// grass runs the executable once per call with the method as its only argument, writes a JSON request to
+// its stdin, and reads a JSON response from its stdout. A non-zero exit status, or a response with a
+// non-empty error, fails the call; anything written to stderr is included in the error.
+const (
+	// KindSearcher plugins answer the "search" method with SearchRequest and SearchResponse.
+	KindSearcher = "searcher"
+	// KindNotifier plugins answer the "notify" method with NotifyRequest and Response.
+	KindNotifier = "notifier"
+)
+
+// SearchRequest asks a searcher plugin for results matching keyword posted after After (Unix seconds).
+type SearchRequest struct {
+	Keyword string `json:"keyword"`
+	After   int64  `json:"after"`
+}
+
+// SearchResponse carries a searcher plugin's results. Results without a platform are attributed to the
+// plugin's name.
+type SearchResponse struct {
+	Results []search.SearchResult `json:"results"`
+	Error   string                `json:"error,omitempty"`
+}
+
+// NotifyRequest asks a notifier plugin to deliver a result.
+type NotifyRequest = search.Notification
+
+// Response is a plugin's reply to a method with no other output.
+type Response struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Executable returns the file name of a plugin.
+func Executable(kind, name string) string {
+	return fmt.Sprintf("grass-%s-%s", kind, name)
+}
+
+// Find returns the path of the named plugin in dir.
+func Find(dir, kind, name string) (string, error) {
+	if dir == "" {
+		return "", fmt.Errorf("no plugin directory configured for %s %q", kind, name)
+	}
+	path, err := filepath.Abs(filepath.Join(dir, Executable(kind, name)))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s plugin %q: %w", kind, name, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("%s plugin %q not found in %s: %w", kind, name, dir, err)
+	}
+	if info.IsDir() || info.Mode()&0o111 == 0 {
+		return "", fmt.Errorf("%s plugin %s is not executable", kind, path)
+	}
+	return path, nil
+}
+
+// List returns the names of the plugins of a kind installed in dir. A missing directory has none.
+func List(dir, kind string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	prefix := Executable(kind, "")
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) || len(entry.Name()) == len(prefix) {
+			continue
+		}
+		names = append(names, strings.TrimPrefix(entry.Name(), prefix))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Searcher runs a searcher plugin for each search.
+type Searcher struct {
+	name string
+	path string
+	env  []string
+}
+
+// NewSearcher finds the named searcher plugin in dir. The plugin runs with the environment at the time it
+// is created, so it receives the same credentials as built-in searchers.
+func NewSearcher(dir, name string) (*Searcher, error) {
+	path, err := Find(dir, KindSearcher, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Searcher{name: name, path: path, env: os.Environ()}, nil
+}
+
+// Platform returns the plugin's name.
+func (s *Searcher) Platform() string {
+	return s.name
+}
+
+// Search asks the plugin for results, killing it if ctx is cancelled.
+func (s *Searcher) Search(ctx context.Context, keyword string, afterEpochSecs int64) ([]search.SearchResult, error) {
+	var resp SearchResponse
+	if err := call(ctx, s.path, s.env, "search", SearchRequest{Keyword: keyword, After: afterEpochSecs}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("searcher plugin %s: %s", s.name, resp.Error)
+	}
+
+	for i := range resp.Results {
+		if resp.Results[i].Platform == "" {
+			resp.Results[i].Platform = s.name
+		}
+		resp.Results[i].Keyword = keyword
+	}
+	return resp.Results, nil
+}
+
+// Notifier runs a notifier plugin for each notification.
+type Notifier struct {
+	name string
+	path string
+	env  []string
+}
+
+// NewNotifier finds the named notifier plugin in dir. Like NewSearcher, the plugin runs with the
+// environment at the time it is created.
+func NewNotifier(dir, name string) (*Notifier, error) {
+	path, err := Find(dir, KindNotifier, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Notifier{name: name, path: path, env: os.Environ()}, nil
+}
+
+// Notify sends the result to the plugin, killing it if ctx is cancelled.
+func (n *Notifier) Notify(ctx context.Context, result search.SearchResult) error {
+	req := search.NewNotification(result)
+	var resp Response
+	if err := call(ctx, n.path, n.env, "notify", req, &resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("notifier plugin %s: %s", n.name, resp.Error)
+	}
+	return nil
+}
+
+// call runs a plugin method with the given environment, encoding req to its stdin and decoding its stdout
+// into resp.
+func call(ctx context.Context, path string, env []string, method string, req, resp any) error {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plugin request: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, method)
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("plugin %s %s failed: %w: %s", filepath.Base(path), method, err, msg)
+		}
+		return fmt.Errorf("plugin %s %s failed: %w", filepath.Base(path), method, err)
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return fmt.Errorf("failed to parse plugin %s %s response: %w", filepath.Base(path), method, err)
+	}
+	return nil
+}
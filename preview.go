@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+)
+
+var (
+	previewCommand = kingpin.Command("preview", "Run every searcher, filter, and notifier for a keyword, showing the message each notifier would send without storing or sending anything")
+	previewKeyword = previewCommand.Arg("keyword", "Keyword to search for, or from:<searcher>/<account> to preview an account's posts").Required().String()
+	previewProfile = previewCommand.Flag("profile", "Preview the searchers, filters, and notifiers of this profile from the config file").String()
+)
+
+// previewOutput is set while the preview command builds its profile, which then renders its notifiers'
+// messages to it and only reads from its storage.
+var previewOutput io.Writer
+
+// runPreview runs the profile's pipeline once for the preview keyword, writing the message each notifier
+// would send for every new result to w. Results already stored are skipped as in a real run, but nothing
+// is stored, last search times are left alone, and digests and rate limits don't apply.
+func runPreview(ctx context.Context, cfg *config.Config, w io.Writer) error {
+	if _, err := search.ParseQuery(*previewKeyword); err != nil {
+		return fmt.Errorf("invalid keyword: %w", err)
+	}
+	names, profileCfgs := profileConfigs(cfg)
+	if !slices.Contains(names, *previewProfile) {
+		if *previewProfile == "" {
+			return fmt.Errorf("the config file defines profiles; choose one with --profile")
+		}
+		return fmt.Errorf("unknown profile %q", *previewProfile)
+	}
+
+	previewOutput = w
+	var p *profile
+	withEnv(cfg.Env, func() {
+		p = newProfile(ctx, cfg, *previewProfile, profileCfgs[*previewProfile], make(map[string]search.Searcher))
+	})
+	defer p.close()
+
+	report := p.bot.Run(ctx, *previewKeyword)
+	if report.AllFailed() {
+		return fmt.Errorf("every search failed")
+	}
+	fmt.Fprintf(os.Stderr, "%s; nothing was stored or sent\n", report)
+	return nil
+}
+
+// previewNotifier returns a notifier previewing the messages the named notifier would send.
+func previewNotifier(botType string, notifierCfg config.Notifier) bot.Notifier {
+	switch botType {
+	case "print":
+		return bot.NewPreviewNotifier(botType, mustTemplate(botType, notifierCfg.Template, bot.DefaultPrintTemplate), nil, previewOutput)
+	case "discord":
+		return bot.NewPreviewNotifier(botType, mustTemplate(botType, notifierCfg.Template, bot.DefaultDiscordTemplate), mustMentions(botType, notifierCfg.Mentions), previewOutput)
+	case "slack":
+		return bot.NewPreviewNotifier(botType, mustTemplate(botType, notifierCfg.Template, bot.DefaultSlackTemplate), mustMentions(botType, notifierCfg.Mentions), previewOutput)
+	default:
+		return bot.NewPreviewNotifier(botType, nil, nil, previewOutput)
+	}
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/plugin"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+// profile is a set of keywords monitored with its own searchers, notifiers, and storage.
+type profile struct {
+	name          string
+	searchers     []search.Searcher
+	searcherNames map[search.Searcher]string
+	storer        storage.Storer
+	// archive is the storage archiving snapshots when --archive-db differs from the profile's database
+	archive storage.Storer
+	bot     *bot.Bot
+	// campaign is set for campaign profiles, which only search within their time box.
+	campaign *campaign
+	// keywordStore holds keywords managed with the keywords command, when the storage backend can keep them.
+	keywordStore storage.KeywordStore
+
+	// mu guards the keywords and schedule, which change when the config file is reloaded.
+	mu       sync.RWMutex
+	keywords []string
+	schedule config.Schedule
+	// config is the profile's configuration with its defaults filled in, and notifierConfigs the settings
+	// of each of its notifiers, kept to tell what a reload changes. They are only used by the daemon's jobs.
+	config          config.Profile
+	notifierConfigs map[string]config.Notifier
+	// triage sets result states for the profile's triage buttons, or is nil without them.
+	triage bot.Triager
+	// scheduled are the keywords the daemon has scheduled searches for. Only the daemon's jobs use it.
+	scheduled []string
+}
+
+// profileConfigs returns the profiles defined in the config file, sorted by name, or a single unnamed
+// profile built from flags and the top-level configuration when there are none. The unnamed profile is
+// left out when the config file only defines campaigns, or tenants are loaded, and no keywords are given on
+// the command line.
+func profileConfigs(cfg *config.Config) ([]string, map[string]config.Profile) {
+	if len(cfg.Profiles) == 0 && (len(cfg.Campaigns) > 0 || *tenantsDir != "") && len(*keywords) == 0 && len(*accounts) == 0 {
+		return nil, nil
+	}
+	if len(cfg.Profiles) == 0 {
+		return []string{""}, map[string]config.Profile{"": {TableName: *tableName}}
+	}
+
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, cfg.Profiles
+}
+
+// newProfile initializes a profile's searchers, storage, and notifiers, exiting on invalid configuration.
+// Searchers are shared between profiles through searcherCache so each platform authenticates once.
+func newProfile(ctx context.Context, cfg *config.Config, name string, p config.Profile, searcherCache map[string]search.Searcher) *profile {
+	p = profileDefaults(cfg, name, p)
+
+	logger := log.With("profile", name)
+
+	keywords, err := profileKeywords(p)
+	if err != nil {
+		logger.Fatalf("Invalid keywords: %v", err)
+	}
+
+	// Initialize the storage backend, fanning out to any secondaries
+	storer, err := newStorer(ctx, p.DB, p.TableName)
+	if err != nil {
+		logger.Fatalf("Failed to initialize storage: %v", err)
+	}
+	if len(p.SecondaryDBs) > 0 {
+		var secondaries []storage.Storer
+		for _, secondaryDB := range p.SecondaryDBs {
+			secondary, err := newStorer(ctx, secondaryDB, p.TableName)
+			if err != nil {
+				logger.Fatalf("Failed to initialize secondary storage: %v", err)
+			}
+			secondaries = append(secondaries, secondary)
+		}
+		storer = storage.NewMultiStorer(storer, secondaries...)
+	}
+	if previewOutput != nil {
+		storer = storage.NewReadOnlyStorer(storer)
+	}
+
+	// Initialize searchers, bounding any authentication requests by the search timeout. Tokens they
+	// authenticate with are kept in storage when it can keep them, so restarts reuse them.
+	var searcherOpts []search.Option
+	if tokens, ok := storage.AsTokenStore(storer); ok {
+		searcherOpts = append(searcherOpts, search.WithTokenStore(tokens))
+	}
+	var searchersList []search.Searcher
+	searcherNames := make(map[search.Searcher]string)
+	for _, searcherName := range p.Searchers {
+		searcher, ok := searcherCache[searcherName]
+		if !ok {
+			initCtx, cancel := withTimeout(ctx, *searchTimeout)
+			var err error
+			searcher, err = newSearcher(initCtx, searcherName, searcherOpts...)
+			cancel()
+			if err != nil {
+				logger.Fatalf("Failed to initialize %s searcher: %v", searcherName, err)
+			}
+			searcherCache[searcherName] = searcher
+		}
+		searchersList = append(searchersList, searcher)
+		searcherNames[searcher] = searcherName
+	}
+
+	// Initialize notifiers, with triage buttons setting states in this profile's storage
+	var triage bot.Triager
+	if *triageButtons && previewOutput == nil {
+		if updater, ok := storage.AsUpdater(storer); ok {
+			triage = func(ctx context.Context, platform, url, state string) (bool, error) {
+				_, found, err := storage.SetState(ctx, updater, platform, url, state)
+				return found, err
+			}
+		} else {
+			logger.Warn("Storage backend can't keep result states; notifications won't have triage buttons", "db", p.DB)
+		}
+	}
+	notifiers := make(map[string]bot.Notifier)
+	notifierConfigs := make(map[string]config.Notifier)
+	for _, botType := range p.Bots {
+		if _, ok := notifiers[botType]; ok {
+			continue
+		}
+		notifierCfg := notifierSettings(cfg, p, botType)
+		notifierConfigs[botType] = notifierCfg
+		// Previews show every message as it would be sent, leaving out digests and rate limits
+		if previewOutput != nil {
+			notifiers[botType] = previewNotifier(botType, notifierCfg)
+			continue
+		}
+		notifier, err := newProfileNotifier(ctx, name, botType, notifierCfg, triage)
+		if err != nil {
+			logger.Fatalf("Failed to initialize %s notifier: %v", botType, err)
+		}
+		notifiers[botType] = notifier
+	}
+
+	router, err := bot.NewRouter(p.Routing, p.Bots)
+	if err != nil {
+		logger.Fatalf("Invalid routing configuration: %v", err)
+	}
+
+	priorities, err := bot.NewPrioritizer(p.Priorities)
+	if err != nil {
+		logger.Fatalf("Invalid priority rules: %v", err)
+	}
+
+	filter, err := bot.NewFilter(p.Filters)
+	if err != nil {
+		logger.Fatalf("Invalid filter configuration: %v", err)
+	}
+
+	spam, err := bot.NewSpamFilter(*spamFilter)
+	if err != nil {
+		logger.Fatalf("Invalid spam filter: %v", err)
+	}
+
+	b := bot.NewBot(searchersList, storer, notifiers, router)
+	b.Priorities = priorities
+	b.Filter = filter
+	b.Spam = spam
+	b.PlatformConcurrency = *platformLimit
+	b.SearchTimeout = *searchTimeout
+	b.NotifyTimeout = *notifyTimeout
+	b.DuplicateWindow = *duplicateWindow
+	b.Backfill = *backfill
+	b.BackfillMarkSeen = *backfillMarkSeen
+	b.Since = *since
+	b.SummaryOnly = *runSummary == "only"
+	b.Until = *until
+	b.Engagement = *engagement
+	if *editDetection != "off" && previewOutput == nil {
+		if _, ok := storage.AsUpdater(storer); !ok {
+			logger.Warn("Storage backend can't read back results; edits won't be detected", "db", p.DB)
+		}
+	}
+	b.EditDetection = *editDetection
+	b.EditThreshold = *editThreshold
+	b.KeywordVariants = variantPatterns(searchersList, searcherNames, p.KeywordVariants)
+	if *summarize {
+		summarizer, err := bot.NewOpenAISummarizer(*summarizeModel, *summarizeMin, sharedHTTPClient)
+		if err != nil {
+			logger.Fatalf("Failed to initialize summarizer: %v", err)
+		}
+		b.Summarizer = summarizer
+	}
+	if *unfurl {
+		b.Unfurler = sharedUnfurler()
+	}
+	b.ThreadContext = *threadContext
+	b.Metrics = statsdMetrics(name)
+	b.Events = profileEvents(name)
+	var archiveStorer storage.Storer
+	if *archive && previewOutput == nil {
+		archiveDB, archiveStore := p.DB, storer
+		if *archiveDBType != "" && *archiveDBType != p.DB {
+			archiveStorer, err = newStorer(ctx, *archiveDBType, p.TableName)
+			if err != nil {
+				logger.Fatalf("Failed to initialize archive storage: %v", err)
+			}
+			archiveDB, archiveStore = *archiveDBType, archiveStorer
+		}
+		store, ok := storage.AsArchiver(archiveStore)
+		if !ok {
+			logger.Fatalf("%s storage can't archive results; set --archive-db to sqlite, s3, or gcs", archiveDB)
+		}
+		b.Archiver = bot.NewArchiver(store, *archivePages, sharedHTTPClient)
+	}
+	if *outbox && previewOutput == nil {
+		queue, ok := storage.AsOutbox(storer)
+		if !ok {
+			logger.Warn("Storage backend has no notification outbox; delivering notifications directly", "db", p.DB)
+		} else {
+			b.Outbox = queue
+		}
+	}
+	if *daemon {
+		b.FollowUpThreshold = *followUpThreshold
+		b.FollowUpWindow = *followUpWindow
+		b.DeletionWindow = *deletionWindow
+		b.NotifyDeletions = *notifyDeletions
+	}
+
+	keywordStore, _ := storage.AsKeywordStore(storer)
+	return &profile{
+		name:            name,
+		keywords:        keywords,
+		searchers:       searchersList,
+		searcherNames:   searcherNames,
+		storer:          storer,
+		archive:         archiveStorer,
+		bot:             b,
+		schedule:        p.Schedule,
+		keywordStore:    keywordStore,
+		config:          p,
+		notifierConfigs: notifierConfigs,
+		triage:          triage,
+	}
+}
+
+// profileKeywords returns the keywords a profile searches: its keywords, followed by its watched accounts
+// searched as account keywords.
+func profileKeywords(p config.Profile) ([]string, error) {
+	for _, keyword := range p.Keywords {
+		if _, err := search.ParseQuery(keyword); err != nil {
+			return nil, err
+		}
+	}
+	keywords := slices.Clone(p.Keywords)
+	for _, account := range p.Accounts {
+		keyword := search.AccountPrefix + account
+		if _, ok := search.ParseAccount(keyword); !ok {
+			return nil, fmt.Errorf("invalid account %q: expected <searcher>/<account>", account)
+		}
+		keywords = append(keywords, keyword)
+	}
+	return keywords, nil
+}
+
+// profileDefaults fills in anything a profile doesn't set from the command line flags and the top-level
+// configuration.
+func profileDefaults(cfg *config.Config, name string, p config.Profile) config.Profile {
+	if len(p.Keywords) == 0 {
+		p.Keywords = *keywords
+	}
+	if len(p.Accounts) == 0 {
+		p.Accounts = *accounts
+	}
+	if len(p.Searchers) == 0 {
+		p.Searchers = *searchers
+	}
+	if len(p.Bots) == 0 {
+		p.Bots = *botTypes
+	}
+	if p.DB == "" {
+		p.DB = *dbType
+	}
+	if len(p.SecondaryDBs) == 0 {
+		p.SecondaryDBs = *secondaryDBs
+	}
+	if p.TableName == "" {
+		p.TableName = name
+	}
+	if len(p.Routing.Rules) == 0 && len(p.Routing.DefaultNotifiers) == 0 {
+		p.Routing = cfg.Routing
+	}
+	if len(p.Filters) == 0 {
+		p.Filters = cfg.Filters
+	}
+	if p.Schedule.Default == "" && len(p.Schedule.Searchers) == 0 && len(p.Schedule.Keywords) == 0 {
+		p.Schedule = cfg.Schedule
+	}
+	if len(p.Priorities) == 0 {
+		p.Priorities = cfg.Priorities
+	}
+	if len(p.KeywordVariants) == 0 {
+		p.KeywordVariants = cfg.KeywordVariants
+	}
+	return p
+}
+
+// activeKeywords returns the keywords the profile searches: those given on the command line or in the
+// config file and those added with the keywords command, less any that are paused.
+func (p *profile) activeKeywords(ctx context.Context) ([]string, error) {
+	if p.keywordStore == nil {
+		return p.configuredKeywords(), nil
+	}
+	managed, err := p.keywordStore.ManagedKeywords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading managed keywords: %w", err)
+	}
+
+	keywords := slices.Clone(p.configuredKeywords())
+	now := time.Now()
+	paused := make(map[string]bool)
+	for _, keyword := range managed {
+		if keyword.PausedAt(now) {
+			paused[keyword.Keyword] = true
+		} else if !slices.Contains(keywords, keyword.Keyword) {
+			keywords = append(keywords, keyword.Keyword)
+		}
+	}
+	return slices.DeleteFunc(keywords, func(keyword string) bool { return paused[keyword] }), nil
+}
+
+// keywordsOrConfigured returns the profile's active keywords, falling back to the configured keywords
+// when the managed keywords can't be read.
+func (p *profile) keywordsOrConfigured(ctx context.Context) []string {
+	keywords, err := p.activeKeywords(ctx)
+	if err != nil {
+		log.Warn("Searching configured keywords only", "profile", p.name, "error", err)
+		return p.configuredKeywords()
+	}
+	return keywords
+}
+
+// configuredKeywords returns the keywords given on the command line or in the config file.
+func (p *profile) configuredKeywords() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.keywords
+}
+
+// scheduleConfig returns the profile's configured schedules.
+func (p *profile) scheduleConfig() config.Schedule {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.schedule
+}
+
+// variantPatterns returns the keyword variant patterns of each searcher's platform: those configured for
+// the searcher, or with --keyword-variants, the platform's defaults.
+func variantPatterns(searchers []search.Searcher, names map[search.Searcher]string, configured map[string][]string) map[string][]string {
+	variants := make(map[string][]string)
+	for _, searcher := range searchers {
+		if patterns, ok := configured[names[searcher]]; ok {
+			variants[searcher.Platform()] = patterns
+		} else if *keywordVariants {
+			variants[searcher.Platform()] = search.DefaultVariants[searcher.Platform()]
+		}
+	}
+	return variants
+}
+
+// sharedUnfurler returns the link unfurler shared by every profile, so a link found by several profiles is
+// fetched once.
+var sharedUnfurler = sync.OnceValue(func() *bot.OpenGraphUnfurler {
+	return bot.NewOpenGraphUnfurler(*unfurlCacheTTL, sharedHTTPClient)
+})
+
+// notifierSettings returns a profile's settings for a notifier, or the top-level settings if it has none.
+func notifierSettings(cfg *config.Config, p config.Profile, botType string) config.Notifier {
+	if notifierCfg, ok := p.Notifiers[botType]; ok {
+		return notifierCfg
+	}
+	return cfg.Notifiers[botType]
+}
+
+// newProfileNotifier creates a notifier for the named profile from its settings, wrapped in its rate limit
+// and digests. Its triage buttons set states with triage, when set.
+func newProfileNotifier(ctx context.Context, name, botType string, notifierCfg config.Notifier, triage bot.Triager) (bot.Notifier, error) {
+	mentions, err := bot.ParseMentions(notifierCfg.Mentions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mentions: %w", err)
+	}
+	opts := []bot.NotifierOption{
+		bot.WithMentions(mentions),
+		bot.WithChannels(notifierCfg.Channels),
+		bot.WithHTTPClient(sharedHTTPClient),
+	}
+	if triage != nil {
+		opts = append(opts, bot.WithTriage(name, triage))
+	}
+	if notifierCfg.Template != "" {
+		tmpl, err := bot.ParseTemplate(botType, notifierCfg.Template, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid message template: %w", err)
+		}
+		opts = append(opts, bot.WithTemplate(tmpl))
+	}
+	if notifierCfg.DigestTemplate != "" {
+		tmpl, err := bot.ParseTemplate(botType+" digest", notifierCfg.DigestTemplate, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid message template: %w", err)
+		}
+		opts = append(opts, bot.WithDigestTemplate(tmpl))
+	}
+	// Keep stdout for the JSON run report
+	if *runOutput == "json" {
+		opts = append(opts, bot.WithOutput(os.Stderr))
+	}
+	initCtx, cancel := withTimeout(ctx, *notifyTimeout)
+	notifier, err := bot.NewNotifier(initCtx, botType, opts...)
+	cancel()
+	switch {
+	case errors.Is(err, bot.ErrUnknownNotifier):
+		pluginNotifier, err := plugin.NewNotifier(pluginDirectory(), botType)
+		if err != nil {
+			return nil, fmt.Errorf("unknown bot type: %w", err)
+		}
+		notifier = pluginNotifier
+	case err != nil:
+		return nil, err
+	}
+
+	limit, window, overflow, throttled, err := throttleSettings(botType, notifierCfg)
+	if err != nil {
+		return nil, err
+	}
+	if throttled {
+		notifier, err = bot.NewThrottleNotifier(notifier, limit, window, overflow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit: %w", err)
+		}
+	}
+	digestWindow, digested, err := digestSettings(notifierCfg)
+	if err != nil {
+		return nil, err
+	}
+	if digested {
+		notifier = bot.NewDigestNotifier(notifier, digestWindow)
+	}
+	return notifier, nil
+}
+
+// digestSettings reports whether a notifier batches its results into digests and how long it collects
+// them for. The notifier's config overrides --digest and --digest-window.
+func digestSettings(notifierCfg config.Notifier) (time.Duration, bool, error) {
+	window := *digestWindow
+	if notifierCfg.DigestWindow != "" {
+		parsed, err := time.ParseDuration(notifierCfg.DigestWindow)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid digest_window: %w", err)
+		}
+		window = parsed
+	}
+	return window, *digest || notifierCfg.Digest || window > 0, nil
+}
+
+// throttleSettings reports whether a notifier's messages are capped, and the cap, window, and overflow
+// behaviour. The notifier's config overrides --rate-limit, --rate-window, and --rate-overflow. The
+// elasticsearch notifier indexes rather than messages, so only its own config caps it.
+func throttleSettings(botType string, notifierCfg config.Notifier) (int, time.Duration, string, bool, error) {
+	limit := *rateLimit
+	if botType == "elasticsearch" {
+		limit = 0
+	}
+	if notifierCfg.RateLimit != 0 {
+		limit = notifierCfg.RateLimit
+	}
+	window := *rateWindow
+	if notifierCfg.RateWindow != "" {
+		parsed, err := time.ParseDuration(notifierCfg.RateWindow)
+		if err != nil {
+			return 0, 0, "", false, fmt.Errorf("invalid rate_window: %w", err)
+		}
+		window = parsed
+	}
+	overflow := *rateOverflow
+	if notifierCfg.RateOverflow != "" {
+		overflow = notifierCfg.RateOverflow
+	}
+	return limit, window, overflow, limit > 0, nil
+}
+
+// close sends any buffered digests and releases the profile's storage.
+func (p *profile) close() {
+	p.bot.FlushDigests(context.Background())
+	if closer, ok := p.storer.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Printf("Failed to close storage for profile %q: %v", p.name, err)
+		}
+	}
+	if closer, ok := p.archive.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Printf("Failed to close archive storage for profile %q: %v", p.name, err)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: grass/v1/grass.proto
+
+package grassv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GrassService_ListProfiles_FullMethodName   = "/grass.v1.GrassService/ListProfiles"
+	GrassService_QueryResults_FullMethodName   = "/grass.v1.GrassService/QueryResults"
+	GrassService_StreamResults_FullMethodName  = "/grass.v1.GrassService/StreamResults"
+	GrassService_Search_FullMethodName         = "/grass.v1.GrassService/Search"
+	GrassService_SetResultState_FullMethodName = "/grass.v1.GrassService/SetResultState"
+)
+
+// GrassServiceClient is the client API for GrassService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// GrassService is served by grass serve --grpc. Every call must carry the serve token as a bearer token in
+// its authorization metadata. Calls that take a profile use the unnamed profile when it is empty.
+type GrassServiceClient interface {
+	// ListProfiles lists the profiles, campaigns, and tenant profiles being served.
+	ListProfiles(ctx context.Context, in *ListProfilesRequest, opts ...grpc.CallOption) (*ListProfilesResponse, error)
+	// QueryResults returns stored results, newest first.
+	QueryResults(ctx context.Context, in *QueryResultsRequest, opts ...grpc.CallOption) (*QueryResultsResponse, error)
+	// StreamResults sends results as they are saved by any run, search, or ingestion until the call is
+	// cancelled. Results saved before the call aren't sent; use QueryResults to catch up.
+	StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamResultsResponse], error)
+	// Search searches every platform for a keyword now, storing and notifying new results.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// SetResultState sets the state of a stored result, to track which results have been handled.
+	SetResultState(ctx context.Context, in *SetResultStateRequest, opts ...grpc.CallOption) (*SetResultStateResponse, error)
+}
+
+type grassServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGrassServiceClient(cc grpc.ClientConnInterface) GrassServiceClient {
+	return &grassServiceClient{cc}
+}
+
+func (c *grassServiceClient) ListProfiles(ctx context.Context, in *ListProfilesRequest, opts ...grpc.CallOption) (*ListProfilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListProfilesResponse)
+	err := c.cc.Invoke(ctx, GrassService_ListProfiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *grassServiceClient) QueryResults(ctx context.Context, in *QueryResultsRequest, opts ...grpc.CallOption) (*QueryResultsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryResultsResponse)
+	err := c.cc.Invoke(ctx, GrassService_QueryResults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *grassServiceClient) StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamResultsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GrassService_ServiceDesc.Streams[0], GrassService_StreamResults_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamResultsRequest, StreamResultsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GrassService_StreamResultsClient = grpc.ServerStreamingClient[StreamResultsResponse]
+
+func (c *grassServiceClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, GrassService_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *grassServiceClient) SetResultState(ctx context.Context, in *SetResultStateRequest, opts ...grpc.CallOption) (*SetResultStateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetResultStateResponse)
+	err := c.cc.Invoke(ctx, GrassService_SetResultState_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GrassServiceServer is the server API for GrassService service.
+// All implementations must embed UnimplementedGrassServiceServer
+// for forward compatibility.
+//
+// GrassService is served by grass serve --grpc. Every call must carry the serve token as a bearer token in
+// its authorization metadata. Calls that take a profile use the unnamed profile when it is empty.
+type GrassServiceServer interface {
+	// ListProfiles lists the profiles, campaigns, and tenant profiles being served.
+	ListProfiles(context.Context, *ListProfilesRequest) (*ListProfilesResponse, error)
+	// QueryResults returns stored results, newest first.
+	QueryResults(context.Context, *QueryResultsRequest) (*QueryResultsResponse, error)
+	// StreamResults sends results as they are saved by any run, search, or ingestion until the call is
+	// cancelled. Results saved before the call aren't sent; use QueryResults to catch up.
+	StreamResults(*StreamResultsRequest, grpc.ServerStreamingServer[StreamResultsResponse]) error
+	// Search searches every platform for a keyword now, storing and notifying new results.
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// SetResultState sets the state of a stored result, to track which results have been handled.
+	SetResultState(context.Context, *SetResultStateRequest) (*SetResultStateResponse, error)
+	mustEmbedUnimplementedGrassServiceServer()
+}
+
+// UnimplementedGrassServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGrassServiceServer struct{}
+
+func (UnimplementedGrassServiceServer) ListProfiles(context.Context, *ListProfilesRequest) (*ListProfilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProfiles not implemented")
+}
+func (UnimplementedGrassServiceServer) QueryResults(context.Context, *QueryResultsRequest) (*QueryResultsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryResults not implemented")
+}
+func (UnimplementedGrassServiceServer) StreamResults(*StreamResultsRequest, grpc.ServerStreamingServer[StreamResultsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamResults not implemented")
+}
+func (UnimplementedGrassServiceServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedGrassServiceServer) SetResultState(context.Context, *SetResultStateRequest) (*SetResultStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetResultState not implemented")
+}
+func (UnimplementedGrassServiceServer) mustEmbedUnimplementedGrassServiceServer() {}
+func (UnimplementedGrassServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeGrassServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GrassServiceServer will
+// result in compilation errors.
+type UnsafeGrassServiceServer interface {
+	mustEmbedUnimplementedGrassServiceServer()
+}
+
+func RegisterGrassServiceServer(s grpc.ServiceRegistrar, srv GrassServiceServer) {
+	// If the following call pancis, it indicates UnimplementedGrassServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GrassService_ServiceDesc, srv)
+}
+
+func _GrassService_ListProfiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProfilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrassServiceServer).ListProfiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GrassService_ListProfiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrassServiceServer).ListProfiles(ctx, req.(*ListProfilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GrassService_QueryResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryResultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrassServiceServer).QueryResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GrassService_QueryResults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrassServiceServer).QueryResults(ctx, req.(*QueryResultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GrassService_StreamResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GrassServiceServer).StreamResults(m, &grpc.GenericServerStream[StreamResultsRequest, StreamResultsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GrassService_StreamResultsServer = grpc.ServerStreamingServer[StreamResultsResponse]
+
+func _GrassService_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrassServiceServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GrassService_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrassServiceServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GrassService_SetResultState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetResultStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrassServiceServer).SetResultState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GrassService_SetResultState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrassServiceServer).SetResultState(ctx, req.(*SetResultStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GrassService_ServiceDesc is the grpc.ServiceDesc for GrassService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GrassService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "grass.v1.GrassService",
+	HandlerType: (*GrassServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListProfiles",
+			Handler:    _GrassService_ListProfiles_Handler,
+		},
+		{
+			MethodName: "QueryResults",
+			Handler:    _GrassService_QueryResults_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _GrassService_Search_Handler,
+		},
+		{
+			MethodName: "SetResultState",
+			Handler:    _GrassService_SetResultState_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamResults",
+			Handler:       _GrassService_StreamResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "grass/v1/grass.proto",
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/grass/bot"
+)
+
+var (
+	pushgatewayURL    = runCommand.Flag("pushgateway-url", "Push the metrics of a one-shot run to this Prometheus Pushgateway before exiting, e.g. http://pushgateway:9091").Envar("GRASS_PUSHGATEWAY_URL").String()
+	pushgatewayJob    = runCommand.Flag("pushgateway-job", "Job label the run's metrics are pushed under").Envar("GRASS_PUSHGATEWAY_JOB").Default("grass").String()
+	pushgatewayLabels = runCommand.Flag("pushgateway-label", "Extra grouping label for the pushed metrics, as <name>=<value>, e.g. instance=cron1 (repeatable)").StringMap()
+)
+
+// pushTimeout bounds pushing a run's metrics.
+const pushTimeout = 15 * time.Second
+
+// metricLabelEscaper escapes label values for the Prometheus text format.
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// pushMetrics pushes the metrics of a one-shot run to the Pushgateway at --pushgateway-url. Successful runs
+// replace the group's metrics. Failed runs only replace the metrics they report, keeping the group's last
+// success timestamp, so alerts can fire when grass hasn't succeeded for a while.
+func pushMetrics(ctx context.Context, report *bot.RunReport) error {
+	endpoint := strings.TrimSuffix(*pushgatewayURL, "/") + "/metrics/job/" + url.PathEscape(*pushgatewayJob)
+	names := make([]string, 0, len(*pushgatewayLabels))
+	for name := range *pushgatewayLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		endpoint += "/" + url.PathEscape(name) + "/" + url.PathEscape((*pushgatewayLabels)[name])
+	}
+
+	failed := report.AllFailed()
+	method := http.MethodPut
+	if failed {
+		method = http.MethodPost
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(runMetrics(report, time.Now())))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := sharedHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// runMetrics renders a run's metrics in the Prometheus text format, as of now. The last success timestamp
+// is only included when the run didn't fail.
+func runMetrics(report *bot.RunReport, now time.Time) []byte {
+	var b bytes.Buffer
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	platforms := report.Platforms()
+	perPlatform := func(name, help string, value func(bot.PlatformReport) float64) {
+		metric(name, "gauge", help)
+		for _, p := range platforms {
+			fmt.Fprintf(&b, "%s{platform=\"%s\"} %g\n", name, metricLabelEscaper.Replace(p.Platform), value(p))
+		}
+	}
+	perPlatform("grass_run_searches", "Searches run by the last run.", func(p bot.PlatformReport) float64 { return float64(p.Searches) })
+	perPlatform("grass_run_search_errors", "Searches of the last run that failed.", func(p bot.PlatformReport) float64 { return float64(p.Failed) })
+	perPlatform("grass_run_results_found", "Results returned by the searches of the last run.", func(p bot.PlatformReport) float64 { return float64(p.Found) })
+	perPlatform("grass_run_results_new", "New results saved by the last run.", func(p bot.PlatformReport) float64 { return float64(p.New) })
+	perPlatform("grass_run_platform_duration_seconds", "Time the last run spent searching each platform.", func(p bot.PlatformReport) float64 { return p.Duration.Seconds() })
+
+	failed := 0
+	if report.AllFailed() {
+		failed = 1
+	}
+	metric("grass_run_failed", "gauge", "Whether every search of the last run failed.")
+	fmt.Fprintf(&b, "grass_run_failed %d\n", failed)
+	metric("grass_run_duration_seconds", "gauge", "How long the last run took.")
+	fmt.Fprintf(&b, "grass_run_duration_seconds %g\n", report.Duration.Seconds())
+	metric("grass_last_run_timestamp_seconds", "gauge", "When the last run finished, in Unix seconds.")
+	fmt.Fprintf(&b, "grass_last_run_timestamp_seconds %d\n", now.Unix())
+	if failed == 0 {
+		metric("grass_last_success_timestamp_seconds", "gauge", "When the last run that didn't fail finished, in Unix seconds.")
+		fmt.Fprintf(&b, "grass_last_success_timestamp_seconds %d\n", now.Unix())
+	}
+	return b.Bytes()
+}
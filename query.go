@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	runCommand     = kingpin.Command("run", "Search for keywords and notify new results (the default)").Default()
+	queryCommand   = kingpin.Command("query", "List stored results, newest first, filtered by --keyword, --since, --until, and --state")
+	queryPlatforms = queryCommand.Flag("platform", "Only list results from this platform, e.g. HackerNews (repeatable)").Strings()
+	queryStates    = queryCommand.Flag("state", "Only list results in this state: new, acknowledged, escalated, dismissed, or actioned (repeatable)").Enums(storage.States...)
+	queryProfile   = queryCommand.Flag("profile", "Query the storage of this profile from the config file instead of --db and --table-name").String()
+	queryOutput    = queryCommand.Flag("output", "Output format: table or json").Default("table").Enum("table", "json")
+	queryLimit     = queryCommand.Flag("limit", "Number of results per page (0 lists every result)").Default("50").Int()
+	queryPage      = queryCommand.Flag("page", "Page of results to list, starting from 1").Default("1").Int()
+)
+
+// queryTitleWidth is the most characters of a title shown in table output.
+const queryTitleWidth = 60
+
+// runQuery lists the stored results selected by the query flags to w, reading them from the profile's
+// primary storage.
+func runQuery(ctx context.Context, cfg *config.Config, location *time.Location, w io.Writer) error {
+	if *queryPage < 1 {
+		return fmt.Errorf("--page must be at least 1")
+	}
+
+	storer, db, err := openStorage(ctx, cfg, *queryProfile)
+	if err != nil {
+		return err
+	}
+	if closer, ok := storer.(io.Closer); ok {
+		defer closer.Close()
+	}
+	querier, ok := storage.AsQuerier(storer)
+	if !ok {
+		return fmt.Errorf("%s storage can't be queried", db)
+	}
+
+	results, err := querier.Query(ctx, storage.Query{
+		Platforms: *queryPlatforms,
+		Keywords:  *keywords,
+		States:    *queryStates,
+		Since:     *since,
+		Until:     *until,
+		Offset:    (*queryPage - 1) * max(*queryLimit, 0),
+		Limit:     *queryLimit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *queryOutput == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if results == nil {
+			results = []search.SearchResult{}
+		}
+		return encoder.Encode(results)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POSTED\tPLATFORM\tKEYWORD\tSTATE\tSCORE\tTITLE\tURL")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			time.Unix(result.Timestamp, 0).In(location).Format(*timeFormat),
+			result.Platform, result.Keyword, storage.ResultState(result), result.Score, queryTitle(result.Title), result.URL)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// A full page may be followed by more
+	if *queryLimit > 0 && len(results) == *queryLimit {
+		fmt.Fprintf(os.Stderr, "More results may follow; list them with --page=%d\n", *queryPage+1)
+	}
+	return nil
+}
+
+// openStorage opens the storage of a config file profile, or the storage selected by --db and --table-name
+// when profile is empty, returning it with its database type.
+func openStorage(ctx context.Context, cfg *config.Config, profile string) (storage.Storer, string, error) {
+	db, table := *dbType, *tableName
+	if profile != "" {
+		p, ok := cfg.Profiles[profile]
+		if !ok {
+			return nil, "", fmt.Errorf("unknown profile %q", profile)
+		}
+		if p.DB != "" {
+			db = p.DB
+		}
+		table = p.TableName
+		if table == "" {
+			table = profile
+		}
+	}
+
+	storer, err := newStorer(ctx, db, table)
+	if err != nil {
+		return nil, "", err
+	}
+	return storer, db, nil
+}
+
+// queryTitle fits a title on one line of table output.
+func queryTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if runes := []rune(title); len(runes) > queryTitleWidth {
+		return string(runes[:queryTitleWidth-1]) + "…"
+	}
+	return title
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/internal/scheduler"
+	"github.com/jaxxstorm/grass/search"
+)
+
+var watchConfig = kingpin.Flag("watch-config", "In daemon mode, reload the config file whenever it changes, as well as on SIGHUP").Envar("GRASS_WATCH_CONFIG").Bool()
+
+// configCheckInterval is how often the daemon checks whether the config file should be reloaded.
+const configCheckInterval = 5 * time.Second
+
+// configReloader reloads the daemon's config file on SIGHUP and, with --watch-config, when its contents
+// change. Keywords, schedules, filters, routing, priorities, keyword variants, and notifiers are updated in
+// place; searchers and storage keep the connections they started with.
+type configReloader struct {
+	path  string
+	watch bool
+	// hangup is set on SIGHUP and cleared when the daemon's config job picks it up.
+	hangup atomic.Bool
+
+	// sum is the SHA-256 of the file contents last loaded, and cfg the configuration last applied. Only
+	// the config job uses them.
+	sum [sha256.Size]byte
+	cfg *config.Config
+}
+
+// reloads reloads the daemon's config file, or is nil when there is none.
+var reloads *configReloader
+
+// startReloads reloads the config file at path, from which cfg was loaded, while the daemon runs. SIGHUP is
+// handled until ctx is cancelled.
+func startReloads(ctx context.Context, path string, cfg *config.Config) error {
+	sum, err := fileSum(path)
+	if err != nil {
+		return err
+	}
+	r := &configReloader{path: path, watch: *watchConfig, sum: sum, cfg: cfg}
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hangups)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangups:
+				log.Info("Received SIGHUP; reloading the config file", "path", path)
+				r.hangup.Store(true)
+			}
+		}
+	}()
+
+	reloads = r
+	log.Debug("Reloading the config file on SIGHUP", "path", path, "watch", r.watch)
+	return nil
+}
+
+// check reloads the config file if SIGHUP was received or, with --watch-config, its contents changed. An
+// invalid file is logged and the previous configuration kept until the file changes again.
+func (r *configReloader) check(ctx context.Context, sched *scheduler.Scheduler, profiles []*profile) {
+	hangup := r.hangup.Swap(false)
+	if !hangup && !r.watch {
+		return
+	}
+	sum, err := fileSum(r.path)
+	if err != nil {
+		log.Error("Failed to read the config file", "path", r.path, "error", err)
+		return
+	}
+	if !hangup && sum == r.sum {
+		return
+	}
+	r.sum = sum
+
+	if err := r.reload(ctx, sched, profiles); err != nil {
+		log.Error("Keeping the previous configuration", "path", r.path, "error", err)
+		return
+	}
+	log.Info("Reloaded the config file", "path", r.path)
+}
+
+// reload loads the config file and applies it to the profiles and campaigns it defines. Nothing is applied
+// unless every one of them is valid.
+func (r *configReloader) reload(ctx context.Context, sched *scheduler.Scheduler, profiles []*profile) error {
+	cfg, err := config.Load(r.path)
+	if err != nil {
+		return err
+	}
+	if err := resolveSecrets(cfg.Env); err != nil {
+		return fmt.Errorf("failed to resolve secret: %w", err)
+	}
+	if err := loadKeywordsFile(cfg, *keywordsFile); err != nil {
+		return fmt.Errorf("invalid --keywords-file: %w", err)
+	}
+
+	names, configs, err := reloadConfigs(cfg)
+	if err != nil {
+		return err
+	}
+	previous, _, _ := reloadConfigs(r.cfg)
+	byName := make(map[string]*profile)
+	for _, p := range profiles {
+		if slices.Contains(previous, p.name) {
+			byName[p.name] = p
+		}
+	}
+
+	var pending []*profileReload
+	withEnv(cfg.Env, func() {
+		for _, name := range names {
+			p, ok := byName[name]
+			if !ok {
+				continue
+			}
+			var reload *profileReload
+			reload, err = p.prepareReload(ctx, cfg, configs[name])
+			if err != nil {
+				err = fmt.Errorf("%s: %w", describeProfile(p), err)
+				return
+			}
+			pending = append(pending, reload)
+		}
+	})
+	if err != nil {
+		for _, reload := range pending {
+			reload.discard()
+		}
+		return err
+	}
+
+	for _, name := range names {
+		if _, ok := byName[name]; !ok {
+			log.Warn("Restart to start the added profile or campaign", "profile", name)
+		}
+	}
+	for _, name := range previous {
+		if _, ok := configs[name]; !ok {
+			log.Warn("Restart to stop the removed profile or campaign", "profile", name)
+		}
+	}
+	if !reflect.DeepEqual(cfg.Env, r.cfg.Env) {
+		log.Warn("Restart for searchers and storage to use the changed env; new notifiers already do")
+	}
+	if cfg.Log != r.cfg.Log {
+		log.Warn("Restart to apply the changed logging settings")
+	}
+	for _, name := range campaignNames(cfg) {
+		old, ok := r.cfg.Campaigns[name]
+		if c := cfg.Campaigns[name]; ok && (c.Start != old.Start || c.End != old.End) {
+			log.Warn("Restart to apply the changed campaign dates", "campaign", name)
+		}
+	}
+
+	for _, reload := range pending {
+		reload.apply(ctx, sched)
+	}
+	r.cfg = cfg
+	return nil
+}
+
+// reloadConfigs returns the names of the profiles and campaigns defined by cfg, sorted with the profiles
+// first, and their configurations, with campaign tables defaulted as when they were created.
+func reloadConfigs(cfg *config.Config) ([]string, map[string]config.Profile, error) {
+	names, profileCfgs := profileConfigs(cfg)
+	configs := make(map[string]config.Profile)
+	for _, name := range names {
+		configs[name] = profileCfgs[name]
+	}
+	for _, name := range campaignNames(cfg) {
+		c := cfg.Campaigns[name]
+		if _, ok := configs[name]; ok {
+			return nil, nil, fmt.Errorf("campaign %q has the same name as a profile", name)
+		}
+		if len(c.Keywords) == 0 {
+			return nil, nil, fmt.Errorf("campaign %q has no keywords", name)
+		}
+		if c.TableName == "" {
+			c.TableName = "campaign-" + name
+		}
+		names = append(names, name)
+		configs[name] = c.Profile
+	}
+	return names, configs, nil
+}
+
+// describeProfile names a profile or campaign in errors.
+func describeProfile(p *profile) string {
+	switch {
+	case p.campaign != nil:
+		return fmt.Sprintf("campaign %q", p.name)
+	case p.name == "":
+		return "configuration"
+	default:
+		return fmt.Sprintf("profile %q", p.name)
+	}
+}
+
+// profileReload is a profile's reloaded configuration, checked and ready to apply.
+type profileReload struct {
+	p               *profile
+	config          config.Profile
+	keywords        []string
+	settings        bot.Settings
+	notifierConfigs map[string]config.Notifier
+	// created are the notifiers created for the reload, closed if it is discarded.
+	created []bot.Notifier
+}
+
+// prepareReload checks a profile's reloaded configuration and creates the notifiers whose settings changed,
+// reusing the others. Settings that can't change without a restart are logged and left as they were.
+func (p *profile) prepareReload(ctx context.Context, cfg *config.Config, pc config.Profile) (*profileReload, error) {
+	pc = profileDefaults(cfg, p.name, pc)
+	for _, setting := range restartSettings(p.config, pc) {
+		log.Warn("Restart to apply the changed setting", "profile", p.name, "setting", setting)
+	}
+	pc.Searchers, pc.DB, pc.SecondaryDBs, pc.TableName = p.config.Searchers, p.config.DB, p.config.SecondaryDBs, p.config.TableName
+
+	keywords, err := profileKeywords(pc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keywords: %w", err)
+	}
+	for _, expr := range scheduleExprs(pc.Schedule) {
+		if _, err := scheduler.Parse(expr); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+		}
+	}
+	router, err := bot.NewRouter(pc.Routing, pc.Bots)
+	if err != nil {
+		return nil, fmt.Errorf("invalid routing configuration: %w", err)
+	}
+	priorities, err := bot.NewPrioritizer(pc.Priorities)
+	if err != nil {
+		return nil, fmt.Errorf("invalid priority rules: %w", err)
+	}
+	filter, err := bot.NewFilter(pc.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter configuration: %w", err)
+	}
+
+	reload := &profileReload{p: p, config: pc, keywords: keywords, notifierConfigs: make(map[string]config.Notifier)}
+	current := p.bot.CurrentNotifiers()
+	notifiers := make(map[string]bot.Notifier)
+	for _, botType := range pc.Bots {
+		if _, ok := notifiers[botType]; ok {
+			continue
+		}
+		notifierCfg := notifierSettings(cfg, pc, botType)
+		reload.notifierConfigs[botType] = notifierCfg
+		if old, ok := p.notifierConfigs[botType]; ok && current[botType] != nil && reflect.DeepEqual(old, notifierCfg) {
+			notifiers[botType] = current[botType]
+			continue
+		}
+		notifier, err := newProfileNotifier(ctx, p.name, botType, notifierCfg, p.triage)
+		if err != nil {
+			reload.discard()
+			return nil, fmt.Errorf("failed to initialize %s notifier: %w", botType, err)
+		}
+		notifiers[botType] = notifier
+		reload.created = append(reload.created, notifier)
+	}
+
+	reload.settings = bot.Settings{
+		Notifiers:       notifiers,
+		Router:          router,
+		Priorities:      priorities,
+		Filter:          filter,
+		KeywordVariants: variantPatterns(p.searchers, p.searcherNames, pc.KeywordVariants),
+	}
+	return reload, nil
+}
+
+// discard closes the notifiers created for a reload that won't be applied.
+func (r *profileReload) discard() {
+	for _, notifier := range r.created {
+		if err := bot.CloseNotifier(notifier); err != nil {
+			log.Warn("Error closing notifier", "profile", r.p.name, "error", err)
+		}
+	}
+}
+
+// apply reconfigures the profile's bot and updates its keywords and schedules, scheduling or removing the
+// searches for keywords added or removed and rescheduling those whose schedule changed. Streaming searchers
+// keep the keywords they started with.
+func (r *profileReload) apply(ctx context.Context, sched *scheduler.Scheduler) {
+	p := r.p
+	p.bot.Reconfigure(ctx, r.settings)
+
+	p.mu.Lock()
+	previous := p.schedule
+	p.keywords, p.schedule = r.keywords, r.config.Schedule
+	p.mu.Unlock()
+	p.config, p.notifierConfigs = r.config, r.notifierConfigs
+
+	scheduled := p.scheduled
+	p.scheduled = syncKeywords(ctx, sched, p, scheduled)
+	for _, keyword := range p.scheduled {
+		if !slices.Contains(scheduled, keyword) {
+			continue
+		}
+		for _, provider := range p.searchers {
+			if _, ok := provider.(search.StreamingSearcher); ok {
+				continue
+			}
+			name := p.searcherNames[provider]
+			if scheduleFor(previous, name, keyword) == scheduleFor(r.config.Schedule, name, keyword) {
+				continue
+			}
+			sched.Remove(p.jobPrefix() + name + ":" + keyword)
+			if err := scheduleSearch(sched, p, provider, keyword); err != nil {
+				log.Error("Failed to reschedule search", "profile", p.name, "searcher", name, "keyword", keyword, "error", err)
+			}
+		}
+	}
+}
+
+// restartSettings returns the names of the settings that differ between a profile's old and new
+// configurations but only take effect on restart.
+func restartSettings(old, updated config.Profile) []string {
+	var changed []string
+	if !slices.Equal(old.Searchers, updated.Searchers) {
+		changed = append(changed, "searchers")
+	}
+	if old.DB != updated.DB {
+		changed = append(changed, "db")
+	}
+	if !slices.Equal(old.SecondaryDBs, updated.SecondaryDBs) {
+		changed = append(changed, "secondary_dbs")
+	}
+	if old.TableName != updated.TableName {
+		changed = append(changed, "table_name")
+	}
+	return changed
+}
+
+// scheduleExprs returns every schedule expression in cfg.
+func scheduleExprs(cfg config.Schedule) []string {
+	var exprs []string
+	if cfg.Default != "" {
+		exprs = append(exprs, cfg.Default)
+	}
+	for _, name := range sortedKeys(cfg.Searchers) {
+		exprs = append(exprs, cfg.Searchers[name])
+	}
+	for _, keyword := range sortedKeys(cfg.Keywords) {
+		exprs = append(exprs, cfg.Keywords[keyword])
+	}
+	return exprs
+}
+
+// fileSum returns the SHA-256 of the contents of the file at path.
+func fileSum(path string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	replayCommand   = kingpin.Command("replay", "Send stored results, filtered by --keyword, --since, and --until, through the notifiers again")
+	replayPlatforms = replayCommand.Flag("platform", "Only replay results from this platform, e.g. HackerNews (repeatable)").Strings()
+	replayTo        = replayCommand.Flag("to", "Only send to this notifier instead of routing each result (repeatable)").Strings()
+	replayProfile   = replayCommand.Flag("profile", "Replay the results and notifiers of this profile from the config file").String()
+	replayDryRun    = replayCommand.Flag("dry-run", "List the results that would be replayed without sending them").Bool()
+)
+
+// runReplay sends the stored results selected by the replay flags through the profile's notifiers, oldest
+// first, or lists them to w with --dry-run.
+func runReplay(ctx context.Context, cfg *config.Config, location *time.Location, w io.Writer) error {
+	names, profileCfgs := profileConfigs(cfg)
+	if !slices.Contains(names, *replayProfile) {
+		if *replayProfile == "" {
+			return fmt.Errorf("the config file defines profiles; choose one with --profile")
+		}
+		return fmt.Errorf("unknown profile %q", *replayProfile)
+	}
+
+	var p *profile
+	withEnv(cfg.Env, func() {
+		p = newProfile(ctx, cfg, *replayProfile, profileCfgs[*replayProfile], make(map[string]search.Searcher))
+	})
+	defer p.close()
+	querier, ok := storage.AsQuerier(p.storer)
+	if !ok {
+		return fmt.Errorf("the profile's storage can't be queried")
+	}
+
+	results, err := querier.Query(ctx, storage.Query{
+		Platforms: *replayPlatforms,
+		Keywords:  *keywords,
+		Since:     *since,
+		Until:     *until,
+	})
+	if err != nil {
+		return err
+	}
+	slices.Reverse(results)
+
+	if *replayDryRun {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "POSTED\tPLATFORM\tKEYWORD\tTITLE\tURL")
+		for _, result := range results {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				time.Unix(result.Timestamp, 0).In(location).Format(*timeFormat),
+				result.Platform, result.Keyword, queryTitle(result.Title), result.URL)
+		}
+		fmt.Fprintf(tw, "\n%d results would be replayed\n", len(results))
+		return tw.Flush()
+	}
+
+	sent, err := p.bot.Replay(ctx, results, *replayTo)
+	log.Info("Replayed results", "profile", p.name, "results", len(results), "sent", sent)
+	return err
+}
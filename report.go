@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var runOutput = runCommand.Flag("output", "Output of a one-shot run: text logs only, or also a JSON report of new results and per-platform counts on stdout, with the print notifier writing to stderr").Envar("GRASS_OUTPUT").Default("text").Enum("text", "json")
+
+// runReport is the JSON form of a bot.RunReport: a run's counts as kept in run history, and the new
+// results it saved. It is written with --output=json and returned by the API's search endpoint.
+type runReport struct {
+	storage.Run
+	Results []search.SearchResult `json:"results"`
+}
+
+// newRun converts a report into a run history entry.
+func newRun(report *bot.RunReport, profile, trigger string) storage.Run {
+	run := storage.Run{
+		Profile:   profile,
+		Trigger:   trigger,
+		Started:   report.Started,
+		Duration:  report.Duration.Seconds(),
+		Failed:    report.AllFailed(),
+		Totals:    newRunPlatform(report.Totals()),
+		Platforms: []storage.RunPlatform{},
+	}
+	for _, p := range report.Platforms() {
+		run.Platforms = append(run.Platforms, newRunPlatform(p))
+	}
+	return run
+}
+
+func newRunPlatform(p bot.PlatformReport) storage.RunPlatform {
+	errs := make([]string, 0, len(p.Errors))
+	for _, err := range p.Errors {
+		errs = append(errs, err.Error())
+	}
+	return storage.RunPlatform{
+		Platform: p.Platform,
+		Searches: p.Searches,
+		Failed:   p.Failed,
+		Found:    p.Found,
+		New:      p.New,
+		Skipped:  p.Skipped,
+		Duration: p.Duration.Seconds(),
+		Errors:   errs,
+	}
+}
+
+// newRunReport converts a report and its new results for output.
+func newRunReport(report *bot.RunReport, profile, trigger string) runReport {
+	out := runReport{Run: newRun(report, profile, trigger), Results: report.Results()}
+	if out.Results == nil {
+		out.Results = []search.SearchResult{}
+	}
+	return out
+}
+
+// writeRunReport writes report to w as JSON. Failed is set when every search failed, as with the exit status.
+func writeRunReport(w io.Writer, report *bot.RunReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(newRunReport(report, "", "run"))
+}
+
+// recordRun adds a profile's run to the run history in its storage, when the backend keeps one.
+func recordRun(ctx context.Context, p *profile, report *bot.RunReport, trigger string) {
+	recorder, ok := storage.AsRunRecorder(p.storer)
+	if !ok {
+		return
+	}
+	if err := recorder.RecordRun(ctx, newRun(report, p.name, trigger)); err != nil {
+		log.Warn("Failed to record run history", "profile", p.name, "error", err)
+	}
+}
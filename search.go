@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jaxxstorm/grass/search"
+)
+
+var (
+	searchCommand = kingpin.Command("search", "Run one searcher for a keyword and print its results, without storing or notifying them")
+	searchName    = searchCommand.Arg("searcher", "Searcher to run: hackernews, reddit, bluesky, fediverse, youtube, or a searcher plugin").Required().String()
+	searchKeyword = searchCommand.Arg("keyword", "Keyword to search for, or from:<searcher>/<account> to list an account's posts").Required().String()
+	searchWindow  = searchCommand.Flag("window", "Search for results posted in this long before now, unless --since is set").Default("24h").Duration()
+	searchOutput  = searchCommand.Flag("output", "Output format: table or json").Default("table").Enum("table", "json")
+)
+
+// runSearch runs the searcher named by the search arguments once and writes its results, newest first, to
+// w. Results posted after --until are left out, and how many there were and how long the search
+// took is reported on stderr.
+func runSearch(ctx context.Context, location *time.Location, w io.Writer) error {
+	initCtx, cancel := withTimeout(ctx, *searchTimeout)
+	searcher, err := newSearcher(initCtx, *searchName)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to initialize %s searcher: %w", *searchName, err)
+	}
+
+	after := time.Now().Add(-*searchWindow)
+	if !since.IsZero() {
+		after = *since
+	}
+
+	searchCtx, cancel := withTimeout(ctx, *searchTimeout)
+	defer cancel()
+	start := time.Now()
+	var found []search.SearchResult
+	if account, ok := search.ParseAccount(*searchKeyword); ok {
+		accountSearcher, ok := searcher.(search.AccountSearcher)
+		if !ok || !account.On(searcher) {
+			return fmt.Errorf("%s searcher can't list the posts of %s", *searchName, *searchKeyword)
+		}
+		found, err = accountSearcher.SearchAccount(searchCtx, account.Name, after.Unix())
+	} else {
+		found, err = searcher.Search(searchCtx, *searchKeyword, after.Unix())
+	}
+	// A truncated search still lists the results it kept; the searcher has warned about the limit
+	if err != nil && !errors.Is(err, search.ErrTruncated) {
+		return err
+	}
+	elapsed := time.Since(start)
+
+	results := []search.SearchResult{}
+	for _, result := range found {
+		if until.IsZero() || result.Timestamp <= until.Unix() {
+			results = append(results, result)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Timestamp > results[j].Timestamp })
+	fmt.Fprintf(os.Stderr, "%s returned %d results (%d in range) in %s\n", searcher.Platform(), len(found), len(results), elapsed.Round(time.Millisecond))
+
+	if *searchOutput == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POSTED\tAUTHOR\tSCORE\tTITLE\tURL")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n",
+			time.Unix(result.Timestamp, 0).In(location).Format(*timeFormat),
+			result.Author, result.Score, queryTitle(result.Title), result.URL)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,45 @@
+// search/account.go
+package search
+
+import (
+	"context"
+	"strings"
+)
+
+// AccountPrefix starts keywords that watch an account instead of searching for text, written
+// "from:<platform>/<account>", such as "from:hackernews/pg" or "from:bluesky/jay.bsky.team". Every new post
+// by the account is a result, reported under the keyword.
+const AccountPrefix = "from:"
+
+// AccountSearcher is implemented by searchers that can list an account's posts, so accounts can be watched
+// regardless of what they post about. Accounts are named the way the platform names them: a Hacker News or
+// Reddit username, a Bluesky handle or DID, or a Fediverse user@instance address.
+type AccountSearcher interface {
+	SearchAccount(ctx context.Context, account string, afterEpochSecs int64) ([]SearchResult, error)
+}
+
+// Account is an account watched by an account keyword.
+type Account struct {
+	// Platform is the platform the account is on, matched case-insensitively against Searcher.Platform.
+	Platform string
+	Name     string
+}
+
+// ParseAccount returns the account watched by keyword, and false if keyword isn't an account keyword.
+func ParseAccount(keyword string) (Account, bool) {
+	rest, ok := strings.CutPrefix(keyword, AccountPrefix)
+	if !ok {
+		return Account{}, false
+	}
+	platform, name, ok := strings.Cut(rest, "/")
+	platform, name = strings.TrimSpace(platform), strings.TrimSpace(name)
+	if !ok || platform == "" || name == "" {
+		return Account{}, false
+	}
+	return Account{Platform: platform, Name: name}, true
+}
+
+// On reports whether the account is on the platform of provider.
+func (a Account) On(provider Searcher) bool {
+	return strings.EqualFold(a.Platform, provider.Platform())
+}
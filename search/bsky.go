@@ -2,22 +2,34 @@ package search
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/charmbracelet/log"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
 type BlueskySearcher struct {
-	accessToken string
+	username   string
+	password   string
+	tokens     TokenStore
+	client     *http.Client
+	maxResults int
+
+	// mu guards session, the current session's access and refresh tokens.
+	mu      sync.Mutex
+	session *Token
 }
 
-// NewBlueskySearcher initializes the BlueskySearcher with API credentials.
-func NewBlueskySearcher() (*BlueskySearcher, error) {
+// NewBlueskySearcher initializes the BlueskySearcher with API credentials, resuming a stored session if
+// it's still current or can be refreshed.
+func NewBlueskySearcher(ctx context.Context, opts ...Option) (*BlueskySearcher, error) {
 	username := os.Getenv("BSKY_USERNAME")
 	password := os.Getenv("BSKY_PASSWORD")
 
@@ -25,68 +37,115 @@ func NewBlueskySearcher() (*BlueskySearcher, error) {
 		return nil, errors.New("missing Bluesky API credentials: BSKY_USERNAME and BSKY_PASSWORD are required")
 	}
 
-	searcher := &BlueskySearcher{}
-	
-	// Try authentication with retries
-	maxRetries := 3
-	for i := 0; i < maxRetries; i++ {
-		err := searcher.authenticate(username, password)
-		if err == nil {
+	// Authentication requests are retried with backoff by the HTTP client
+	o := newOptions(opts)
+	searcher := &BlueskySearcher{username: username, password: password, tokens: o.tokens, client: o.client, maxResults: o.maxResults}
+	searcher.session = loadToken(ctx, o.tokens, searcher.tokenKey())
+	if _, err := searcher.accessToken(ctx); err != nil {
+		if errors.Is(err, errBlueskyRateLimited) {
+			log.Warn("could not authenticate due to rate limits, continuing and authenticating again on the next search")
 			return searcher, nil
 		}
+		return nil, fmt.Errorf("failed to authenticate with Bluesky: %w", err)
+	}
+	return searcher, nil
+}
 
-		// Check if it's a rate limit error
-		if strings.Contains(err.Error(), "status code: 429") {
-			retryDelay := time.Duration(5*(i+1)) * time.Second // Exponential backoff
-			log.Warn("authentication rate limited, retrying...",
-				"attempt", i+1,
-				"max_attempts", maxRetries,
-				"retry_delay", retryDelay)
-			time.Sleep(retryDelay)
-			continue
-		}
+// errBlueskyRateLimited is returned when authentication is still rate limited after retries.
+var errBlueskyRateLimited = errors.New("authentication rate limited")
 
-		// If it's not a rate limit error, return the error immediately
-		return nil, fmt.Errorf("failed to authenticate with Bluesky: %w", err)
+// tokenKey is the key the account's session is stored under.
+func (b *BlueskySearcher) tokenKey() string {
+	return "bluesky/" + b.username
+}
+
+// accessToken returns the current session's access token. An expired session is refreshed, and a new one
+// is only created when there is no session or it can't be refreshed, since Bluesky rate limits creating
+// sessions far more tightly. New sessions are stored for later processes.
+func (b *BlueskySearcher) accessToken(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.session.Valid() {
+		return b.session.AccessToken, nil
 	}
 
-	// If we've exhausted all retries
-	log.Warn("could not authenticate due to rate limits, continuing with empty searcher")
-	return searcher, nil
+	var session *Token
+	if b.session != nil && b.session.RefreshToken != "" {
+		if expiry := jwtExpiry(b.session.RefreshToken); expiry.IsZero() || time.Until(expiry) > tokenExpiryMargin {
+			var err error
+			session, err = b.requestSession(ctx, "com.atproto.server.refreshSession", b.session.RefreshToken, nil)
+			if errors.Is(err, errBlueskyRateLimited) {
+				return "", err
+			} else if err != nil {
+				log.Debug("Failed to refresh Bluesky session, creating a new one", "error", err)
+			}
+		}
+	}
+	if session == nil {
+		payload, err := json.Marshal(map[string]string{"identifier": b.username, "password": b.password})
+		if err != nil {
+			return "", err
+		}
+		if session, err = b.requestSession(ctx, "com.atproto.server.createSession", "", payload); err != nil {
+			return "", err
+		}
+	}
+
+	b.session = session
+	saveToken(ctx, b.tokens, b.tokenKey(), *session)
+	return session.AccessToken, nil
 }
 
-// authenticate logs in to Bluesky and retrieves an access token.
-func (b *BlueskySearcher) authenticate(username, password string) error {
-	url := "https://bsky.social/xrpc/com.atproto.server.createSession"
-	payload := map[string]string{"identifier": username, "password": password}
-	jsonData, err := json.Marshal(payload)
+// authorize sets the Authorization header of a request to the session's access token, refreshing the
+// session first if it has expired.
+func (b *BlueskySearcher) authorize(ctx context.Context, req *http.Request) error {
+	token, err := b.accessToken(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to authenticate with Bluesky: %w", err)
 	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+// requestSession calls a Bluesky session method, authorized with token if it isn't empty, and returns the
+// session it responds with.
+func (b *BlueskySearcher) requestSession(ctx context.Context, method, token string, payload []byte) (*Token, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://bsky.social/xrpc/"+method, bytes.NewReader(payload))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+		// Creating a session is safe to repeat, so mark it idempotent for retries (nil headers aren't sent).
+		// Refreshing isn't, since each refresh token can only be used once.
+		req.Header["X-Idempotency-Key"] = nil
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusTooManyRequests {
-		return fmt.Errorf("status code: 429")
+		return nil, errBlueskyRateLimited
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("authentication failed with status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("authentication failed with status code: %d", resp.StatusCode)
 	}
 
 	var result struct {
-		AccessJwt string `json:"accessJwt"`
+		AccessJwt  string `json:"accessJwt"`
+		RefreshJwt string `json:"refreshJwt"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("failed to parse access token: %w", err)
+		return nil, fmt.Errorf("failed to parse access token: %w", err)
 	}
-
-	b.accessToken = result.AccessJwt
-	return nil
+	return &Token{AccessToken: result.AccessJwt, RefreshToken: result.RefreshJwt, Expiry: jwtExpiry(result.AccessJwt)}, nil
 }
 
 // Platform returns the platform name for this searcher.
@@ -106,70 +165,164 @@ func convertAtURLToHTTPS(atURL string) string {
 	return fmt.Sprintf("https://bsky.app/profile/%s/post/%s", did, postID)
 }
 
-// Search queries Bluesky for posts matching a keyword.
-func (b *BlueskySearcher) Search(keyword string, afterEpochSecs int64) ([]SearchResult, error) {
-	// If we don't have an access token, return empty results
-	if b.accessToken == "" {
-		log.Warn("search attempted without valid authentication",
-			"platform", "Bluesky",
-			"keyword", keyword)
-		return []SearchResult{}, nil
+// blueskyPageSize is the most posts searchPosts returns per page.
+const blueskyPageSize = 100
+
+// Search queries Bluesky for posts matching a keyword, newest first, paging back until a post is older
+// than the epoch time.
+func (b *BlueskySearcher) Search(ctx context.Context, keyword string, afterEpochSecs int64) ([]SearchResult, error) {
+	// Without an access token the search can't run; report it so the last search time isn't advanced
+	if _, err := b.accessToken(ctx); err != nil {
+		return nil, fmt.Errorf("search attempted without valid authentication: %w", err)
 	}
 
-	url := fmt.Sprintf("https://bsky.social/xrpc/app.bsky.feed.searchPosts?q=%s", keyword)
-	req, err := http.NewRequest("GET", url, nil)
+	return paginate(ctx, b.Platform(), b.maxResults, func(ctx context.Context, cursor string) ([]SearchResult, string, error) {
+		return b.searchPage(ctx, keyword, afterEpochSecs, cursor)
+	})
+}
+
+// searchPage fetches the page of posts at cursor, returning the posts newer than afterEpochSecs and the
+// cursor of the next page, if any.
+func (b *BlueskySearcher) searchPage(ctx context.Context, keyword string, afterEpochSecs int64, cursor string) ([]SearchResult, string, error) {
+	query := neturl.Values{"q": {platformQuery(keyword, false)}, "sort": {"latest"}, "limit": {fmt.Sprint(blueskyPageSize)}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://bsky.social/xrpc/app.bsky.feed.searchPosts?"+query.Encode(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+b.accessToken)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	if err := b.authorize(ctx, req); err != nil {
+		return nil, "", err
+	}
+	resp, err := b.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, "", fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Handle rate limiting
 	if resp.StatusCode == http.StatusTooManyRequests {
-		retryAfter := resp.Header.Get("Retry-After")
-		log.Warn("rate limit exceeded", 
-			"platform", b.Platform(),
-			"keyword", keyword,
-			"retry_after", retryAfter)
-		return []SearchResult{}, nil
+		return nil, "", fmt.Errorf("rate limit exceeded, retry after %q", resp.Header.Get("Retry-After"))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("search request failed with status code: %d", resp.StatusCode)
+	}
+
+	var data struct {
+		Posts  []blueskyPost `json:"posts"`
+		Cursor string        `json:"cursor"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, "", fmt.Errorf("failed to parse search results: %w", err)
+	}
+
+	results, older := b.postResults(keyword, data.Posts, afterEpochSecs)
+	if older {
+		return results, "", nil
+	}
+	return results, data.Cursor, nil
+}
+
+// SearchAccount returns the posts and replies by a Bluesky account, given by handle or DID, after the
+// epoch time. Reposts are left out.
+func (b *BlueskySearcher) SearchAccount(ctx context.Context, account string, afterEpochSecs int64) ([]SearchResult, error) {
+	if _, err := b.accessToken(ctx); err != nil {
+		return nil, fmt.Errorf("search attempted without valid authentication: %w", err)
+	}
+
+	actor := strings.TrimPrefix(account, "@")
+	return paginate(ctx, b.Platform(), b.maxResults, func(ctx context.Context, cursor string) ([]SearchResult, string, error) {
+		return b.authorFeedPage(ctx, actor, afterEpochSecs, cursor)
+	})
+}
+
+// authorFeedPage fetches the page of an account's feed at cursor, returning its posts newer than
+// afterEpochSecs and the cursor of the next page, if any.
+func (b *BlueskySearcher) authorFeedPage(ctx context.Context, actor string, afterEpochSecs int64, cursor string) ([]SearchResult, string, error) {
+	query := neturl.Values{"actor": {actor}, "filter": {"posts_with_replies"}, "limit": {fmt.Sprint(blueskyPageSize)}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://bsky.social/xrpc/app.bsky.feed.getAuthorFeed?"+query.Encode(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
+	if err := b.authorize(ctx, req); err != nil {
+		return nil, "", err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, "", fmt.Errorf("rate limit exceeded, retry after %q", resp.Header.Get("Retry-After"))
+	}
 	if resp.StatusCode != http.StatusOK {
-		log.Warn("search request failed",
-			"platform", b.Platform(),
-			"keyword", keyword,
-			"status_code", resp.StatusCode)
-		return []SearchResult{}, nil
+		return nil, "", fmt.Errorf("getAuthorFeed request failed with status code: %d", resp.StatusCode)
 	}
 
 	var data struct {
-		Posts []struct {
-			Uri    string `json:"uri"`
-			Author struct {
-				DisplayName string `json:"displayName"`
-			} `json:"author"`
-			Record struct {
-				CreatedAt string `json:"createdAt"`
-				Text      string `json:"text"`
-			} `json:"record"`
-		} `json:"posts"`
+		Feed []struct {
+			Post blueskyPost `json:"post"`
+			// Reason is set on reposts and pinned posts, which aren't new posts by the account
+			Reason json.RawMessage `json:"reason"`
+		} `json:"feed"`
+		Cursor string `json:"cursor"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		log.Warn("failed to parse search results",
-			"platform", b.Platform(),
-			"keyword", keyword,
-			"error", err)
-		return []SearchResult{}, nil
+		return nil, "", fmt.Errorf("failed to parse author feed: %w", err)
 	}
 
+	var posts []blueskyPost
+	for _, item := range data.Feed {
+		if len(item.Reason) == 0 {
+			posts = append(posts, item.Post)
+		}
+	}
+	results, older := b.postResults(actor, posts, afterEpochSecs)
+	if older {
+		return results, "", nil
+	}
+	return results, data.Cursor, nil
+}
+
+// blueskyPost is a post view as returned by Bluesky's feed and search endpoints.
+type blueskyPost struct {
+	Uri    string `json:"uri"`
+	Author struct {
+		Handle      string `json:"handle"`
+		DisplayName string `json:"displayName"`
+		CreatedAt   string `json:"createdAt"`
+	} `json:"author"`
+	LikeCount   int64 `json:"likeCount"`
+	RepostCount int64 `json:"repostCount"`
+	ReplyCount  int64 `json:"replyCount"`
+	Record      struct {
+		CreatedAt string   `json:"createdAt"`
+		Text      string   `json:"text"`
+		Tags      []string `json:"tags"`
+		Langs     []string `json:"langs"`
+		// Reply is set on replies, referring to the post replied to
+		Reply *struct {
+			Parent struct {
+				Uri string `json:"uri"`
+			} `json:"parent"`
+		} `json:"reply"`
+	} `json:"record"`
+}
+
+// postResults converts newest-first posts into results, keeping those newer than afterEpochSecs and
+// reporting whether any were older, meaning later pages are older still.
+func (b *BlueskySearcher) postResults(keyword string, posts []blueskyPost, afterEpochSecs int64) ([]SearchResult, bool) {
 	var results []SearchResult
-	for _, post := range data.Posts {
+	older := false
+	for _, post := range posts {
 		if post.Record.CreatedAt == "" {
 			log.Warn("skipping post with missing created_at",
 				"platform", b.Platform(),
@@ -186,16 +339,194 @@ func (b *BlueskySearcher) Search(keyword string, afterEpochSecs int64) ([]Search
 			continue
 		}
 
-		if createdTime.Unix() > afterEpochSecs {
-			results = append(results, SearchResult{
-				Platform:  b.Platform(),
-				Keyword:   keyword,
-				Title:     fmt.Sprintf("Post by %s", post.Author.DisplayName),
-				URL:       convertAtURLToHTTPS(post.Uri),
-				Timestamp: createdTime.Unix(),
-			})
+		if createdTime.Unix() <= afterEpochSecs {
+			older = true
+			continue
+		}
+
+		// Account creation times are optional in the API, so a missing or invalid one is left unset
+		var authorCreatedAt int64
+		if accountTime, err := time.Parse(time.RFC3339, post.Author.CreatedAt); err == nil {
+			authorCreatedAt = accountTime.Unix()
 		}
+
+		var metadata map[string]string
+		if len(post.Record.Langs) > 0 {
+			metadata = map[string]string{"language": post.Record.Langs[0]}
+		}
+
+		results = append(results, SearchResult{
+			Platform:  b.Platform(),
+			Keyword:   keyword,
+			Title:     fmt.Sprintf("Post by %s", post.Author.DisplayName),
+			URL:       convertAtURLToHTTPS(post.Uri),
+			Timestamp: createdTime.Unix(),
+			Content:   post.Record.Text,
+			Author:    post.Author.Handle,
+			Score:     post.LikeCount,
+			Comments:  post.ReplyCount,
+			Reposts:   post.RepostCount,
+			Tags:      post.Record.Tags,
+			Metadata:  metadata,
+
+			AuthorCreatedAt: authorCreatedAt,
+		})
+	}
+
+	return results, older
+}
+
+// blueskyGetPostsMax is the most posts getPosts returns per request.
+const blueskyGetPostsMax = 25
+
+// Engagement updates the like, reply, and repost counts of Bluesky posts, looking them up in batches.
+func (b *BlueskySearcher) Engagement(ctx context.Context, results []SearchResult) error {
+	if _, err := b.accessToken(ctx); err != nil {
+		return fmt.Errorf("engagement requested without valid authentication: %w", err)
+	}
+
+	indexes := make(map[string][]int)
+	var uris []string
+	var errs []error
+	for i, result := range results {
+		uri, ok := convertHTTPSToAtURL(result.URL)
+		if !ok {
+			errs = append(errs, fmt.Errorf("not a Bluesky post URL: %s", result.URL))
+			continue
+		}
+		if _, ok := indexes[uri]; !ok {
+			uris = append(uris, uri)
+		}
+		indexes[uri] = append(indexes[uri], i)
+	}
+
+	for start := 0; start < len(uris); start += blueskyGetPostsMax {
+		query := neturl.Values{"uris": uris[start:min(start+blueskyGetPostsMax, len(uris))]}
+		req, err := http.NewRequestWithContext(ctx, "GET", "https://bsky.social/xrpc/app.bsky.feed.getPosts?"+query.Encode(), nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create request: %w", err))
+			continue
+		}
+		if err := b.authorize(ctx, req); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		resp, err := b.client.Do(req)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("request failed: %w", err))
+			continue
+		}
+
+		var data struct {
+			Posts []struct {
+				Uri         string `json:"uri"`
+				LikeCount   int64  `json:"likeCount"`
+				RepostCount int64  `json:"repostCount"`
+				ReplyCount  int64  `json:"replyCount"`
+			} `json:"posts"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("getPosts request failed with status code: %d", resp.StatusCode)
+		} else if decodeErr := json.NewDecoder(resp.Body).Decode(&data); decodeErr != nil {
+			err = fmt.Errorf("failed to parse posts: %w", decodeErr)
+		}
+		resp.Body.Close()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, post := range data.Posts {
+			for _, i := range indexes[post.Uri] {
+				results[i].Score = post.LikeCount
+				results[i].Comments = post.ReplyCount
+				results[i].Reposts = post.RepostCount
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Deleted reports whether a Bluesky post has been deleted or taken down, which getPosts reports by
+// leaving it out.
+func (b *BlueskySearcher) Deleted(ctx context.Context, result SearchResult) (bool, error) {
+	if _, err := b.accessToken(ctx); err != nil {
+		return false, fmt.Errorf("deletion check requested without valid authentication: %w", err)
 	}
+	uri, ok := convertHTTPSToAtURL(result.URL)
+	if !ok {
+		return false, fmt.Errorf("not a Bluesky post URL: %s", result.URL)
+	}
+
+	posts, err := b.getPosts(ctx, uri)
+	if err != nil {
+		return false, err
+	}
+	return len(posts) == 0, nil
+}
+
+// Parent fetches the post a Bluesky reply responds to.
+func (b *BlueskySearcher) Parent(ctx context.Context, result SearchResult) (*ParentPost, error) {
+	if _, err := b.accessToken(ctx); err != nil {
+		return nil, fmt.Errorf("thread context requested without valid authentication: %w", err)
+	}
+	uri, ok := convertHTTPSToAtURL(result.URL)
+	if !ok {
+		return nil, fmt.Errorf("not a Bluesky post URL: %s", result.URL)
+	}
+
+	posts, err := b.getPosts(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 || posts[0].Record.Reply == nil {
+		return nil, nil
+	}
+	parents, err := b.getPosts(ctx, posts[0].Record.Reply.Parent.Uri)
+	if err != nil {
+		return nil, err
+	}
+	if len(parents) == 0 {
+		return nil, fmt.Errorf("parent of Bluesky post not found: %s", result.URL)
+	}
+	parent := parents[0]
+	return &ParentPost{URL: convertAtURLToHTTPS(parent.Uri), Author: parent.Author.Handle, Content: parent.Record.Text}, nil
+}
 
-	return results, nil
-}
\ No newline at end of file
+// getPosts fetches posts by their "at://" URIs, leaving out any that no longer exist.
+func (b *BlueskySearcher) getPosts(ctx context.Context, uris ...string) ([]blueskyPost, error) {
+	query := neturl.Values{"uris": uris}
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://bsky.social/xrpc/app.bsky.feed.getPosts?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := b.authorize(ctx, req); err != nil {
+		return nil, err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getPosts request failed with status code: %d", resp.StatusCode)
+	}
+	var data struct {
+		Posts []blueskyPost `json:"posts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse posts: %w", err)
+	}
+	return data.Posts, nil
+}
+
+// convertHTTPSToAtURL reverses convertAtURLToHTTPS, returning the "at://" URI of a post's web URL.
+func convertHTTPSToAtURL(postURL string) (string, bool) {
+	// Web URLs look like https://bsky.app/profile/<did>/post/<id>
+	parts := strings.Split(strings.TrimPrefix(postURL, "https://bsky.app/"), "/")
+	if len(parts) != 4 || parts[0] != "profile" || parts[2] != "post" {
+		return "", false
+	}
+	return fmt.Sprintf("at://%s/app.bsky.feed.post/%s", parts[1], parts[3]), true
+}
@@ -0,0 +1,188 @@
+// search/cache.go
+package search
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
+
+// maxCachedBody is the largest response body kept for revalidation. Larger responses are passed through.
+const maxCachedBody = 4 << 20
+
+// responseCache is shared by every searcher's client, so an unchanged response is revalidated rather than
+// downloaded again however many profiles search for it.
+var responseCache = NewResponseCache(1000)
+
+// SetCacheSize limits how many responses are kept for revalidation. Zero disables the cache.
+func SetCacheSize(entries int) {
+	responseCache.resize(entries)
+}
+
+// ResponseCache holds the last response to GET requests that carried an ETag or Last-Modified validator,
+// evicting the least recently used once it holds its maximum number of entries.
+type ResponseCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type cachedResponse struct {
+	key          string
+	etag         string
+	lastModified string
+	status       int
+	header       http.Header
+	body         []byte
+}
+
+// NewResponseCache creates a cache holding up to max responses.
+func NewResponseCache(max int) *ResponseCache {
+	return &ResponseCache{max: max, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+func (c *ResponseCache) get(key string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedResponse)
+}
+
+func (c *ResponseCache) put(entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.max <= 0 {
+		return
+	}
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	c.evict()
+}
+
+func (c *ResponseCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+func (c *ResponseCache) resize(max int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.max = max
+	c.evict()
+}
+
+// evict drops the least recently used entries over the limit. c.mu must be held.
+func (c *ResponseCache) evict() {
+	for c.order.Len() > max(c.max, 0) {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// CacheTransport makes GET requests conditional on the validators of the last response to the same URL,
+// answering a 304 Not Modified with the cached response so searchers parse it as usual. Platforms usually
+// don't count 304s against API quotas, and they skip the download.
+type CacheTransport struct {
+	Base  http.RoundTripper
+	Cache *ResponseCache
+}
+
+// RoundTrip sends the request, conditionally if its response is cached.
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests that set their own validators or vary by credentials are sent as they are
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") != "" ||
+		req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.Base.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	cached := t.Cache.get(key)
+	if cached != nil {
+		// RoundTrippers must not modify the caller's request
+		req = req.Clone(req.Context())
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		log.Debug("Response not modified", "host", req.URL.Host, "path", req.URL.Path)
+		return cached.response(req), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if (etag == "" && lastModified == "") || strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		t.Cache.remove(key)
+		return resp, nil
+	}
+
+	// Keep the body if it fits, otherwise stitch what was read back onto the rest
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBody+1))
+	if err != nil || len(body) > maxCachedBody {
+		t.Cache.remove(key)
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.Cache.put(&cachedResponse{
+		key:          key,
+		etag:         etag,
+		lastModified: lastModified,
+		status:       resp.StatusCode,
+		header:       resp.Header.Clone(),
+		body:         body,
+	})
+	return resp, nil
+}
+
+// response rebuilds the cached response as the answer to req.
+func (c *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.status, http.StatusText(c.status)),
+		StatusCode:    c.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.body)),
+		ContentLength: int64(len(c.body)),
+		Request:       req,
+	}
+}
+
+// readCloser reads from one reader and closes another.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -2,13 +2,16 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,20 +21,25 @@ import (
 // FediverseSearcher is a searcher for posts on multiple Mastodon instances with OAuth2 support.
 type FediverseSearcher struct {
 	instanceURLs map[string]string // Instance URL -> access token
+	client       *http.Client
+	maxResults   int
 }
 
 // NewFediverseSearcher initializes the searcher with a list of instance URLs and obtains access tokens.
-func NewFediverseSearcher() (*FediverseSearcher, error) {
+func NewFediverseSearcher(ctx context.Context, opts ...Option) (*FediverseSearcher, error) {
 	instancesEnv := os.Getenv("FEDIVERSE_INSTANCES")
 	if instancesEnv == "" {
 		return nil, fmt.Errorf("missing environment variable: FEDIVERSE_INSTANCES")
 	}
 
+	o := newOptions(opts)
+	client := o.client
+
 	// Parse and initialize instances with tokens
 	instanceURLs := make(map[string]string)
 	for _, instanceURL := range strings.Split(instancesEnv, ",") {
 		instanceURL = strings.TrimSpace(instanceURL)
-		token, err := getAccessTokenForInstance(instanceURL)
+		token, err := getAccessTokenForInstance(ctx, client, o.tokens, instanceURL)
 		if err != nil {
 			log.Printf("Error obtaining access token for instance %s: %v", instanceURL, err)
 			continue
@@ -39,7 +47,7 @@ func NewFediverseSearcher() (*FediverseSearcher, error) {
 		instanceURLs[instanceURL] = token
 	}
 
-	return &FediverseSearcher{instanceURLs: instanceURLs}, nil
+	return &FediverseSearcher{instanceURLs: instanceURLs, client: client, maxResults: o.maxResults}, nil
 }
 
 // Platform returns the platform name for this searcher.
@@ -47,10 +55,16 @@ func (f *FediverseSearcher) Platform() string {
 	return "Fediverse"
 }
 
-// getAccessTokenForInstance authenticates with the instance and retrieves an access token.
-func getAccessTokenForInstance(instanceURL string) (string, error) {
-	// Construct environment variable names dynamically based on the instance URL
-	instanceEnvPrefix := strings.ToUpper(strings.ReplaceAll(strings.ReplaceAll(instanceURL, "https://", ""), ".", "_"))
+// FediverseEnvPrefix returns the prefix of the environment variables holding an instance's credentials, such
+// as MASTODON_SOCIAL for https://mastodon.social's MASTODON_SOCIAL_ACCESS_TOKEN.
+func FediverseEnvPrefix(instanceURL string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.ReplaceAll(instanceURL, "https://", ""), ".", "_"))
+}
+
+// getAccessTokenForInstance authenticates with the instance and retrieves an access token, reusing one
+// kept in tokens by an earlier process.
+func getAccessTokenForInstance(ctx context.Context, client *http.Client, tokens TokenStore, instanceURL string) (string, error) {
+	instanceEnvPrefix := FediverseEnvPrefix(instanceURL)
 	clientID := os.Getenv(instanceEnvPrefix + "_CLIENT_ID")
 	clientSecret := os.Getenv(instanceEnvPrefix + "_CLIENT_SECRET")
 	accessToken := os.Getenv(instanceEnvPrefix + "_ACCESS_TOKEN")
@@ -62,6 +76,10 @@ func getAccessTokenForInstance(instanceURL string) (string, error) {
 	if clientID == "" || clientSecret == "" {
 		return "", fmt.Errorf("missing client ID or client secret for instance %s", instanceURL)
 	}
+	tokenKey := "fediverse/" + instanceURL + "/" + clientID
+	if token := loadToken(ctx, tokens, tokenKey); token.Valid() {
+		return token.AccessToken, nil
+	}
 
 	// Authenticate with the instance to obtain a new access token
 	data := url.Values{}
@@ -71,7 +89,15 @@ func getAccessTokenForInstance(instanceURL string) (string, error) {
 	data.Set("scope", "read")
 
 	tokenURL := fmt.Sprintf("%s/oauth/token", instanceURL)
-	resp, err := http.PostForm(tokenURL, data)
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create access token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// Requesting a token is safe to repeat, so mark it idempotent for retries (nil headers aren't sent)
+	req.Header["X-Idempotency-Key"] = nil
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to request access token: %w", err)
 	}
@@ -83,11 +109,13 @@ func getAccessTokenForInstance(instanceURL string) (string, error) {
 
 	var result struct {
 		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return "", fmt.Errorf("failed to parse access token: %w", err)
 	}
 
+	saveToken(ctx, tokens, tokenKey, Token{AccessToken: result.AccessToken, Expiry: expiresIn(result.ExpiresIn)})
 	return result.AccessToken, nil
 }
 
@@ -101,77 +129,338 @@ func cleanHTMLContent(content string) string {
 	return html.UnescapeString(content)
 }
 
-// Search performs a search for posts matching `@tailscale` or `#tailscale` on each specified instance.
-func (f *FediverseSearcher) Search(keyword string, afterEpochSecs int64) ([]SearchResult, error) {
+// fediversePageSize is the most statuses Mastodon's search returns per page.
+const fediversePageSize = 40
+
+// Search performs a search for statuses matching keyword on each specified instance, paging through each
+// instance's results. Hashtag and mention forms such as `#tailscale` are searched as keyword variants. If any instance fails the search reports an error, so the
+// platform is searched again from the same point. The result limit applies across all instances.
+func (f *FediverseSearcher) Search(ctx context.Context, keyword string, afterEpochSecs int64) ([]SearchResult, error) {
 	var allResults []SearchResult
+	var errs []error
+	truncated := false
 
 	for instanceURL, accessToken := range f.instanceURLs {
-		searchURL := fmt.Sprintf("%s/api/v2/search?q=%s&resolve=true", instanceURL, url.QueryEscape(keyword))
-
-		// Create a new request with Authorization header
-		req, err := http.NewRequest("GET", searchURL, nil)
-		if err != nil {
-			log.Printf("Failed to create search request for instance %s: %v", instanceURL, err)
+		limit := f.maxResults
+		if limit > 0 {
+			limit -= len(allResults)
+			if limit <= 0 {
+				// The instances left unsearched may have results too
+				truncated = true
+				break
+			}
+		}
+		results, err := paginate(ctx, f.Platform(), limit, func(ctx context.Context, cursor string) ([]SearchResult, string, error) {
+			return f.searchPage(ctx, instanceURL, accessToken, keyword, afterEpochSecs, cursor)
+		})
+		if errors.Is(err, ErrTruncated) {
+			truncated = true
+		} else if err != nil {
+			errs = append(errs, err)
 			continue
 		}
+		allResults = append(allResults, results...)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if truncated {
+		return allResults, ErrTruncated
+	}
+	return allResults, nil
+}
+
+// searchPage searches one instance from the offset in cursor, returning the statuses newer than
+// afterEpochSecs and the offset of the next page, if the page was full.
+func (f *FediverseSearcher) searchPage(ctx context.Context, instanceURL, accessToken, keyword string, afterEpochSecs int64, cursor string) ([]SearchResult, string, error) {
+	offset, _ := strconv.Atoi(cursor)
+	searchURL := fmt.Sprintf("%s/api/v2/search?q=%s&resolve=true&type=statuses&limit=%d&offset=%d", instanceURL, url.QueryEscape(platformQuery(keyword, false)), fediversePageSize, offset)
+
+	// Create a new request with Authorization header
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create search request for instance %s: %w", instanceURL, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	// Send the request
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to perform search request on instance %s: %w", instanceURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("search request failed on instance %s with status code: %d", instanceURL, resp.StatusCode)
+	}
+
+	// Parse the response JSON
+	var data struct {
+		Statuses []fediversePost `json:"statuses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, "", fmt.Errorf("failed to parse search results from instance %s: %w", instanceURL, err)
+	}
+
+	// Search results aren't ordered by date, so only a short page ends the search
+	next := ""
+	if len(data.Statuses) == fediversePageSize {
+		next = strconv.Itoa(offset + fediversePageSize)
+	}
+
+	results, _ := f.postResults(instanceURL, keyword, data.Statuses, afterEpochSecs)
+	return results, next, nil
+}
+
+// SearchAccount returns the posts by a Fediverse account, given as user@instance, after the epoch time,
+// reading them from the instance that hosts the account. Boosts are left out. A configured access token is
+// used when the account is on one of FEDIVERSE_INSTANCES.
+func (f *FediverseSearcher) SearchAccount(ctx context.Context, account string, afterEpochSecs int64) ([]SearchResult, error) {
+	user, domain, ok := strings.Cut(strings.TrimPrefix(account, "@"), "@")
+	if !ok || user == "" || domain == "" {
+		return nil, fmt.Errorf("fediverse account %q must be given as user@instance", account)
+	}
+	instanceURL := "https://" + domain
+	accessToken := f.instanceURLs[instanceURL]
+
+	var lookup struct {
+		ID string `json:"id"`
+	}
+	lookupURL := fmt.Sprintf("%s/api/v1/accounts/lookup?acct=%s", instanceURL, url.QueryEscape(user))
+	if err := f.get(ctx, lookupURL, accessToken, &lookup); err != nil {
+		return nil, fmt.Errorf("failed to look up account %s: %w", account, err)
+	}
+
+	return paginate(ctx, f.Platform(), f.maxResults, func(ctx context.Context, cursor string) ([]SearchResult, string, error) {
+		statusesURL := fmt.Sprintf("%s/api/v1/accounts/%s/statuses?exclude_reblogs=true&limit=%d", instanceURL, url.PathEscape(lookup.ID), fediversePageSize)
+		if cursor != "" {
+			statusesURL += "&max_id=" + url.QueryEscape(cursor)
+		}
+		var statuses []fediversePost
+		if err := f.get(ctx, statusesURL, accessToken, &statuses); err != nil {
+			return nil, "", fmt.Errorf("failed to list posts by %s: %w", account, err)
+		}
+
+		// Statuses are newest first, so an older one, or a short page, ends the search
+		results, older := f.postResults(instanceURL, account, statuses, afterEpochSecs)
+		next := ""
+		if !older && len(statuses) == fediversePageSize {
+			next = statuses[len(statuses)-1].ID
+		}
+		return results, next, nil
+	})
+}
+
+// get fetches a JSON document from an instance into v, authorized with accessToken if it isn't empty.
+func (f *FediverseSearcher) get(ctx context.Context, apiURL, accessToken string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
+	if accessToken != "" {
 		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// fediversePost is a status as returned by Mastodon's search and timeline endpoints.
+type fediversePost struct {
+	ID         string `json:"id"`
+	Content    string `json:"content"`
+	URL        string `json:"url"`
+	CreatedAt  string `json:"created_at"`
+	Favourites int64  `json:"favourites_count"`
+	Reblogs    int64  `json:"reblogs_count"`
+	Replies    int64  `json:"replies_count"`
+	Language   string `json:"language"`
+	Tags       []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	Account struct {
+		DisplayName string `json:"display_name"`
+		Acct        string `json:"acct"`
+		CreatedAt   string `json:"created_at"`
+	} `json:"account"`
+}
 
-		// Send the request
-		client := &http.Client{}
-		resp, err := client.Do(req)
+// postResults converts statuses read from an instance into results, keeping those newer than
+// afterEpochSecs and reporting whether any were older.
+func (f *FediverseSearcher) postResults(instanceURL, keyword string, statuses []fediversePost, afterEpochSecs int64) ([]SearchResult, bool) {
+	var results []SearchResult
+	older := false
+	for _, status := range statuses {
+		// Only include results after the specified epoch time
+		createdTime, err := time.Parse(time.RFC3339, status.CreatedAt)
 		if err != nil {
-			log.Printf("Failed to perform search request on instance %s: %v", instanceURL, err)
+			log.Printf("Skipping post with invalid CreatedAt format on instance %s: %v", instanceURL, status.CreatedAt)
 			continue
 		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("Search request failed on instance %s with status code: %d", instanceURL, resp.StatusCode)
+		if createdTime.Unix() <= afterEpochSecs {
+			older = true
 			continue
 		}
 
-		// Parse the response JSON
-		var data struct {
-			Statuses []struct {
-				Content   string `json:"content"`
-				URL       string `json:"url"`
-				CreatedAt string `json:"created_at"`
-				Account   struct {
-					DisplayName string `json:"display_name"`
-					Acct        string `json:"acct"`
-				} `json:"account"`
-			} `json:"statuses"`
+		// Clean the content before creating the SearchResult
+		cleanedContent := cleanHTMLContent(status.Content)
+
+		var authorCreatedAt int64
+		if accountTime, err := time.Parse(time.RFC3339, status.Account.CreatedAt); err == nil {
+			authorCreatedAt = accountTime.Unix()
 		}
-		if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-			log.Printf("Failed to parse search results from instance %s: %v", instanceURL, err)
-			continue
+
+		var tags []string
+		for _, tag := range status.Tags {
+			tags = append(tags, tag.Name)
+		}
+		metadata := map[string]string{"instance": instanceURL}
+		if status.Language != "" {
+			metadata["language"] = status.Language
 		}
 
-		// Filter and format results
-		for _, status := range data.Statuses {
-			// Only include results after the specified epoch time
-			createdTime, err := time.Parse(time.RFC3339, status.CreatedAt)
-			if err != nil {
-				log.Printf("Skipping post with invalid CreatedAt format on instance %s: %v", instanceURL, status.CreatedAt)
-				continue
-			}
-			if createdTime.Unix() <= afterEpochSecs {
-				continue
-			}
+		results = append(results, SearchResult{
+			Platform:  f.Platform(),
+			Keyword:   keyword,
+			Title:     fmt.Sprintf("Post by %s (@%s)", status.Account.DisplayName, status.Account.Acct),
+			URL:       status.URL,
+			Timestamp: createdTime.Unix(),
+			Content:   cleanedContent,
+			Author:    status.Account.Acct,
+			Score:     status.Favourites,
+			Comments:  status.Replies,
+			Reposts:   status.Reblogs,
+			Tags:      tags,
+			Metadata:  metadata,
+
+			AuthorCreatedAt: authorCreatedAt,
+		})
+	}
+
+	return results, older
+}
+
+// fediverseStatus is a post's current engagement, and the post it replies to, if any.
+type fediverseStatus struct {
+	FavouritesCount int64  `json:"favourites_count"`
+	ReblogsCount    int64  `json:"reblogs_count"`
+	RepliesCount    int64  `json:"replies_count"`
+	InReplyToID     string `json:"in_reply_to_id"`
+	// instanceURL is the instance the status was read from
+	instanceURL string
+}
+
+// Replies returns the reply count of a post, read from the instance that hosts it. A configured access
+// token is used when the post is on one of FEDIVERSE_INSTANCES.
+func (f *FediverseSearcher) Replies(ctx context.Context, result SearchResult) (int64, error) {
+	status, err := f.status(ctx, result.URL)
+	if err != nil {
+		return 0, err
+	}
+	return status.RepliesCount, nil
+}
+
+// Deleted reports whether a post has been deleted or removed from the instance that hosts it.
+func (f *FediverseSearcher) Deleted(ctx context.Context, result SearchResult) (bool, error) {
+	_, err := f.status(ctx, result.URL)
+	if errors.Is(err, errStatusGone) {
+		return true, nil
+	}
+	return false, err
+}
+
+// Parent fetches the post a Fediverse reply responds to, from the instance that hosts the reply.
+func (f *FediverseSearcher) Parent(ctx context.Context, result SearchResult) (*ParentPost, error) {
+	status, err := f.status(ctx, result.URL)
+	if err != nil {
+		return nil, err
+	}
+	if status.InReplyToID == "" {
+		return nil, nil
+	}
 
-			// Clean the content before creating the SearchResult
-			cleanedContent := cleanHTMLContent(status.Content)
-
-			allResults = append(allResults, SearchResult{
-				Platform:  f.Platform(),
-				Keyword:   keyword,
-				Title:     fmt.Sprintf("Post by %s (@%s)", status.Account.DisplayName, status.Account.Acct),
-				URL:       status.URL,
-				Timestamp: createdTime.Unix(),
-				Content:   cleanedContent,
-			})
+	var parent fediversePost
+	if err := f.statusByID(ctx, status.instanceURL, status.InReplyToID, &parent); err != nil {
+		return nil, err
+	}
+	return &ParentPost{URL: parent.URL, Author: parent.Account.Acct, Content: cleanHTMLContent(parent.Content)}, nil
+}
+
+// Engagement updates the favourite, reply, and boost counts of posts, reading each from the instance that
+// hosts it.
+func (f *FediverseSearcher) Engagement(ctx context.Context, results []SearchResult) error {
+	var errs []error
+	for i := range results {
+		status, err := f.status(ctx, results[i].URL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
+		results[i].Score = status.FavouritesCount
+		results[i].Comments = status.RepliesCount
+		results[i].Reposts = status.ReblogsCount
 	}
+	return errors.Join(errs...)
+}
 
-	return allResults, nil
+// errStatusGone is returned for posts their instance no longer has.
+var errStatusGone = errors.New("post not found")
+
+// status fetches a post from the instance that hosts it.
+func (f *FediverseSearcher) status(ctx context.Context, postURL string) (fediverseStatus, error) {
+	u, err := url.Parse(postURL)
+	if err != nil || u.Host == "" {
+		return fediverseStatus{}, fmt.Errorf("not a Fediverse post URL: %s", postURL)
+	}
+	// Post URLs end with the status ID, e.g. https://mastodon.social/@user/123456
+	statusID := u.Path[strings.LastIndex(u.Path, "/")+1:]
+	if statusID == "" {
+		return fediverseStatus{}, fmt.Errorf("not a Fediverse post URL: %s", postURL)
+	}
+
+	var status fediverseStatus
+	status.instanceURL = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	if err := f.statusByID(ctx, status.instanceURL, statusID, &status); err != nil {
+		return fediverseStatus{}, err
+	}
+	return status, nil
+}
+
+// statusByID fetches a status from an instance into v. A configured access token is used when the instance
+// is one of FEDIVERSE_INSTANCES.
+func (f *FediverseSearcher) statusByID(ctx context.Context, instanceURL, statusID string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/statuses/%s", instanceURL, url.PathEscape(statusID)), nil)
+	if err != nil {
+		return err
+	}
+	if accessToken, ok := f.instanceURLs[instanceURL]; ok {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return fmt.Errorf("%w: status %s on instance %s", errStatusGone, statusID, instanceURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status request failed on instance %s with status code: %d", instanceURL, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse status from instance %s: %w", instanceURL, err)
+	}
+	return nil
 }
@@ -1,17 +1,24 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/charmbracelet/log"
 	"net/http"
-	"time"
+	"net/url"
+	"strconv"
 )
 
-type HackerNewsSearcher struct{}
+type HackerNewsSearcher struct {
+	client     *http.Client
+	maxResults int
+}
 
-func NewHackerNewsSearcher() *HackerNewsSearcher {
-	return &HackerNewsSearcher{}
+func NewHackerNewsSearcher(opts ...Option) *HackerNewsSearcher {
+	o := newOptions(opts)
+	return &HackerNewsSearcher{client: o.client, maxResults: o.maxResults}
 }
 
 // Platform returns the name of the platform for this searcher.
@@ -19,44 +26,89 @@ func (h *HackerNewsSearcher) Platform() string {
 	return "HackerNews"
 }
 
-// Search performs a keyword search on Hacker News after a specified epoch time.
-func (h *HackerNewsSearcher) Search(keyword string, afterEpochSecs int64) ([]SearchResult, error) {
+// hackerNewsPageSize and hackerNewsMaxPages bound how many hits are fetched per search. Algolia serves at
+// most 1000 hits for a query.
+const (
+	hackerNewsPageSize = 100
+	hackerNewsMaxPages = 10
+)
+
+type hackerNewsHit struct {
+	Title       string   `json:"title"`
+	URL         string   `json:"url"`
+	ObjectID    string   `json:"objectID"`
+	CreatedAt   int64    `json:"created_at_i"`
+	Points      int64    `json:"points"`
+	NumComments int64    `json:"num_comments"`
+	Author      string   `json:"author"`
+	CommentText string   `json:"comment_text"`
+	StoryTitle  string   `json:"story_title"`
+	Type        []string `json:"_tags"`
+}
+
+// Search performs a keyword search on Hacker News after a specified epoch time, paging through results so
+// long gaps since the last search (or backfills) aren't cut short. A failure on any page fails the search,
+// so the missing hits are fetched again next time.
+func (h *HackerNewsSearcher) Search(ctx context.Context, keyword string, afterEpochSecs int64) ([]SearchResult, error) {
+	return h.search(ctx, keyword, platformQuery(keyword, false), "(story,comment)", afterEpochSecs)
+}
+
+// SearchAccount returns the stories and comments posted by a Hacker News user after the epoch time.
+func (h *HackerNewsSearcher) SearchAccount(ctx context.Context, account string, afterEpochSecs int64) ([]SearchResult, error) {
+	return h.search(ctx, account, "", "(story,comment),author_"+account, afterEpochSecs)
+}
+
+// search pages through the hits for query with tags, reporting them under keyword.
+func (h *HackerNewsSearcher) search(ctx context.Context, keyword, query, tags string, afterEpochSecs int64) ([]SearchResult, error) {
+	return paginate(ctx, h.Platform(), h.maxResults, func(ctx context.Context, cursor string) ([]SearchResult, string, error) {
+		page, _ := strconv.Atoi(cursor)
+		hits, pages, err := h.searchPage(ctx, query, tags, afterEpochSecs, page)
+		if err != nil {
+			return nil, "", fmt.Errorf("page %d: %w", page, err)
+		}
+		next := ""
+		if page+1 < min(pages, hackerNewsMaxPages) {
+			next = strconv.Itoa(page + 1)
+		}
+		return h.results(keyword, hits), next, nil
+	})
+}
+
+// searchPage fetches one page of hits, returning them with the total number of pages.
+func (h *HackerNewsSearcher) searchPage(ctx context.Context, query, tags string, afterEpochSecs int64, page int) ([]hackerNewsHit, int, error) {
 	apiURL := fmt.Sprintf(
-		"https://hn.algolia.com/api/v1/search_by_date?query=%s&tags=(story,comment)&numericFilters=created_at_i>%d",
-		keyword, afterEpochSecs,
+		"https://hn.algolia.com/api/v1/search_by_date?query=%s&tags=%s&numericFilters=created_at_i>%d&hitsPerPage=%d&page=%d",
+		url.QueryEscape(query), url.QueryEscape(tags), afterEpochSecs, hackerNewsPageSize, page,
 	)
-	resp, err := http.Get(apiURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := h.client.Do(req)
 	if err != nil {
-		log.Warn("failed to make request", "error", err)
-		return []SearchResult{}, nil
+		return nil, 0, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Warn("unexpected status code", "status", resp.Status)
-		return []SearchResult{}, nil
+		return nil, 0, fmt.Errorf("unexpected status code: %s", resp.Status)
 	}
 
 	var result struct {
-		Hits []struct {
-			Title       string   `json:"title"`
-			URL         string   `json:"url"`
-			ObjectID    string   `json:"objectID"`
-			CreatedAt   int64    `json:"created_at_i"`
-			CommentText string   `json:"comment_text"`
-			StoryTitle  string   `json:"story_title"`
-			Type        []string `json:"_tags"`
-		} `json:"hits"`
+		Hits    []hackerNewsHit `json:"hits"`
+		NbPages int             `json:"nbPages"`
 	}
-
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		log.Warn("failed to decode response", "error", err)
-		return []SearchResult{}, nil
+		return nil, 0, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	return result.Hits, result.NbPages, nil
+}
+
+// results converts hits into search results, skipping hits without an ID or title.
+func (h *HackerNewsSearcher) results(keyword string, hits []hackerNewsHit) []SearchResult {
 	var results []SearchResult
-	timestamp := time.Now().Unix()
-	for _, hit := range result.Hits {
+	for _, hit := range hits {
 		if hit.ObjectID == "" {
 			log.Debug("skipping hit due to missing objectID")
 			continue
@@ -97,9 +149,124 @@ func (h *HackerNewsSearcher) Search(keyword string, afterEpochSecs int64) ([]Sea
 			Title:     title,
 			URL:       hackerNewsURL,
 			Content:   content,
-			Timestamp: timestamp,
+			Timestamp: hit.CreatedAt,
+			Author:    hit.Author,
+			Score:     hit.Points,
+			Comments:  hit.NumComments,
+			Link:      hit.URL,
 		})
 	}
 
-	return results, nil
+	return results
+}
+
+// hackerNewsItem is an item from the official Hacker News API.
+type hackerNewsItem struct {
+	Type   string `json:"type"`
+	By     string `json:"by"`
+	Title  string `json:"title"`
+	Text   string `json:"text"`
+	Parent int64  `json:"parent"`
+	Score  int64  `json:"score"`
+	// Deleted items are deleted by their author; dead items are flagged or killed by moderators
+	Deleted bool `json:"deleted"`
+	Dead    bool `json:"dead"`
+	// Only stories report descendants; comments list their direct replies
+	Descendants *int64  `json:"descendants"`
+	Kids        []int64 `json:"kids"`
+}
+
+// replies counts the comments under a story, or the direct replies to a comment.
+func (i hackerNewsItem) replies() int64 {
+	if i.Descendants != nil {
+		return *i.Descendants
+	}
+	return int64(len(i.Kids))
+}
+
+// Replies counts the comments under a Hacker News story, or the direct replies to a comment, using the
+// official Hacker News API.
+func (h *HackerNewsSearcher) Replies(ctx context.Context, result SearchResult) (int64, error) {
+	item, err := h.item(ctx, result.URL)
+	if err != nil {
+		return 0, err
+	}
+	return item.replies(), nil
+}
+
+// Deleted reports whether a Hacker News item has been deleted or killed.
+func (h *HackerNewsSearcher) Deleted(ctx context.Context, result SearchResult) (bool, error) {
+	item, err := h.item(ctx, result.URL)
+	if err != nil {
+		return false, err
+	}
+	return item.Deleted || item.Dead, nil
+}
+
+// Parent fetches the story or comment a Hacker News comment replies to, using the official Hacker News
+// API.
+func (h *HackerNewsSearcher) Parent(ctx context.Context, result SearchResult) (*ParentPost, error) {
+	item, err := h.item(ctx, result.URL)
+	if err != nil {
+		return nil, err
+	}
+	if item.Type != "comment" || item.Parent == 0 {
+		return nil, nil
+	}
+
+	parentURL := fmt.Sprintf("https://news.ycombinator.com/item?id=%d", item.Parent)
+	parent, err := h.item(ctx, parentURL)
+	if err != nil {
+		return nil, err
+	}
+	return &ParentPost{URL: parentURL, Title: parent.Title, Author: parent.By, Content: cleanHTMLContent(parent.Text)}, nil
+}
+
+// Engagement updates the points and comment counts of Hacker News results, fetching each item from the
+// official Hacker News API.
+func (h *HackerNewsSearcher) Engagement(ctx context.Context, results []SearchResult) error {
+	var errs []error
+	for i := range results {
+		item, err := h.item(ctx, results[i].URL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		results[i].Score = item.Score
+		results[i].Comments = item.replies()
+	}
+	return errors.Join(errs...)
+}
+
+// item fetches the item a Hacker News URL links to.
+func (h *HackerNewsSearcher) item(ctx context.Context, itemURL string) (hackerNewsItem, error) {
+	u, err := url.Parse(itemURL)
+	if err != nil || u.Query().Get("id") == "" {
+		return hackerNewsItem{}, fmt.Errorf("not a Hacker News item URL: %s", itemURL)
+	}
+
+	apiURL := fmt.Sprintf("https://hacker-news.firebaseio.com/v0/item/%s.json", url.PathEscape(u.Query().Get("id")))
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return hackerNewsItem{}, err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return hackerNewsItem{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return hackerNewsItem{}, fmt.Errorf("item request failed: %s", resp.Status)
+	}
+
+	var item *hackerNewsItem
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		return hackerNewsItem{}, fmt.Errorf("failed to decode item: %w", err)
+	}
+	// Items that no longer exist are returned as null
+	if item == nil {
+		return hackerNewsItem{Deleted: true}, nil
+	}
+	return *item, nil
 }
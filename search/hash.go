@@ -0,0 +1,63 @@
+// search/hash.go
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+// minHashWords is the fewest words a result needs before it is hashed. Short, generic titles such as
+// "Post by Jane" would otherwise collide across unrelated results.
+const minHashWords = 4
+
+// hashPrefixes are platform-specific title prefixes stripped before hashing.
+var hashPrefixes = []string{"show hn", "ask hn", "tell hn", "launch hn"}
+
+// ContentHash returns a hash of the result's normalized title and content: lower case, punctuation
+// removed, and whitespace collapsed. Results with too little text return an empty hash.
+func ContentHash(result SearchResult) string {
+	normalized := strings.Join(normalizedWords(result), " ")
+	for _, prefix := range hashPrefixes {
+		normalized = strings.TrimPrefix(normalized, prefix+" ")
+	}
+
+	if len(strings.Fields(normalized)) < minHashWords {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:16])
+}
+
+// normalizedWords returns the words of the result's title and content in lower case, without punctuation.
+func normalizedWords(result SearchResult) []string {
+	return strings.FieldsFunc(strings.ToLower(result.Title+" "+result.Content), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ChangedFraction returns how much of a result's normalized title and content differs between two
+// versions, from 0 (the same words) to 1 (no words in common): the words in one version but not the
+// other, as a fraction of the longer version's words. Case and punctuation changes are ignored.
+func ChangedFraction(before, after SearchResult) float64 {
+	beforeWords, afterWords := normalizedWords(before), normalizedWords(after)
+	longest := max(len(beforeWords), len(afterWords))
+	if longest == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(beforeWords))
+	for _, word := range beforeWords {
+		counts[word]++
+	}
+	shared := 0
+	for _, word := range afterWords {
+		if counts[word] > 0 {
+			counts[word]--
+			shared++
+		}
+	}
+	return float64(longest-shared) / float64(longest)
+}
@@ -0,0 +1,46 @@
+// search/notification.go
+package search
+
+// Notification is a result handed on to be notified, such as a queued notification or a request to a
+// notifier plugin. SearchResult leaves the fields added for notifications out of its JSON, so they are
+// carried alongside it.
+type Notification struct {
+	Result     SearchResult   `json:"result"`
+	Duplicates []SearchResult `json:"duplicates,omitempty"`
+	Summary    string         `json:"summary,omitempty"`
+	Replies    int64          `json:"replies,omitempty"`
+	NewReplies int64          `json:"new_replies,omitempty"`
+	Edited     bool           `json:"edited,omitempty"`
+	Link       string         `json:"link,omitempty"`
+	Preview    *LinkPreview   `json:"preview,omitempty"`
+	Parent     *ParentPost    `json:"parent,omitempty"`
+}
+
+// NewNotification carries result with the fields added for notifying it.
+func NewNotification(result SearchResult) Notification {
+	return Notification{
+		Result:     result,
+		Duplicates: result.Duplicates,
+		Summary:    result.Summary,
+		Replies:    result.Replies,
+		NewReplies: result.NewReplies,
+		Edited:     result.Edited,
+		Link:       result.Link,
+		Preview:    result.Preview,
+		Parent:     result.Parent,
+	}
+}
+
+// SearchResult returns the result to notify, with the fields added for notifications set again.
+func (n Notification) SearchResult() SearchResult {
+	result := n.Result
+	result.Duplicates = n.Duplicates
+	result.Summary = n.Summary
+	result.Replies = n.Replies
+	result.NewReplies = n.NewReplies
+	result.Edited = n.Edited
+	result.Link = n.Link
+	result.Preview = n.Preview
+	result.Parent = n.Parent
+	return result
+}
@@ -0,0 +1,64 @@
+// search/paginate.go
+package search
+
+import (
+	"context"
+	"errors"
+
+	"github.com/charmbracelet/log"
+)
+
+// DefaultMaxResults is how many results a built-in searcher collects per search unless WithMaxResults
+// says otherwise.
+const DefaultMaxResults = 500
+
+// ErrTruncated is returned, along with the results that were kept, by a search that reached its result
+// limit with more results left, so the caller knows results were dropped and can search for them again.
+var ErrTruncated = errors.New("search reached the result limit")
+
+// maxPages stops a search whose platform keeps returning next-page cursors, whatever its result limit.
+const maxPages = 50
+
+// WithMaxResults limits how many results a searcher collects from one search, across all the pages it
+// walks. Zero or less removes the limit.
+func WithMaxResults(limit int) Option {
+	return func(o *options) {
+		o.maxResults = limit
+	}
+}
+
+// pageFunc fetches the page at cursor ("" for the first page), returning its results and the cursor of the
+// next page, or "" if there are no more pages worth fetching.
+type pageFunc func(ctx context.Context, cursor string) ([]SearchResult, string, error)
+
+// paginate walks pages from fetch until the last page, or until limit results have been collected (zero
+// or less is unlimited). The results of a search that hits the limit with more left are truncated to it and
+// returned with ErrTruncated. An error on any page fails the whole search, so the missing results are
+// searched for again next time.
+func paginate(ctx context.Context, platform string, limit int, fetch pageFunc) ([]SearchResult, error) {
+	var results []SearchResult
+	seen := make(map[string]bool)
+	cursor := ""
+	for page := 0; page < maxPages; page++ {
+		pageResults, next, err := fetch(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, pageResults...)
+		if limit > 0 && len(results) >= limit {
+			if len(results) > limit || next != "" {
+				log.Warn("Search reached the result limit", "platform", platform, "limit", limit)
+				return results[:limit], ErrTruncated
+			}
+			return results, nil
+		}
+		// A repeated cursor would fetch the same page forever
+		if next == "" || seen[next] {
+			return results, nil
+		}
+		seen[next] = true
+		cursor = next
+	}
+	log.Warn("Search reached the page limit", "platform", platform, "pages", maxPages)
+	return results, nil
+}
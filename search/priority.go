@@ -0,0 +1,37 @@
+// search/priority.go
+package search
+
+import "fmt"
+
+// Priority indicates how urgently a result should be brought to someone's attention.
+type Priority string
+
+const (
+	PriorityInfo     Priority = "info"
+	PriorityWarn     Priority = "warn"
+	PriorityCritical Priority = "critical"
+)
+
+// ParsePriority validates a priority name. An empty string is treated as info.
+func ParsePriority(value string) (Priority, error) {
+	switch Priority(value) {
+	case "", PriorityInfo:
+		return PriorityInfo, nil
+	case PriorityWarn, PriorityCritical:
+		return Priority(value), nil
+	default:
+		return "", fmt.Errorf("unknown priority %q: must be one of info, warn, critical", value)
+	}
+}
+
+// Rank orders priorities so they can be compared. Unset priorities rank as info.
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityWarn:
+		return 1
+	case PriorityCritical:
+		return 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,434 @@
+// search/query.go
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Query is a keyword parsed as a boolean query, e.g. `"tailscale" AND (outage OR down) NOT headscale`.
+// AND, OR, and NOT must be upper case; adjacent terms are implicitly ANDed, and a leading "-" negates a term.
+// Keywords without operators, quotes, or parentheses are plain keywords and are passed to platforms as-is.
+type Query struct {
+	raw     string
+	root    queryNode
+	boolean bool
+}
+
+type queryOp int
+
+const (
+	opTerm queryOp = iota
+	opAnd
+	opOr
+	opNot
+)
+
+type queryNode struct {
+	op       queryOp
+	term     string
+	children []queryNode
+}
+
+// ParseQuery parses a keyword into a query.
+func ParseQuery(keyword string) (*Query, error) {
+	tokens, boolean, err := tokenizeQuery(keyword)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %w", keyword, err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("invalid query %q: no search terms", keyword)
+	}
+
+	p := &queryParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %w", keyword, err)
+	}
+
+	return &Query{raw: keyword, root: root, boolean: boolean}, nil
+}
+
+// IsBoolean reports whether the keyword used any query syntax. Plain keywords are not filtered client-side.
+func (q *Query) IsBoolean() bool {
+	return q.boolean
+}
+
+// MatchOptions controls how query terms are compared with text. The zero value matches substrings,
+// ignoring case.
+type MatchOptions struct {
+	// WholeWord requires terms to start and end at word boundaries, so "age" doesn't match "page".
+	WholeWord bool
+	// CaseSensitive compares terms with their exact case.
+	CaseSensitive bool
+	// FoldAccents ignores diacritics, so "cafe" matches "café".
+	FoldAccents bool
+}
+
+// Match evaluates the query against text, matching terms case-insensitively.
+func (q *Query) Match(text string) bool {
+	return q.MatchWith(text, MatchOptions{})
+}
+
+// MatchWith evaluates the query against text using the given options.
+func (q *Query) MatchWith(text string, opts MatchOptions) bool {
+	return q.root.match(opts.normalize(text), opts)
+}
+
+// normalize applies accent folding and case folding to text or a term.
+func (o MatchOptions) normalize(s string) string {
+	if o.FoldAccents {
+		folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+		if err == nil {
+			s = folded
+		}
+	}
+	if !o.CaseSensitive {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+// contains reports whether normalized text contains a term, honouring WholeWord.
+func (o MatchOptions) contains(text, term string) bool {
+	term = o.normalize(term)
+	if !o.WholeWord {
+		return strings.Contains(text, term)
+	}
+
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], term)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(term)
+		if isWordBoundary(text, start, true) && isWordBoundary(text, end, false) {
+			return true
+		}
+		offset = start + 1
+		for offset < len(text) && !utf8.RuneStart(text[offset]) {
+			offset++
+		}
+	}
+}
+
+// isWordBoundary reports whether the rune before (or at) i is not part of a word.
+func isWordBoundary(text string, i int, before bool) bool {
+	var r rune
+	if before {
+		if i == 0 {
+			return true
+		}
+		r, _ = utf8.DecodeLastRuneInString(text[:i])
+	} else {
+		if i >= len(text) {
+			return true
+		}
+		r, _ = utf8.DecodeRuneInString(text[i:])
+	}
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+// Boolean renders the query in AND/OR/NOT syntax for platforms that support it natively, such as Reddit.
+func (q *Query) Boolean() string {
+	if !q.boolean {
+		return q.raw
+	}
+	return q.root.boolean(false)
+}
+
+// Terms renders the terms every match must contain, for platforms with no boolean syntax. The platform
+// returns a superset of matches, so the full query must still be applied client-side with Match. When no
+// term is required (e.g. `a OR b`), every positive term is returned.
+func (q *Query) Terms() string {
+	if !q.boolean {
+		return q.raw
+	}
+	terms := q.root.required()
+	if len(terms) == 0 {
+		terms = q.root.positive()
+	}
+	return strings.Join(terms, " ")
+}
+
+func (n queryNode) match(text string, opts MatchOptions) bool {
+	switch n.op {
+	case opTerm:
+		return opts.contains(text, n.term)
+	case opAnd:
+		for _, child := range n.children {
+			if !child.match(text, opts) {
+				return false
+			}
+		}
+		return true
+	case opOr:
+		for _, child := range n.children {
+			if child.match(text, opts) {
+				return true
+			}
+		}
+		return false
+	case opNot:
+		return !n.children[0].match(text, opts)
+	}
+	return false
+}
+
+func (n queryNode) boolean(nested bool) string {
+	switch n.op {
+	case opTerm:
+		return quoteTerm(n.term)
+	case opNot:
+		return "NOT " + n.children[0].boolean(true)
+	}
+
+	sep := " AND "
+	if n.op == opOr {
+		sep = " OR "
+	}
+	parts := make([]string, len(n.children))
+	for i, child := range n.children {
+		parts[i] = child.boolean(true)
+	}
+	if nested {
+		return "(" + strings.Join(parts, sep) + ")"
+	}
+	return strings.Join(parts, sep)
+}
+
+// required returns the terms present in every match.
+func (n queryNode) required() []string {
+	switch n.op {
+	case opTerm:
+		return []string{quoteTerm(n.term)}
+	case opAnd:
+		var terms []string
+		for _, child := range n.children {
+			terms = append(terms, child.required()...)
+		}
+		return terms
+	case opOr:
+		if len(n.children) == 1 {
+			return n.children[0].required()
+		}
+	}
+	return nil
+}
+
+// positive returns every term that isn't negated.
+func (n queryNode) positive() []string {
+	switch n.op {
+	case opTerm:
+		return []string{quoteTerm(n.term)}
+	case opNot:
+		return nil
+	}
+	var terms []string
+	for _, child := range n.children {
+		terms = append(terms, child.positive()...)
+	}
+	return terms
+}
+
+func quoteTerm(term string) string {
+	if strings.ContainsFunc(term, unicode.IsSpace) {
+		return `"` + term + `"`
+	}
+	return term
+}
+
+type tokenKind int
+
+const (
+	tokTerm tokenKind = iota
+	tokAnd
+	tokOr
+	tokNot
+	tokOpen
+	tokClose
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+}
+
+// tokenizeQuery splits a query into tokens, reporting whether any query syntax was used.
+func tokenizeQuery(s string) ([]queryToken, bool, error) {
+	var tokens []queryToken
+	boolean := false
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			kind := tokOpen
+			if r == ')' {
+				kind = tokClose
+			}
+			tokens = append(tokens, queryToken{kind: kind, text: string(r)})
+			boolean = true
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, false, fmt.Errorf("unterminated quote")
+			}
+			if phrase := strings.TrimSpace(string(runes[i+1 : end])); phrase != "" {
+				tokens = append(tokens, queryToken{kind: tokTerm, text: phrase})
+			}
+			boolean = true
+			i = end + 1
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && (i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '('):
+			tokens = append(tokens, queryToken{kind: tokNot, text: "-"})
+			boolean = true
+			i++
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+				end++
+			}
+			word := string(runes[i:end])
+			switch word {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokAnd, text: word})
+				boolean = true
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokOr, text: word})
+				boolean = true
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokNot, text: word})
+				boolean = true
+			default:
+				tokens = append(tokens, queryToken{kind: tokTerm, text: word})
+			}
+			i = end
+		}
+	}
+
+	return tokens, boolean, nil
+}
+
+// queryParser is a recursive descent parser. NOT binds tightest, then AND (explicit or implicit), then OR.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return queryNode{}, err
+	}
+	children := []queryNode{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokOr {
+			break
+		}
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return queryNode{}, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return queryNode{op: opOr, children: children}, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return queryNode{}, err
+	}
+	children := []queryNode{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokOr || tok.kind == tokClose {
+			break
+		}
+		if tok.kind == tokAnd {
+			p.pos++
+		}
+		next, err := p.parseUnary()
+		if err != nil {
+			return queryNode{}, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return queryNode{op: opAnd, children: children}, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return queryNode{}, fmt.Errorf("unexpected end of query")
+	}
+
+	switch tok.kind {
+	case tokNot:
+		p.pos++
+		child, err := p.parseUnary()
+		if err != nil {
+			return queryNode{}, err
+		}
+		return queryNode{op: opNot, children: []queryNode{child}}, nil
+	case tokOpen:
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return queryNode{}, err
+		}
+		if tok, ok := p.peek(); !ok || tok.kind != tokClose {
+			return queryNode{}, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+	case tokTerm:
+		p.pos++
+		return queryNode{op: opTerm, term: tok.text}, nil
+	}
+	return queryNode{}, fmt.Errorf("unexpected %q", tok.text)
+}
+
+// platformQuery converts a keyword into the query sent to a platform. Platforms with native boolean search
+// get the full query; the rest get its required terms. Unparseable keywords are sent unchanged.
+func platformQuery(keyword string, nativeBoolean bool) string {
+	q, err := ParseQuery(keyword)
+	if err != nil {
+		return keyword
+	}
+	if nativeBoolean {
+		return q.Boolean()
+	}
+	return q.Terms()
+}
@@ -0,0 +1,122 @@
+package search
+
+import "testing"
+
+func TestParseQueryPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyword string
+		boolean bool
+		want    string
+	}{
+		{name: "plain keyword", keyword: "tailscale", want: "tailscale"},
+		{name: "hyphenated word", keyword: "well-known", want: "well-known"},
+		{name: "AND binds tighter than OR", keyword: "a OR b c", boolean: true, want: "a OR (b AND c)"},
+		{name: "implicit AND before OR", keyword: "a b OR c", boolean: true, want: "(a AND b) OR c"},
+		{name: "NOT binds tighter than AND", keyword: "NOT a b", boolean: true, want: "NOT a AND b"},
+		{name: "leading dash negates", keyword: "-a OR b", boolean: true, want: "NOT a OR b"},
+		{name: "parentheses group", keyword: "a (b OR c)", boolean: true, want: "a AND (b OR c)"},
+		{name: "quoted phrase", keyword: `"tailscale outage" AND (down OR broken)`, boolean: true, want: `"tailscale outage" AND (down OR broken)`},
+		{name: "quoted single word", keyword: `"tailscale"`, boolean: true, want: "tailscale"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuery(tt.keyword)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) returned error: %v", tt.keyword, err)
+			}
+			if q.IsBoolean() != tt.boolean {
+				t.Errorf("IsBoolean() = %v, want %v", q.IsBoolean(), tt.boolean)
+			}
+			if got := q.Boolean(); got != tt.want {
+				t.Errorf("Boolean() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyword string
+	}{
+		{name: "empty", keyword: ""},
+		{name: "empty phrase", keyword: `""`},
+		{name: "unterminated quote", keyword: `"tailscale outage`},
+		{name: "missing closing parenthesis", keyword: "(a OR b"},
+		{name: "unexpected closing parenthesis", keyword: "a)"},
+		{name: "trailing operator", keyword: "a OR"},
+		{name: "operator alone", keyword: "AND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseQuery(tt.keyword); err == nil {
+				t.Errorf("ParseQuery(%q) returned no error", tt.keyword)
+			}
+		})
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	tests := []struct {
+		keyword string
+		want    string
+	}{
+		{keyword: "tailscale", want: "tailscale"},
+		{keyword: `"tailscale" AND (outage OR down) NOT headscale`, want: "tailscale"},
+		{keyword: `"open source" -proprietary`, want: `"open source"`},
+		{keyword: "a OR b", want: "a b"},
+		{keyword: "a b", want: "a b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keyword, func(t *testing.T) {
+			q, err := ParseQuery(tt.keyword)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) returned error: %v", tt.keyword, err)
+			}
+			if got := q.Terms(); got != tt.want {
+				t.Errorf("Terms() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyword string
+		opts    MatchOptions
+		text    string
+		want    bool
+	}{
+		{name: "all required terms", keyword: `"tailscale" AND (outage OR down) NOT headscale`, text: "Tailscale outage today", want: true},
+		{name: "alternative term", keyword: `"tailscale" AND (outage OR down) NOT headscale`, text: "tailscale is down", want: true},
+		{name: "missing alternative", keyword: `"tailscale" AND (outage OR down) NOT headscale`, text: "tailscale release", want: false},
+		{name: "negated term", keyword: `"tailscale" AND (outage OR down) NOT headscale`, text: "Tailscale outage, headscale unaffected", want: false},
+		{name: "phrase in order", keyword: `"open source" vpn`, text: "an Open Source VPN", want: true},
+		{name: "phrase out of order", keyword: `"open source" vpn`, text: "source open vpn", want: false},
+		{name: "OR of lone term", keyword: "a OR b c", text: "a", want: true},
+		{name: "AND needs both terms", keyword: "a OR b c", text: "b", want: false},
+		{name: "substring by default", keyword: "age", text: "page", want: true},
+		{name: "whole word rejects substring", keyword: "age", opts: MatchOptions{WholeWord: true}, text: "page", want: false},
+		{name: "whole word", keyword: "age", opts: MatchOptions{WholeWord: true}, text: "old age", want: true},
+		{name: "case sensitive", keyword: "AGE", opts: MatchOptions{CaseSensitive: true}, text: "age", want: false},
+		{name: "accents kept", keyword: "cafe", text: "Café", want: false},
+		{name: "accents folded", keyword: "cafe", opts: MatchOptions{FoldAccents: true}, text: "Café", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuery(tt.keyword)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) returned error: %v", tt.keyword, err)
+			}
+			if got := q.MatchWith(tt.text, tt.opts); got != tt.want {
+				t.Errorf("MatchWith(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,40 +3,74 @@ package search
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
-	"time"
+	"strings"
+	"sync"
 )
 
+// Reddit's OAuth endpoints.
+const (
+	RedditAuthURL  = "https://www.reddit.com/api/v1/authorize"
+	RedditTokenURL = "https://www.reddit.com/api/v1/access_token"
+)
+
+// RedditUserAgent identifies grass to Reddit, which throttles requests without a descriptive user agent.
+const RedditUserAgent = "GoRedditBot/1.0"
+
 type RedditSearcher struct {
 	clientID     string
 	clientSecret string
 	username     string
 	password     string
-	accessToken  string
+	refreshToken string
+	tokens       TokenStore
+	client       *http.Client
+	maxResults   int
+
+	// mu guards token, the current access token.
+	mu    sync.Mutex
+	token *Token
 }
 
-func NewRedditSearcher() (*RedditSearcher, error) {
+// NewRedditSearcher creates a Reddit searcher, authenticating as the account of a script app, or with the
+// refresh token of an installed or web app from grass login, which is used instead when set.
+func NewRedditSearcher(ctx context.Context, opts ...Option) (*RedditSearcher, error) {
 	clientID := os.Getenv("REDDIT_CLIENT_ID")
 	clientSecret := os.Getenv("REDDIT_CLIENT_SECRET")
 	username := os.Getenv("REDDIT_USERNAME")
 	password := os.Getenv("REDDIT_PASSWORD")
+	refreshToken := os.Getenv("REDDIT_REFRESH_TOKEN")
 
-	if clientID == "" || clientSecret == "" || username == "" || password == "" {
+	// Installed apps have no secret, so only the client ID is needed with a refresh token
+	if refreshToken != "" {
+		if clientID == "" {
+			return nil, errors.New("missing Reddit API credentials: REDDIT_CLIENT_ID is required with REDDIT_REFRESH_TOKEN")
+		}
+	} else if clientID == "" || clientSecret == "" || username == "" || password == "" {
 		return nil, errors.New("missing Reddit API credentials")
 	}
 
+	o := newOptions(opts)
 	searcher := &RedditSearcher{
 		clientID:     clientID,
 		clientSecret: clientSecret,
 		username:     username,
 		password:     password,
+		refreshToken: refreshToken,
+		tokens:       o.tokens,
+		client:       o.client,
+		maxResults:   o.maxResults,
 	}
-	if err := searcher.authenticate(); err != nil {
+	searcher.token = loadToken(ctx, o.tokens, searcher.tokenKey())
+	if _, err := searcher.accessToken(ctx); err != nil {
 		return nil, err
 	}
 	return searcher, nil
@@ -47,97 +81,360 @@ func (r *RedditSearcher) Platform() string {
 	return "Reddit"
 }
 
+// tokenKey is the key the access token is stored under, which differs for each account and refresh token
+// so a token isn't reused after the credentials change.
+func (r *RedditSearcher) tokenKey() string {
+	if r.refreshToken != "" {
+		sum := sha256.Sum256([]byte(r.refreshToken))
+		return "reddit/" + r.clientID + "/" + hex.EncodeToString(sum[:8])
+	}
+	return "reddit/" + r.clientID + "/" + r.username
+}
+
+// accessToken returns a current access token, authenticating again once the last one has expired. New
+// tokens are stored for later processes.
+func (r *RedditSearcher) accessToken(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token.Valid() {
+		return r.token.AccessToken, nil
+	}
+	token, err := r.authenticate(ctx)
+	if err != nil {
+		return "", err
+	}
+	r.token = token
+	saveToken(ctx, r.tokens, r.tokenKey(), *token)
+	return token.AccessToken, nil
+}
+
+// authorize sets the Authorization and User-Agent headers of a request to the Reddit API.
+func (r *RedditSearcher) authorize(ctx context.Context, req *http.Request) error {
+	token, err := r.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", RedditUserAgent)
+	return nil
+}
+
 // Authenticate with Reddit to get an access token
-func (r *RedditSearcher) authenticate() error {
+func (r *RedditSearcher) authenticate(ctx context.Context) (*Token, error) {
 	data := url.Values{}
-	data.Set("grant_type", "password")
-	data.Set("username", r.username)
-	data.Set("password", r.password)
+	if r.refreshToken != "" {
+		data.Set("grant_type", "refresh_token")
+		data.Set("refresh_token", r.refreshToken)
+	} else {
+		data.Set("grant_type", "password")
+		data.Set("username", r.username)
+		data.Set("password", r.password)
+	}
 
-	req, err := http.NewRequest("POST", "https://www.reddit.com/api/v1/access_token", bytes.NewBufferString(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", RedditTokenURL, bytes.NewBufferString(data.Encode()))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.SetBasicAuth(r.clientID, r.clientSecret)
-	req.Header.Set("User-Agent", "GoRedditBot/1.0")
+	req.Header.Set("User-Agent", RedditUserAgent)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// Requesting a token is safe to repeat, so mark it idempotent for retries (nil headers aren't sent)
+	req.Header["X-Idempotency-Key"] = nil
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to authenticate with Reddit: %s", resp.Status)
+		return nil, fmt.Errorf("failed to authenticate with Reddit: %s", resp.Status)
 	}
 
 	var result struct {
 		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return err
+		return nil, err
 	}
+	return &Token{AccessToken: result.AccessToken, Expiry: expiresIn(result.ExpiresIn)}, nil
+}
 
-	r.accessToken = result.AccessToken
-	return nil
+// redditPageSize is the most posts Reddit returns per search page.
+const redditPageSize = 100
+
+// Search Reddit for posts matching a keyword after a specific epoch time, paging back through newer posts
+// until one is older than the epoch time.
+func (r *RedditSearcher) Search(ctx context.Context, keyword string, afterEpochSecs int64) ([]SearchResult, error) {
+	searchURL := fmt.Sprintf("https://oauth.reddit.com/search?q=%s&sort=new&limit=%d", url.QueryEscape(platformQuery(keyword, true)), redditPageSize)
+	return paginate(ctx, r.Platform(), r.maxResults, func(ctx context.Context, cursor string) ([]SearchResult, string, error) {
+		return r.searchPage(ctx, searchURL, keyword, afterEpochSecs, cursor)
+	})
+}
+
+// SearchAccount returns the posts a Reddit user submitted after the epoch time. The account may be given
+// with or without its "u/" prefix.
+func (r *RedditSearcher) SearchAccount(ctx context.Context, account string, afterEpochSecs int64) ([]SearchResult, error) {
+	name := strings.TrimPrefix(strings.TrimPrefix(account, "/"), "u/")
+	submittedURL := fmt.Sprintf("https://oauth.reddit.com/user/%s/submitted?sort=new&limit=%d", url.PathEscape(name), redditPageSize)
+	return paginate(ctx, r.Platform(), r.maxResults, func(ctx context.Context, cursor string) ([]SearchResult, string, error) {
+		return r.searchPage(ctx, submittedURL, account, afterEpochSecs, cursor)
+	})
 }
 
-// Search Reddit for posts matching a keyword after a specific epoch time
-func (r *RedditSearcher) Search(keyword string, afterEpochSecs int64) ([]SearchResult, error) {
-	searchURL := fmt.Sprintf("https://oauth.reddit.com/search?q=%s&sort=new&restrict_sr=1", url.QueryEscape(keyword))
-	req, err := http.NewRequest("GET", searchURL, nil)
+// searchPage fetches the page of the post listing at listingURL after the fullname in cursor, returning the
+// posts newer than afterEpochSecs and the cursor of the next page, if any.
+func (r *RedditSearcher) searchPage(ctx context.Context, listingURL, keyword string, afterEpochSecs int64, cursor string) ([]SearchResult, string, error) {
+	searchURL := listingURL
+	if cursor != "" {
+		searchURL += "&after=" + url.QueryEscape(cursor)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	if err := r.authorize(ctx, req); err != nil {
+		return nil, "", err
 	}
-	req.Header.Set("Authorization", "Bearer "+r.accessToken)
-	req.Header.Set("User-Agent", "GoRedditBot/1.0")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("search request failed: %s", resp.Status)
+		return nil, "", fmt.Errorf("search request failed: %s", resp.Status)
 	}
 
 	var data struct {
 		Data struct {
 			Children []struct {
 				Data struct {
-					Title     string  `json:"title"`
-					URL       string  `json:"url"`
-					Permalink string  `json:"permalink"`
-					CreatedAt float64 `json:"created_utc"`
+					Title       string  `json:"title"`
+					URL         string  `json:"url"`
+					Permalink   string  `json:"permalink"`
+					CreatedAt   float64 `json:"created_utc"`
+					Score       int64   `json:"score"`
+					NumComments int64   `json:"num_comments"`
+					IsSelf      bool    `json:"is_self"`
+					SelfText    string  `json:"selftext"`
+					Domain      string  `json:"domain"`
+					Author      string  `json:"author"`
+					Subreddit   string  `json:"subreddit"`
+					Flair       string  `json:"link_flair_text"`
 				} `json:"data"`
 			} `json:"children"`
+			After string `json:"after"`
 		} `json:"data"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var results []SearchResult
-	timestamp := time.Now().Unix()
+	next := data.Data.After
 	for _, child := range data.Data.Children {
 		post := child.Data
-		// Only include results after the specified epoch time
-		if int64(post.CreatedAt) > afterEpochSecs {
-			// Use permalink to link directly to the Reddit post
-			postURL := fmt.Sprintf("https://www.reddit.com%s", post.Permalink)
-			results = append(results, SearchResult{
-				Platform:  r.Platform(),
-				Keyword:   keyword,
-				Title:     post.Title,
-				URL:       postURL,
-				Timestamp: timestamp,
-			})
+		// Only include results after the specified epoch time; older posts mean later pages are older still
+		if int64(post.CreatedAt) <= afterEpochSecs {
+			next = ""
+			continue
+		}
+		// Use permalink to link directly to the Reddit post
+		postURL := fmt.Sprintf("https://www.reddit.com%s", post.Permalink)
+		// Link submissions point elsewhere; text posts' URL is their own permalink
+		var link string
+		if !post.IsSelf && post.URL != postURL {
+			link = post.URL
+		}
+		metadata := map[string]string{"subreddit": post.Subreddit}
+		if post.Flair != "" {
+			metadata["flair"] = post.Flair
 		}
+		// Link submissions have no body of their own, so record where they link to instead
+		if link != "" && post.Domain != "" {
+			metadata["domain"] = post.Domain
+		}
+		results = append(results, SearchResult{
+			Platform:  r.Platform(),
+			Keyword:   keyword,
+			Title:     post.Title,
+			URL:       postURL,
+			Content:   post.SelfText,
+			Timestamp: int64(post.CreatedAt),
+			Author:    post.Author,
+			Score:     post.Score,
+			Comments:  post.NumComments,
+			Metadata:  metadata,
+			Link:      link,
+		})
 	}
 
-	return results, nil
+	return results, next, nil
+}
+
+// redditInfoMax is the most posts the info endpoint returns per request.
+const redditInfoMax = 100
+
+// redditPost is a post or comment from the info endpoint, with its current engagement.
+type redditPost struct {
+	Name        string `json:"name"`
+	Score       int64  `json:"score"`
+	NumComments int64  `json:"num_comments"`
+	Title       string `json:"title"`
+	Author      string `json:"author"`
+	Permalink   string `json:"permalink"`
+	// SelfText is a text post's body; Body is a comment's
+	SelfText string `json:"selftext"`
+	Body     string `json:"body"`
+	// ParentID is the fullname of the post or comment a comment replies to
+	ParentID string `json:"parent_id"`
+	// RemovedByCategory says who removed a post, e.g. "moderator" or "deleted" by its author, and is
+	// null for posts that are still up.
+	RemovedByCategory *string `json:"removed_by_category"`
+}
+
+// Replies returns the comment count of a Reddit post.
+func (r *RedditSearcher) Replies(ctx context.Context, result SearchResult) (int64, error) {
+	id, err := redditPostID(result.URL)
+	if err != nil {
+		return 0, err
+	}
+	posts, err := r.info(ctx, []string{id})
+	if err != nil {
+		return 0, err
+	}
+	if len(posts) == 0 {
+		return 0, fmt.Errorf("Reddit post not found: %s", result.URL)
+	}
+	return posts[0].NumComments, nil
+}
+
+// Deleted reports whether a Reddit post has been deleted by its author or removed by moderators.
+func (r *RedditSearcher) Deleted(ctx context.Context, result SearchResult) (bool, error) {
+	id, err := redditPostID(result.URL)
+	if err != nil {
+		return false, err
+	}
+	posts, err := r.info(ctx, []string{id})
+	if err != nil {
+		return false, err
+	}
+	return len(posts) == 0 || posts[0].RemovedByCategory != nil, nil
+}
+
+// Engagement updates the score and comment counts of Reddit posts, looking them up in batches.
+func (r *RedditSearcher) Engagement(ctx context.Context, results []SearchResult) error {
+	indexes := make(map[string][]int)
+	var ids []string
+	var errs []error
+	for i, result := range results {
+		id, err := redditPostID(result.URL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, ok := indexes[id]; !ok {
+			ids = append(ids, id)
+		}
+		indexes[id] = append(indexes[id], i)
+	}
+
+	for start := 0; start < len(ids); start += redditInfoMax {
+		posts, err := r.info(ctx, ids[start:min(start+redditInfoMax, len(ids))])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, post := range posts {
+			for _, i := range indexes[post.Name] {
+				results[i].Score = post.Score
+				results[i].Comments = post.NumComments
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Parent fetches the post or comment a Reddit comment replies to. Results linking to posts rather than
+// comments have no parent.
+func (r *RedditSearcher) Parent(ctx context.Context, result SearchResult) (*ParentPost, error) {
+	// Comment permalinks look like /r/<subreddit>/comments/<post id>/<slug>/<comment id>/
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(result.URL, "https://www.reddit.com"), "/"), "/")
+	if len(parts) < 6 || parts[2] != "comments" || parts[5] == "" {
+		return nil, nil
+	}
+	comments, err := r.info(ctx, []string{"t1_" + parts[5]})
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 || comments[0].ParentID == "" {
+		return nil, fmt.Errorf("Reddit comment not found: %s", result.URL)
+	}
+
+	parents, err := r.info(ctx, []string{comments[0].ParentID})
+	if err != nil {
+		return nil, err
+	}
+	if len(parents) == 0 {
+		return nil, fmt.Errorf("parent of Reddit comment not found: %s", result.URL)
+	}
+	parent := parents[0]
+	return &ParentPost{
+		URL:     "https://www.reddit.com" + parent.Permalink,
+		Title:   parent.Title,
+		Author:  parent.Author,
+		Content: parent.SelfText + parent.Body,
+	}, nil
+}
+
+// redditPostID returns the fullname (t3_<id>) of the post a permalink links to.
+func redditPostID(permalink string) (string, error) {
+	// Permalinks look like /r/<subreddit>/comments/<id>/<slug>/
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(permalink, "https://www.reddit.com"), "/"), "/")
+	if len(parts) < 4 || parts[2] != "comments" {
+		return "", fmt.Errorf("not a Reddit post URL: %s", permalink)
+	}
+	return "t3_" + parts[3], nil
+}
+
+// info looks up posts by fullname.
+func (r *RedditSearcher) info(ctx context.Context, ids []string) ([]redditPost, error) {
+	infoURL := fmt.Sprintf("https://oauth.reddit.com/api/info?id=%s", url.QueryEscape(strings.Join(ids, ",")))
+	req, err := http.NewRequestWithContext(ctx, "GET", infoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.authorize(ctx, req); err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("info request failed: %s", resp.Status)
+	}
+
+	var data struct {
+		Data struct {
+			Children []struct {
+				Data redditPost `json:"data"`
+			} `json:"children"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	posts := make([]redditPost, 0, len(data.Data.Children))
+	for _, child := range data.Data.Children {
+		posts = append(posts, child.Data)
+	}
+	return posts, nil
 }
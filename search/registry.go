@@ -0,0 +1,79 @@
+// search/registry.go
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownSearcher is returned by New for a name no searcher is registered under.
+var ErrUnknownSearcher = errors.New("unknown searcher")
+
+// Factory creates a searcher configured by opts.
+type Factory func(ctx context.Context, opts ...Option) (Searcher, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+func init() {
+	Register("hackernews", func(ctx context.Context, opts ...Option) (Searcher, error) {
+		return NewHackerNewsSearcher(opts...), nil
+	})
+	Register("reddit", func(ctx context.Context, opts ...Option) (Searcher, error) {
+		return asSearcher(NewRedditSearcher(ctx, opts...))
+	})
+	Register("bluesky", func(ctx context.Context, opts ...Option) (Searcher, error) {
+		return asSearcher(NewBlueskySearcher(ctx, opts...))
+	})
+	Register("fediverse", func(ctx context.Context, opts ...Option) (Searcher, error) {
+		return asSearcher(NewFediverseSearcher(ctx, opts...))
+	})
+	Register("youtube", func(ctx context.Context, opts ...Option) (Searcher, error) {
+		return asSearcher(NewYouTubeSearcher(opts...))
+	})
+}
+
+// Register makes a searcher available to New under name, replacing any registered under the same name.
+// Programs embedding grass register their own searchers this way.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// Registered returns the names searchers are registered under, sorted.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the searcher registered under name, returning an error wrapping ErrUnknownSearcher if there
+// is none.
+func New(ctx context.Context, name string, opts ...Option) (Searcher, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSearcher, name)
+	}
+	return factory(ctx, opts...)
+}
+
+// asSearcher returns a constructor's searcher as a Searcher, or a nil Searcher if it failed.
+func asSearcher[S Searcher](searcher S, err error) (Searcher, error) {
+	if err != nil {
+		return nil, err
+	}
+	return searcher, nil
+}
@@ -0,0 +1,201 @@
+// search/retry.go
+package search
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// httpClient is shared by every searcher without its own client, so all platform requests go through the
+// response cache and retry layer.
+var httpClient = retryClient(nil)
+
+// SetHTTPClient sends the requests of searchers created afterwards without their own client, and of
+// shortened URL resolution, through client's transport. Call it before creating searchers.
+func SetHTTPClient(client *http.Client) {
+	httpClient = retryClient(client)
+	shortURLClient = newShortURLClient(client.Transport)
+}
+
+// retryClient copies client, or a default client if it is nil, with its transport behind a retry layer and
+// the shared response cache.
+func retryClient(client *http.Client) *http.Client {
+	var retrying http.Client
+	if client != nil {
+		retrying = *client
+	}
+	transport := retrying.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	retrying.Transport = &CacheTransport{Base: NewRetryTransport(transport), Cache: responseCache}
+	return &retrying
+}
+
+// Option configures a built-in searcher.
+type Option func(*options)
+
+type options struct {
+	client     *http.Client
+	maxResults int
+	tokens     TokenStore
+}
+
+// WithHTTPClient sends a searcher's requests through client, behind its own retry layer, instead of the
+// shared client. It gives a searcher its own proxy or TLS settings.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = retryClient(client)
+	}
+}
+
+// newOptions applies opts over the defaults.
+func newOptions(opts []Option) options {
+	o := options{client: httpClient, maxResults: DefaultMaxResults}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// RetryTransport retries idempotent requests that fail with a network error, 429, or 5xx response, using
+// exponential backoff with full jitter and honouring Retry-After. A retry budget shared by every request
+// through the transport stops retries from piling onto a platform that is down.
+type RetryTransport struct {
+	Base http.RoundTripper
+	// MaxAttempts is the most times a request is sent, including the first.
+	MaxAttempts int
+	// BaseDelay and MaxDelay bound the backoff between attempts. Retry-After is capped at MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	budget *retryBudget
+}
+
+// NewRetryTransport wraps base with three attempts, backing off from 500ms up to 30s.
+func NewRetryTransport(base http.RoundTripper) *RetryTransport {
+	return &RetryTransport{
+		Base:        base,
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		budget:      newRetryBudget(10, 0.1),
+	}
+}
+
+// RoundTrip sends the request, retrying it when it is safe and worthwhile to do so.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := t.Base.RoundTrip(req)
+		if !retryable(req, resp, err) || attempt >= t.MaxAttempts || !idempotent(req) {
+			if err == nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+				t.budget.success()
+			}
+			return resp, err
+		}
+		if !t.budget.spend() {
+			log.Debug("Retry budget exhausted", "host", req.URL.Host)
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if resp != nil {
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		log.Debug("Retrying request", "host", req.URL.Host, "path", req.URL.Path, "attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt: the response's Retry-After if present, otherwise an
+// exponentially growing delay with full jitter.
+func (t *RetryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, t.MaxDelay)
+		}
+		if at, err := http.ParseTime(resp.Header.Get("Retry-After")); err == nil {
+			return min(max(time.Until(at), 0), t.MaxDelay)
+		}
+	}
+
+	ceiling := min(t.BaseDelay<<(attempt-1), t.MaxDelay)
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// retryable reports whether a response or error is worth retrying. Errors after the request's context is
+// done are final.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// idempotent reports whether a request can be safely sent again. Like net/http, requests with an
+// Idempotency-Key or X-Idempotency-Key header (even a nil one, which isn't sent) count as idempotent, and
+// requests with a body must be able to replay it.
+func idempotent(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, hasKey := req.Header["Idempotency-Key"]
+	_, hasXKey := req.Header["X-Idempotency-Key"]
+	return hasKey || hasXKey
+}
+
+// retryBudget is a token bucket limiting retries to a fraction of successful requests.
+type retryBudget struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	refill float64
+}
+
+func newRetryBudget(max, refill float64) *retryBudget {
+	return &retryBudget{tokens: max, max: max, refill: refill}
+}
+
+// spend takes a token for a retry, reporting false when the budget is exhausted.
+func (b *retryBudget) spend() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// success earns back part of a token.
+func (b *retryBudget) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+b.refill, b.max)
+}
@@ -1,6 +1,8 @@
 // search/search.go
 package search
 
+import "context"
+
 type SearchResult struct {
 	Platform  string
 	Keyword   string
@@ -8,10 +10,116 @@ type SearchResult struct {
 	URL       string
 	Timestamp int64
 	Content   string
+	Author    string
+	Score     int64
+	// Comments, Reposts, and Views are engagement counts alongside Score (points, upvotes, likes, or
+	// favourites, depending on the platform), for platforms that report them. They are as of when the
+	// result was found, or just before it was saved when engagement is fetched.
+	Comments int64
+	Reposts  int64
+	Views    int64
+	// Tags are labels attached to the result by its platform, such as hashtags, or by processors.
+	Tags []string `json:",omitempty"`
+	// Metadata holds extra details about the result that have no field of their own, such as the
+	// subreddit a Reddit post was found in, set by searchers and processors. Keys are lower case by
+	// convention.
+	Metadata map[string]string `json:",omitempty"`
+	Priority Priority
+	// Keywords lists every keyword the result matched when several did, starting with Keyword. It is set
+	// on notified results and, by storers that track them, stored alongside Keyword.
+	Keywords []string `json:",omitempty"`
+	// ContentHash identifies results with the same normalized title and content, so the same story posted
+	// to several platforms can be recognised. See ContentHash.
+	ContentHash string
+	// Duplicates holds copies of this result found on other platforms, for grouped notifications. It is
+	// never stored.
+	Duplicates []SearchResult `json:"-"`
+	// AuthorCreatedAt is when the author's account was created, in Unix seconds, for platforms that report
+	// it. It is used to spot spam from new accounts and is never stored.
+	AuthorCreatedAt int64 `json:"-"`
+	// Summary is a short summary of long content, added for notifications when summarization is enabled.
+	// It is never stored.
+	Summary string `json:"-"`
+	// Replies and NewReplies are set on follow-up notifications: the discussion's total replies and how
+	// many arrived since the last notification. They are never stored.
+	Replies    int64 `json:"-"`
+	NewReplies int64 `json:"-"`
+	// DeletedAt is when the result's post was found to be deleted or removed, in Unix seconds, or zero.
+	DeletedAt int64 `json:",omitempty"`
+	// Edited is set when a result is notified again because its post was edited after it was first saved.
+	// It is never stored.
+	Edited bool `json:"-"`
+	// Link is the page a link post points to, such as a Hacker News story's or Reddit submission's URL,
+	// when it differs from URL. It is never stored.
+	Link string `json:"-"`
+	// Preview describes Link, added for notifications when link unfurling is enabled. It is never stored.
+	Preview *LinkPreview `json:"-"`
+	// Parent is the post a comment or reply result responds to, added for notifications when thread
+	// context is enabled. It is never stored.
+	Parent *ParentPost `json:"-"`
+}
+
+// LinkPreview describes a linked page from its OpenGraph metadata, falling back to its HTML title and
+// description.
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// ParentPost is the post a comment or reply responds to. Title is set when the parent is a story or
+// submission with a title of its own, and Content is a snippet of its text.
+type ParentPost struct {
+	URL     string `json:"url"`
+	Title   string `json:"title,omitempty"`
+	Author  string `json:"author,omitempty"`
+	Content string `json:"content,omitempty"`
 }
 
-// Searcher defines the interface that all search providers must implement.
+// Searcher defines the interface that all search providers must implement. Search should abandon its
+// requests when ctx is cancelled or its deadline passes. A search that stops at its result limit returns
+// the results it kept along with ErrTruncated.
 type Searcher interface {
-	Search(keyword string, afterEpochSecs int64) ([]SearchResult, error)
+	Search(ctx context.Context, keyword string, afterEpochSecs int64) ([]SearchResult, error)
 	Platform() string
 }
+
+// ActivityChecker is implemented by searchers that can count the replies to a result they returned, so
+// discussions that develop after a result is notified can be followed up.
+type ActivityChecker interface {
+	Replies(ctx context.Context, result SearchResult) (int64, error)
+}
+
+// EngagementFetcher is implemented by searchers that can fetch the current engagement of results they
+// returned. Engagement updates each result's Score, Comments, Reposts, and Views in place, leaving results
+// it can't find unchanged.
+type EngagementFetcher interface {
+	Engagement(ctx context.Context, results []SearchResult) error
+}
+
+// DeletionChecker is implemented by searchers that can tell whether a result they returned has since been
+// deleted by its author or removed by moderators, so removals of notified results can be reported.
+type DeletionChecker interface {
+	Deleted(ctx context.Context, result SearchResult) (bool, error)
+}
+
+// ThreadFetcher is implemented by searchers that can fetch the post a result they returned replies to, so
+// comments can be notified with the context of their thread. Parent returns nil when the result isn't a
+// reply.
+type ThreadFetcher interface {
+	Parent(ctx context.Context, result SearchResult) (*ParentPost, error)
+}
+
+// StreamingSearcher is implemented by searchers for continuous sources, such as firehoses and streaming
+// APIs, that push results as they are posted. In daemon mode they are streamed instead of polled; Search is
+// still used by one-shot runs and to catch up on results posted while the stream was disconnected.
+//
+// Stream sends results matching any of keywords to results, with Keyword set to the keyword they matched,
+// until ctx is cancelled or the connection fails. It always returns once ctx is cancelled, and returns an
+// error describing why the stream ended otherwise.
+type StreamingSearcher interface {
+	Searcher
+	Stream(ctx context.Context, keywords []string, results chan<- SearchResult) error
+}
@@ -0,0 +1,99 @@
+// search/token.go
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// tokenExpiryMargin is how long before its expiry an access token is refreshed, so it doesn't expire
+// partway through a search.
+const tokenExpiryMargin = time.Minute
+
+// Token is a credential a searcher authenticates with, such as a Bluesky session or a Reddit access token.
+type Token struct {
+	AccessToken string `json:"access_token"`
+	// RefreshToken renews AccessToken without authenticating from scratch, for platforms that issue one.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// Expiry is when AccessToken expires, or zero if it doesn't.
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// Valid reports whether the access token is set and not about to expire.
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Until(t.Expiry) > tokenExpiryMargin)
+}
+
+// TokenStore keeps the tokens searchers authenticate with, so a restarted process reuses and refreshes
+// them instead of authenticating from scratch, which platforms such as Bluesky rate limit.
+type TokenStore interface {
+	// Token returns the token stored under key, or nil if there is none.
+	Token(ctx context.Context, key string) (*Token, error)
+	// PutToken stores a token under key, replacing any stored before.
+	PutToken(ctx context.Context, key string, token Token) error
+}
+
+// WithTokenStore keeps the tokens a searcher authenticates with in store.
+func WithTokenStore(store TokenStore) Option {
+	return func(o *options) {
+		o.tokens = store
+	}
+}
+
+// loadToken returns the token stored under key, or nil if there is none or no store. A store that can't be
+// read is only logged, so the searcher authenticates from scratch instead.
+func loadToken(ctx context.Context, store TokenStore, key string) *Token {
+	if store == nil {
+		return nil
+	}
+	token, err := store.Token(ctx, key)
+	if err != nil {
+		log.Warn("Failed to load stored token", "key", key, "error", err)
+		return nil
+	}
+	return token
+}
+
+// saveToken stores a token under key if there is a store. Failing to store it is only logged, since the
+// token is still good for this process.
+func saveToken(ctx context.Context, store TokenStore, key string, token Token) {
+	if store == nil {
+		return
+	}
+	if err := store.PutToken(ctx, key, token); err != nil {
+		log.Warn("Failed to store token", "key", key, "error", err)
+	}
+}
+
+// jwtExpiry returns the expiry of a JWT from its exp claim, or zero if it has none. The token's signature
+// isn't checked; the expiry only decides when to refresh it.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// expiresIn returns the expiry of a token issued now that lasts seconds, or zero if seconds isn't positive.
+func expiresIn(seconds int64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(seconds) * time.Second)
+}
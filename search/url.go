@@ -0,0 +1,171 @@
+// search/url.go
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxShortURLHops bounds how many redirects are followed when resolving a shortened URL.
+const maxShortURLHops = 5
+
+// trackingParams are query parameters that only identify where a link was shared from.
+var trackingParams = map[string]bool{
+	"fbclid":     true,
+	"gclid":      true,
+	"dclid":      true,
+	"msclkid":    true,
+	"igshid":     true,
+	"mc_cid":     true,
+	"mc_eid":     true,
+	"ref_src":    true,
+	"ref_url":    true,
+	"si":         true,
+	"feature":    true,
+	"_hsenc":     true,
+	"_hsmi":      true,
+	"s_cid":      true,
+	"cmpid":      true,
+	"share_id":   true,
+	"utm":        true,
+	"amp":        true,
+	"outputtype": true,
+}
+
+// shortenerHosts are link shorteners whose URLs are resolved to their destination.
+var shortenerHosts = map[string]bool{
+	"bit.ly":      true,
+	"buff.ly":     true,
+	"dlvr.it":     true,
+	"goo.gl":      true,
+	"is.gd":       true,
+	"lnkd.in":     true,
+	"ow.ly":       true,
+	"redd.it":     true,
+	"t.co":        true,
+	"tinyurl.com": true,
+	"trib.al":     true,
+}
+
+// shortURLClient resolves shortened URLs one redirect at a time so each hop can be inspected.
+var shortURLClient = newShortURLClient(nil)
+
+// newShortURLClient creates a client that doesn't follow redirects, sending requests through transport
+// (nil uses the default transport).
+func newShortURLClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// CanonicalURL resolves known link shorteners and normalizes the result, so the same link shared with
+// different tracking tags or through a shortener deduplicates to one URL. Shortened URLs that cannot be
+// resolved are normalized as they are.
+func CanonicalURL(ctx context.Context, raw string) string {
+	return NormalizeURL(ResolveShortURL(ctx, raw))
+}
+
+// NormalizeURL returns a canonical form of raw: the scheme and host are lower cased, default ports,
+// fragments, tracking parameters (utm_* and friends), trailing slashes, and AMP variants are removed, and
+// the remaining query parameters are sorted. URLs that cannot be parsed are returned unchanged.
+func NormalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	u = unwrapAMPCache(u)
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "https" && strings.HasSuffix(u.Host, ":443")) || (u.Scheme == "http" && strings.HasSuffix(u.Host, ":80")) {
+		u.Host = u.Host[:strings.LastIndex(u.Host, ":")]
+	}
+	u.Host = strings.TrimPrefix(u.Host, "amp.")
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	u.Path = strings.TrimSuffix(u.Path, "/amp")
+	u.Path = strings.TrimSuffix(u.Path, "/amp/")
+	if strings.HasPrefix(u.Path, "/amp/") {
+		u.Path = u.Path[len("/amp"):]
+	}
+	u.Path = strings.TrimSuffix(u.Path, ".amp")
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+	}
+	if u.Path == "/" {
+		u.Path = ""
+	}
+	u.RawPath = ""
+
+	query := u.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			query.Del(name)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// unwrapAMPCache returns the publisher URL for links served through Google's AMP cache, e.g.
+// https://www.google.com/amp/s/example.com/story.
+func unwrapAMPCache(u *url.URL) *url.URL {
+	host := strings.ToLower(u.Host)
+	if (host != "www.google.com" && host != "google.com") || !strings.HasPrefix(u.Path, "/amp/") {
+		return u
+	}
+
+	target := strings.TrimPrefix(u.Path, "/amp/")
+	scheme := "http://"
+	if strings.HasPrefix(target, "s/") {
+		target = strings.TrimPrefix(target, "s/")
+		scheme = "https://"
+	}
+	unwrapped, err := url.Parse(scheme + target)
+	if err != nil || unwrapped.Host == "" {
+		return u
+	}
+	unwrapped.RawQuery = u.RawQuery
+	return unwrapped
+}
+
+// ResolveShortURL follows redirects from known link shorteners to the destination URL. Other URLs, and
+// shortened URLs that fail to resolve, are returned unchanged.
+func ResolveShortURL(ctx context.Context, raw string) string {
+	current := raw
+	for hop := 0; hop < maxShortURLHops; hop++ {
+		u, err := url.Parse(current)
+		if err != nil || !shortenerHosts[strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")] {
+			break
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, current, nil)
+		if err != nil {
+			return raw
+		}
+		resp, err := shortURLClient.Do(req)
+		if err != nil {
+			return raw
+		}
+		resp.Body.Close()
+
+		location, err := resp.Location()
+		if err != nil {
+			// Not a redirect; the shortener has nothing to resolve
+			break
+		}
+		current = location.String()
+	}
+	return current
+}
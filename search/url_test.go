@@ -0,0 +1,97 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "already canonical", raw: "https://example.com/post?id=1", want: "https://example.com/post?id=1"},
+		{name: "utm parameters", raw: "https://example.com/post?utm_source=x&utm_medium=y&id=1", want: "https://example.com/post?id=1"},
+		{name: "utm parameters in upper case", raw: "https://example.com/post?UTM_Campaign=x", want: "https://example.com/post"},
+		{name: "click identifiers", raw: "https://example.com/post?fbclid=abc&gclid=def", want: "https://example.com/post"},
+		{name: "share identifier", raw: "https://youtu.be/abc?si=xyz", want: "https://youtu.be/abc"},
+		{name: "sorted parameters", raw: "https://example.com/post?b=2&a=1", want: "https://example.com/post?a=1&b=2"},
+		{name: "empty query", raw: "https://example.com/post?", want: "https://example.com/post"},
+		{name: "trailing slash", raw: "https://example.com/post/", want: "https://example.com/post"},
+		{name: "several trailing slashes", raw: "https://example.com/post//", want: "https://example.com/post"},
+		{name: "root path", raw: "https://example.com/", want: "https://example.com"},
+		{name: "fragment", raw: "https://example.com/post#comments", want: "https://example.com/post"},
+		{name: "scheme and host case", raw: "HTTPS://Example.COM/Post", want: "https://example.com/Post"},
+		{name: "default https port", raw: "https://example.com:443/post", want: "https://example.com/post"},
+		{name: "default http port", raw: "http://example.com:80/post", want: "http://example.com/post"},
+		{name: "other port", raw: "https://example.com:8443/post", want: "https://example.com:8443/post"},
+		{name: "amp suffix", raw: "https://example.com/story/amp", want: "https://example.com/story"},
+		{name: "amp extension", raw: "https://example.com/story.amp", want: "https://example.com/story"},
+		{name: "amp host and prefix", raw: "https://amp.example.com/amp/story", want: "https://example.com/story"},
+		{name: "amp cache", raw: "https://www.google.com/amp/s/example.com/story", want: "https://example.com/story"},
+		{name: "surrounding whitespace", raw: " https://example.com/post ", want: "https://example.com/post"},
+		{name: "not a URL", raw: "not a url", want: "not a url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeURL(tt.raw); got != tt.want {
+				t.Errorf("NormalizeURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+// roundTripFunc stubs a transport with a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCanonicalURL(t *testing.T) {
+	redirects := map[string]string{
+		"https://bit.ly/abc": "https://example.com/post/?utm_source=twitter",
+		"https://t.co/xyz":   "https://bit.ly/abc",
+		"https://bit.ly/own": "https://bit.ly/own",
+	}
+	original := shortURLClient
+	shortURLClient = newShortURLClient(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "is.gd" {
+			return nil, errors.New("connection refused")
+		}
+		resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("")), Request: req}
+		if location, ok := redirects[req.URL.String()]; ok {
+			resp.StatusCode = http.StatusMovedPermanently
+			resp.Header.Set("Location", location)
+		}
+		return resp, nil
+	}))
+	t.Cleanup(func() { shortURLClient = original })
+
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "shortened", raw: "https://bit.ly/abc", want: "https://example.com/post"},
+		{name: "chained shorteners", raw: "https://t.co/xyz", want: "https://example.com/post"},
+		{name: "redirect loop", raw: "https://bit.ly/own", want: "https://bit.ly/own"},
+		{name: "unresolvable shortener", raw: "https://is.gd/abc?utm_source=x", want: "https://is.gd/abc"},
+		{name: "shortener without redirect", raw: "https://bit.ly/none", want: "https://bit.ly/none"},
+		{name: "not a shortener", raw: "https://example.com/post?utm_source=x", want: "https://example.com/post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanonicalURL(context.Background(), tt.raw); got != tt.want {
+				t.Errorf("CanonicalURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// search/variants.go
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultVariants are the keyword variants searched on each platform when automatic variants are enabled
+// and the platform has none configured, keyed by platform. Hashtags and mentions are how these platforms
+// tag topics and accounts, and their full-text search often misses them.
+var DefaultVariants = map[string][]string{
+	"Fediverse": {"#{tag}", "@{tag}"},
+	"Bluesky":   {"#{tag}"},
+}
+
+// ExpandVariants returns the forms of a keyword to search for: the keyword itself, followed by each of
+// patterns with {keyword} replaced by the keyword and {tag} by the keyword with everything but letters,
+// digits, and underscores removed, so "open source" becomes the hashtag "#opensource" from "#{tag}".
+// Repeated forms are dropped. Boolean queries and account keywords are only ever searched as written.
+func ExpandVariants(keyword string, patterns []string) []string {
+	forms := []string{keyword}
+	if _, ok := ParseAccount(keyword); ok || len(patterns) == 0 {
+		return forms
+	}
+	if query, err := ParseQuery(keyword); err != nil || query.IsBoolean() {
+		return forms
+	}
+
+	tag := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return -1
+	}, keyword)
+	replacer := strings.NewReplacer("{keyword}", keyword, "{tag}", tag)
+
+	seen := map[string]bool{keyword: true}
+	for _, pattern := range patterns {
+		if tag == "" && strings.Contains(pattern, "{tag}") {
+			continue
+		}
+		form := strings.TrimSpace(replacer.Replace(pattern))
+		if form == "" || seen[form] {
+			continue
+		}
+		seen[form] = true
+		forms = append(forms, form)
+	}
+	return forms
+}
@@ -1,27 +1,69 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 )
 
 // YouTubeSearcher implements the Searcher interface for YouTube.
 type YouTubeSearcher struct {
-	apiKey string
+	apiKey     string
+	client     *http.Client
+	maxResults int
 }
 
-// NewYouTubeSearcher initializes YouTubeSearcher with the API key.
-func NewYouTubeSearcher() (*YouTubeSearcher, error) {
+// NewYouTubeSearcher initializes YouTubeSearcher with the API key, or with the refresh token of an OAuth
+// client from grass login, which is used instead when both are set.
+func NewYouTubeSearcher(opts ...Option) (*YouTubeSearcher, error) {
+	o := newOptions(opts)
+	if refreshToken := os.Getenv("YOUTUBE_REFRESH_TOKEN"); refreshToken != "" {
+		clientID := os.Getenv("YOUTUBE_CLIENT_ID")
+		clientSecret := os.Getenv("YOUTUBE_CLIENT_SECRET")
+		if clientID == "" || clientSecret == "" {
+			return nil, errors.New("missing YouTube OAuth client: YOUTUBE_CLIENT_ID and YOUTUBE_CLIENT_SECRET are required with YOUTUBE_REFRESH_TOKEN")
+		}
+		// Access tokens are refreshed as they expire, through the searcher's own client
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, o.client)
+		client := YouTubeOAuthConfig(clientID, clientSecret).Client(ctx, &oauth2.Token{RefreshToken: refreshToken})
+		client.Timeout = o.client.Timeout
+		return &YouTubeSearcher{client: client, maxResults: o.maxResults}, nil
+	}
+
 	apiKey := os.Getenv("YOUTUBE_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("missing YouTube API key: YOUTUBE_API_KEY is required")
 	}
+	return &YouTubeSearcher{apiKey: apiKey, client: o.client, maxResults: o.maxResults}, nil
+}
+
+// YouTubeOAuthConfig returns the OAuth configuration of a Google client, which must be of the "TVs and
+// Limited Input devices" type to log in with the device flow, for reading YouTube.
+func YouTubeOAuthConfig(clientID, clientSecret string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     google.Endpoint,
+		Scopes:       []string{"https://www.googleapis.com/auth/youtube.readonly"},
+	}
+}
 
-	return &YouTubeSearcher{apiKey: apiKey}, nil
+// keyParam returns the query parameter authorizing requests with the API key, or nothing when requests are
+// authorized with an OAuth token instead.
+func (y *YouTubeSearcher) keyParam() string {
+	if y.apiKey == "" {
+		return ""
+	}
+	return "&key=" + url.QueryEscape(y.apiKey)
 }
 
 // Platform returns the platform name for this searcher.
@@ -29,23 +71,44 @@ func (y *YouTubeSearcher) Platform() string {
 	return "YouTube"
 }
 
-// Search performs a keyword search on YouTube and filters results based on the timestamp.
-func (y *YouTubeSearcher) Search(keyword string, afterEpochSecs int64) ([]SearchResult, error) {
+// youTubePageSize is the most videos the search endpoint returns per page. Each page costs quota, so
+// searches only ask for videos published after the epoch time.
+const youTubePageSize = 50
+
+// Search performs a keyword search on YouTube and filters results based on the timestamp, paging through
+// every video published since.
+func (y *YouTubeSearcher) Search(ctx context.Context, keyword string, afterEpochSecs int64) ([]SearchResult, error) {
+	return paginate(ctx, y.Platform(), y.maxResults, func(ctx context.Context, cursor string) ([]SearchResult, string, error) {
+		return y.searchPage(ctx, keyword, afterEpochSecs, cursor)
+	})
+}
+
+// searchPage fetches the page of videos at the page token in cursor, returning them with the next page's
+// token, if any.
+func (y *YouTubeSearcher) searchPage(ctx context.Context, keyword string, afterEpochSecs int64, cursor string) ([]SearchResult, string, error) {
 	// YouTube API URL
 	searchURL := fmt.Sprintf(
-		"https://www.googleapis.com/youtube/v3/search?part=snippet&q=%s&key=%s&type=video&order=date",
-		url.QueryEscape(keyword), y.apiKey,
+		"https://www.googleapis.com/youtube/v3/search?part=snippet&q=%s&type=video&order=date&maxResults=%d&publishedAfter=%s%s",
+		url.QueryEscape(platformQuery(keyword, false)), youTubePageSize,
+		url.QueryEscape(time.Unix(afterEpochSecs, 0).UTC().Format(time.RFC3339)), y.keyParam(),
 	)
+	if cursor != "" {
+		searchURL += "&pageToken=" + url.QueryEscape(cursor)
+	}
 
 	// Send HTTP GET request
-	resp, err := http.Get(searchURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create YouTube search request: %w", err)
+	}
+	resp, err := y.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform YouTube search request: %w", err)
+		return nil, "", fmt.Errorf("failed to perform YouTube search request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("YouTube search request failed with status code: %d", resp.StatusCode)
+		return nil, "", fmt.Errorf("YouTube search request failed with status code: %d", resp.StatusCode)
 	}
 
 	// Parse response JSON
@@ -55,14 +118,16 @@ func (y *YouTubeSearcher) Search(keyword string, afterEpochSecs int64) ([]Search
 				VideoID string `json:"videoId"`
 			} `json:"id"`
 			Snippet struct {
-				Title       string `json:"title"`
-				PublishedAt string `json:"publishedAt"`
-				Description string `json:"description"`
+				Title        string `json:"title"`
+				PublishedAt  string `json:"publishedAt"`
+				Description  string `json:"description"`
+				ChannelTitle string `json:"channelTitle"`
 			} `json:"snippet"`
 		} `json:"items"`
+		NextPageToken string `json:"nextPageToken"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, fmt.Errorf("failed to parse YouTube search results: %w", err)
+		return nil, "", fmt.Errorf("failed to parse YouTube search results: %w", err)
 	}
 
 	// Filter and format results
@@ -84,9 +149,82 @@ func (y *YouTubeSearcher) Search(keyword string, afterEpochSecs int64) ([]Search
 				URL:       videoURL,
 				Timestamp: publishedTime.Unix(),
 				Content:   item.Snippet.Description,
+				Author:    item.Snippet.ChannelTitle,
 			})
 		}
 	}
 
-	return results, nil
+	return results, data.NextPageToken, nil
+}
+
+// youTubeVideosMax is the most videos the videos endpoint returns per request.
+const youTubeVideosMax = 50
+
+// Engagement updates the like, comment, and view counts of YouTube videos, looking them up in batches.
+// Search results carry no statistics, so this is the only way YouTube results get engagement counts.
+func (y *YouTubeSearcher) Engagement(ctx context.Context, results []SearchResult) error {
+	indexes := make(map[string][]int)
+	var ids []string
+	var errs []error
+	for i, result := range results {
+		u, err := url.Parse(result.URL)
+		if err != nil || u.Query().Get("v") == "" {
+			errs = append(errs, fmt.Errorf("not a YouTube video URL: %s", result.URL))
+			continue
+		}
+		id := u.Query().Get("v")
+		if _, ok := indexes[id]; !ok {
+			ids = append(ids, id)
+		}
+		indexes[id] = append(indexes[id], i)
+	}
+
+	for start := 0; start < len(ids); start += youTubeVideosMax {
+		batch := ids[start:min(start+youTubeVideosMax, len(ids))]
+		videosURL := fmt.Sprintf(
+			"https://www.googleapis.com/youtube/v3/videos?part=statistics&id=%s%s",
+			url.QueryEscape(strings.Join(batch, ",")), y.keyParam(),
+		)
+		req, err := http.NewRequestWithContext(ctx, "GET", videosURL, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create YouTube videos request: %w", err))
+			continue
+		}
+		resp, err := y.client.Do(req)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to perform YouTube videos request: %w", err))
+			continue
+		}
+
+		// Counts are strings, and hidden counts are left out
+		var data struct {
+			Items []struct {
+				ID         string `json:"id"`
+				Statistics struct {
+					ViewCount    int64 `json:"viewCount,string"`
+					LikeCount    int64 `json:"likeCount,string"`
+					CommentCount int64 `json:"commentCount,string"`
+				} `json:"statistics"`
+			} `json:"items"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("YouTube videos request failed with status code: %d", resp.StatusCode)
+		} else if decodeErr := json.NewDecoder(resp.Body).Decode(&data); decodeErr != nil {
+			err = fmt.Errorf("failed to parse YouTube video statistics: %w", decodeErr)
+		}
+		resp.Body.Close()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, item := range data.Items {
+			for _, i := range indexes[item.ID] {
+				results[i].Score = item.Statistics.LikeCount
+				results[i].Comments = item.Statistics.CommentCount
+				results[i].Views = item.Statistics.ViewCount
+			}
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/jaxxstorm/grass/internal/httpclient"
+	"github.com/jaxxstorm/grass/internal/proxy"
+	"github.com/jaxxstorm/grass/plugin"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// newSearcher creates the searcher selected by --searchers, configuring built-in searchers with opts too.
+// Searchers that authenticate at startup do so within ctx, through the searcher's proxy.
+func newSearcher(ctx context.Context, name string, opts ...search.Option) (search.Searcher, error) {
+	setting, ok := (*searcherProxies)[name]
+	opts = append([]search.Option{search.WithMaxResults(*maxResults)}, opts...)
+	if ok {
+		searcherProxy, err := proxy.Parse(setting)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy for %s: %w", name, err)
+		}
+		client, err := httpclient.New(httpConfig(searcherProxy))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, search.WithHTTPClient(client))
+	}
+
+	searcher, err := search.New(ctx, name, opts...)
+	if !errors.Is(err, search.ErrUnknownSearcher) {
+		return searcher, err
+	}
+
+	// Plugins make their own requests, so their proxy is passed through the standard variables
+	env, err := proxyEnv(setting)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy for %s: %w", name, err)
+	}
+	var pluginSearcher *plugin.Searcher
+	withEnv(env, func() {
+		pluginSearcher, err = plugin.NewSearcher(pluginDirectory(), name)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unknown searcher %s: %w", name, err)
+	}
+	return pluginSearcher, nil
+}
+
+// proxyEnv returns the environment variables that send requests made outside grass's own HTTP clients, such
+// as by plugins, through a --proxy or --searcher-proxy setting. The variables can't rotate between
+// proxies, so only a single proxy is accepted.
+func proxyEnv(setting string) (map[string]string, error) {
+	switch setting {
+	case "":
+		return nil, nil
+	case proxy.Direct:
+		return map[string]string{"HTTP_PROXY": "", "HTTPS_PROXY": "", "ALL_PROXY": "", "NO_PROXY": "*"}, nil
+	}
+	if strings.Contains(setting, ",") {
+		return nil, errors.New("only a single proxy can be passed to plugins")
+	}
+	if _, err := proxy.Parse(setting); err != nil {
+		return nil, err
+	}
+	return map[string]string{"HTTP_PROXY": setting, "HTTPS_PROXY": setting, "ALL_PROXY": setting}, nil
+}
+
+// pluginDirectory returns --plugin-dir, defaulting to grass/plugins in the user's config directory.
+func pluginDirectory() string {
+	if *pluginDir != "" {
+		return *pluginDir
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "grass", "plugins")
+}
+
+// logPlugins reports the plugins installed in the plugin directory.
+func logPlugins() {
+	dir := pluginDirectory()
+	for _, kind := range []string{plugin.KindSearcher, plugin.KindNotifier} {
+		names, err := plugin.List(dir, kind)
+		if err != nil {
+			log.Warn("Failed to list plugins", "dir", dir, "error", err)
+			return
+		}
+		if len(names) > 0 {
+			log.Debug("Found plugins", "kind", kind, "dir", dir, "names", names)
+		}
+	}
+}
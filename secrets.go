@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/grass/internal/secrets"
+)
+
+// secretTimeout bounds resolving every secret reference of the environment or a config file.
+const secretTimeout = time.Minute
+
+// secretResolver resolves the secret references of the environment and every config file, fetching each
+// secret once.
+var secretResolver = secrets.NewResolver()
+
+// resolveSecretEnv replaces environment variables holding secret references, such as
+// REDDIT_CLIENT_SECRET=aws-sm://grass/reddit#client_secret, with the secrets they refer to. It runs before
+// flags are parsed so flags read from the environment get the secrets too.
+func resolveSecretEnv() error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if !secrets.IsReference(value) {
+			continue
+		}
+		secret, err := secretResolver.Resolve(ctx, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		os.Setenv(key, secret)
+	}
+	return nil
+}
+
+// resolveSecrets replaces the values of env holding secret references, such as those of a config file's env
+// section, with the secrets they refer to.
+func resolveSecrets(env map[string]string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	for key, value := range env {
+		if !secrets.IsReference(value) {
+			continue
+		}
+		secret, err := secretResolver.Resolve(ctx, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		env[key] = secret
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+)
+
+const releasesURL = "https://api.github.com/repos/jaxxstorm/grass/releases"
+
+var (
+	selfUpdateCommand = kingpin.Command("self-update", "Replace this binary with the latest GitHub release")
+	selfUpdateCheck   = selfUpdateCommand.Flag("check", "Only report whether a newer release is available").Bool()
+	selfUpdateTag     = selfUpdateCommand.Flag("tag", "Install this release tag, e.g. v1.2.3, instead of the latest").String()
+	selfUpdateForce   = selfUpdateCommand.Flag("force", "Install the release even if it isn't newer than this binary").Bool()
+)
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// asset returns the download URL of the release asset with the given name, or of the one ending in it when
+// suffix is set.
+func (r githubRelease) asset(name string, suffix bool) (string, string, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name || (suffix && strings.HasSuffix(asset.Name, name)) {
+			return asset.Name, asset.URL, true
+		}
+	}
+	return "", "", false
+}
+
+// runSelfUpdate downloads the release archive for this OS and architecture, checks it against the
+// release's checksums, and replaces the running executable with the binary inside it.
+func runSelfUpdate(ctx context.Context, w io.Writer) error {
+	endpoint := releasesURL + "/latest"
+	if *selfUpdateTag != "" {
+		endpoint = releasesURL + "/tags/" + *selfUpdateTag
+	}
+	var release githubRelease
+	body, err := download(ctx, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to look up the release: %w", err)
+	}
+	if err := json.Unmarshal(body, &release); err != nil {
+		return fmt.Errorf("failed to parse the release: %w", err)
+	}
+
+	latest := strings.TrimPrefix(release.TagName, "v")
+	newer := newerVersion(latest, Version)
+	if *selfUpdateCheck {
+		if newer {
+			fmt.Fprintf(w, "grass %s is available (running %s)\n", latest, Version)
+		} else {
+			fmt.Fprintf(w, "grass %s is up to date\n", Version)
+		}
+		return nil
+	}
+	if !newer && !*selfUpdateForce {
+		if Version == "dev" {
+			return fmt.Errorf("this is a development build; pass --force to replace it with %s", latest)
+		}
+		fmt.Fprintf(w, "grass %s is up to date\n", Version)
+		return nil
+	}
+
+	name, url, ok := release.asset(archiveName(), false)
+	if !ok {
+		return fmt.Errorf("release %s has no archive for %s/%s", release.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	_, checksumsURL, ok := release.asset("checksums.txt", true)
+	if !ok {
+		return fmt.Errorf("release %s has no checksums", release.TagName)
+	}
+	checksums, err := download(ctx, checksumsURL)
+	if err != nil {
+		return fmt.Errorf("failed to download checksums: %w", err)
+	}
+	want, ok := findChecksum(checksums, name)
+	if !ok {
+		return fmt.Errorf("release %s has no checksum for %s", release.TagName, name)
+	}
+
+	log.Info("Downloading release", "version", latest, "archive", name)
+	archive, err := download(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	sum := sha256.Sum256(archive)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	binary, err := extractBinary(name, archive)
+	if err != nil {
+		return err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return err
+	}
+	if err := replaceExecutable(executable, binary); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", executable, err)
+	}
+	fmt.Fprintf(w, "Updated grass from %s to %s\n", Version, latest)
+	return nil
+}
+
+// download returns the body of a GET request to url.
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := sharedHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// archiveName returns the name GoReleaser gives the release archive for this OS and architecture.
+func archiveName() string {
+	arch := runtime.GOARCH
+	if arch == "amd64" {
+		arch = "x86_64"
+	}
+	ext := ".tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = ".zip"
+	}
+	return "grass_" + strings.ToUpper(runtime.GOOS[:1]) + runtime.GOOS[1:] + "_" + arch + ext
+}
+
+// findChecksum returns the hex SHA-256 listed for name in a sha256sum-style checksums file.
+func findChecksum(checksums []byte, name string) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	return "", false
+}
+
+// extractBinary returns the grass executable from a release archive.
+func extractBinary(name string, archive []byte) ([]byte, error) {
+	binary := "grass"
+	if runtime.GOOS == "windows" {
+		binary = "grass.exe"
+	}
+	if strings.HasSuffix(name, ".zip") {
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range reader.File {
+			if path.Base(file.Name) == binary {
+				f, err := file.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer f.Close()
+				return io.ReadAll(f)
+			}
+		}
+		return nil, fmt.Errorf("%s has no %s", name, binary)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s has no %s", name, binary)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg && path.Base(header.Name) == binary {
+			return io.ReadAll(reader)
+		}
+	}
+}
+
+// replaceExecutable writes binary next to executable and renames it into place. Windows won't replace a
+// running executable, but will rename it, so the old binary is moved aside first.
+func replaceExecutable(executable string, binary []byte) error {
+	info, err := os.Stat(executable)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(executable), ".grass-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0o111); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		old := executable + ".old"
+		os.Remove(old)
+		if err := os.Rename(executable, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), executable)
+}
+
+// newerVersion reports whether the dotted version a is later than b. A development build's version is
+// unknown, so no release counts as newer than it.
+func newerVersion(a, b string) bool {
+	if b == "dev" {
+		return false
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(strings.SplitN(as[i], "-", 2)[0])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(strings.SplitN(bs[i], "-", 2)[0])
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
@@ -0,0 +1,560 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	slackSigningSecret = serveCommand.Flag("slack-signing-secret", "Serve the /grass slash command and App Home of a Slack app at /slack/commands and /slack/events, verifying requests with the app's signing secret").Envar("GRASS_SLACK_SIGNING_SECRET").String()
+	slackProfile       = serveCommand.Flag("slack-profile", "Profile the Slack app manages").Envar("GRASS_SLACK_PROFILE").String()
+	slackUsers         = serveCommand.Flag("slack-user", "Only accept Slack commands from this Slack user ID, e.g. U024BE7LH (repeatable); everyone in the workspace may use them when unset").Envar("GRASS_SLACK_USERS").Strings()
+)
+
+const (
+	// slackMaxAge is how old a Slack request's timestamp may be, so captured requests can't be replayed.
+	slackMaxAge = 5 * time.Minute
+	// slackMaxBody caps the size of a Slack request body.
+	slackMaxBody = 1 << 20
+	// slackWorkTimeout bounds the work done after acknowledging a Slack request, such as publishing App Home
+	// or triaging a result.
+	slackWorkTimeout = 30 * time.Second
+	// slackStatsWindow is the window App Home counts results over.
+	slackStatsWindow = "7d"
+	// slackStatsTop is how many keywords stats show.
+	slackStatsTop = 10
+	// slackPublishURL is the Slack Web API method App Home is published with.
+	slackPublishURL = "https://slack.com/api/views.publish"
+)
+
+// slackUnescaper undoes the escaping Slack applies to the text of slash commands.
+var slackUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// slackHelp lists the slash command's subcommands.
+const slackHelp = "Usage:\n" +
+	"• `/grass add <keyword>` searches for a keyword\n" +
+	"• `/grass remove <keyword>` stops searching for a keyword added from Slack, the API, or `grass keywords`\n" +
+	"• `/grass mute <keyword> [duration]` pauses a keyword, for a duration such as `24h` or `7d` if given\n" +
+	"• `/grass unmute <keyword>` resumes a paused keyword\n" +
+	"• `/grass keywords` lists the keywords\n" +
+	"• `/grass stats [24h|7d|30d]` counts the results found per platform and keyword"
+
+// slackHandler serves a Slack app for a profile: its slash command at /slack/commands, and its Home tab,
+// published when users open it, from events at /slack/events. Presses of the triage buttons on
+// notifications arrive at /slack/interactions and set states in the profile the button names. Requests
+// must be signed with the app's signing secret.
+type slackHandler struct {
+	mux      *http.ServeMux
+	profile  *profile
+	profiles map[string]*profile
+	secret   string
+	users    []string
+	// token is the bot token App Home is published with. Without one, App Home is left empty.
+	token  string
+	client *http.Client
+}
+
+// slackCommandResponse is the body of a reply to a slash command. Ephemeral replies are only shown to the
+// user who ran the command.
+type slackCommandResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// slackEvent is the body of a request to the events endpoint: a URL verification challenge when the
+// endpoint is set up, or an event the app subscribes to.
+type slackEvent struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Event     struct {
+		Type string `json:"type"`
+		User string `json:"user"`
+		Tab  string `json:"tab"`
+	} `json:"event"`
+}
+
+// slackInteraction is the payload of a request to the interactions endpoint, such as a press of a button
+// on a message.
+type slackInteraction struct {
+	Type string `json:"type"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	ResponseURL string `json:"response_url"`
+	Message     struct {
+		Text   string            `json:"text"`
+		Blocks []json.RawMessage `json:"blocks"`
+	} `json:"message"`
+}
+
+// newSlackHandler creates a Slack app handler for the profile named name. Only users may run commands and
+// triage results unless it is empty. App Home is published as SLACK_BOT_TOKEN.
+func newSlackHandler(profiles []*profile, name, secret string, users []string) (*slackHandler, error) {
+	byName := profilesByName(profiles)
+	p, ok := byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	h := &slackHandler{
+		mux:      http.NewServeMux(),
+		profile:  p,
+		profiles: byName,
+		secret:   secret,
+		users:    users,
+		token:    os.Getenv("SLACK_BOT_TOKEN"),
+		client:   sharedHTTPClient,
+	}
+	if h.token == "" {
+		log.Warn("SLACK_BOT_TOKEN isn't set; the Slack app's Home tab won't be published")
+	}
+	h.mux.HandleFunc("POST /slack/commands", h.command)
+	h.mux.HandleFunc("POST /slack/events", h.event)
+	h.mux.HandleFunc("POST /slack/interactions", h.interaction)
+	return h, nil
+}
+
+func (h *slackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, slackMaxBody))
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := verifySlackRequest(r.Header, body, h.secret, time.Now()); err != nil {
+		log.Warn("Rejected Slack request", "path", r.URL.Path, "error", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	h.mux.ServeHTTP(w, r)
+}
+
+// verifySlackRequest checks the signature Slack computes from its signing secret, the request's timestamp,
+// and its body, rejecting requests older than slackMaxAge as of now.
+func verifySlackRequest(header http.Header, body []byte, secret string, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("missing request timestamp")
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > slackMaxAge || age < -slackMaxAge {
+		return fmt.Errorf("request timestamp is %s off", age.Round(time.Second))
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// allowed reports whether user may use the app.
+func (h *slackHandler) allowed(user string) bool {
+	return len(h.users) == 0 || slices.Contains(h.users, user)
+}
+
+// command runs a slash command and replies with what it did. Changes to keywords are shown to the whole
+// channel, everything else only to the user who ran the command.
+func (h *slackHandler) command(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	user := r.PostForm.Get("user_id")
+	reply := func(responseType, text string) {
+		writeAPIResponse(w, http.StatusOK, slackCommandResponse{ResponseType: responseType, Text: text})
+	}
+	if !h.allowed(user) {
+		reply("ephemeral", "You aren't allowed to manage grass.")
+		return
+	}
+
+	subcommand, arg, _ := strings.Cut(strings.TrimSpace(slackUnescaper.Replace(r.PostForm.Get("text"))), " ")
+	arg = strings.TrimSpace(arg)
+	ctx := r.Context()
+	switch strings.ToLower(subcommand) {
+	case "add", "remove", "mute", "pause", "unmute", "resume":
+		text, err := h.changeKeyword(ctx, strings.ToLower(subcommand), arg)
+		if err != nil {
+			reply("ephemeral", err.Error())
+			return
+		}
+		log.Info("Keywords changed from Slack", "profile", h.profile.name, "user", user, "command", subcommand, "text", arg)
+		reply("in_channel", fmt.Sprintf("<@%s> %s", user, text))
+	case "keywords", "list":
+		text, err := h.keywords(ctx)
+		if err != nil {
+			reply("ephemeral", err.Error())
+			return
+		}
+		reply("ephemeral", text)
+	case "stats":
+		window := arg
+		if window == "" {
+			window = slackStatsWindow
+		}
+		if _, ok := statsWindows[window]; !ok {
+			reply("ephemeral", fmt.Sprintf("Unknown window %q; use 24h, 7d, or 30d.", window))
+			return
+		}
+		text, err := h.stats(ctx, window)
+		if err != nil {
+			reply("ephemeral", err.Error())
+			return
+		}
+		reply("ephemeral", text)
+	default:
+		reply("ephemeral", slackHelp)
+	}
+}
+
+// changeKeyword adds, removes, mutes, or unmutes a keyword, returning what it did as a sentence following
+// the user's mention. Mutes end after a duration given after the keyword.
+func (h *slackHandler) changeKeyword(ctx context.Context, subcommand, arg string) (string, error) {
+	keyword, until := arg, time.Time{}
+	if subcommand == "mute" || subcommand == "pause" {
+		if i := strings.LastIndex(arg, " "); i > 0 {
+			if d, err := parseSlackDuration(arg[i+1:]); err == nil {
+				keyword, until = strings.TrimSpace(arg[:i]), time.Now().Add(d)
+			}
+		}
+	}
+	keyword = strings.Trim(keyword, "\"“”")
+	if keyword == "" {
+		return "", fmt.Errorf("Which keyword? Try `/grass %s <keyword>`.", subcommand)
+	}
+	set, err := h.keywordSet(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	switch subcommand {
+	case "add":
+		added, err := set.add(ctx, keyword)
+		if err != nil {
+			return "", err
+		}
+		if !added {
+			return "", fmt.Errorf("%s is already managed", keyword)
+		}
+		return fmt.Sprintf("added `%s`", keyword), nil
+	case "remove":
+		if err := set.remove(ctx, keyword); err != nil {
+			return "", err
+		}
+		if slices.Contains(set.configured, keyword) {
+			return fmt.Sprintf("removed `%s` from storage; it is still configured", keyword), nil
+		}
+		return fmt.Sprintf("removed `%s`", keyword), nil
+	case "mute", "pause":
+		if err := set.setPaused(ctx, keyword, true, until); err != nil {
+			return "", err
+		}
+		if until.IsZero() {
+			return fmt.Sprintf("muted `%s` until it is unmuted", keyword), nil
+		}
+		return fmt.Sprintf("muted `%s` until <!date^%d^{date_short_pretty} at {time}|%s>", keyword, until.Unix(), until.UTC().Format(time.RFC1123)), nil
+	default:
+		if err := set.setPaused(ctx, keyword, false, time.Time{}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("unmuted `%s`", keyword), nil
+	}
+}
+
+// parseSlackDuration parses a duration such as 90m or 24h, or a number of days such as 7d.
+func parseSlackDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	}
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("invalid duration %q", value)
+	}
+	return d, err
+}
+
+// keywordSet loads the profile's keywords.
+func (h *slackHandler) keywordSet(ctx context.Context) (*keywordSet, error) {
+	if h.profile.keywordStore == nil {
+		return nil, errors.New("The profile's storage can't keep keywords; use sqlite, dynamodb, redis, bolt, or ndjson.")
+	}
+	return loadKeywordSet(ctx, h.profile.keywordStore, h.profile.configuredKeywords())
+}
+
+// keywords lists the profile's keywords in Slack's markup.
+func (h *slackHandler) keywords(ctx context.Context) (string, error) {
+	set, err := h.keywordSet(ctx)
+	if err != nil {
+		return "", err
+	}
+	entries := set.list()
+	if len(entries) == 0 {
+		return "No keywords yet; add one with `/grass add <keyword>`.", nil
+	}
+	var b strings.Builder
+	b.WriteString("*Keywords*")
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "\n• `%s`", entry.Keyword)
+		switch {
+		case entry.PausedUntil != 0:
+			fmt.Fprintf(&b, " _muted until <!date^%d^{date_short_pretty} at {time}|%s>_", entry.PausedUntil, time.Unix(entry.PausedUntil, 0).UTC().Format(time.RFC1123))
+		case entry.Paused:
+			b.WriteString(" _muted_")
+		}
+		if entry.Source == "config" {
+			b.WriteString(" (configured)")
+		}
+	}
+	return b.String(), nil
+}
+
+// stats counts the profile's results over window, one of statsWindows, in Slack's markup.
+func (h *slackHandler) stats(ctx context.Context, window string) (string, error) {
+	report, err := countStats(ctx, h.profile.storer, window, nil, nil, slackStatsTop)
+	if err != nil {
+		return "", fmt.Errorf("counting results: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Results in the last %s*, compared with the %s before", window, window)
+	if len(report.TopKeywords) == 0 {
+		b.WriteString("\nNothing found yet.")
+		return b.String(), nil
+	}
+	platforms := make(map[string]*statsRow)
+	var names []string
+	for _, row := range report.Counts {
+		if platforms[row.Platform] == nil {
+			platforms[row.Platform] = &statsRow{Platform: row.Platform}
+			names = append(names, row.Platform)
+		}
+		platforms[row.Platform].Count += row.Count
+		platforms[row.Platform].Previous += row.Previous
+	}
+	b.WriteString("\n\n*Platforms*")
+	for _, name := range names {
+		row := platforms[name].withChange()
+		fmt.Fprintf(&b, "\n• %s: %d (%s)", name, row.Count, row.change())
+	}
+	b.WriteString("\n\n*Top keywords*")
+	for _, row := range report.TopKeywords {
+		fmt.Fprintf(&b, "\n• `%s`: %d (%s)", row.Keyword, row.Count, row.change())
+	}
+	return b.String(), nil
+}
+
+// event answers URL verification challenges and publishes App Home to users who open it. Slack retries
+// events that aren't acknowledged within three seconds, so App Home is published after responding.
+func (h *slackHandler) event(w http.ResponseWriter, r *http.Request) {
+	var event slackEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case event.Type == "url_verification":
+		writeAPIResponse(w, http.StatusOK, map[string]string{"challenge": event.Challenge})
+		return
+	case event.Type == "event_callback" && event.Event.Type == "app_home_opened" && event.Event.Tab == "home" && h.token != "":
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), slackWorkTimeout)
+			defer cancel()
+			if err := h.publishHome(ctx, event.Event.User); err != nil {
+				log.Error("Failed to publish the Slack App Home", "user", event.Event.User, "error", err)
+			}
+		}()
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// interaction sets the state of the result whose triage button was pressed, then notes who pressed it on
+// the message, or tells them privately why it failed. Slack expects interactions to be acknowledged within
+// three seconds, so the work happens after responding.
+func (h *slackHandler) interaction(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	var interaction slackInteraction
+	if err := json.Unmarshal([]byte(r.PostForm.Get("payload")), &interaction); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if interaction.Type != "block_actions" || interaction.ResponseURL == "" {
+		return
+	}
+
+	for _, action := range interaction.Actions {
+		state, ok := strings.CutPrefix(action.ActionID, bot.SlackTriageActionPrefix)
+		if !ok {
+			continue
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), slackWorkTimeout)
+			defer cancel()
+			if err := h.triage(ctx, interaction, state, action.Value); err != nil {
+				log.Error("Failed to triage result from Slack", "user", interaction.User.ID, "state", state, "error", err)
+			}
+		}()
+	}
+}
+
+// triage sets the state of the result named by a triage button's value and replaces the message it was
+// pressed on with one noting who triaged it, keeping the buttons so the state can be changed again.
+func (h *slackHandler) triage(ctx context.Context, interaction slackInteraction, state, value string) error {
+	user := interaction.User.ID
+	fail := func(err error) error {
+		text := fmt.Sprintf("Couldn't mark the result %s: %v", state, err)
+		if respondErr := h.respond(ctx, interaction.ResponseURL, map[string]any{"response_type": "ephemeral", "replace_original": false, "text": text}); respondErr != nil {
+			return errors.Join(err, respondErr)
+		}
+		return err
+	}
+	if !h.allowed(user) {
+		return fail(errors.New("you aren't allowed to manage grass"))
+	}
+	var ref bot.SlackTriageValue
+	if err := json.Unmarshal([]byte(value), &ref); err != nil {
+		return fail(fmt.Errorf("invalid button value: %w", err))
+	}
+	p, ok := h.profiles[ref.Profile]
+	if !ok {
+		return fail(fmt.Errorf("unknown profile %q", ref.Profile))
+	}
+	updater, ok := storage.AsUpdater(p.storer)
+	if !ok {
+		return fail(errors.New("the profile's storage can't keep result states"))
+	}
+	_, found, err := storage.SetState(ctx, updater, ref.Platform, ref.URL, state)
+	if err == nil && !found {
+		err = errors.New("the result is no longer stored")
+	}
+	if err != nil {
+		return fail(err)
+	}
+	log.Info("Triaged result from Slack", "profile", p.name, "user", user, "platform", ref.Platform, "url", ref.URL, "state", state)
+
+	// Replace any earlier note with one for this press
+	blocks := make([]any, 0, len(interaction.Message.Blocks)+1)
+	for _, block := range interaction.Message.Blocks {
+		var id struct {
+			BlockID string `json:"block_id"`
+		}
+		if json.Unmarshal(block, &id) == nil && id.BlockID == bot.SlackTriageStatusBlock {
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	blocks = append(blocks, map[string]any{
+		"type":     "context",
+		"block_id": bot.SlackTriageStatusBlock,
+		"elements": []map[string]string{{"type": "mrkdwn", "text": bot.TriagedText(state, "<@"+user+">")}},
+	})
+	return h.respond(ctx, interaction.ResponseURL, map[string]any{"replace_original": true, "text": interaction.Message.Text, "blocks": blocks})
+}
+
+// respond posts a message to an interaction's response URL.
+func (h *slackHandler) respond(ctx context.Context, responseURL string, message map[string]any) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Slack returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// publishHome publishes the profile's keywords and stats as user's App Home with views.publish.
+func (h *slackHandler) publishHome(ctx context.Context, user string) error {
+	section := func(text string) map[string]any {
+		return map[string]any{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}}
+	}
+	var blocks []map[string]any
+	if !h.allowed(user) {
+		blocks = append(blocks, section("You aren't allowed to manage grass."))
+	} else {
+		keywords, err := h.keywords(ctx)
+		if err != nil {
+			keywords = err.Error()
+		}
+		stats, err := h.stats(ctx, slackStatsWindow)
+		if err != nil {
+			stats = err.Error()
+		}
+		blocks = append(blocks,
+			section(keywords),
+			map[string]any{"type": "divider"},
+			section(stats),
+			map[string]any{"type": "context", "elements": []map[string]string{{"type": "mrkdwn", "text": "Manage keywords with `/grass help`."}}},
+		)
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"user_id": user,
+		"view":    map[string]any{"type": "home", "blocks": blocks},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slackPublishURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+h.token)
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return fmt.Errorf("Slack returned %s", resp.Status)
+	}
+	if !body.OK {
+		return fmt.Errorf("Slack returned %s", body.Error)
+	}
+	return nil
+}
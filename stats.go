@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	statsCommand   = kingpin.Command("stats", "Show stored result counts per platform and keyword over a window, compared with the window before it")
+	statsWindow    = statsCommand.Flag("window", "Window to count results over, ending now: 24h, 7d, or 30d").Default("7d").Enum("24h", "7d", "30d")
+	statsTop       = statsCommand.Flag("top", "Number of top keywords to show").Default("10").Int()
+	statsPlatforms = statsCommand.Flag("platform", "Only count results from this platform, e.g. HackerNews (repeatable)").Strings()
+	statsProfile   = statsCommand.Flag("profile", "Count the results in this profile's storage from the config file instead of --db and --table-name").String()
+	statsOutput    = statsCommand.Flag("output", "Output format: table or json").Default("table").Enum("table", "json")
+)
+
+// statsWindows are the durations of the windows --window accepts.
+var statsWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// statsRow is the number of results found in the window and in the window before it.
+type statsRow struct {
+	Platform string `json:"platform,omitempty"`
+	Keyword  string `json:"keyword"`
+	Count    int64  `json:"count"`
+	Previous int64  `json:"previous"`
+	Change   int64  `json:"change"`
+	// ChangePercent is Change relative to Previous, and is left out when Previous is zero.
+	ChangePercent *float64 `json:"change_percent,omitempty"`
+}
+
+// statsReport is the output of the stats command.
+type statsReport struct {
+	Window      string     `json:"window"`
+	Since       time.Time  `json:"since"`
+	Until       time.Time  `json:"until"`
+	Counts      []statsRow `json:"counts"`
+	TopKeywords []statsRow `json:"top_keywords"`
+}
+
+// runStats writes result counts for the window selected by the stats flags to w, reading them from the
+// profile's primary storage. Counts are by platform and keyword, restricted by --platform and --keyword,
+// with the change from the window before.
+func runStats(ctx context.Context, cfg *config.Config, w io.Writer) error {
+	storer, _, err := openStorage(ctx, cfg, *statsProfile)
+	if err != nil {
+		return err
+	}
+	if closer, ok := storer.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	report, err := countStats(ctx, storer, *statsWindow, *statsPlatforms, *keywords, *statsTop)
+	if err != nil {
+		return err
+	}
+
+	if *statsOutput == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Results in the last %s, compared with the %s before\n\n", report.Window, report.Window)
+	fmt.Fprintln(tw, "PLATFORM\tKEYWORD\tCOUNT\tPREVIOUS\tCHANGE")
+	for _, row := range report.Counts {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", row.Platform, row.Keyword, row.Count, row.Previous, row.change())
+	}
+	fmt.Fprintln(tw, "\nTOP KEYWORDS\tCOUNT\tPREVIOUS\tCHANGE")
+	for _, row := range report.TopKeywords {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", row.Keyword, row.Count, row.Previous, row.change())
+	}
+	return tw.Flush()
+}
+
+// countStats counts the results in storer over window, one of statsWindows, and the window before it, by
+// platform and keyword. Only platforms and keywords are counted unless they are empty, and at most top
+// keywords are ranked unless top is negative.
+func countStats(ctx context.Context, storer storage.Storer, window string, platforms, keywords []string, top int) (statsReport, error) {
+	until := time.Now()
+	since := until.Add(-statsWindows[window])
+	current, err := storage.CountResults(ctx, storer, since, until)
+	if err != nil {
+		return statsReport{}, err
+	}
+	previous, err := storage.CountResults(ctx, storer, since.Add(-statsWindows[window]), since)
+	if err != nil {
+		return statsReport{}, err
+	}
+
+	report := statsReport{Window: window, Since: since.UTC(), Until: until.UTC()}
+	rows := make(map[[2]string]*statsRow)
+	keywordRows := make(map[string]*statsRow)
+	tally := func(counts []storage.ResultCount, add func(row *statsRow, count int64)) {
+		for _, count := range counts {
+			if len(platforms) > 0 && !slices.Contains(platforms, count.Platform) {
+				continue
+			}
+			if len(keywords) > 0 && !slices.Contains(keywords, count.Keyword) {
+				continue
+			}
+			key := [2]string{count.Platform, count.Keyword}
+			if rows[key] == nil {
+				rows[key] = &statsRow{Platform: count.Platform, Keyword: count.Keyword}
+			}
+			if keywordRows[count.Keyword] == nil {
+				keywordRows[count.Keyword] = &statsRow{Keyword: count.Keyword}
+			}
+			add(rows[key], count.Count)
+			add(keywordRows[count.Keyword], count.Count)
+		}
+	}
+	tally(current, func(row *statsRow, count int64) { row.Count += count })
+	tally(previous, func(row *statsRow, count int64) { row.Previous += count })
+
+	for _, row := range rows {
+		report.Counts = append(report.Counts, row.withChange())
+	}
+	sort.Slice(report.Counts, func(i, j int) bool {
+		if report.Counts[i].Platform != report.Counts[j].Platform {
+			return report.Counts[i].Platform < report.Counts[j].Platform
+		}
+		return report.Counts[i].Keyword < report.Counts[j].Keyword
+	})
+	for _, row := range keywordRows {
+		report.TopKeywords = append(report.TopKeywords, row.withChange())
+	}
+	sort.Slice(report.TopKeywords, func(i, j int) bool {
+		if report.TopKeywords[i].Count != report.TopKeywords[j].Count {
+			return report.TopKeywords[i].Count > report.TopKeywords[j].Count
+		}
+		return report.TopKeywords[i].Keyword < report.TopKeywords[j].Keyword
+	})
+	if top >= 0 && len(report.TopKeywords) > top {
+		report.TopKeywords = report.TopKeywords[:top]
+	}
+	return report, nil
+}
+
+// withChange returns the row with its change from the previous window filled in.
+func (r statsRow) withChange() statsRow {
+	r.Change = r.Count - r.Previous
+	if r.Previous > 0 {
+		percent := float64(r.Change) / float64(r.Previous) * 100
+		r.ChangePercent = &percent
+	}
+	return r
+}
+
+// change renders the row's change for table output.
+func (r statsRow) change() string {
+	if r.ChangePercent == nil {
+		if r.Count == 0 {
+			return "0"
+		}
+		return fmt.Sprintf("%+d (new)", r.Change)
+	}
+	return fmt.Sprintf("%+d (%+.0f%%)", r.Change, *r.ChangePercent)
+}
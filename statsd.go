@@ -0,0 +1,97 @@
+package main
+
+import (
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/internal/statsd"
+)
+
+var (
+	statsdAddr          = kingpin.Flag("statsd-addr", "Send metrics of every search to this StatsD or DogStatsD agent, as host:port or unix:///path/to/dsd.socket").Envar("GRASS_STATSD_ADDR").String()
+	statsdPrefix        = kingpin.Flag("statsd-prefix", "Prefix of the metric names sent to StatsD").Envar("GRASS_STATSD_PREFIX").Default("grass.").String()
+	statsdTags          = kingpin.Flag("statsd-tag", "Tag added to every metric sent to StatsD, as <name>:<value>, e.g. env:prod (repeatable)").Envar("GRASS_STATSD_TAGS").Strings()
+	statsdSampleRate    = kingpin.Flag("statsd-sample-rate", "Fraction of search durations sent to StatsD, between 0 and 1; counts are always sent in full").Envar("GRASS_STATSD_SAMPLE_RATE").Default("1").Float64()
+	statsdFlushInterval = kingpin.Flag("statsd-flush-interval", "How often the metrics buffered for StatsD are sent").Envar("GRASS_STATSD_FLUSH_INTERVAL").Default("10s").Duration()
+	statsdKeywordTags   = kingpin.Flag("statsd-keyword-tag", "Tag search metrics sent to StatsD with their keyword; disable with --no-statsd-keyword-tag if keywords change often").Envar("GRASS_STATSD_KEYWORD_TAG").Default("true").Bool()
+)
+
+// statsdClient sends search metrics to the agent at --statsd-addr, or is nil when it isn't set.
+var statsdClient *statsd.Client
+
+// startStatsd connects to the StatsD agent at --statsd-addr, if set.
+func startStatsd() {
+	if *statsdAddr == "" {
+		return
+	}
+	if *statsdSampleRate <= 0 || *statsdSampleRate > 1 {
+		log.Fatalf("Invalid --statsd-sample-rate %g; it must be greater than 0 and at most 1", *statsdSampleRate)
+	}
+	client, err := statsd.New(statsd.Config{
+		Addr:          *statsdAddr,
+		Prefix:        *statsdPrefix,
+		Tags:          *statsdTags,
+		SampleRate:    *statsdSampleRate,
+		FlushInterval: *statsdFlushInterval,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up StatsD: %v", err)
+	}
+	statsdClient = client
+	log.Debug("Sending metrics to StatsD", "addr", *statsdAddr)
+}
+
+// flushStatsd sends the metrics buffered for StatsD, for example before a Lambda invocation returns and the
+// function is frozen.
+func flushStatsd() {
+	if statsdClient == nil {
+		return
+	}
+	if err := statsdClient.Flush(); err != nil {
+		log.Warn("Failed to send metrics to StatsD", "error", err)
+	}
+}
+
+// closeStatsd sends the metrics buffered for StatsD and disconnects.
+func closeStatsd() {
+	if statsdClient == nil {
+		return
+	}
+	if err := statsdClient.Close(); err != nil {
+		log.Warn("Failed to send metrics to StatsD", "error", err)
+	}
+	statsdClient = nil
+}
+
+// statsdMetrics records the searches of a profile's bot as StatsD metrics, or returns nil when StatsD isn't
+// set up.
+func statsdMetrics(profile string) bot.SearchMetrics {
+	if statsdClient == nil {
+		return nil
+	}
+	return &searchMetrics{client: statsdClient, profile: profile}
+}
+
+// searchMetrics emits a count of searches, failures, and results, and the duration of each search, tagged
+// with the platform, keyword, and profile.
+type searchMetrics struct {
+	client  *statsd.Client
+	profile string
+}
+
+func (m *searchMetrics) RecordSearch(keyword string, search bot.PlatformReport) {
+	tags := []string{statsd.Tag("platform", search.Platform)}
+	if *statsdKeywordTags {
+		tags = append(tags, statsd.Tag("keyword", keyword))
+	}
+	if m.profile != "" {
+		tags = append(tags, statsd.Tag("profile", m.profile))
+	}
+	m.client.Count("searches", int64(search.Searches), tags...)
+	m.client.Count("search.errors", int64(search.Failed), tags...)
+	m.client.Count("results.found", int64(search.Found), tags...)
+	m.client.Count("results.new", int64(search.New), tags...)
+	m.client.Count("results.skipped", int64(search.Skipped), tags...)
+	m.client.Timing("search.duration", search.Duration, tags...)
+}
@@ -0,0 +1,42 @@
+// storage/archive.go
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Snapshot is an archived copy of a result as it was when saved, kept so mentions can still be reviewed
+// after their post is edited, deleted, or taken down. Snapshots are never pruned.
+type Snapshot struct {
+	Result search.SearchResult `json:"result"`
+	// ArchivedAt is when the snapshot was taken, in Unix seconds. An edited result is archived again, so a
+	// result can have several snapshots.
+	ArchivedAt int64 `json:"archived_at"`
+	// Page is the HTML of the page the result links to, when page snapshots are enabled and it could be
+	// fetched.
+	Page string `json:"page,omitempty"`
+}
+
+// Archiver is implemented by storers that can keep snapshots of results alongside them.
+type Archiver interface {
+	Archive(ctx context.Context, snapshots []Snapshot) error
+}
+
+// AsArchiver returns the storer's archive if its backend has one. A MultiStorer's archive is its primary's.
+func AsArchiver(s Storer) (Archiver, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	archiver, ok := s.(Archiver)
+	return archiver, ok
+}
+
+// snapshotID identifies a result's snapshots in backends that key them by name.
+func snapshotID(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:16])
+}
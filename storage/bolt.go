@@ -0,0 +1,607 @@
+// storage/bolt.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+	bolt "go.etcd.io/bbolt"
+)
+
+// lastSearchTimeBucket holds one key per platform. Result buckets are named after their platform, so this
+// name is chosen not to collide with any platform name.
+var lastSearchTimeBucket = []byte("__last_search_time")
+
+// outboxBucket holds queued notifications keyed by entry ID.
+var outboxBucket = []byte("__outbox")
+
+// keywordsBucket holds managed keywords keyed by keyword.
+var keywordsBucket = []byte("__keywords")
+
+// runsBucket holds run history keyed by start time, so runs are stored in the order they started.
+var runsBucket = []byte("__runs")
+
+// tokensBucket holds the tokens searchers authenticate with, keyed by token key.
+var tokensBucket = []byte("__tokens")
+
+// internalBucket reports whether a bucket holds bookkeeping rather than a platform's results.
+func internalBucket(name []byte) bool {
+	return bytes.Equal(name, lastSearchTimeBucket) || bytes.Equal(name, outboxBucket) || bytes.Equal(name, keywordsBucket) || bytes.Equal(name, runsBucket) || bytes.Equal(name, tokensBucket)
+}
+
+// BoltStorer stores results in an embedded bbolt database, using one bucket per platform keyed by URL.
+// Unlike SQLite it is pure Go, so binaries built with CGO_ENABLED=0 can use it.
+type BoltStorer struct {
+	db *bolt.DB
+}
+
+// NewBoltStorer opens (or creates) the database file at dbPath with a .bolt extension.
+func NewBoltStorer(dbPath string) (*BoltStorer, error) {
+	db, err := bolt.Open(fmt.Sprintf("%s.bolt", dbPath), 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bbolt database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{lastSearchTimeBucket, outboxBucket, keywordsBucket, runsBucket, tokensBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bbolt buckets: %w", err)
+	}
+
+	return &BoltStorer{db: db}, nil
+}
+
+// Exists checks if a specific item already exists in bbolt.
+func (b *BoltStorer) Exists(ctx context.Context, platform, url string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(platform))
+		exists = bucket != nil && bucket.Get([]byte(url)) != nil
+		return nil
+	})
+	return exists, err
+}
+
+// Save stores a new search result in bbolt.
+func (b *BoltStorer) Save(ctx context.Context, result search.SearchResult) error {
+	return b.SaveBatch(ctx, []search.SearchResult{result})
+}
+
+// SaveBatch stores several results in a single bbolt transaction.
+func (b *BoltStorer) SaveBatch(ctx context.Context, results []search.SearchResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, result := range results {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(result.Platform))
+			if err != nil {
+				return fmt.Errorf("failed to create bucket for %s: %w", result.Platform, err)
+			}
+			if bucket.Get([]byte(result.URL)) != nil {
+				continue
+			}
+
+			value, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("failed to marshal result: %w", err)
+			}
+			if err := bucket.Put([]byte(result.URL), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the stored result at url.
+func (b *BoltStorer) Get(ctx context.Context, platform, url string) (search.SearchResult, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return search.SearchResult{}, false, err
+	}
+
+	var result search.SearchResult
+	var found bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(platform))
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get([]byte(url))
+		if value == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(value, &result)
+	})
+	return result, found, err
+}
+
+// Update replaces the mutable fields of a stored result, keeping its keyword, author, timestamp, and
+// priority.
+func (b *BoltStorer) Update(ctx context.Context, result search.SearchResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(result.Platform))
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get([]byte(result.URL))
+		if value == nil {
+			return nil
+		}
+
+		var stored search.SearchResult
+		if err := json.Unmarshal(value, &stored); err != nil {
+			return fmt.Errorf("failed to parse stored result: %w", err)
+		}
+		stored.Title = result.Title
+		stored.Content = result.Content
+		stored.Score = result.Score
+		stored.Comments = result.Comments
+		stored.Reposts = result.Reposts
+		stored.Views = result.Views
+		stored.Tags = result.Tags
+		stored.Metadata = result.Metadata
+		stored.ContentHash = result.ContentHash
+		stored.DeletedAt = result.DeletedAt
+
+		updated, err := json.Marshal(stored)
+		if err != nil {
+			return fmt.Errorf("failed to marshal result: %w", err)
+		}
+		return bucket.Put([]byte(result.URL), updated)
+	})
+}
+
+// AddKeywords records further keywords matched by stored results in a single bbolt transaction.
+func (b *BoltStorer) AddKeywords(ctx context.Context, results []search.SearchResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, result := range results {
+			bucket := tx.Bucket([]byte(result.Platform))
+			if bucket == nil {
+				continue
+			}
+			value := bucket.Get([]byte(result.URL))
+			if value == nil {
+				continue
+			}
+
+			var stored search.SearchResult
+			if err := json.Unmarshal(value, &stored); err != nil {
+				return fmt.Errorf("failed to parse stored result: %w", err)
+			}
+			keywords, added := addKeywords(stored, result.Keywords)
+			if !added {
+				continue
+			}
+			stored.Keywords = keywords
+
+			updated, err := json.Marshal(stored)
+			if err != nil {
+				return fmt.Errorf("failed to marshal result: %w", err)
+			}
+			if err := bucket.Put([]byte(result.URL), updated); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetLastSearchTime retrieves the last search time for a given platform from bbolt.
+func (b *BoltStorer) GetLastSearchTime(ctx context.Context, platform string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var lastSearchTime int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(lastSearchTimeBucket).Get([]byte(platform))
+		if value == nil {
+			return nil
+		}
+
+		parsed, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse LastSearchTime: %w", err)
+		}
+		lastSearchTime = parsed
+		return nil
+	})
+	return lastSearchTime, err
+}
+
+// SetLastSearchTime updates the last search time for a given platform in bbolt.
+func (b *BoltStorer) SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(lastSearchTimeBucket).Put([]byte(platform), []byte(strconv.FormatInt(epochTime, 10)))
+	})
+}
+
+// LastSearchTimes returns every last search time stored in bbolt.
+func (b *BoltStorer) LastSearchTimes(ctx context.Context) (map[string]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	times := make(map[string]int64)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(lastSearchTimeBucket).ForEach(func(key, value []byte) error {
+			parsed, err := strconv.ParseInt(string(value), 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse LastSearchTime: %w", err)
+			}
+			times[string(key)] = parsed
+			return nil
+		})
+	})
+	return times, err
+}
+
+// Close closes the bbolt database.
+func (b *BoltStorer) Close() error {
+	return b.db.Close()
+}
+
+// Prune deletes results older than the given time from every platform bucket.
+func (b *BoltStorer) Prune(ctx context.Context, olderThan time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if internalBucket(name) {
+				return nil
+			}
+
+			var expired [][]byte
+			err := bucket.ForEach(func(key, value []byte) error {
+				var result search.SearchResult
+				if err := json.Unmarshal(value, &result); err != nil {
+					return fmt.Errorf("failed to parse stored result %s: %w", key, err)
+				}
+				if result.Timestamp < olderThan.Unix() {
+					expired = append(expired, key)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, key := range expired {
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// FindByContentHash scans every platform bucket for results with a matching content hash saved at or
+// after since.
+func (b *BoltStorer) FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []search.SearchResult
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if internalBucket(name) {
+				return nil
+			}
+
+			return bucket.ForEach(func(key, value []byte) error {
+				// Skip decoding values that can't contain the hash
+				if !bytes.Contains(value, []byte(hash)) {
+					return nil
+				}
+				var result search.SearchResult
+				if err := json.Unmarshal(value, &result); err != nil {
+					return fmt.Errorf("failed to parse stored result %s: %w", key, err)
+				}
+				if result.ContentHash == hash && result.Timestamp >= since.Unix() {
+					results = append(results, result)
+				}
+				return nil
+			})
+		})
+	})
+	return results, err
+}
+
+// Enqueue adds notifications to the outbox bucket.
+func (b *BoltStorer) Enqueue(ctx context.Context, entries []OutboxEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(outboxBucket)
+		for _, entry := range entries {
+			if bucket.Get([]byte(entry.ID)) != nil {
+				continue
+			}
+			if err := putOutboxEntry(bucket, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DueOutbox returns queued notifications that are due for delivery, oldest first.
+func (b *BoltStorer) DueOutbox(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var entries []OutboxEntry
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).ForEach(func(key, value []byte) error {
+			var entry OutboxEntry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return fmt.Errorf("failed to parse outbox entry %s: %w", key, err)
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dueEntries(entries, now, limit), nil
+}
+
+// UpdateOutbox records a failed delivery attempt. Entries that have since been deleted are left deleted.
+func (b *BoltStorer) UpdateOutbox(ctx context.Context, entry OutboxEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(outboxBucket)
+		if bucket.Get([]byte(entry.ID)) == nil {
+			return nil
+		}
+		return putOutboxEntry(bucket, entry)
+	})
+}
+
+// DeleteOutbox removes a notification from the outbox bucket.
+func (b *BoltStorer) DeleteOutbox(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).Delete([]byte(id))
+	})
+}
+
+func putOutboxEntry(bucket *bolt.Bucket, entry OutboxEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox entry: %w", err)
+	}
+	return bucket.Put([]byte(entry.ID), value)
+}
+
+// CountResults scans every platform bucket, counting results by platform and keyword.
+func (b *BoltStorer) CountResults(ctx context.Context, since, until time.Time) ([]ResultCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tally := newResultTally(since, until)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if internalBucket(name) {
+				return nil
+			}
+
+			return bucket.ForEach(func(key, value []byte) error {
+				var result search.SearchResult
+				if err := json.Unmarshal(value, &result); err != nil {
+					return fmt.Errorf("failed to parse stored result %s: %w", key, err)
+				}
+				tally.add(result.Platform, result.Keyword, result.Timestamp)
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tally.results(), nil
+}
+
+// Query scans every platform bucket for results matching q.
+func (b *BoltStorer) Query(ctx context.Context, q Query) ([]search.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []search.SearchResult
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if internalBucket(name) {
+				return nil
+			}
+
+			return bucket.ForEach(func(key, value []byte) error {
+				var result search.SearchResult
+				if err := json.Unmarshal(value, &result); err != nil {
+					return fmt.Errorf("failed to parse stored result %s: %w", key, err)
+				}
+				if q.matches(result) {
+					results = append(results, result)
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q.page(results), nil
+}
+
+// ManagedKeywords returns every managed keyword in the keywords bucket, sorted.
+func (b *BoltStorer) ManagedKeywords(ctx context.Context) ([]ManagedKeyword, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var keywords []ManagedKeyword
+	err := b.db.View(func(tx *bolt.Tx) error {
+		// Keys are sorted, so keywords come out in order
+		return tx.Bucket(keywordsBucket).ForEach(func(key, value []byte) error {
+			var keyword ManagedKeyword
+			if err := json.Unmarshal(value, &keyword); err != nil {
+				return fmt.Errorf("failed to parse managed keyword %s: %w", key, err)
+			}
+			keywords = append(keywords, keyword)
+			return nil
+		})
+	})
+	return keywords, err
+}
+
+// PutKeyword adds or replaces a managed keyword in the keywords bucket.
+func (b *BoltStorer) PutKeyword(ctx context.Context, keyword ManagedKeyword) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(keyword)
+	if err != nil {
+		return fmt.Errorf("failed to marshal managed keyword: %w", err)
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(keywordsBucket).Put([]byte(keyword.Keyword), value)
+	})
+}
+
+// DeleteKeyword removes a managed keyword from the keywords bucket.
+func (b *BoltStorer) DeleteKeyword(ctx context.Context, keyword string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(keywordsBucket).Delete([]byte(keyword))
+	})
+}
+
+// RecordRun adds a run to the runs bucket.
+func (b *BoltStorer) RecordRun(ctx context.Context, run Run) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(runsBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		// The sequence keeps runs started at the same moment apart
+		key := binary.BigEndian.AppendUint64(nil, uint64(run.Started.UnixNano()))
+		return bucket.Put(binary.BigEndian.AppendUint64(key, seq), value)
+	})
+}
+
+// Runs returns up to limit runs from the runs bucket, most recently started first.
+func (b *BoltStorer) Runs(ctx context.Context, limit int) ([]Run, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	err := b.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(runsBucket).Cursor()
+		for key, value := cursor.Last(); key != nil && (limit <= 0 || len(runs) < limit); key, value = cursor.Prev() {
+			var run Run
+			if err := json.Unmarshal(value, &run); err != nil {
+				return fmt.Errorf("failed to parse run: %w", err)
+			}
+			runs = append(runs, run)
+		}
+		return nil
+	})
+	return runs, err
+}
+
+// Token returns the token stored under key in the tokens bucket, or nil if there is none.
+func (b *BoltStorer) Token(ctx context.Context, key string) (*search.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var token *search.Token
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(tokensBucket).Get([]byte(key))
+		if value == nil {
+			return nil
+		}
+		token = &search.Token{}
+		if err := json.Unmarshal(value, token); err != nil {
+			return fmt.Errorf("failed to parse token %s: %w", key, err)
+		}
+		return nil
+	})
+	return token, err
+}
+
+// PutToken stores a token under key in the tokens bucket, replacing any stored before.
+func (b *BoltStorer) PutToken(ctx context.Context, key string, token search.Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tokensBucket).Put([]byte(key), value)
+	})
+}
@@ -0,0 +1,306 @@
+// storage/clickhouse.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/grass/internal/httpclient"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// clickHouseBatchSize is how many results are inserted in one request.
+const clickHouseBatchSize = 500
+
+// ClickHouseStorer stores results in an append-only ClickHouse table using the HTTP interface. The results
+// table is a ReplacingMergeTree ordered by platform and URL, so duplicate inserts collapse on merge, and
+// each batch of saved results is inserted in as few requests as possible.
+type ClickHouseStorer struct {
+	baseURL  string
+	database string
+	table    string
+	username string
+	password string
+	client   *http.Client
+}
+
+type clickHouseRow struct {
+	Platform    string            `json:"Platform"`
+	Keyword     string            `json:"Keyword"`
+	Title       string            `json:"Title"`
+	URL         string            `json:"URL"`
+	Timestamp   int64             `json:"Timestamp"`
+	Content     string            `json:"Content"`
+	Author      string            `json:"Author"`
+	Score       int64             `json:"Score"`
+	Comments    int64             `json:"Comments"`
+	Reposts     int64             `json:"Reposts"`
+	Views       int64             `json:"Views"`
+	Tags        []string          `json:"Tags"`
+	Metadata    map[string]string `json:"Metadata"`
+	Priority    string            `json:"Priority"`
+	ContentHash string            `json:"ContentHash"`
+	InsertedAt  int64             `json:"InsertedAt"`
+}
+
+// NewClickHouseStorer connects to CLICKHOUSE_URL (defaulting to localhost) with client, and creates the
+// tables if needed. A nil client uses a default client.
+func NewClickHouseStorer(ctx context.Context, table string, client *http.Client) (*ClickHouseStorer, error) {
+	baseURL := os.Getenv("CLICKHOUSE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8123"
+	}
+	database := os.Getenv("CLICKHOUSE_DATABASE")
+	if database == "" {
+		database = "default"
+	}
+
+	c := &ClickHouseStorer{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		database: database,
+		table:    table,
+		username: os.Getenv("CLICKHOUSE_USER"),
+		password: os.Getenv("CLICKHOUSE_PASSWORD"),
+		client:   httpclient.WithTimeout(client, 60*time.Second),
+	}
+
+	createTables := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			Platform LowCardinality(String),
+			Keyword String,
+			Title String,
+			URL String,
+			Timestamp DateTime,
+			Content String,
+			Author String,
+			Score Int64,
+			Comments Int64,
+			Reposts Int64,
+			Views Int64,
+			Tags Array(String),
+			Metadata Map(String, String),
+			Priority LowCardinality(String),
+			ContentHash String,
+			InsertedAt DateTime
+		) ENGINE = ReplacingMergeTree(InsertedAt)
+		ORDER BY (Platform, URL)`, c.tableName("")),
+		// Tables created by older versions lack the metadata columns.
+		fmt.Sprintf(`ALTER TABLE %s
+			ADD COLUMN IF NOT EXISTS Content String,
+			ADD COLUMN IF NOT EXISTS Author String,
+			ADD COLUMN IF NOT EXISTS Score Int64,
+			ADD COLUMN IF NOT EXISTS Priority LowCardinality(String),
+			ADD COLUMN IF NOT EXISTS ContentHash String,
+			ADD COLUMN IF NOT EXISTS Comments Int64,
+			ADD COLUMN IF NOT EXISTS Reposts Int64,
+			ADD COLUMN IF NOT EXISTS Views Int64,
+			ADD COLUMN IF NOT EXISTS Tags Array(String),
+			ADD COLUMN IF NOT EXISTS Metadata Map(String, String)`, c.tableName("")),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			Platform String,
+			LastSearchTime Int64,
+			UpdatedAt DateTime64(3)
+		) ENGINE = ReplacingMergeTree(UpdatedAt)
+		ORDER BY Platform`, c.tableName("_last_search_time")),
+	}
+	for _, query := range createTables {
+		if _, err := c.query(ctx, query, nil, nil); err != nil {
+			return nil, fmt.Errorf("failed to create ClickHouse tables: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// tableName returns the fully qualified, quoted table name with an optional suffix.
+func (c *ClickHouseStorer) tableName(suffix string) string {
+	return fmt.Sprintf("`%s`.`%s%s`", c.database, c.table, suffix)
+}
+
+// query runs a statement over the HTTP interface. Parameters are bound server-side using {name:Type} placeholders.
+func (c *ClickHouseStorer) query(ctx context.Context, query string, params map[string]string, body []byte) ([]byte, error) {
+	values := url.Values{}
+	values.Set("database", c.database)
+	for name, value := range params {
+		values.Set("param_"+name, value)
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		values.Set("query", query)
+		reqBody = bytes.NewReader(body)
+	} else {
+		reqBody = strings.NewReader(query)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/?"+values.Encode(), reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.username != "" {
+		req.Header.Set("X-ClickHouse-User", c.username)
+		req.Header.Set("X-ClickHouse-Key", c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ClickHouse request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ClickHouse response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ClickHouse query failed with status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	return data, nil
+}
+
+// Exists checks if a specific item already exists in ClickHouse.
+func (c *ClickHouseStorer) Exists(ctx context.Context, platform, url string) (bool, error) {
+	data, err := c.query(ctx,
+		fmt.Sprintf("SELECT count() FROM %s WHERE Platform = {platform:String} AND URL = {url:String}", c.tableName("")),
+		map[string]string{"platform": platform, "url": url}, nil,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse ClickHouse count: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Save inserts a new search result.
+func (c *ClickHouseStorer) Save(ctx context.Context, result search.SearchResult) error {
+	return c.SaveBatch(ctx, []search.SearchResult{result})
+}
+
+// SaveBatch inserts several results, up to clickHouseBatchSize per request. Results are stored once it
+// returns, so they are never lost to a crash after being reported as saved.
+func (c *ClickHouseStorer) SaveBatch(ctx context.Context, results []search.SearchResult) error {
+	insertedAt := time.Now().Unix()
+	for start := 0; start < len(results); start += clickHouseBatchSize {
+		batch := results[start:min(start+clickHouseBatchSize, len(results))]
+
+		var body bytes.Buffer
+		encoder := json.NewEncoder(&body)
+		for _, result := range batch {
+			row := clickHouseRow{
+				Platform:    result.Platform,
+				Keyword:     result.Keyword,
+				Title:       result.Title,
+				URL:         result.URL,
+				Timestamp:   result.Timestamp,
+				Content:     result.Content,
+				Author:      result.Author,
+				Score:       result.Score,
+				Comments:    result.Comments,
+				Reposts:     result.Reposts,
+				Views:       result.Views,
+				Tags:        result.Tags,
+				Metadata:    result.Metadata,
+				Priority:    string(result.Priority),
+				ContentHash: result.ContentHash,
+				InsertedAt:  insertedAt,
+			}
+			if err := encoder.Encode(row); err != nil {
+				return fmt.Errorf("failed to encode row: %w", err)
+			}
+		}
+
+		if _, err := c.query(ctx, fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", c.tableName("")), nil, body.Bytes()); err != nil {
+			return fmt.Errorf("failed to insert %d results into ClickHouse: %w", len(batch), err)
+		}
+	}
+	return nil
+}
+
+// GetLastSearchTime retrieves the last search time for a given platform from ClickHouse.
+func (c *ClickHouseStorer) GetLastSearchTime(ctx context.Context, platform string) (int64, error) {
+	data, err := c.query(ctx,
+		fmt.Sprintf("SELECT argMax(LastSearchTime, UpdatedAt) FROM %s WHERE Platform = {platform:String}", c.tableName("_last_search_time")),
+		map[string]string{"platform": platform}, nil,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	lastSearchTime, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse LastSearchTime: %w", err)
+	}
+	return lastSearchTime, nil
+}
+
+// SetLastSearchTime records the last search time for a platform.
+func (c *ClickHouseStorer) SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error {
+	_, err := c.query(ctx,
+		fmt.Sprintf("INSERT INTO %s (Platform, LastSearchTime, UpdatedAt) VALUES ({platform:String}, {time:Int64}, now64(3))", c.tableName("_last_search_time")),
+		map[string]string{"platform": platform, "time": strconv.FormatInt(epochTime, 10)}, nil,
+	)
+	return err
+}
+
+// Prune deletes results older than the given time from ClickHouse. The mutation runs asynchronously on the server.
+func (c *ClickHouseStorer) Prune(ctx context.Context, olderThan time.Time) error {
+	_, err := c.query(ctx,
+		fmt.Sprintf("ALTER TABLE %s DELETE WHERE Timestamp < toDateTime({cutoff:Int64})", c.tableName("")),
+		map[string]string{"cutoff": strconv.FormatInt(olderThan.Unix(), 10)}, nil,
+	)
+	return err
+}
+
+// FindByContentHash returns results with a matching content hash saved at or after since.
+func (c *ClickHouseStorer) FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error) {
+	data, err := c.query(ctx,
+		fmt.Sprintf(`SELECT Platform, Keyword, Title, URL, toUnixTimestamp(Timestamp) AS Timestamp, Content, Author, Score, Comments, Reposts, Views,
+			Tags, Metadata, Priority, ContentHash FROM %s FINAL WHERE ContentHash = {hash:String} AND Timestamp >= toDateTime({since:Int64})
+			ORDER BY Timestamp FORMAT JSONEachRow`, c.tableName("")),
+		map[string]string{"hash": hash, "since": strconv.FormatInt(since.Unix(), 10)}, nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []search.SearchResult
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var row clickHouseRow
+		if err := decoder.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to parse ClickHouse row: %w", err)
+		}
+		results = append(results, search.SearchResult{
+			Platform:    row.Platform,
+			Keyword:     row.Keyword,
+			Title:       row.Title,
+			URL:         row.URL,
+			Timestamp:   row.Timestamp,
+			Content:     row.Content,
+			Author:      row.Author,
+			Score:       row.Score,
+			Comments:    row.Comments,
+			Reposts:     row.Reposts,
+			Views:       row.Views,
+			Tags:        row.Tags,
+			Metadata:    row.Metadata,
+			Priority:    search.Priority(row.Priority),
+			ContentHash: row.ContentHash,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,93 @@
+// storage/count.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ResultCount is the number of stored results found on a platform for a keyword.
+type ResultCount struct {
+	Platform string
+	Keyword  string
+	Count    int64
+}
+
+// Counter is implemented by storers that can summarize their stored results for reports.
+type Counter interface {
+	// CountResults counts stored results posted at or after since and before until, by platform and
+	// keyword. A zero since or until leaves that end of the range open.
+	CountResults(ctx context.Context, since, until time.Time) ([]ResultCount, error)
+}
+
+// AsCounter returns the storer's counter if its backend has one. A MultiStorer counts from its primary.
+func AsCounter(s Storer) (Counter, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	counter, ok := s.(Counter)
+	return counter, ok
+}
+
+// CountResults counts a storer's results posted at or after since and before until, by platform and
+// keyword, using its Counter, or by querying its results when it has none.
+func CountResults(ctx context.Context, s Storer, since, until time.Time) ([]ResultCount, error) {
+	if counter, ok := AsCounter(s); ok {
+		return counter.CountResults(ctx, since, until)
+	}
+	querier, ok := AsQuerier(s)
+	if !ok {
+		return nil, errors.New("storage can't count or query results")
+	}
+	results, err := querier.Query(ctx, Query{Since: since, Until: until})
+	if err != nil {
+		return nil, err
+	}
+	tally := newResultTally(since, until)
+	for _, result := range results {
+		tally.add(result.Platform, result.Keyword, result.Timestamp)
+	}
+	return tally.results(), nil
+}
+
+// resultTally accumulates counts for backends that scan their results.
+type resultTally struct {
+	since, until time.Time
+	counts       map[[2]string]int64
+}
+
+func newResultTally(since, until time.Time) *resultTally {
+	return &resultTally{since: since, until: until, counts: make(map[[2]string]int64)}
+}
+
+// add counts a result if its timestamp is in range.
+func (t *resultTally) add(platform, keyword string, timestamp int64) {
+	if !t.since.IsZero() && timestamp < t.since.Unix() {
+		return
+	}
+	if !t.until.IsZero() && timestamp >= t.until.Unix() {
+		return
+	}
+	t.counts[[2]string{platform, keyword}]++
+}
+
+// results returns the counts sorted by platform and keyword.
+func (t *resultTally) results() []ResultCount {
+	counts := make([]ResultCount, 0, len(t.counts))
+	for key, count := range t.counts {
+		counts = append(counts, ResultCount{Platform: key[0], Keyword: key[1], Count: count})
+	}
+	sortResultCounts(counts)
+	return counts
+}
+
+func sortResultCounts(counts []ResultCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Platform != counts[j].Platform {
+			return counts[i].Platform < counts[j].Platform
+		}
+		return counts[i].Keyword < counts[j].Keyword
+	})
+}
@@ -2,26 +2,70 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/charmbracelet/log"
 	"github.com/jaxxstorm/grass/search"
 )
 
+const (
+	// dynamoDBTTLAttribute holds the epoch second after which DynamoDB may expire a result.
+	dynamoDBTTLAttribute = "ExpiresAt"
+	// dynamoDBKeywordIndex is a GSI on Keyword and Timestamp for querying results by keyword over time.
+	dynamoDBKeywordIndex = "KeywordTimestampIndex"
+	// dynamoDBContentHashPrefix prefixes the partition key of content hash index items.
+	dynamoDBContentHashPrefix = "ContentHash#"
+	// dynamoDBKeywordsPartition is the partition key of managed keyword items, sorted by keyword.
+	dynamoDBKeywordsPartition = "ManagedKeywords"
+	// dynamoDBRunsPartition is the partition key of run history items, sorted by start time.
+	dynamoDBRunsPartition = "Runs"
+	// dynamoDBTokensPartition is the partition key of the tokens searchers authenticate with, sorted by key.
+	dynamoDBTokensPartition = "Tokens"
+	// dynamoDBLeasesPartition is the partition key of lease items, sorted by lease name.
+	dynamoDBLeasesPartition = "Leases"
+)
+
 type DynamoDBStorer struct {
 	client    *dynamodb.Client
 	tableName string
+	retention time.Duration
+}
+
+// DynamoDBOptions controls optional table provisioning and retention.
+type DynamoDBOptions struct {
+	// CreateTable creates the table (on-demand billing, TTL enabled, keyword GSI) if it does not exist.
+	CreateTable bool
+	// Retention sets a TTL on saved results, relative to their timestamp. Zero disables expiry.
+	Retention time.Duration
+	// HTTPClient sends requests to DynamoDB. Nil uses the AWS SDK's default client.
+	HTTPClient *http.Client
+}
+
+// awsHTTPClient returns the option that sends AWS requests with client, or no options if it is nil.
+func awsHTTPClient(client *http.Client) []func(*config.LoadOptions) error {
+	if client == nil {
+		return nil
+	}
+	return []func(*config.LoadOptions) error{config.WithHTTPClient(client)}
 }
 
-func NewDynamoDBStorer(dbName string) (*DynamoDBStorer, error) {
-	ctx := context.TODO()
+func NewDynamoDBStorer(ctx context.Context, dbName string, opts DynamoDBOptions) (*DynamoDBStorer, error) {
 
 	// Load AWS config with detailed logging
-	cfg, err := config.LoadDefaultConfig(ctx)
+	cfg, err := config.LoadDefaultConfig(ctx, awsHTTPClient(opts.HTTPClient)...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
@@ -29,14 +73,97 @@ func NewDynamoDBStorer(dbName string) (*DynamoDBStorer, error) {
 
 	client := dynamodb.NewFromConfig(cfg)
 
-	return &DynamoDBStorer{
+	d := &DynamoDBStorer{
 		client:    client,
 		tableName: dbName,
-	}, nil
+		retention: opts.Retention,
+	}
+
+	if err := d.ensureTable(ctx, opts.CreateTable); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// ensureTable checks the table has the key schema grass expects, creating it first if requested.
+func (d *DynamoDBStorer) ensureTable(ctx context.Context, create bool) error {
+	out, err := d.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(d.tableName)})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		if !create {
+			return fmt.Errorf("DynamoDB table %s does not exist; create it with a Platform (string) partition key and SortKey (string) sort key, or pass --dynamodb-create-table", d.tableName)
+		}
+		return d.createTable(ctx)
+	} else if err != nil {
+		return fmt.Errorf("failed to describe DynamoDB table %s: %w", d.tableName, err)
+	}
+
+	keys := map[types.KeyType]string{}
+	for _, key := range out.Table.KeySchema {
+		keys[key.KeyType] = aws.ToString(key.AttributeName)
+	}
+	if keys[types.KeyTypeHash] != "Platform" || keys[types.KeyTypeRange] != "SortKey" {
+		return fmt.Errorf("DynamoDB table %s has key schema %s/%s, expected Platform (partition key) and SortKey (sort key)",
+			d.tableName, keys[types.KeyTypeHash], keys[types.KeyTypeRange])
+	}
+
+	return nil
+}
+
+// createTable provisions an on-demand table with the grass key schema, a keyword GSI, and TTL enabled.
+func (d *DynamoDBStorer) createTable(ctx context.Context) error {
+	log.Info("Creating DynamoDB table", "table", d.tableName)
+
+	_, err := d.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String(d.tableName),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("Platform"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("SortKey"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("Keyword"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("Timestamp"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("Platform"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("SortKey"), KeyType: types.KeyTypeRange},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String(dynamoDBKeywordIndex),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("Keyword"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("Timestamp"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create DynamoDB table %s: %w", d.tableName, err)
+	}
+
+	waiter := dynamodb.NewTableExistsWaiter(d.client)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(d.tableName)}, 5*time.Minute); err != nil {
+		return fmt.Errorf("timed out waiting for DynamoDB table %s to become active: %w", d.tableName, err)
+	}
+
+	_, err = d.client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(d.tableName),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String(dynamoDBTTLAttribute),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enable TTL on DynamoDB table %s: %w", d.tableName, err)
+	}
+
+	return nil
 }
 
 // Exists checks if a specific item (platform + URL) already exists in DynamoDB.
-func (d *DynamoDBStorer) Exists(platform, url string) (bool, error) {
+func (d *DynamoDBStorer) Exists(ctx context.Context, platform, url string) (bool, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(d.tableName),
 		Key: map[string]types.AttributeValue{
@@ -45,7 +172,7 @@ func (d *DynamoDBStorer) Exists(platform, url string) (bool, error) {
 		},
 	}
 
-	result, err := d.client.GetItem(context.TODO(), input)
+	result, err := d.client.GetItem(ctx, input)
 	if err != nil {
 		return false, fmt.Errorf("failed to get item from DynamoDB: %w", err)
 	}
@@ -54,29 +181,197 @@ func (d *DynamoDBStorer) Exists(platform, url string) (bool, error) {
 }
 
 // Save stores a new search result in DynamoDB.
-func (d *DynamoDBStorer) Save(result search.SearchResult) error {
-	item := map[string]types.AttributeValue{
-		"Platform":  &types.AttributeValueMemberS{Value: result.Platform},
-		"SortKey":   &types.AttributeValueMemberS{Value: result.URL},
-		"Keyword":   &types.AttributeValueMemberS{Value: result.Keyword},
-		"Title":     &types.AttributeValueMemberS{Value: result.Title},
-		"Timestamp": &types.AttributeValueMemberN{Value: strconv.FormatInt(result.Timestamp, 10)},
-	}
-
+func (d *DynamoDBStorer) Save(ctx context.Context, result search.SearchResult) error {
 	input := &dynamodb.PutItemInput{
 		TableName: aws.String(d.tableName),
-		Item:      item,
+		Item:      d.resultItem(result),
 	}
 
-	_, err := d.client.PutItem(context.TODO(), input)
+	_, err := d.client.PutItem(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to put item into DynamoDB: %w", err)
 	}
 	return nil
 }
 
+// SaveBatch stores several results using BatchWriteItem.
+func (d *DynamoDBStorer) SaveBatch(ctx context.Context, results []search.SearchResult) error {
+	requests := make([]types.WriteRequest, 0, len(results))
+	for _, result := range results {
+		requests = append(requests, types.WriteRequest{
+			PutRequest: &types.PutRequest{Item: d.resultItem(result)},
+		})
+		if result.ContentHash != "" {
+			requests = append(requests, types.WriteRequest{
+				PutRequest: &types.PutRequest{Item: d.contentHashItem(result)},
+			})
+		}
+	}
+	return d.batchWrite(ctx, requests)
+}
+
+// Get returns the stored result at url.
+func (d *DynamoDBStorer) Get(ctx context.Context, platform, url string) (search.SearchResult, bool, error) {
+	output, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"Platform": &types.AttributeValueMemberS{Value: platform},
+			"SortKey":  &types.AttributeValueMemberS{Value: url},
+		},
+	})
+	if err != nil {
+		return search.SearchResult{}, false, fmt.Errorf("failed to get result from DynamoDB: %w", err)
+	}
+	if output.Item == nil {
+		return search.SearchResult{}, false, nil
+	}
+	return dynamoDBResult(output.Item), true, nil
+}
+
+// Update replaces the mutable attributes of a stored result, keeping its keyword, author, timestamp, and
+// priority. Optional attributes that are now empty are removed, as resultItem would leave them out.
+func (d *DynamoDBStorer) Update(ctx context.Context, result search.SearchResult) error {
+	item := d.resultItem(result)
+	names := make(map[string]string)
+	values := make(map[string]types.AttributeValue)
+	var set, remove []string
+	for _, attribute := range []string{"Title", "Score", "Comments", "Reposts", "Views", "Content", "Tags", "Metadata", "ContentHash", "DeletedAt"} {
+		// Names are aliased since DynamoDB reserves many common words
+		name := "#" + attribute
+		names[name] = attribute
+		if value, ok := item[attribute]; ok {
+			values[":"+attribute] = value
+			set = append(set, name+" = :"+attribute)
+		} else {
+			remove = append(remove, name)
+		}
+	}
+	expression := "SET " + strings.Join(set, ", ")
+	if len(remove) > 0 {
+		expression += " REMOVE " + strings.Join(remove, ", ")
+	}
+	_, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"Platform": &types.AttributeValueMemberS{Value: result.Platform},
+			"SortKey":  &types.AttributeValueMemberS{Value: result.URL},
+		},
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String("attribute_exists(SortKey)"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	var missing *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &missing) {
+		return fmt.Errorf("failed to update result in DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// AddKeywords adds further keywords matched by stored results to their Keywords string sets, skipping
+// results that aren't stored.
+func (d *DynamoDBStorer) AddKeywords(ctx context.Context, results []search.SearchResult) error {
+	for _, result := range results {
+		if len(result.Keywords) == 0 {
+			continue
+		}
+		_, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(d.tableName),
+			Key: map[string]types.AttributeValue{
+				"Platform": &types.AttributeValueMemberS{Value: result.Platform},
+				"SortKey":  &types.AttributeValueMemberS{Value: result.URL},
+			},
+			UpdateExpression:          aws.String("ADD Keywords :keywords"),
+			ConditionExpression:       aws.String("attribute_exists(SortKey)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":keywords": &types.AttributeValueMemberSS{Value: result.Keywords}},
+		})
+		var missing *types.ConditionalCheckFailedException
+		if err != nil && !errors.As(err, &missing) {
+			return fmt.Errorf("failed to add keywords in DynamoDB: %w", err)
+		}
+	}
+	return nil
+}
+
+// contentHashItem indexes a result under its content hash. Index items live in their own partition so a
+// lookup is a single Query rather than a table scan; they carry a Timestamp (and TTL) so Prune and
+// expiry remove them along with results. The keyword is stored as ResultKeyword to keep index items out
+// of the keyword GSI.
+func (d *DynamoDBStorer) contentHashItem(result search.SearchResult) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"Platform":       &types.AttributeValueMemberS{Value: dynamoDBContentHashPrefix + result.ContentHash},
+		"SortKey":        &types.AttributeValueMemberS{Value: result.Platform + "#" + result.URL},
+		"ResultPlatform": &types.AttributeValueMemberS{Value: result.Platform},
+		"URL":            &types.AttributeValueMemberS{Value: result.URL},
+		"Title":          &types.AttributeValueMemberS{Value: result.Title},
+		"Timestamp":      &types.AttributeValueMemberN{Value: strconv.FormatInt(result.Timestamp, 10)},
+	}
+	if result.Keyword != "" {
+		item["ResultKeyword"] = &types.AttributeValueMemberS{Value: result.Keyword}
+	}
+	if d.retention > 0 {
+		expiresAt := time.Unix(result.Timestamp, 0).Add(d.retention).Unix()
+		item[dynamoDBTTLAttribute] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt, 10)}
+	}
+	return item
+}
+
+// resultItem converts a search result into a DynamoDB item.
+func (d *DynamoDBStorer) resultItem(result search.SearchResult) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"Platform":  &types.AttributeValueMemberS{Value: result.Platform},
+		"SortKey":   &types.AttributeValueMemberS{Value: result.URL},
+		"Title":     &types.AttributeValueMemberS{Value: result.Title},
+		"Timestamp": &types.AttributeValueMemberN{Value: strconv.FormatInt(result.Timestamp, 10)},
+		"Score":     &types.AttributeValueMemberN{Value: strconv.FormatInt(result.Score, 10)},
+		"Comments":  &types.AttributeValueMemberN{Value: strconv.FormatInt(result.Comments, 10)},
+		"Reposts":   &types.AttributeValueMemberN{Value: strconv.FormatInt(result.Reposts, 10)},
+		"Views":     &types.AttributeValueMemberN{Value: strconv.FormatInt(result.Views, 10)},
+	}
+	// DynamoDB rejects empty string attributes in some contexts, so only set optional fields when present.
+	// Keyword is the keyword index's partition key, where an empty string is always rejected; pushed results
+	// may have none.
+	if result.Keyword != "" {
+		item["Keyword"] = &types.AttributeValueMemberS{Value: result.Keyword}
+	}
+	if result.Content != "" {
+		item["Content"] = &types.AttributeValueMemberS{Value: result.Content}
+	}
+	if result.Author != "" {
+		item["Author"] = &types.AttributeValueMemberS{Value: result.Author}
+	}
+	if len(result.Tags) > 0 {
+		tags := make([]types.AttributeValue, len(result.Tags))
+		for i, tag := range result.Tags {
+			tags[i] = &types.AttributeValueMemberS{Value: tag}
+		}
+		item["Tags"] = &types.AttributeValueMemberL{Value: tags}
+	}
+	if len(result.Metadata) > 0 {
+		metadata := make(map[string]types.AttributeValue, len(result.Metadata))
+		for key, value := range result.Metadata {
+			metadata[key] = &types.AttributeValueMemberS{Value: value}
+		}
+		item["Metadata"] = &types.AttributeValueMemberM{Value: metadata}
+	}
+	if result.Priority != "" {
+		item["Priority"] = &types.AttributeValueMemberS{Value: string(result.Priority)}
+	}
+	if result.ContentHash != "" {
+		item["ContentHash"] = &types.AttributeValueMemberS{Value: result.ContentHash}
+	}
+	if result.DeletedAt != 0 {
+		item["DeletedAt"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(result.DeletedAt, 10)}
+	}
+	if d.retention > 0 {
+		expiresAt := time.Unix(result.Timestamp, 0).Add(d.retention).Unix()
+		item[dynamoDBTTLAttribute] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt, 10)}
+	}
+	return item
+}
+
 // GetLastSearchTime retrieves the last search time for a given platform from DynamoDB.
-func (d *DynamoDBStorer) GetLastSearchTime(platform string) (int64, error) {
+func (d *DynamoDBStorer) GetLastSearchTime(ctx context.Context, platform string) (int64, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(d.tableName),
 		Key: map[string]types.AttributeValue{
@@ -85,7 +380,7 @@ func (d *DynamoDBStorer) GetLastSearchTime(platform string) (int64, error) {
 		},
 	}
 
-	result, err := d.client.GetItem(context.TODO(), input)
+	result, err := d.client.GetItem(ctx, input)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get item from DynamoDB: %w", err)
 	}
@@ -108,7 +403,7 @@ func (d *DynamoDBStorer) GetLastSearchTime(platform string) (int64, error) {
 }
 
 // SetLastSearchTime updates the last search time for a given platform in DynamoDB.
-func (d *DynamoDBStorer) SetLastSearchTime(platform string, epochTime int64) error {
+func (d *DynamoDBStorer) SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error {
 	item := map[string]types.AttributeValue{
 		"Platform":  &types.AttributeValueMemberS{Value: platform},
 		"SortKey":   &types.AttributeValueMemberS{Value: "LastSearchTime"},
@@ -120,9 +415,555 @@ func (d *DynamoDBStorer) SetLastSearchTime(platform string, epochTime int64) err
 		Item:      item,
 	}
 
-	_, err := d.client.PutItem(context.TODO(), input)
+	_, err := d.client.PutItem(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to put item into DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// LastSearchTimes scans DynamoDB for every last search time item.
+func (d *DynamoDBStorer) LastSearchTimes(ctx context.Context) (map[string]int64, error) {
+	paginator := dynamodb.NewScanPaginator(d.client, &dynamodb.ScanInput{
+		TableName:            aws.String(d.tableName),
+		FilterExpression:     aws.String("SortKey = :lastSearchTime"),
+		ProjectionExpression: aws.String("Platform, #ts"),
+		ExpressionAttributeNames: map[string]string{
+			"#ts": "Timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":lastSearchTime": &types.AttributeValueMemberS{Value: "LastSearchTime"},
+		},
+	})
+
+	times := make(map[string]int64)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan DynamoDB table: %w", err)
+		}
+		for _, item := range page.Items {
+			platform, platformOK := item["Platform"].(*types.AttributeValueMemberS)
+			timestamp, timestampOK := item["Timestamp"].(*types.AttributeValueMemberN)
+			if !platformOK || !timestampOK {
+				continue
+			}
+			lastSearchTime, err := strconv.ParseInt(timestamp.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse LastSearchTime: %w", err)
+			}
+			times[platform.Value] = lastSearchTime
+		}
+	}
+	return times, nil
+}
+
+// Prune deletes results older than the given time from DynamoDB. Last search time items are kept.
+func (d *DynamoDBStorer) Prune(ctx context.Context, olderThan time.Time) error {
+	paginator := dynamodb.NewScanPaginator(d.client, &dynamodb.ScanInput{
+		TableName:            aws.String(d.tableName),
+		FilterExpression:     aws.String("#ts < :cutoff AND SortKey <> :lastSearchTime"),
+		ProjectionExpression: aws.String("Platform, SortKey"),
+		ExpressionAttributeNames: map[string]string{
+			"#ts": "Timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":cutoff":         &types.AttributeValueMemberN{Value: strconv.FormatInt(olderThan.Unix(), 10)},
+			":lastSearchTime": &types.AttributeValueMemberS{Value: "LastSearchTime"},
+		},
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to scan DynamoDB table: %w", err)
+		}
+
+		var requests []types.WriteRequest
+		for _, item := range page.Items {
+			requests = append(requests, types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{Key: item},
+			})
+		}
+		if err := d.batchWrite(ctx, requests); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ManagedKeywords queries the managed keywords partition, sorted by keyword.
+func (d *DynamoDBStorer) ManagedKeywords(ctx context.Context) ([]ManagedKeyword, error) {
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("Platform = :partition"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":partition": &types.AttributeValueMemberS{Value: dynamoDBKeywordsPartition},
+		},
+	})
+
+	var keywords []ManagedKeyword
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query DynamoDB: %w", err)
+		}
+		for _, item := range page.Items {
+			var keyword ManagedKeyword
+			if v, ok := item["SortKey"].(*types.AttributeValueMemberS); ok {
+				keyword.Keyword = v.Value
+			}
+			if v, ok := item["Paused"].(*types.AttributeValueMemberBOOL); ok {
+				keyword.Paused = v.Value
+			}
+			if v, ok := item["PausedUntil"].(*types.AttributeValueMemberN); ok {
+				keyword.PausedUntil, _ = strconv.ParseInt(v.Value, 10, 64)
+			}
+			if v, ok := item["AddedAt"].(*types.AttributeValueMemberN); ok {
+				keyword.AddedAt, _ = strconv.ParseInt(v.Value, 10, 64)
+			}
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords, nil
+}
+
+// PutKeyword adds or replaces a managed keyword item. The items have no Timestamp, so Prune keeps them.
+func (d *DynamoDBStorer) PutKeyword(ctx context.Context, keyword ManagedKeyword) error {
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item: map[string]types.AttributeValue{
+			"Platform":    &types.AttributeValueMemberS{Value: dynamoDBKeywordsPartition},
+			"SortKey":     &types.AttributeValueMemberS{Value: keyword.Keyword},
+			"Paused":      &types.AttributeValueMemberBOOL{Value: keyword.Paused},
+			"PausedUntil": &types.AttributeValueMemberN{Value: strconv.FormatInt(keyword.PausedUntil, 10)},
+			"AddedAt":     &types.AttributeValueMemberN{Value: strconv.FormatInt(keyword.AddedAt, 10)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put item into DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// DeleteKeyword removes a managed keyword item.
+func (d *DynamoDBStorer) DeleteKeyword(ctx context.Context, keyword string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"Platform": &types.AttributeValueMemberS{Value: dynamoDBKeywordsPartition},
+			"SortKey":  &types.AttributeValueMemberS{Value: keyword},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item from DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// RecordRun adds a run history item. Its sort key is the zero-padded start time in nanoseconds, so items
+// sort by start time, and it has no Timestamp, so Prune keeps it.
+func (d *DynamoDBStorer) RecordRun(ctx context.Context, run Run) error {
+	value, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item: map[string]types.AttributeValue{
+			"Platform": &types.AttributeValueMemberS{Value: dynamoDBRunsPartition},
+			"SortKey":  &types.AttributeValueMemberS{Value: fmt.Sprintf("%019d", run.Started.UnixNano())},
+			"Run":      &types.AttributeValueMemberS{Value: string(value)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put item into DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// Runs queries the run history partition backwards, returning up to limit runs, most recently started first.
+func (d *DynamoDBStorer) Runs(ctx context.Context, limit int) ([]Run, error) {
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("Platform = :partition"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":partition": &types.AttributeValueMemberS{Value: dynamoDBRunsPartition},
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+
+	var runs []Run
+	for paginator.HasMorePages() && (limit <= 0 || len(runs) < limit) {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query DynamoDB: %w", err)
+		}
+		for _, item := range page.Items {
+			v, ok := item["Run"].(*types.AttributeValueMemberS)
+			if !ok {
+				continue
+			}
+			var run Run
+			if err := json.Unmarshal([]byte(v.Value), &run); err != nil {
+				return nil, fmt.Errorf("failed to parse run: %w", err)
+			}
+			runs = append(runs, run)
+		}
+	}
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// Token gets the token item stored under key, or nil if there is none.
+func (d *DynamoDBStorer) Token(ctx context.Context, key string) (*search.Token, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"Platform": &types.AttributeValueMemberS{Value: dynamoDBTokensPartition},
+			"SortKey":  &types.AttributeValueMemberS{Value: key},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item from DynamoDB: %w", err)
+	}
+	v, ok := out.Item["Token"].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, nil
+	}
+	var token search.Token
+	if err := json.Unmarshal([]byte(v.Value), &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token %s: %w", key, err)
+	}
+	return &token, nil
+}
+
+// PutToken adds or replaces the token item stored under key. The items have no Timestamp, so Prune keeps
+// them.
+func (d *DynamoDBStorer) PutToken(ctx context.Context, key string, token search.Token) error {
+	value, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item: map[string]types.AttributeValue{
+			"Platform": &types.AttributeValueMemberS{Value: dynamoDBTokensPartition},
+			"SortKey":  &types.AttributeValueMemberS{Value: key},
+			"Token":    &types.AttributeValueMemberS{Value: string(value)},
+		},
+	})
 	if err != nil {
 		return fmt.Errorf("failed to put item into DynamoDB: %w", err)
 	}
 	return nil
 }
+
+// AcquireLease takes or renews the named lease with a conditional write, which fails while another holder's
+// lease, expiring at LeaseUntil in Unix milliseconds, is live. The items have no Timestamp, so Prune keeps
+// them.
+func (d *DynamoDBStorer) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item: map[string]types.AttributeValue{
+			"Platform":   &types.AttributeValueMemberS{Value: dynamoDBLeasesPartition},
+			"SortKey":    &types.AttributeValueMemberS{Value: name},
+			"Holder":     &types.AttributeValueMemberS{Value: holder},
+			"LeaseUntil": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(ttl).UnixMilli(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(SortKey) OR Holder = :holder OR LeaseUntil <= :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":holder": &types.AttributeValueMemberS{Value: holder},
+			":now":    &types.AttributeValueMemberN{Value: strconv.FormatInt(now.UnixMilli(), 10)},
+		},
+	})
+	var held *types.ConditionalCheckFailedException
+	if errors.As(err, &held) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to put lease into DynamoDB: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseLease deletes the named lease item if holder has it.
+func (d *DynamoDBStorer) ReleaseLease(ctx context.Context, name, holder string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"Platform": &types.AttributeValueMemberS{Value: dynamoDBLeasesPartition},
+			"SortKey":  &types.AttributeValueMemberS{Value: name},
+		},
+		ConditionExpression:       aws.String("Holder = :holder"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":holder": &types.AttributeValueMemberS{Value: holder}},
+	})
+	var held *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &held) {
+		return fmt.Errorf("failed to delete lease from DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// batchWrite sends write requests in chunks of 25, the BatchWriteItem limit, retrying unprocessed items.
+func (d *DynamoDBStorer) batchWrite(ctx context.Context, requests []types.WriteRequest) error {
+	for start := 0; start < len(requests); start += 25 {
+		end := min(start+25, len(requests))
+		pending := map[string][]types.WriteRequest{d.tableName: requests[start:end]}
+
+		for attempt := 0; len(pending[d.tableName]) > 0; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+				}
+			}
+			if attempt == 5 {
+				return fmt.Errorf("failed to write %d items to DynamoDB after retries", len(pending[d.tableName]))
+			}
+
+			out, err := d.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return fmt.Errorf("failed to batch write to DynamoDB: %w", err)
+			}
+			pending = out.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// FindByContentHash queries the content hash index partition for results saved at or after since.
+func (d *DynamoDBStorer) FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error) {
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("Platform = :hash"),
+		FilterExpression:       aws.String("#ts >= :since"),
+		ExpressionAttributeNames: map[string]string{
+			"#ts": "Timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":hash":  &types.AttributeValueMemberS{Value: dynamoDBContentHashPrefix + hash},
+			":since": &types.AttributeValueMemberN{Value: strconv.FormatInt(since.Unix(), 10)},
+		},
+	})
+
+	var results []search.SearchResult
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query DynamoDB content hash index: %w", err)
+		}
+
+		for _, item := range page.Items {
+			result := search.SearchResult{ContentHash: hash}
+			if v, ok := item["ResultPlatform"].(*types.AttributeValueMemberS); ok {
+				result.Platform = v.Value
+			}
+			if v, ok := item["URL"].(*types.AttributeValueMemberS); ok {
+				result.URL = v.Value
+			}
+			if v, ok := item["Title"].(*types.AttributeValueMemberS); ok {
+				result.Title = v.Value
+			}
+			if v, ok := item["ResultKeyword"].(*types.AttributeValueMemberS); ok {
+				result.Keyword = v.Value
+			}
+			if v, ok := item["Timestamp"].(*types.AttributeValueMemberN); ok {
+				result.Timestamp, _ = strconv.ParseInt(v.Value, 10, 64)
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// Query returns the results matching q, querying the keyword index for each keyword, or each platform's
+// partition, and scanning the table when q selects neither.
+func (d *DynamoDBStorer) Query(ctx context.Context, q Query) ([]search.SearchResult, error) {
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+	if !q.Since.IsZero() {
+		from = q.Since.Unix()
+	}
+	if !q.Until.IsZero() {
+		to = q.Until.Unix() - 1
+	}
+	values := map[string]types.AttributeValue{
+		":from": &types.AttributeValueMemberN{Value: strconv.FormatInt(from, 10)},
+		":to":   &types.AttributeValueMemberN{Value: strconv.FormatInt(to, 10)},
+	}
+	names := map[string]string{"#ts": "Timestamp"}
+
+	// Only result items have a Score attribute; last search times, content hash index items, and the other
+	// items sharing the table don't
+	var inputs []*dynamodb.QueryInput
+	var scan *dynamodb.ScanInput
+	switch {
+	case len(q.Keywords) > 0:
+		// The keyword index only holds the keyword a result was saved with, so results that matched a
+		// keyword later, recorded in their Keywords set, are found by a scan
+		scanValues := values
+		var matches []string
+		for i, keyword := range q.Keywords {
+			inputs = append(inputs, &dynamodb.QueryInput{
+				TableName:                 aws.String(d.tableName),
+				IndexName:                 aws.String(dynamoDBKeywordIndex),
+				KeyConditionExpression:    aws.String("Keyword = :keyword AND #ts BETWEEN :from AND :to"),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: withAttribute(values, ":keyword", keyword),
+			})
+			name := fmt.Sprintf(":keyword%d", i)
+			scanValues = withAttribute(scanValues, name, keyword)
+			matches = append(matches, "contains(Keywords, "+name+")")
+		}
+		scan = &dynamodb.ScanInput{
+			TableName:                 aws.String(d.tableName),
+			FilterExpression:          aws.String("attribute_exists(Score) AND #ts BETWEEN :from AND :to AND (" + strings.Join(matches, " OR ") + ")"),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: scanValues,
+		}
+	case len(q.Platforms) > 0:
+		for _, platform := range q.Platforms {
+			inputs = append(inputs, &dynamodb.QueryInput{
+				TableName:                 aws.String(d.tableName),
+				KeyConditionExpression:    aws.String("Platform = :platform"),
+				FilterExpression:          aws.String("attribute_exists(Score) AND #ts BETWEEN :from AND :to"),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: withAttribute(values, ":platform", platform),
+			})
+		}
+	default:
+		return d.scanResults(ctx, q, &dynamodb.ScanInput{
+			TableName:                 aws.String(d.tableName),
+			FilterExpression:          aws.String("attribute_exists(Score) AND #ts BETWEEN :from AND :to"),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		})
+	}
+
+	seen := make(map[[2]string]bool)
+	var results []search.SearchResult
+	for _, input := range inputs {
+		paginator := dynamodb.NewQueryPaginator(d.client, input)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query DynamoDB: %w", err)
+			}
+			for _, item := range page.Items {
+				result := dynamoDBResult(item)
+				key := [2]string{result.Platform, result.URL}
+				if !seen[key] && q.matches(result) {
+					seen[key] = true
+					results = append(results, result)
+				}
+			}
+		}
+	}
+	if scan != nil {
+		paginator := dynamodb.NewScanPaginator(d.client, scan)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan DynamoDB table: %w", err)
+			}
+			for _, item := range page.Items {
+				result := dynamoDBResult(item)
+				key := [2]string{result.Platform, result.URL}
+				if !seen[key] && q.matches(result) {
+					seen[key] = true
+					results = append(results, result)
+				}
+			}
+		}
+	}
+	return q.page(results), nil
+}
+
+// scanResults scans the table for the results matching q.
+func (d *DynamoDBStorer) scanResults(ctx context.Context, q Query, input *dynamodb.ScanInput) ([]search.SearchResult, error) {
+	var results []search.SearchResult
+	paginator := dynamodb.NewScanPaginator(d.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan DynamoDB table: %w", err)
+		}
+		for _, item := range page.Items {
+			if result := dynamoDBResult(item); q.matches(result) {
+				results = append(results, result)
+			}
+		}
+	}
+	return q.page(results), nil
+}
+
+// withAttribute returns a copy of values with one more attribute value.
+func withAttribute(values map[string]types.AttributeValue, name, value string) map[string]types.AttributeValue {
+	copied := make(map[string]types.AttributeValue, len(values)+1)
+	for k, v := range values {
+		copied[k] = v
+	}
+	copied[name] = &types.AttributeValueMemberS{Value: value}
+	return copied
+}
+
+// dynamoDBResult converts a result item back into a search result.
+func dynamoDBResult(item map[string]types.AttributeValue) search.SearchResult {
+	str := func(name string) string {
+		if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+	num := func(name string) int64 {
+		if v, ok := item[name].(*types.AttributeValueMemberN); ok {
+			n, _ := strconv.ParseInt(v.Value, 10, 64)
+			return n
+		}
+		return 0
+	}
+
+	result := search.SearchResult{
+		Platform:    str("Platform"),
+		URL:         str("SortKey"),
+		Keyword:     str("Keyword"),
+		Title:       str("Title"),
+		Timestamp:   num("Timestamp"),
+		Content:     str("Content"),
+		Author:      str("Author"),
+		Score:       num("Score"),
+		Comments:    num("Comments"),
+		Reposts:     num("Reposts"),
+		Views:       num("Views"),
+		Priority:    search.Priority(str("Priority")),
+		ContentHash: str("ContentHash"),
+		DeletedAt:   num("DeletedAt"),
+	}
+	if v, ok := item["Keywords"].(*types.AttributeValueMemberSS); ok {
+		// Sets are unordered, so list them after the keyword the result was saved with
+		others := slices.DeleteFunc(slices.Clone(v.Value), func(keyword string) bool { return keyword == result.Keyword })
+		sort.Strings(others)
+		result.Keywords = append([]string{result.Keyword}, others...)
+	}
+	if v, ok := item["Tags"].(*types.AttributeValueMemberL); ok {
+		for _, tag := range v.Value {
+			if s, ok := tag.(*types.AttributeValueMemberS); ok {
+				result.Tags = append(result.Tags, s.Value)
+			}
+		}
+	}
+	if v, ok := item["Metadata"].(*types.AttributeValueMemberM); ok {
+		result.Metadata = make(map[string]string, len(v.Value))
+		for key, value := range v.Value {
+			if s, ok := value.(*types.AttributeValueMemberS); ok {
+				result.Metadata[key] = s.Value
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jaxxstorm/grass/search"
+)
+
+func TestResultItemKeyword(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyword string
+		want    bool
+	}{
+		{name: "searched result", keyword: "tailscale", want: true},
+		{name: "pushed result without keyword", keyword: "", want: false},
+	}
+
+	d := &DynamoDBStorer{tableName: "grass"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := d.resultItem(search.SearchResult{Platform: "webhook", URL: "https://example.com/1", Keyword: tt.keyword})
+			keyword, ok := item["Keyword"]
+			if ok != tt.want {
+				t.Fatalf("Keyword attribute present = %v, want %v", ok, tt.want)
+			}
+			if ok && keyword.(*types.AttributeValueMemberS).Value != tt.keyword {
+				t.Errorf("Keyword = %q, want %q", keyword.(*types.AttributeValueMemberS).Value, tt.keyword)
+			}
+			if _, ok := item["Score"]; !ok {
+				t.Error("result item has no Score attribute, which queries use to tell result items apart")
+			}
+			if result := dynamoDBResult(item); result.Keyword != tt.keyword {
+				t.Errorf("round-tripped Keyword = %q, want %q", result.Keyword, tt.keyword)
+			}
+		})
+	}
+}
+
+func TestDynamoDBResultRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		result search.SearchResult
+	}{
+		{
+			name:   "minimal result",
+			result: search.SearchResult{Platform: "HackerNews", URL: "https://example.com/1", Keyword: "tailscale", Title: "Tailscale"},
+		},
+		{
+			name: "edited and deleted result",
+			result: search.SearchResult{
+				Platform: "Reddit", URL: "https://example.com/2", Keyword: "tailscale", Title: "Tailscale", Timestamp: 1700000000,
+				Content: "updated", Author: "someone", Score: 10, Comments: 2, Reposts: 1, Views: 100,
+				Tags: []string{"vpn"}, Metadata: map[string]string{"state": "acknowledged"},
+				ContentHash: "abc", DeletedAt: 1700000100,
+			},
+		},
+	}
+
+	d := &DynamoDBStorer{tableName: "grass"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dynamoDBResult(d.resultItem(tt.result)); !reflect.DeepEqual(got, tt.result) {
+				t.Errorf("round-tripped result = %+v, want %+v", got, tt.result)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+// storage/elasticsearch.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jaxxstorm/grass/internal/elastic"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// elasticsearchMetaMapping is the mapping for the small index holding last search times.
+const elasticsearchMetaMapping = `{
+  "mappings": {
+    "properties": {
+      "platform":         { "type": "keyword" },
+      "last_search_time": { "type": "long" }
+    }
+  }
+}`
+
+// ElasticsearchStorer stores results in an Elasticsearch or OpenSearch index, keyed by a document ID derived
+// from platform and URL, and last search times in a separate <index>-meta index. It uses the same document
+// shape as the Elasticsearch notifier, so both can share an index.
+type ElasticsearchStorer struct {
+	client    *elastic.Client
+	index     string
+	metaIndex string
+}
+
+type elasticsearchResult struct {
+	Platform    string            `json:"platform"`
+	Keyword     string            `json:"keyword"`
+	Title       string            `json:"title"`
+	URL         string            `json:"url"`
+	Content     string            `json:"content,omitempty"`
+	Author      string            `json:"author,omitempty"`
+	Score       int64             `json:"score"`
+	Comments    int64             `json:"comments"`
+	Reposts     int64             `json:"reposts"`
+	Views       int64             `json:"views"`
+	Tags        []string          `json:"tags,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	ContentHash string            `json:"content_hash,omitempty"`
+	Timestamp   int64             `json:"timestamp"`
+	IndexedAt   int64             `json:"indexed_at"`
+}
+
+type elasticsearchMeta struct {
+	Platform       string `json:"platform"`
+	LastSearchTime int64  `json:"last_search_time"`
+}
+
+// NewElasticsearchStorer configures the storer from the environment and creates its indices if needed,
+// sending requests with httpClient.
+func NewElasticsearchStorer(ctx context.Context, index string, httpClient *http.Client) (*ElasticsearchStorer, error) {
+	client, err := elastic.NewClientFromEnv(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &ElasticsearchStorer{client: client, index: index, metaIndex: index + "-meta"}
+	if _, err := client.EnsureIndex(ctx, e.index, elastic.ResultMapping); err != nil {
+		return nil, fmt.Errorf("failed to prepare Elasticsearch index %s: %w", e.index, err)
+	}
+	if _, err := client.EnsureIndex(ctx, e.metaIndex, elasticsearchMetaMapping); err != nil {
+		return nil, fmt.Errorf("failed to prepare Elasticsearch index %s: %w", e.metaIndex, err)
+	}
+
+	return e, nil
+}
+
+// Exists checks if a specific item already exists in Elasticsearch by looking up its document ID.
+func (e *ElasticsearchStorer) Exists(ctx context.Context, platform, resultURL string) (bool, error) {
+	resp, err := e.client.Do(ctx, "HEAD", elastic.DocumentPath(e.index, elastic.DocumentID(platform, resultURL)), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up document in Elasticsearch: %w", err)
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("Elasticsearch lookup failed with status code: %d", resp.StatusCode)
+	}
+}
+
+// Save stores a new search result in Elasticsearch. Existing documents are left untouched.
+func (e *ElasticsearchStorer) Save(ctx context.Context, result search.SearchResult) error {
+	body, err := json.Marshal(newElasticsearchResult(result))
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	path := fmt.Sprintf("/%s/_create/%s", url.PathEscape(e.index), elastic.DocumentID(result.Platform, result.URL))
+	resp, err := e.client.Do(ctx, "PUT", path, body)
+	if err != nil {
+		return fmt.Errorf("failed to index document in Elasticsearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusConflict:
+		return nil
+	default:
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Elasticsearch index request failed with status code %d: %s", resp.StatusCode, respBody)
+	}
+}
+
+// GetLastSearchTime retrieves the last search time for a given platform from the metadata index.
+func (e *ElasticsearchStorer) GetLastSearchTime(ctx context.Context, platform string) (int64, error) {
+	resp, err := e.client.Do(ctx, "GET", elastic.DocumentPath(e.metaIndex, platform), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last search time from Elasticsearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Elasticsearch get request failed with status code: %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Source elasticsearchMeta `json:"_source"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return 0, fmt.Errorf("failed to parse LastSearchTime: %w", err)
+	}
+	return doc.Source.LastSearchTime, nil
+}
+
+// SetLastSearchTime updates the last search time for a given platform in the metadata index.
+func (e *ElasticsearchStorer) SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error {
+	body, err := json.Marshal(elasticsearchMeta{Platform: platform, LastSearchTime: epochTime})
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	resp, err := e.client.Do(ctx, "PUT", elastic.DocumentPath(e.metaIndex, platform), body)
+	if err != nil {
+		return fmt.Errorf("failed to set last search time in Elasticsearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("Elasticsearch index request failed with status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Prune deletes results older than the given time using a delete-by-query request.
+func (e *ElasticsearchStorer) Prune(ctx context.Context, olderThan time.Time) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{
+			"range": map[string]interface{}{
+				"timestamp": map[string]interface{}{"lt": olderThan.Unix()},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	resp, err := e.client.Do(ctx, "POST", fmt.Sprintf("/%s/_delete_by_query?conflicts=proceed", url.PathEscape(e.index)), body)
+	if err != nil {
+		return fmt.Errorf("failed to prune Elasticsearch index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Elasticsearch delete-by-query failed with status code %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// SaveBatch stores several results with a single _bulk request. Results that already exist are left untouched.
+func (e *ElasticsearchStorer) SaveBatch(ctx context.Context, results []search.SearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, result := range results {
+		action := map[string]interface{}{
+			"create": map[string]string{"_id": elastic.DocumentID(result.Platform, result.URL)},
+		}
+		if err := encoder.Encode(action); err != nil {
+			return fmt.Errorf("failed to encode bulk action: %w", err)
+		}
+		if err := encoder.Encode(newElasticsearchResult(result)); err != nil {
+			return fmt.Errorf("failed to encode document: %w", err)
+		}
+	}
+
+	resp, err := e.client.Do(ctx, "POST", fmt.Sprintf("/%s/_bulk", url.PathEscape(e.index)), body.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to bulk index documents in Elasticsearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Elasticsearch bulk request failed with status code %d: %s", resp.StatusCode, respBody)
+	}
+
+	var bulk struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&bulk); err != nil {
+		return fmt.Errorf("failed to parse bulk response: %w", err)
+	}
+	if !bulk.Errors {
+		return nil
+	}
+
+	failed := 0
+	for _, item := range bulk.Items {
+		for _, op := range item {
+			if op.Status >= 300 && op.Status != http.StatusConflict {
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to index %d of %d documents in Elasticsearch", failed, len(results))
+	}
+	return nil
+}
+
+// newElasticsearchResult converts a search result into its document form.
+func newElasticsearchResult(result search.SearchResult) elasticsearchResult {
+	return elasticsearchResult{
+		Platform:    result.Platform,
+		Keyword:     result.Keyword,
+		Title:       result.Title,
+		URL:         result.URL,
+		Content:     result.Content,
+		Author:      result.Author,
+		Score:       result.Score,
+		Comments:    result.Comments,
+		Reposts:     result.Reposts,
+		Views:       result.Views,
+		Tags:        result.Tags,
+		Metadata:    result.Metadata,
+		Priority:    string(result.Priority),
+		ContentHash: result.ContentHash,
+		Timestamp:   result.Timestamp,
+		IndexedAt:   time.Now().Unix(),
+	}
+}
+
+// FindByContentHash searches for results with a matching content hash saved at or after since. Documents
+// become searchable after the index refreshes, typically within a second of being saved.
+func (e *ElasticsearchStorer) FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"size": 100,
+		"sort": []interface{}{map[string]string{"timestamp": "asc"}},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]string{"content_hash": hash}},
+					map[string]interface{}{"range": map[string]interface{}{"timestamp": map[string]int64{"gte": since.Unix()}}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	resp, err := e.client.Do(ctx, "POST", fmt.Sprintf("/%s/_search", url.PathEscape(e.index)), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search Elasticsearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Elasticsearch search failed with status code %d: %s", resp.StatusCode, respBody)
+	}
+
+	var found struct {
+		Hits struct {
+			Hits []struct {
+				Source elasticsearchResult `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		return nil, fmt.Errorf("failed to parse search response: %w", err)
+	}
+
+	var results []search.SearchResult
+	for _, hit := range found.Hits.Hits {
+		doc := hit.Source
+		results = append(results, search.SearchResult{
+			Platform:    doc.Platform,
+			Keyword:     doc.Keyword,
+			Title:       doc.Title,
+			URL:         doc.URL,
+			Timestamp:   doc.Timestamp,
+			Content:     doc.Content,
+			Author:      doc.Author,
+			Score:       doc.Score,
+			Comments:    doc.Comments,
+			Reposts:     doc.Reposts,
+			Views:       doc.Views,
+			Tags:        doc.Tags,
+			Metadata:    doc.Metadata,
+			Priority:    search.Priority(doc.Priority),
+			ContentHash: doc.ContentHash,
+		})
+	}
+	return results, nil
+}
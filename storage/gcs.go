@@ -0,0 +1,161 @@
+// storage/gcs.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// GCSStorer persists results and last search times as one JSON object per platform in a Google Cloud
+// Storage bucket. It mirrors S3Storer, using object generations for conditional writes.
+type GCSStorer struct {
+	*objectStorer
+}
+
+type gcsBackend struct {
+	client *http.Client
+	bucket string
+}
+
+// NewGCSStorer creates a storer writing objects under prefix in the bucket named by GCS_BUCKET.
+// Credentials come from Application Default Credentials, and requests are sent through httpClient's
+// transport unless it is nil.
+func NewGCSStorer(ctx context.Context, prefix string, httpClient *http.Client) (*GCSStorer, error) {
+	bucket := os.Getenv("GCS_BUCKET")
+	if bucket == "" {
+		return nil, errors.New("missing GCS configuration: GCS_BUCKET is required")
+	}
+
+	if httpClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	}
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/devstorage.read_write")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Application Default Credentials: %w", err)
+	}
+
+	backend := &gcsBackend{client: client, bucket: bucket}
+	return &GCSStorer{objectStorer: newObjectStorer(backend, prefix)}, nil
+}
+
+func (g *gcsBackend) get(ctx context.Context, key string) ([]byte, string, error) {
+	objectURL := fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o/%s?alt=media",
+		url.PathEscape(g.bucket), url.PathEscape(key))
+	req, err := http.NewRequestWithContext(ctx, "GET", objectURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get object %s from GCS: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", errObjectNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to get object %s from GCS with status code: %d", key, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read object %s from GCS: %w", key, err)
+	}
+
+	return data, resp.Header.Get("X-Goog-Generation"), nil
+}
+
+func (g *gcsBackend) put(ctx context.Context, key string, data []byte, generation string) error {
+	// A generation of 0 means the object must not exist yet.
+	if generation == "" {
+		generation = "0"
+	}
+
+	query := url.Values{}
+	query.Set("uploadType", "media")
+	query.Set("name", key)
+	query.Set("ifGenerationMatch", generation)
+	uploadURL := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?%s", url.PathEscape(g.bucket), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to put object %s into GCS: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return errPreconditionFailed
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to put object %s into GCS with status code %d: %s", key, resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+func (g *gcsBackend) list(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	pageToken := ""
+	for {
+		query := url.Values{}
+		query.Set("prefix", prefix)
+		query.Set("fields", "items(name),nextPageToken")
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		listURL := fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o?%s", url.PathEscape(g.bucket), query.Encode())
+		req, err := http.NewRequestWithContext(ctx, "GET", listURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := g.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects in GCS: %w", err)
+		}
+
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to list objects in GCS with status code: %d", resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse GCS object list: %w", err)
+		}
+
+		for _, item := range page.Items {
+			keys = append(keys, item.Name)
+		}
+		if page.NextPageToken == "" {
+			return keys, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
@@ -0,0 +1,100 @@
+// storage/keywords.go
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// KeywordRecorder is implemented by storers that track every keyword a stored result matched, so a post
+// found by several keywords is stored once rather than once per keyword.
+type KeywordRecorder interface {
+	// AddKeywords records the Keywords of each result against the stored result with the same platform and
+	// URL, in addition to the keywords it was saved with. Keywords already recorded, and results that
+	// aren't stored, are ignored.
+	AddKeywords(ctx context.Context, results []search.SearchResult) error
+}
+
+// AsKeywordRecorder returns the storer's keyword recorder if its backend has one. A MultiStorer records
+// keywords in its primary.
+func AsKeywordRecorder(s Storer) (KeywordRecorder, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	recorder, ok := s.(KeywordRecorder)
+	return recorder, ok
+}
+
+// resultKeywords returns the keywords a result is saved with: its Keywords, or its Keyword if Keywords
+// is empty.
+func resultKeywords(keyword string, keywords []string) []string {
+	if len(keywords) > 0 {
+		return keywords
+	}
+	if keyword == "" {
+		return nil
+	}
+	return []string{keyword}
+}
+
+// addKeywords returns the keywords a stored result is saved with followed by those of keywords it lacks,
+// and whether there were any.
+func addKeywords(stored search.SearchResult, keywords []string) ([]string, bool) {
+	merged := slices.Clone(resultKeywords(stored.Keyword, stored.Keywords))
+	added := false
+	for _, keyword := range keywords {
+		if keyword != "" && !slices.Contains(merged, keyword) {
+			merged = append(merged, keyword)
+			added = true
+		}
+	}
+	return merged, added
+}
+
+// ManagedKeyword is a keyword kept in storage and managed with the keywords command, searched alongside
+// the keywords given on the command line or in the config file.
+type ManagedKeyword struct {
+	Keyword string `json:"keyword"`
+	// Paused keywords aren't searched. A keyword from the command line or config file can be paused too.
+	Paused bool `json:"paused"`
+	// PausedUntil is the Unix time a paused keyword is searched again from, or zero if it stays paused until
+	// it is resumed.
+	PausedUntil int64 `json:"paused_until,omitempty"`
+	AddedAt     int64 `json:"added_at"`
+}
+
+// PausedAt reports whether the keyword is paused at t, which it no longer is once PausedUntil has passed.
+func (k ManagedKeyword) PausedAt(t time.Time) bool {
+	return k.Paused && (k.PausedUntil == 0 || t.Unix() < k.PausedUntil)
+}
+
+// KeywordStore is implemented by storers that can keep managed keywords, so keywords can be changed
+// without editing flags or redeploying.
+type KeywordStore interface {
+	// ManagedKeywords returns every managed keyword, sorted.
+	ManagedKeywords(ctx context.Context) ([]ManagedKeyword, error)
+	// PutKeyword adds a managed keyword, or replaces the one with the same Keyword.
+	PutKeyword(ctx context.Context, keyword ManagedKeyword) error
+	// DeleteKeyword removes a managed keyword. Removing a keyword that isn't stored is not an error.
+	DeleteKeyword(ctx context.Context, keyword string) error
+}
+
+// AsKeywordStore returns the storer's keyword store if its backend has one. A MultiStorer keeps keywords
+// in its primary.
+func AsKeywordStore(s Storer) (KeywordStore, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	store, ok := s.(KeywordStore)
+	return store, ok
+}
+
+// sortKeywords sorts managed keywords by keyword.
+func sortKeywords(keywords []ManagedKeyword) []ManagedKeyword {
+	sort.Slice(keywords, func(i, j int) bool { return keywords[i].Keyword < keywords[j].Keyword })
+	return keywords
+}
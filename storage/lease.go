@@ -0,0 +1,27 @@
+// storage/lease.go
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Locker is implemented by storers that can hold leases, so only one of several grass processes sharing
+// the storage does something at a time.
+type Locker interface {
+	// AcquireLease takes the named lease for holder, or renews it if holder already has it, until ttl from
+	// now. It reports false if another holder's lease hasn't expired.
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// ReleaseLease gives up the named lease if holder still has it, so another holder can take it at once.
+	ReleaseLease(ctx context.Context, name, holder string) error
+}
+
+// AsLocker returns the storer's locker if its backend can hold leases. A MultiStorer's leases are held in
+// its primary.
+func AsLocker(s Storer) (Locker, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	locker, ok := s.(Locker)
+	return locker, ok
+}
@@ -0,0 +1,40 @@
+// storage/metadata.go
+package storage
+
+import "encoding/json"
+
+// encodeTags and encodeMetadata encode a result's tags and metadata as JSON for backends that store them
+// as text, returning "" when there are none.
+func encodeTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(tags)
+	return string(data)
+}
+
+func encodeMetadata(metadata map[string]string) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(metadata)
+	return string(data)
+}
+
+// decodeTags and decodeMetadata reverse encodeTags and encodeMetadata. Text that isn't valid JSON decodes
+// as empty.
+func decodeTags(text string) []string {
+	var tags []string
+	if text != "" {
+		json.Unmarshal([]byte(text), &tags)
+	}
+	return tags
+}
+
+func decodeMetadata(text string) map[string]string {
+	var metadata map[string]string
+	if text != "" {
+		json.Unmarshal([]byte(text), &metadata)
+	}
+	return metadata
+}
@@ -0,0 +1,86 @@
+// storage/multi.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// MultiStorer fans writes out to several storers while reading deduplication state from a designated
+// primary. Failures writing to a secondary are logged rather than returned, so an analytics store being
+// down never blocks notifications.
+type MultiStorer struct {
+	primary     Storer
+	secondaries []Storer
+}
+
+// NewMultiStorer creates a composite storer. The primary answers Exists, GetLastSearchTime, and
+// FindByContentHash.
+func NewMultiStorer(primary Storer, secondaries ...Storer) *MultiStorer {
+	return &MultiStorer{primary: primary, secondaries: secondaries}
+}
+
+// Exists checks the primary storer.
+func (m *MultiStorer) Exists(ctx context.Context, platform, url string) (bool, error) {
+	return m.primary.Exists(ctx, platform, url)
+}
+
+// Save stores a result in the primary and then every secondary.
+func (m *MultiStorer) Save(ctx context.Context, result search.SearchResult) error {
+	return m.fanOut("save", func(s Storer) error { return s.Save(ctx, result) })
+}
+
+// SaveBatch stores several results in the primary and then every secondary.
+func (m *MultiStorer) SaveBatch(ctx context.Context, results []search.SearchResult) error {
+	return m.fanOut("save batch", func(s Storer) error { return s.SaveBatch(ctx, results) })
+}
+
+// GetLastSearchTime reads the primary storer.
+func (m *MultiStorer) GetLastSearchTime(ctx context.Context, platform string) (int64, error) {
+	return m.primary.GetLastSearchTime(ctx, platform)
+}
+
+// SetLastSearchTime records the last search time in every storer.
+func (m *MultiStorer) SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error {
+	return m.fanOut("set last search time", func(s Storer) error { return s.SetLastSearchTime(ctx, platform, epochTime) })
+}
+
+// Prune prunes every storer.
+func (m *MultiStorer) Prune(ctx context.Context, olderThan time.Time) error {
+	return m.fanOut("prune", func(s Storer) error { return s.Prune(ctx, olderThan) })
+}
+
+// FindByContentHash reads the primary storer.
+func (m *MultiStorer) FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error) {
+	return m.primary.FindByContentHash(ctx, hash, since)
+}
+
+// Close closes every storer that holds resources.
+func (m *MultiStorer) Close() error {
+	var errs []error
+	for _, s := range append([]Storer{m.primary}, m.secondaries...) {
+		if closer, ok := s.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fanOut applies op to the primary, returning its error, then to each secondary, logging theirs.
+func (m *MultiStorer) fanOut(name string, op func(Storer) error) error {
+	if err := op(m.primary); err != nil {
+		return err
+	}
+
+	for i, secondary := range m.secondaries {
+		if err := op(secondary); err != nil {
+			log.Error("Secondary storage operation failed", "operation", name, "secondary", i+1, "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,804 @@
+// storage/ndjson.go
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// ndjsonRecord is a single line in the NDJSON file. Results and last search times share the file and are
+// distinguished by Type; later last search time, outbox, token, and result keyword records override earlier
+// ones. The first line is a header whose generation changes whenever the file is compacted, telling other
+// processes to re-read it.
+type ndjsonRecord struct {
+	Type           string               `json:"type"`
+	Generation     int64                `json:"generation,omitempty"`
+	Result         *search.SearchResult `json:"result,omitempty"`
+	Platform       string               `json:"platform,omitempty"`
+	LastSearchTime int64                `json:"last_search_time,omitempty"`
+	Outbox         *OutboxEntry         `json:"outbox,omitempty"`
+	OutboxID       string               `json:"outbox_id,omitempty"`
+	ManagedKeyword *ManagedKeyword      `json:"managed_keyword,omitempty"`
+	Run            *Run                 `json:"run,omitempty"`
+	TokenKey       string               `json:"token_key,omitempty"`
+	Token          *search.Token        `json:"token,omitempty"`
+}
+
+const (
+	ndjsonHeaderRecord         = "header"
+	ndjsonResultRecord         = "result"
+	ndjsonLastSearchTimeRecord = "last_search_time"
+	ndjsonOutboxRecord         = "outbox"
+	ndjsonOutboxDoneRecord     = "outbox_done"
+	ndjsonKeywordRecord        = "keyword"
+	ndjsonKeywordRemovedRecord = "keyword_removed"
+	ndjsonRunRecord            = "run"
+	ndjsonTokenRecord          = "token"
+	ndjsonResultKeywordsRecord = "result_keywords"
+)
+
+// NDJSONStorer persists results to an append-only NDJSON file with an in-memory index. Every access takes a
+// file lock and catches up on lines appended by other processes, so several grass processes can share a file.
+type NDJSONStorer struct {
+	mu         sync.Mutex
+	path       string
+	file       *os.File
+	offset     int64
+	generation int64
+
+	results        map[string]bool
+	lastSearchTime map[string]int64
+	// matched holds the keywords each indexed result is saved with, including those recorded since.
+	matched map[string][]string
+	// contentHashes indexes hashed results by content hash for duplicate detection.
+	contentHashes map[string][]search.SearchResult
+	// outbox holds the latest state of every pending outbox entry.
+	outbox map[string]OutboxEntry
+	// keywords holds the latest state of every managed keyword.
+	keywords map[string]ManagedKeyword
+	// runs holds the run history in the order it was recorded.
+	runs []Run
+	// tokens holds the latest token stored under each key.
+	tokens map[string]search.Token
+}
+
+// NewNDJSONStorer opens (or creates) <path>.ndjson and builds the index from its contents.
+func NewNDJSONStorer(path string) (*NDJSONStorer, error) {
+	path = fmt.Sprintf("%s.ndjson", path)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open NDJSON file: %w", err)
+	}
+
+	n := &NDJSONStorer{
+		path:           path,
+		file:           file,
+		results:        make(map[string]bool),
+		matched:        make(map[string][]string),
+		lastSearchTime: make(map[string]int64),
+		contentHashes:  make(map[string][]search.SearchResult),
+		outbox:         make(map[string]OutboxEntry),
+		keywords:       make(map[string]ManagedKeyword),
+		tokens:         make(map[string]search.Token),
+	}
+
+	err = n.withLock(true, func() error {
+		if n.offset > 0 {
+			return nil
+		}
+		return n.append(ndjsonRecord{Type: ndjsonHeaderRecord, Generation: 1})
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func ndjsonKey(platform, url string) string {
+	return platform + "\x00" + url
+}
+
+// withLock runs fn while holding the file lock, after reading any records appended since the last access.
+// If another process compacted the file meanwhile, the compacted file is opened and locked instead.
+func (n *NDJSONStorer) withLock(exclusive bool, fn func() error) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for {
+		if err := lockFile(n.file, exclusive); err != nil {
+			return fmt.Errorf("failed to lock NDJSON file: %w", err)
+		}
+		replaced, err := n.replaced()
+		if err != nil {
+			unlockFile(n.file)
+			return err
+		}
+		if !replaced {
+			break
+		}
+		unlockFile(n.file)
+		if err := n.reopen(); err != nil {
+			return err
+		}
+	}
+	locked := n.file
+	defer func() {
+		unlockFile(locked)
+		if n.file != locked {
+			// Prune renamed a compacted file over this one
+			locked.Close()
+		}
+	}()
+
+	if err := n.catchUp(); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// replaced reports whether the file at the path is no longer the open one, because Prune renamed a
+// compacted file over it.
+func (n *NDJSONStorer) replaced() (bool, error) {
+	opened, err := n.file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat NDJSON file: %w", err)
+	}
+	current, err := os.Stat(n.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat NDJSON file: %w", err)
+	}
+	return !os.SameFile(opened, current), nil
+}
+
+// reopen opens the file now at the path in place of the open one, to be indexed from the start.
+func (n *NDJSONStorer) reopen() error {
+	file, err := os.OpenFile(n.path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open NDJSON file: %w", err)
+	}
+	n.file.Close()
+	n.file = file
+	n.generation = 0
+	return nil
+}
+
+// catchUp indexes records written after the current offset, starting over if the file was compacted.
+func (n *NDJSONStorer) catchUp() error {
+	if _, err := n.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek NDJSON file: %w", err)
+	}
+	header, err := bufio.NewReader(n.file).ReadBytes('\n')
+	if err == nil {
+		var record ndjsonRecord
+		if err := json.Unmarshal(header, &record); err == nil && record.Type == ndjsonHeaderRecord && record.Generation != n.generation {
+			n.generation = record.Generation
+			n.offset = 0
+			n.results = make(map[string]bool)
+			n.matched = make(map[string][]string)
+			n.lastSearchTime = make(map[string]int64)
+			n.contentHashes = make(map[string][]search.SearchResult)
+			n.outbox = make(map[string]OutboxEntry)
+			n.keywords = make(map[string]ManagedKeyword)
+			n.runs = nil
+			n.tokens = make(map[string]search.Token)
+		}
+	}
+
+	if _, err := n.file.Seek(n.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek NDJSON file: %w", err)
+	}
+
+	reader := bufio.NewReader(n.file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// Ignore a trailing partial line left by an interrupted write; the next append ends it.
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read NDJSON file: %w", err)
+		}
+
+		var record ndjsonRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			// A torn or hand-edited line mustn't stop the rest of the file being read
+			log.Warn("Skipping unreadable NDJSON record", "offset", n.offset, "error", err)
+		} else {
+			n.index(record)
+		}
+		n.offset += int64(len(line))
+	}
+}
+
+func (n *NDJSONStorer) index(record ndjsonRecord) {
+	switch record.Type {
+	case ndjsonResultRecord:
+		if record.Result != nil {
+			key := ndjsonKey(record.Result.Platform, record.Result.URL)
+			n.results[key] = true
+			n.matched[key] = resultKeywords(record.Result.Keyword, record.Result.Keywords)
+			if hash := record.Result.ContentHash; hash != "" {
+				n.contentHashes[hash] = append(n.contentHashes[hash], *record.Result)
+			}
+		}
+	case ndjsonResultKeywordsRecord:
+		if record.Result != nil {
+			n.matched[ndjsonKey(record.Result.Platform, record.Result.URL)] = record.Result.Keywords
+		}
+	case ndjsonLastSearchTimeRecord:
+		n.lastSearchTime[record.Platform] = record.LastSearchTime
+	case ndjsonOutboxRecord:
+		if record.Outbox != nil {
+			n.outbox[record.Outbox.ID] = *record.Outbox
+		}
+	case ndjsonOutboxDoneRecord:
+		delete(n.outbox, record.OutboxID)
+	case ndjsonKeywordRecord:
+		if record.ManagedKeyword != nil {
+			n.keywords[record.ManagedKeyword.Keyword] = *record.ManagedKeyword
+		}
+	case ndjsonKeywordRemovedRecord:
+		if record.ManagedKeyword != nil {
+			delete(n.keywords, record.ManagedKeyword.Keyword)
+		}
+	case ndjsonRunRecord:
+		if record.Run != nil {
+			n.runs = append(n.runs, *record.Run)
+		}
+	case ndjsonTokenRecord:
+		if record.Token != nil {
+			n.tokens[record.TokenKey] = *record.Token
+		}
+	}
+}
+
+// encodeRecords marshals records as NDJSON lines.
+func encodeRecords(records []ndjsonRecord) ([]byte, error) {
+	var buf []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal NDJSON record: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	return buf, nil
+}
+
+// append writes records to the end of the file in a single write. Callers must hold the exclusive lock.
+func (n *NDJSONStorer) append(records ...ndjsonRecord) error {
+	buf, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+
+	end, err := n.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to seek NDJSON file: %w", err)
+	}
+	if end > 0 {
+		// Writers hold the exclusive lock, so a last line without a newline was torn by one that was
+		// interrupted. End it so the records start on a line of their own, leaving it to be skipped.
+		last := make([]byte, 1)
+		if _, err := n.file.ReadAt(last, end-1); err != nil {
+			return fmt.Errorf("failed to read NDJSON file: %w", err)
+		}
+		if last[0] != '\n' {
+			log.Warn("Ending a partial NDJSON record left by an interrupted write", "offset", n.offset)
+			buf = append([]byte{'\n'}, buf...)
+		}
+	}
+	if _, err := n.file.Write(buf); err != nil {
+		return fmt.Errorf("failed to write NDJSON record: %w", err)
+	}
+	if err := n.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync NDJSON file: %w", err)
+	}
+
+	for _, record := range records {
+		n.index(record)
+	}
+	n.offset = end + int64(len(buf))
+	return nil
+}
+
+// Exists checks if a specific item already exists in the NDJSON file.
+func (n *NDJSONStorer) Exists(ctx context.Context, platform, url string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err := n.withLock(false, func() error {
+		exists = n.results[ndjsonKey(platform, url)]
+		return nil
+	})
+	return exists, err
+}
+
+// Save appends a new search result to the NDJSON file.
+func (n *NDJSONStorer) Save(ctx context.Context, result search.SearchResult) error {
+	return n.SaveBatch(ctx, []search.SearchResult{result})
+}
+
+// SaveBatch appends several results to the NDJSON file in a single write.
+func (n *NDJSONStorer) SaveBatch(ctx context.Context, results []search.SearchResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		var records []ndjsonRecord
+		seen := make(map[string]bool)
+		for i := range results {
+			key := ndjsonKey(results[i].Platform, results[i].URL)
+			if n.results[key] || seen[key] {
+				continue
+			}
+			seen[key] = true
+			records = append(records, ndjsonRecord{Type: ndjsonResultRecord, Result: &results[i]})
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		return n.append(records...)
+	})
+}
+
+// AddKeywords appends a record of the keywords each stored result is now saved with, for those that matched
+// keywords they weren't saved with.
+func (n *NDJSONStorer) AddKeywords(ctx context.Context, results []search.SearchResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		var records []ndjsonRecord
+		batched := make(map[string][]string)
+		for _, result := range results {
+			key := ndjsonKey(result.Platform, result.URL)
+			if !n.results[key] {
+				continue
+			}
+			saved, ok := batched[key]
+			if !ok {
+				saved = n.matched[key]
+			}
+			keywords, added := addKeywords(search.SearchResult{Keywords: saved}, result.Keywords)
+			if !added {
+				continue
+			}
+			batched[key] = keywords
+			records = append(records, ndjsonRecord{Type: ndjsonResultKeywordsRecord, Result: &search.SearchResult{Platform: result.Platform, URL: result.URL, Keywords: keywords}})
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		return n.append(records...)
+	})
+}
+
+// withMatched sets the keywords a result read from the file is saved with, once it matched several, and
+// returns it.
+func (n *NDJSONStorer) withMatched(result *search.SearchResult) *search.SearchResult {
+	if keywords := n.matched[ndjsonKey(result.Platform, result.URL)]; len(keywords) > 1 {
+		result.Keywords = keywords
+	}
+	return result
+}
+
+// GetLastSearchTime retrieves the last search time for a given platform.
+func (n *NDJSONStorer) GetLastSearchTime(ctx context.Context, platform string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var lastSearchTime int64
+	err := n.withLock(false, func() error {
+		lastSearchTime = n.lastSearchTime[platform]
+		return nil
+	})
+	return lastSearchTime, err
+}
+
+// SetLastSearchTime appends a last search time record for a given platform.
+func (n *NDJSONStorer) SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		return n.append(ndjsonRecord{Type: ndjsonLastSearchTimeRecord, Platform: platform, LastSearchTime: epochTime})
+	})
+}
+
+// LastSearchTimes returns the latest last search time recorded for each key.
+func (n *NDJSONStorer) LastSearchTimes(ctx context.Context) (map[string]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var times map[string]int64
+	err := n.withLock(false, func() error {
+		times = maps.Clone(n.lastSearchTime)
+		return nil
+	})
+	return times, err
+}
+
+// ManagedKeywords returns the latest state of every managed keyword, sorted.
+func (n *NDJSONStorer) ManagedKeywords(ctx context.Context) ([]ManagedKeyword, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var keywords []ManagedKeyword
+	err := n.withLock(false, func() error {
+		for _, keyword := range n.keywords {
+			keywords = append(keywords, keyword)
+		}
+		return nil
+	})
+	return sortKeywords(keywords), err
+}
+
+// PutKeyword appends a managed keyword record, replacing any earlier state of the keyword.
+func (n *NDJSONStorer) PutKeyword(ctx context.Context, keyword ManagedKeyword) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		return n.append(ndjsonRecord{Type: ndjsonKeywordRecord, ManagedKeyword: &keyword})
+	})
+}
+
+// DeleteKeyword appends a record removing a managed keyword.
+func (n *NDJSONStorer) DeleteKeyword(ctx context.Context, keyword string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		return n.append(ndjsonRecord{Type: ndjsonKeywordRemovedRecord, ManagedKeyword: &ManagedKeyword{Keyword: keyword}})
+	})
+}
+
+// RecordRun appends a run record.
+func (n *NDJSONStorer) RecordRun(ctx context.Context, run Run) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		return n.append(ndjsonRecord{Type: ndjsonRunRecord, Run: &run})
+	})
+}
+
+// Runs returns up to limit indexed runs, most recently started first.
+func (n *NDJSONStorer) Runs(ctx context.Context, limit int) ([]Run, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	err := n.withLock(false, func() error {
+		runs = slices.Clone(n.runs)
+		return nil
+	})
+	return latestRuns(runs, limit), err
+}
+
+// Token returns the latest token stored under key, or nil if there is none.
+func (n *NDJSONStorer) Token(ctx context.Context, key string) (*search.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var token *search.Token
+	err := n.withLock(false, func() error {
+		if stored, ok := n.tokens[key]; ok {
+			token = &stored
+		}
+		return nil
+	})
+	return token, err
+}
+
+// PutToken appends a token record, replacing any token stored under key before.
+func (n *NDJSONStorer) PutToken(ctx context.Context, key string, token search.Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		return n.append(ndjsonRecord{Type: ndjsonTokenRecord, TokenKey: key, Token: &token})
+	})
+}
+
+// Close closes the NDJSON file.
+func (n *NDJSONStorer) Close() error {
+	return n.file.Close()
+}
+
+// Prune compacts the file, dropping results older than the given time, delivered outbox entries, removed
+// keywords, and all but the latest last search time per platform and token per key. The compacted file is
+// written next to it, synced, and renamed over it under the exclusive lock, so a crash leaves one file or the
+// other intact. Other processes sharing it notice the rename the next time they take the lock and re-index.
+func (n *NDJSONStorer) Prune(ctx context.Context, olderThan time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		if _, err := n.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek NDJSON file: %w", err)
+		}
+
+		var kept []ndjsonRecord
+		reader := bufio.NewReader(n.file)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("failed to read NDJSON file: %w", err)
+			}
+
+			var record ndjsonRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				// catchUp has already warned about it, and it isn't worth keeping
+				continue
+			}
+			if record.Type == ndjsonResultRecord && record.Result != nil && record.Result.Timestamp >= olderThan.Unix() {
+				n.withMatched(record.Result)
+				kept = append(kept, record)
+			}
+			if record.Type == ndjsonRunRecord && record.Run != nil {
+				kept = append(kept, record)
+			}
+		}
+		for platform, lastSearchTime := range n.lastSearchTime {
+			kept = append(kept, ndjsonRecord{Type: ndjsonLastSearchTimeRecord, Platform: platform, LastSearchTime: lastSearchTime})
+		}
+		for _, entry := range n.outbox {
+			kept = append(kept, ndjsonRecord{Type: ndjsonOutboxRecord, Outbox: &entry})
+		}
+		for _, keyword := range n.keywords {
+			kept = append(kept, ndjsonRecord{Type: ndjsonKeywordRecord, ManagedKeyword: &keyword})
+		}
+		for key, token := range n.tokens {
+			kept = append(kept, ndjsonRecord{Type: ndjsonTokenRecord, TokenKey: key, Token: &token})
+		}
+
+		records := append([]ndjsonRecord{{Type: ndjsonHeaderRecord, Generation: n.generation + 1}}, kept...)
+		file, err := n.writeCompacted(records)
+		if err != nil {
+			return err
+		}
+
+		n.file = file
+		n.generation++
+		n.results = make(map[string]bool)
+		n.matched = make(map[string][]string)
+		n.lastSearchTime = make(map[string]int64)
+		n.contentHashes = make(map[string][]search.SearchResult)
+		n.outbox = make(map[string]OutboxEntry)
+		n.keywords = make(map[string]ManagedKeyword)
+		n.runs = nil
+		n.tokens = make(map[string]search.Token)
+		for _, record := range records {
+			n.index(record)
+		}
+		n.offset, err = file.Seek(0, io.SeekEnd)
+		if err != nil {
+			return fmt.Errorf("failed to seek NDJSON file: %w", err)
+		}
+		return nil
+	})
+}
+
+// writeCompacted writes records to a new file beside the open one and renames it over the path, returning
+// the new file still open.
+func (n *NDJSONStorer) writeCompacted(records []ndjsonRecord) (*os.File, error) {
+	data, err := encodeRecords(records)
+	if err != nil {
+		return nil, err
+	}
+	info, err := n.file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat NDJSON file: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(n.path), filepath.Base(n.path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compacted NDJSON file: %w", err)
+	}
+	err = file.Chmod(info.Mode().Perm())
+	if err == nil {
+		_, err = file.Write(data)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), n.path)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to write compacted NDJSON file: %w", err)
+	}
+	return file, nil
+}
+
+// FindByContentHash returns indexed results with a matching content hash saved at or after since.
+func (n *NDJSONStorer) FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []search.SearchResult
+	err := n.withLock(false, func() error {
+		for _, result := range n.contentHashes[hash] {
+			if result.Timestamp >= since.Unix() {
+				results = append(results, result)
+			}
+		}
+		return nil
+	})
+	return results, err
+}
+
+// Enqueue appends outbox records for notifications that aren't already queued.
+func (n *NDJSONStorer) Enqueue(ctx context.Context, entries []OutboxEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		var records []ndjsonRecord
+		for i := range entries {
+			if _, ok := n.outbox[entries[i].ID]; ok {
+				continue
+			}
+			records = append(records, ndjsonRecord{Type: ndjsonOutboxRecord, Outbox: &entries[i]})
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		return n.append(records...)
+	})
+}
+
+// DueOutbox returns queued notifications that are due for delivery, oldest first.
+func (n *NDJSONStorer) DueOutbox(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var entries []OutboxEntry
+	err := n.withLock(false, func() error {
+		for _, entry := range n.outbox {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dueEntries(entries, now, limit), nil
+}
+
+// UpdateOutbox appends the entry's new state after a failed delivery attempt.
+func (n *NDJSONStorer) UpdateOutbox(ctx context.Context, entry OutboxEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		if _, ok := n.outbox[entry.ID]; !ok {
+			return nil
+		}
+		return n.append(ndjsonRecord{Type: ndjsonOutboxRecord, Outbox: &entry})
+	})
+}
+
+// DeleteOutbox appends a record marking the entry as done.
+func (n *NDJSONStorer) DeleteOutbox(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return n.withLock(true, func() error {
+		if _, ok := n.outbox[id]; !ok {
+			return nil
+		}
+		return n.append(ndjsonRecord{Type: ndjsonOutboxDoneRecord, OutboxID: id})
+	})
+}
+
+// CountResults reads the file, counting results by platform and keyword.
+func (n *NDJSONStorer) CountResults(ctx context.Context, since, until time.Time) ([]ResultCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tally := newResultTally(since, until)
+	err := n.withLock(false, func() error {
+		if _, err := n.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek NDJSON file: %w", err)
+		}
+
+		reader := bufio.NewReader(n.file)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("failed to read NDJSON file: %w", err)
+			}
+
+			var record ndjsonRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				continue
+			}
+			if record.Type == ndjsonResultRecord && record.Result != nil {
+				tally.add(record.Result.Platform, record.Result.Keyword, record.Result.Timestamp)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tally.results(), nil
+}
+
+// Query reads the file for results matching q.
+func (n *NDJSONStorer) Query(ctx context.Context, q Query) ([]search.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []search.SearchResult
+	err := n.withLock(false, func() error {
+		if _, err := n.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek NDJSON file: %w", err)
+		}
+
+		reader := bufio.NewReader(n.file)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("failed to read NDJSON file: %w", err)
+			}
+
+			var record ndjsonRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				continue
+			}
+			if record.Type == ndjsonResultRecord && record.Result != nil && q.matches(*n.withMatched(record.Result)) {
+				results = append(results, *record.Result)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q.page(results), nil
+}
@@ -0,0 +1,360 @@
+// storage/object.go
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+var (
+	// errObjectNotFound is returned by object backends when a key does not exist.
+	errObjectNotFound = errors.New("object not found")
+	// errPreconditionFailed is returned by object backends when a conditional write loses a race.
+	errPreconditionFailed = errors.New("precondition failed")
+)
+
+// maxObjectWriteAttempts bounds how many times a conflicting write is retried against a fresh copy.
+const maxObjectWriteAttempts = 5
+
+// objectBackend is the minimal interface an object store needs for objectStorer. Writes are conditional:
+// an empty etag means the object must not exist yet, otherwise the stored object must still match it.
+type objectBackend interface {
+	get(ctx context.Context, key string) (data []byte, etag string, err error)
+	put(ctx context.Context, key string, data []byte, etag string) error
+	list(ctx context.Context, prefix string) ([]string, error)
+}
+
+// objectDocument is the JSON object stored per platform.
+type objectDocument struct {
+	LastSearchTime int64                          `json:"last_search_time"`
+	Results        map[string]search.SearchResult `json:"results"`
+}
+
+type cachedDocument struct {
+	doc  *objectDocument
+	etag string
+}
+
+// objectStorer implements Storer on top of an object store, keeping one JSON document per platform and
+// using ETag-conditional writes so concurrent writers never silently overwrite each other.
+type objectStorer struct {
+	backend objectBackend
+	prefix  string
+
+	mu    sync.Mutex
+	cache map[string]cachedDocument
+}
+
+func newObjectStorer(backend objectBackend, prefix string) *objectStorer {
+	return &objectStorer{backend: backend, prefix: prefix, cache: make(map[string]cachedDocument)}
+}
+
+func (o *objectStorer) key(platform string) string {
+	return fmt.Sprintf("%s/%s.json", o.prefix, url.PathEscape(platform))
+}
+
+// load fetches a platform document, using the cached copy unless refresh is set.
+func (o *objectStorer) load(ctx context.Context, platform string, refresh bool) (cachedDocument, error) {
+	if cached, ok := o.cache[platform]; ok && !refresh {
+		return cached, nil
+	}
+
+	data, etag, err := o.backend.get(ctx, o.key(platform))
+	if errors.Is(err, errObjectNotFound) {
+		cached := cachedDocument{doc: &objectDocument{Results: map[string]search.SearchResult{}}}
+		o.cache[platform] = cached
+		return cached, nil
+	} else if err != nil {
+		return cachedDocument{}, err
+	}
+
+	doc := &objectDocument{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return cachedDocument{}, fmt.Errorf("failed to parse %s: %w", o.key(platform), err)
+	}
+	if doc.Results == nil {
+		doc.Results = map[string]search.SearchResult{}
+	}
+
+	cached := cachedDocument{doc: doc, etag: etag}
+	o.cache[platform] = cached
+	return cached, nil
+}
+
+// update applies fn to the platform document and writes it back, reloading and retrying on conflicts.
+// fn returns false when it made no change, in which case nothing is written.
+func (o *objectStorer) update(ctx context.Context, platform string, fn func(doc *objectDocument) bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	refresh := false
+	for attempt := 0; attempt < maxObjectWriteAttempts; attempt++ {
+		cached, err := o.load(ctx, platform, refresh)
+		if err != nil {
+			return err
+		}
+
+		if !fn(cached.doc) {
+			return nil
+		}
+		// fn changed the cached copy, which no longer matches what is stored whether or not the write
+		// succeeds, so the next read loads it again (picking up the new ETag after a successful write).
+		delete(o.cache, platform)
+
+		data, err := json.Marshal(cached.doc)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", o.key(platform), err)
+		}
+
+		err = o.backend.put(ctx, o.key(platform), data, cached.etag)
+		if errors.Is(err, errPreconditionFailed) {
+			// Another writer got there first; start again from their version.
+			refresh = true
+			continue
+		}
+		return err
+	}
+
+	return fmt.Errorf("failed to write %s after %d conflicting attempts", o.key(platform), maxObjectWriteAttempts)
+}
+
+// Exists checks if a specific item already exists in the platform document.
+func (o *objectStorer) Exists(ctx context.Context, platform, url string) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	cached, err := o.load(ctx, platform, false)
+	if err != nil {
+		return false, err
+	}
+	_, ok := cached.doc.Results[url]
+	return ok, nil
+}
+
+// Save stores a new search result in the platform document.
+func (o *objectStorer) Save(ctx context.Context, result search.SearchResult) error {
+	return o.SaveBatch(ctx, []search.SearchResult{result})
+}
+
+// SaveBatch stores several results with one conditional write per platform document.
+func (o *objectStorer) SaveBatch(ctx context.Context, results []search.SearchResult) error {
+	platforms, byPlatform := groupByPlatform(results)
+	for _, platform := range platforms {
+		err := o.update(ctx, platform, func(doc *objectDocument) bool {
+			changed := false
+			for _, result := range byPlatform[platform] {
+				if _, ok := doc.Results[result.URL]; !ok {
+					doc.Results[result.URL] = result
+					changed = true
+				}
+			}
+			return changed
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddKeywords records further keywords matched by stored results with one conditional write per platform
+// document.
+func (o *objectStorer) AddKeywords(ctx context.Context, results []search.SearchResult) error {
+	platforms, byPlatform := groupByPlatform(results)
+	for _, platform := range platforms {
+		err := o.update(ctx, platform, func(doc *objectDocument) bool {
+			changed := false
+			for _, result := range byPlatform[platform] {
+				stored, ok := doc.Results[result.URL]
+				if !ok {
+					continue
+				}
+				if keywords, added := addKeywords(stored, result.Keywords); added {
+					stored.Keywords = keywords
+					doc.Results[result.URL] = stored
+					changed = true
+				}
+			}
+			return changed
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupByPlatform groups results by platform, returning the platforms in the order they first appear.
+func groupByPlatform(results []search.SearchResult) ([]string, map[string][]search.SearchResult) {
+	byPlatform := make(map[string][]search.SearchResult)
+	var platforms []string
+	for _, result := range results {
+		if _, ok := byPlatform[result.Platform]; !ok {
+			platforms = append(platforms, result.Platform)
+		}
+		byPlatform[result.Platform] = append(byPlatform[result.Platform], result)
+	}
+	return platforms, byPlatform
+}
+
+// GetLastSearchTime retrieves the last search time for a given platform.
+func (o *objectStorer) GetLastSearchTime(ctx context.Context, platform string) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	cached, err := o.load(ctx, platform, false)
+	if err != nil {
+		return 0, err
+	}
+	return cached.doc.LastSearchTime, nil
+}
+
+// SetLastSearchTime updates the last search time for a given platform.
+func (o *objectStorer) SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error {
+	return o.update(ctx, platform, func(doc *objectDocument) bool {
+		doc.LastSearchTime = epochTime
+		return true
+	})
+}
+
+// LastSearchTimes returns the last search time of every document under the prefix that has one.
+func (o *objectStorer) LastSearchTimes(ctx context.Context) (map[string]int64, error) {
+	keys, err := o.backend.list(ctx, o.prefix+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	times := make(map[string]int64)
+	for _, platform := range o.platforms(keys) {
+		cached, err := o.load(ctx, platform, false)
+		if err != nil {
+			return nil, err
+		}
+		if cached.doc.LastSearchTime != 0 {
+			times[platform] = cached.doc.LastSearchTime
+		}
+	}
+	return times, nil
+}
+
+// Prune deletes results older than the given time from every platform document under the prefix.
+func (o *objectStorer) Prune(ctx context.Context, olderThan time.Time) error {
+	keys, err := o.backend.list(ctx, o.prefix+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, platform := range o.platforms(keys) {
+		err = o.update(ctx, platform, func(doc *objectDocument) bool {
+			changed := false
+			for resultURL, result := range doc.Results {
+				if result.Timestamp < olderThan.Unix() {
+					delete(doc.Results, resultURL)
+					changed = true
+				}
+			}
+			return changed
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindByContentHash returns results with a matching content hash saved at or after since, searching every
+// platform document under the prefix.
+func (o *objectStorer) FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error) {
+	keys, err := o.backend.list(ctx, o.prefix+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var results []search.SearchResult
+	for _, platform := range o.platforms(keys) {
+		cached, err := o.load(ctx, platform, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range cached.doc.Results {
+			if result.ContentHash == hash && result.Timestamp >= since.Unix() {
+				results = append(results, result)
+			}
+		}
+	}
+	return results, nil
+}
+
+// Query searches every platform document under the prefix for results matching q.
+func (o *objectStorer) Query(ctx context.Context, q Query) ([]search.SearchResult, error) {
+	keys, err := o.backend.list(ctx, o.prefix+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var results []search.SearchResult
+	for _, platform := range o.platforms(keys) {
+		if len(q.Platforms) > 0 && !slices.Contains(q.Platforms, platform) {
+			continue
+		}
+		cached, err := o.load(ctx, platform, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range cached.doc.Results {
+			if q.matches(result) {
+				results = append(results, result)
+			}
+		}
+	}
+	return q.page(results), nil
+}
+
+// Archive writes each snapshot to its own object under the prefix's archive/ directory, which platform
+// documents never use. Objects are written once; a snapshot that already exists is left as it is.
+func (o *objectStorer) Archive(ctx context.Context, snapshots []Snapshot) error {
+	for _, snapshot := range snapshots {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to marshal snapshot of %s: %w", snapshot.Result.URL, err)
+		}
+		key := fmt.Sprintf("%s/archive/%s/%s-%d.json", o.prefix, url.PathEscape(snapshot.Result.Platform), snapshotID(snapshot.Result.URL), snapshot.ArchivedAt)
+		if err := o.backend.put(ctx, key, data, ""); err != nil && !errors.Is(err, errPreconditionFailed) {
+			return err
+		}
+	}
+	return nil
+}
+
+// platforms maps listed object keys back to the platform documents they hold, skipping anything else.
+func (o *objectStorer) platforms(keys []string) []string {
+	var platforms []string
+	for _, key := range keys {
+		name := strings.TrimSuffix(strings.TrimPrefix(key, o.prefix+"/"), ".json")
+		platform, err := url.PathUnescape(name)
+		if err != nil || strings.Contains(name, "/") {
+			continue
+		}
+		platforms = append(platforms, platform)
+	}
+	return platforms
+}
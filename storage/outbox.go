@@ -0,0 +1,57 @@
+// storage/outbox.go
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// OutboxEntry is a queued notification waiting to be delivered to one notifier.
+type OutboxEntry struct {
+	ID       string `json:"id"`
+	Notifier string `json:"notifier"`
+	// Payload is the encoded notification; storers treat it as opaque.
+	Payload     []byte `json:"payload"`
+	Attempts    int    `json:"attempts"`
+	NextAttempt int64  `json:"next_attempt"`
+	CreatedAt   int64  `json:"created_at"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// Outbox is implemented by storers that can queue notifications, so deliveries that fail are retried
+// on later runs instead of being lost.
+type Outbox interface {
+	// Enqueue adds entries to the outbox.
+	Enqueue(ctx context.Context, entries []OutboxEntry) error
+	// DueOutbox returns up to limit entries whose NextAttempt is at or before now, oldest first.
+	DueOutbox(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error)
+	// UpdateOutbox records a failed delivery attempt: the entry's attempts, next attempt, and last error.
+	UpdateOutbox(ctx context.Context, entry OutboxEntry) error
+	// DeleteOutbox removes a delivered or abandoned entry.
+	DeleteOutbox(ctx context.Context, id string) error
+}
+
+// AsOutbox returns the storer's outbox if its backend has one. A MultiStorer's outbox is its primary's.
+func AsOutbox(s Storer) (Outbox, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	outbox, ok := s.(Outbox)
+	return outbox, ok
+}
+
+// dueEntries filters entries to those due at now, sorted oldest first and capped at limit.
+func dueEntries(entries []OutboxEntry, now time.Time, limit int) []OutboxEntry {
+	var due []OutboxEntry
+	for _, entry := range entries {
+		if entry.NextAttempt <= now.Unix() {
+			due = append(due, entry)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].CreatedAt < due[j].CreatedAt })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due
+}
@@ -0,0 +1,101 @@
+// storage/query.go
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Query selects stored results. Empty fields match every result.
+type Query struct {
+	Platforms []string
+	// Keywords match results found by any of them, including, for storers that track them, keywords a
+	// result matched besides its own.
+	Keywords []string
+	// States match results in any of these states. See ResultState.
+	States []string
+	// Since and Until select results posted at or after Since and before Until. A zero value leaves that
+	// end of the range open.
+	Since, Until time.Time
+	// Offset skips that many matching results, and Limit caps how many are returned after it. A zero
+	// Limit returns every remaining result.
+	Offset, Limit int
+}
+
+// Querier is implemented by storers that can list their stored results.
+type Querier interface {
+	// Query returns the stored results matching q, newest first.
+	Query(ctx context.Context, q Query) ([]search.SearchResult, error)
+}
+
+// AsQuerier returns the storer's querier if its backend has one. A MultiStorer queries its primary.
+func AsQuerier(s Storer) (Querier, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	querier, ok := s.(Querier)
+	return querier, ok
+}
+
+// SearchTimeLister is implemented by storers that can list their stored last search times, so they can be
+// copied to another storer.
+type SearchTimeLister interface {
+	// LastSearchTimes returns every stored last search time, keyed as they were set.
+	LastSearchTimes(ctx context.Context) (map[string]int64, error)
+}
+
+// AsSearchTimeLister returns the storer's search time lister if its backend has one. A MultiStorer lists
+// its primary's.
+func AsSearchTimeLister(s Storer) (SearchTimeLister, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	lister, ok := s.(SearchTimeLister)
+	return lister, ok
+}
+
+// matches reports whether a result matches the query, for backends that scan their results.
+func (q Query) matches(result search.SearchResult) bool {
+	if len(q.Platforms) > 0 && !slices.Contains(q.Platforms, result.Platform) {
+		return false
+	}
+	if len(q.Keywords) > 0 {
+		found := append([]string{result.Keyword}, result.Keywords...)
+		if !slices.ContainsFunc(found, func(keyword string) bool { return slices.Contains(q.Keywords, keyword) }) {
+			return false
+		}
+	}
+	if len(q.States) > 0 && !slices.Contains(q.States, ResultState(result)) {
+		return false
+	}
+	if !q.Since.IsZero() && result.Timestamp < q.Since.Unix() {
+		return false
+	}
+	if !q.Until.IsZero() && result.Timestamp >= q.Until.Unix() {
+		return false
+	}
+	return true
+}
+
+// page sorts matching results newest first and applies the query's offset and limit, for backends that
+// scan their results.
+func (q Query) page(results []search.SearchResult) []search.SearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Timestamp != results[j].Timestamp {
+			return results[i].Timestamp > results[j].Timestamp
+		}
+		return results[i].URL < results[j].URL
+	})
+	if q.Offset >= len(results) {
+		return nil
+	}
+	results = results[max(q.Offset, 0):]
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
@@ -0,0 +1,65 @@
+// storage/readonly.go
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// ReadOnlyStorer answers reads from another storer and drops every write, so the pipeline can run against
+// stored results without changing them. Optional interfaces such as Updater and Outbox aren't passed
+// through, so features writing through them are off.
+type ReadOnlyStorer struct {
+	storer Storer
+}
+
+// NewReadOnlyStorer wraps storer so nothing is written to it.
+func NewReadOnlyStorer(storer Storer) *ReadOnlyStorer {
+	return &ReadOnlyStorer{storer: storer}
+}
+
+// Exists checks the wrapped storer.
+func (r *ReadOnlyStorer) Exists(ctx context.Context, platform, url string) (bool, error) {
+	return r.storer.Exists(ctx, platform, url)
+}
+
+// Save drops the result.
+func (r *ReadOnlyStorer) Save(ctx context.Context, result search.SearchResult) error {
+	return nil
+}
+
+// SaveBatch drops the results.
+func (r *ReadOnlyStorer) SaveBatch(ctx context.Context, results []search.SearchResult) error {
+	return nil
+}
+
+// GetLastSearchTime reads the wrapped storer.
+func (r *ReadOnlyStorer) GetLastSearchTime(ctx context.Context, platform string) (int64, error) {
+	return r.storer.GetLastSearchTime(ctx, platform)
+}
+
+// SetLastSearchTime leaves the last search time as it was.
+func (r *ReadOnlyStorer) SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error {
+	return nil
+}
+
+// Prune keeps every result.
+func (r *ReadOnlyStorer) Prune(ctx context.Context, olderThan time.Time) error {
+	return nil
+}
+
+// FindByContentHash reads the wrapped storer.
+func (r *ReadOnlyStorer) FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error) {
+	return r.storer.FindByContentHash(ctx, hash, since)
+}
+
+// Close closes the wrapped storer if it holds resources.
+func (r *ReadOnlyStorer) Close() error {
+	if closer, ok := r.storer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
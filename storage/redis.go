@@ -0,0 +1,552 @@
+// storage/redis.go
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStorer stores results as hashes keyed by platform and URL, and last search times in a single hash.
+type RedisStorer struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisStorer connects to the Redis server in REDIS_URL (defaulting to localhost). Keys are namespaced
+// with prefix, and result keys expire after ttl when it is non-zero.
+func NewRedisStorer(ctx context.Context, prefix string, ttl time.Duration) (*RedisStorer, error) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		redisURL = "redis://localhost:6379/0"
+	}
+
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse REDIS_URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &RedisStorer{client: client, prefix: prefix, ttl: ttl}, nil
+}
+
+func (r *RedisStorer) resultKey(platform, url string) string {
+	return fmt.Sprintf("%s:result:%s:%s", r.prefix, platform, url)
+}
+
+// contentHashKey is a sorted set of result keys sharing a content hash, scored by timestamp.
+func (r *RedisStorer) contentHashKey(hash string) string {
+	return r.prefix + ":content_hash:" + hash
+}
+
+func (r *RedisStorer) lastSearchTimeKey() string {
+	return r.prefix + ":last_search_time"
+}
+
+// keywordsKey is a hash of managed keywords, keyed by keyword with JSON-encoded keywords as values.
+func (r *RedisStorer) keywordsKey() string {
+	return r.prefix + ":keywords"
+}
+
+// runsKey is a sorted set of JSON-encoded runs, scored by start time in milliseconds.
+func (r *RedisStorer) runsKey() string {
+	return r.prefix + ":runs"
+}
+
+// tokensKey is a hash of the tokens searchers authenticate with, keyed by token key with JSON-encoded tokens
+// as values.
+func (r *RedisStorer) tokensKey() string {
+	return r.prefix + ":tokens"
+}
+
+// leaseKey holds the holder of the named lease, expiring with it.
+func (r *RedisStorer) leaseKey(name string) string {
+	return r.prefix + ":lease:" + name
+}
+
+// outboxKey is a hash of queued notifications, keyed by entry ID with JSON-encoded entries as values.
+func (r *RedisStorer) outboxKey() string {
+	return r.prefix + ":outbox"
+}
+
+// Exists checks if a specific item already exists in Redis.
+func (r *RedisStorer) Exists(ctx context.Context, platform, url string) (bool, error) {
+	n, err := r.client.Exists(ctx, r.resultKey(platform, url)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check key in Redis: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Save stores a new search result in Redis.
+func (r *RedisStorer) Save(ctx context.Context, result search.SearchResult) error {
+	return r.SaveBatch(ctx, []search.SearchResult{result})
+}
+
+// SaveBatch stores several results in a single Redis transaction pipeline.
+func (r *RedisStorer) SaveBatch(ctx context.Context, results []search.SearchResult) error {
+	pipe := r.client.TxPipeline()
+	for _, result := range results {
+		r.queueSave(ctx, pipe, result)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save results to Redis: %w", err)
+	}
+	return nil
+}
+
+// queueSave adds the commands storing a result to a pipeline.
+func (r *RedisStorer) queueSave(ctx context.Context, pipe redis.Pipeliner, result search.SearchResult) {
+	key := r.resultKey(result.Platform, result.URL)
+	pipe.HSet(ctx, key,
+		"Platform", result.Platform,
+		"Keyword", result.Keyword,
+		"Keywords", encodeTags(result.Keywords),
+		"Title", result.Title,
+		"URL", result.URL,
+		"Timestamp", result.Timestamp,
+		"Content", result.Content,
+		"Author", result.Author,
+		"Score", result.Score,
+		"Comments", result.Comments,
+		"Reposts", result.Reposts,
+		"Views", result.Views,
+		"Tags", encodeTags(result.Tags),
+		"Metadata", encodeMetadata(result.Metadata),
+		"Priority", string(result.Priority),
+		"ContentHash", result.ContentHash,
+	)
+	if r.ttl > 0 {
+		pipe.Expire(ctx, key, r.ttl)
+	}
+
+	if result.ContentHash != "" {
+		hashKey := r.contentHashKey(result.ContentHash)
+		pipe.ZAdd(ctx, hashKey, redis.Z{Score: float64(result.Timestamp), Member: key})
+		if r.ttl > 0 {
+			pipe.Expire(ctx, hashKey, r.ttl)
+		}
+	}
+}
+
+// AddKeywords records further keywords matched by stored results, reading them in one pipeline and
+// writing those that gained keywords in a single transaction.
+func (r *RedisStorer) AddKeywords(ctx context.Context, results []search.SearchResult) error {
+	pipe := r.client.Pipeline()
+	reads := make([]*redis.SliceCmd, len(results))
+	for i, result := range results {
+		reads[i] = pipe.HMGet(ctx, r.resultKey(result.Platform, result.URL), "Keyword", "Keywords")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to read results from Redis: %w", err)
+	}
+
+	tx := r.client.TxPipeline()
+	for i, result := range results {
+		values := reads[i].Val()
+		keyword, ok := values[0].(string)
+		if !ok {
+			// The result isn't stored
+			continue
+		}
+		stored := search.SearchResult{Keyword: keyword}
+		if encoded, ok := values[1].(string); ok {
+			stored.Keywords = decodeTags(encoded)
+		}
+		if keywords, added := addKeywords(stored, result.Keywords); added {
+			tx.HSet(ctx, r.resultKey(result.Platform, result.URL), "Keywords", encodeTags(keywords))
+		}
+	}
+	if _, err := tx.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to record keywords in Redis: %w", err)
+	}
+	return nil
+}
+
+// GetLastSearchTime retrieves the last search time for a given platform from Redis.
+func (r *RedisStorer) GetLastSearchTime(ctx context.Context, platform string) (int64, error) {
+	value, err := r.client.HGet(ctx, r.lastSearchTimeKey(), platform).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to get last search time from Redis: %w", err)
+	}
+
+	lastSearchTime, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse LastSearchTime: %w", err)
+	}
+	return lastSearchTime, nil
+}
+
+// SetLastSearchTime updates the last search time for a given platform in Redis.
+func (r *RedisStorer) SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error {
+	if err := r.client.HSet(ctx, r.lastSearchTimeKey(), platform, epochTime).Err(); err != nil {
+		return fmt.Errorf("failed to set last search time in Redis: %w", err)
+	}
+	return nil
+}
+
+// LastSearchTimes returns every last search time stored in Redis.
+func (r *RedisStorer) LastSearchTimes(ctx context.Context) (map[string]int64, error) {
+	values, err := r.client.HGetAll(ctx, r.lastSearchTimeKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last search times from Redis: %w", err)
+	}
+
+	times := make(map[string]int64, len(values))
+	for platform, value := range values {
+		lastSearchTime, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse LastSearchTime: %w", err)
+		}
+		times[platform] = lastSearchTime
+	}
+	return times, nil
+}
+
+// Close closes the Redis connection.
+func (r *RedisStorer) Close() error {
+	return r.client.Close()
+}
+
+// Prune deletes results older than the given time from Redis. Keys with a TTL also expire on their own.
+func (r *RedisStorer) Prune(ctx context.Context, olderThan time.Time) error {
+	iter := r.client.Scan(ctx, 0, r.prefix+":result:*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		values, err := r.client.HMGet(ctx, key, "Timestamp", "ContentHash").Result()
+		if err != nil {
+			return fmt.Errorf("failed to read %s from Redis: %w", key, err)
+		}
+
+		value, _ := values[0].(string)
+		timestamp, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || timestamp >= olderThan.Unix() {
+			continue
+		}
+
+		if err := r.client.Del(ctx, key).Err(); err != nil {
+			return fmt.Errorf("failed to delete %s from Redis: %w", key, err)
+		}
+		if hash, _ := values[1].(string); hash != "" {
+			if err := r.client.ZRem(ctx, r.contentHashKey(hash), key).Err(); err != nil {
+				return fmt.Errorf("failed to remove %s from its content hash index: %w", key, err)
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan Redis keys: %w", err)
+	}
+	return nil
+}
+
+// FindByContentHash returns results with a matching content hash saved at or after since.
+func (r *RedisStorer) FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error) {
+	keys, err := r.client.ZRangeByScore(ctx, r.contentHashKey(hash), &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content hash index from Redis: %w", err)
+	}
+
+	var results []search.SearchResult
+	for _, key := range keys {
+		fields, err := r.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from Redis: %w", key, err)
+		}
+		// The result may have expired while its index entry remains
+		if len(fields) == 0 {
+			continue
+		}
+
+		results = append(results, redisResult(fields))
+	}
+	return results, nil
+}
+
+// redisResult decodes the fields of a stored result's hash.
+func redisResult(fields map[string]string) search.SearchResult {
+	timestamp, _ := strconv.ParseInt(fields["Timestamp"], 10, 64)
+	score, _ := strconv.ParseInt(fields["Score"], 10, 64)
+	comments, _ := strconv.ParseInt(fields["Comments"], 10, 64)
+	reposts, _ := strconv.ParseInt(fields["Reposts"], 10, 64)
+	views, _ := strconv.ParseInt(fields["Views"], 10, 64)
+	return search.SearchResult{
+		Platform:    fields["Platform"],
+		Keyword:     fields["Keyword"],
+		Keywords:    decodeTags(fields["Keywords"]),
+		Title:       fields["Title"],
+		URL:         fields["URL"],
+		Timestamp:   timestamp,
+		Content:     fields["Content"],
+		Author:      fields["Author"],
+		Score:       score,
+		Comments:    comments,
+		Reposts:     reposts,
+		Views:       views,
+		Tags:        decodeTags(fields["Tags"]),
+		Metadata:    decodeMetadata(fields["Metadata"]),
+		Priority:    search.Priority(fields["Priority"]),
+		ContentHash: fields["ContentHash"],
+	}
+}
+
+// Enqueue adds notifications to the outbox hash, leaving entries that are already queued untouched.
+func (r *RedisStorer) Enqueue(ctx context.Context, entries []OutboxEntry) error {
+	pipe := r.client.TxPipeline()
+	for _, entry := range entries {
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal outbox entry: %w", err)
+		}
+		pipe.HSetNX(ctx, r.outboxKey(), entry.ID, value)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to enqueue notifications in Redis: %w", err)
+	}
+	return nil
+}
+
+// DueOutbox returns queued notifications that are due for delivery, oldest first.
+func (r *RedisStorer) DueOutbox(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error) {
+	values, err := r.client.HGetAll(ctx, r.outboxKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox from Redis: %w", err)
+	}
+
+	entries := make([]OutboxEntry, 0, len(values))
+	for id, value := range values {
+		var entry OutboxEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse outbox entry %s: %w", id, err)
+		}
+		entries = append(entries, entry)
+	}
+	return dueEntries(entries, now, limit), nil
+}
+
+// UpdateOutbox records a failed delivery attempt. Entries that have since been deleted are left deleted.
+func (r *RedisStorer) UpdateOutbox(ctx context.Context, entry OutboxEntry) error {
+	exists, err := r.client.HExists(ctx, r.outboxKey(), entry.ID).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check outbox entry in Redis: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox entry: %w", err)
+	}
+	if err := r.client.HSet(ctx, r.outboxKey(), entry.ID, value).Err(); err != nil {
+		return fmt.Errorf("failed to update outbox entry in Redis: %w", err)
+	}
+	return nil
+}
+
+// DeleteOutbox removes a notification from the outbox hash.
+func (r *RedisStorer) DeleteOutbox(ctx context.Context, id string) error {
+	if err := r.client.HDel(ctx, r.outboxKey(), id).Err(); err != nil {
+		return fmt.Errorf("failed to delete outbox entry from Redis: %w", err)
+	}
+	return nil
+}
+
+// ManagedKeywords returns every managed keyword stored in Redis, sorted.
+func (r *RedisStorer) ManagedKeywords(ctx context.Context) ([]ManagedKeyword, error) {
+	values, err := r.client.HGetAll(ctx, r.keywordsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read managed keywords from Redis: %w", err)
+	}
+
+	keywords := make([]ManagedKeyword, 0, len(values))
+	for name, value := range values {
+		var keyword ManagedKeyword
+		if err := json.Unmarshal([]byte(value), &keyword); err != nil {
+			return nil, fmt.Errorf("failed to parse managed keyword %s: %w", name, err)
+		}
+		keywords = append(keywords, keyword)
+	}
+	return sortKeywords(keywords), nil
+}
+
+// PutKeyword adds or replaces a managed keyword in Redis.
+func (r *RedisStorer) PutKeyword(ctx context.Context, keyword ManagedKeyword) error {
+	value, err := json.Marshal(keyword)
+	if err != nil {
+		return fmt.Errorf("failed to marshal managed keyword: %w", err)
+	}
+	if err := r.client.HSet(ctx, r.keywordsKey(), keyword.Keyword, value).Err(); err != nil {
+		return fmt.Errorf("failed to store managed keyword in Redis: %w", err)
+	}
+	return nil
+}
+
+// DeleteKeyword removes a managed keyword from Redis.
+func (r *RedisStorer) DeleteKeyword(ctx context.Context, keyword string) error {
+	if err := r.client.HDel(ctx, r.keywordsKey(), keyword).Err(); err != nil {
+		return fmt.Errorf("failed to delete managed keyword from Redis: %w", err)
+	}
+	return nil
+}
+
+// RecordRun adds a run to the run history in Redis.
+func (r *RedisStorer) RecordRun(ctx context.Context, run Run) error {
+	value, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+	if err := r.client.ZAdd(ctx, r.runsKey(), redis.Z{Score: float64(run.Started.UnixMilli()), Member: value}).Err(); err != nil {
+		return fmt.Errorf("failed to store run in Redis: %w", err)
+	}
+	return nil
+}
+
+// Runs returns up to limit runs from the run history in Redis, most recently started first.
+func (r *RedisStorer) Runs(ctx context.Context, limit int) ([]Run, error) {
+	values, err := r.client.ZRevRange(ctx, r.runsKey(), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs from Redis: %w", err)
+	}
+
+	runs := make([]Run, 0, len(values))
+	for _, value := range values {
+		var run Run
+		if err := json.Unmarshal([]byte(value), &run); err != nil {
+			return nil, fmt.Errorf("failed to parse run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// Token returns the token stored under key in Redis, or nil if there is none.
+func (r *RedisStorer) Token(ctx context.Context, key string) (*search.Token, error) {
+	value, err := r.client.HGet(ctx, r.tokensKey(), key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read token from Redis: %w", err)
+	}
+	var token search.Token
+	if err := json.Unmarshal([]byte(value), &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token %s: %w", key, err)
+	}
+	return &token, nil
+}
+
+// PutToken stores a token under key in Redis, replacing any stored before.
+func (r *RedisStorer) PutToken(ctx context.Context, key string, token search.Token) error {
+	value, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	if err := r.client.HSet(ctx, r.tokensKey(), key, value).Err(); err != nil {
+		return fmt.Errorf("failed to store token in Redis: %w", err)
+	}
+	return nil
+}
+
+// redisAcquireLease sets the lease key to the holder with the TTL in milliseconds, unless another holder
+// has it.
+var redisAcquireLease = redis.NewScript(`
+local holder = redis.call("GET", KEYS[1])
+if holder and holder ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+// redisReleaseLease deletes the lease key if the holder has it.
+var redisReleaseLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireLease takes or renews the named lease in Redis, where its key expires with it.
+func (r *RedisStorer) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	acquired, err := redisAcquireLease.Run(ctx, r.client, []string{r.leaseKey(name)}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease in Redis: %w", err)
+	}
+	return acquired == 1, nil
+}
+
+// ReleaseLease deletes the named lease from Redis if holder has it.
+func (r *RedisStorer) ReleaseLease(ctx context.Context, name, holder string) error {
+	if err := redisReleaseLease.Run(ctx, r.client, []string{r.leaseKey(name)}, holder).Err(); err != nil {
+		return fmt.Errorf("failed to release lease in Redis: %w", err)
+	}
+	return nil
+}
+
+// CountResults scans result keys, counting results by platform and keyword.
+func (r *RedisStorer) CountResults(ctx context.Context, since, until time.Time) ([]ResultCount, error) {
+	tally := newResultTally(since, until)
+	iter := r.client.Scan(ctx, 0, r.prefix+":result:*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		values, err := r.client.HMGet(ctx, key, "Platform", "Keyword", "Timestamp").Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from Redis: %w", key, err)
+		}
+
+		platform, _ := values[0].(string)
+		keyword, _ := values[1].(string)
+		value, _ := values[2].(string)
+		timestamp, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		tally.add(platform, keyword, timestamp)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan Redis keys: %w", err)
+	}
+	return tally.results(), nil
+}
+
+// Query scans result keys for results matching q.
+func (r *RedisStorer) Query(ctx context.Context, q Query) ([]search.SearchResult, error) {
+	var results []search.SearchResult
+	iter := r.client.Scan(ctx, 0, r.prefix+":result:*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		fields, err := r.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from Redis: %w", key, err)
+		}
+		// The result may have expired since the scan found it
+		if len(fields) == 0 {
+			continue
+		}
+		if result := redisResult(fields); q.matches(result) {
+			results = append(results, result)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan Redis keys: %w", err)
+	}
+	return q.page(results), nil
+}
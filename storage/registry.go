@@ -0,0 +1,142 @@
+// storage/registry.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownBackend is returned by New for a name no backend is registered under.
+var ErrUnknownBackend = errors.New("unknown storage backend")
+
+// Option configures a storage backend created with New. Backends ignore options that don't apply to them.
+type Option func(*options)
+
+type options struct {
+	httpClient  *http.Client
+	retention   time.Duration
+	createTable bool
+	ttl         time.Duration
+}
+
+// WithHTTPClient sends the backend's requests with client, for backends reached over HTTP.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithRetention sets a TTL on saved results, relative to their timestamp, in DynamoDB.
+func WithRetention(retention time.Duration) Option {
+	return func(o *options) {
+		o.retention = retention
+	}
+}
+
+// WithCreateTable creates the DynamoDB table if it does not exist.
+func WithCreateTable(create bool) Option {
+	return func(o *options) {
+		o.createTable = create
+	}
+}
+
+// WithTTL expires Redis result keys after ttl.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// Factory creates a backend storing results in table, which each backend takes as its table, file, index,
+// or key prefix.
+type Factory func(ctx context.Context, table string, opts ...Option) (Storer, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+func init() {
+	Register("dynamodb", func(ctx context.Context, table string, opts ...Option) (Storer, error) {
+		o := newOptions(opts)
+		return asStorer(NewDynamoDBStorer(ctx, table, DynamoDBOptions{CreateTable: o.createTable, Retention: o.retention, HTTPClient: o.httpClient}))
+	})
+	Register("sqlite", func(ctx context.Context, table string, opts ...Option) (Storer, error) {
+		return asStorer(NewSQLiteStorer(table))
+	})
+	Register("redis", func(ctx context.Context, table string, opts ...Option) (Storer, error) {
+		return asStorer(NewRedisStorer(ctx, table, newOptions(opts).ttl))
+	})
+	Register("bolt", func(ctx context.Context, table string, opts ...Option) (Storer, error) {
+		return asStorer(NewBoltStorer(table))
+	})
+	Register("ndjson", func(ctx context.Context, table string, opts ...Option) (Storer, error) {
+		return asStorer(NewNDJSONStorer(table))
+	})
+	Register("s3", func(ctx context.Context, table string, opts ...Option) (Storer, error) {
+		return asStorer(NewS3Storer(ctx, table, newOptions(opts).httpClient))
+	})
+	Register("gcs", func(ctx context.Context, table string, opts ...Option) (Storer, error) {
+		return asStorer(NewGCSStorer(ctx, table, newOptions(opts).httpClient))
+	})
+	Register("clickhouse", func(ctx context.Context, table string, opts ...Option) (Storer, error) {
+		return asStorer(NewClickHouseStorer(ctx, table, newOptions(opts).httpClient))
+	})
+	Register("elasticsearch", func(ctx context.Context, table string, opts ...Option) (Storer, error) {
+		return asStorer(NewElasticsearchStorer(ctx, table, newOptions(opts).httpClient))
+	})
+}
+
+// Register makes a backend available to New under name, replacing any registered under the same name.
+// Programs embedding grass register their own backends this way.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// Backends returns the names backends are registered under, sorted.
+func Backends() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the backend registered under name, returning an error wrapping ErrUnknownBackend if there is
+// none.
+func New(ctx context.Context, name, table string, opts ...Option) (Storer, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, name)
+	}
+	return factory(ctx, table, opts...)
+}
+
+// newOptions applies opts over the defaults.
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// asStorer returns a constructor's backend as a Storer, or a nil Storer if it failed.
+func asStorer[S Storer](storer S, err error) (Storer, error) {
+	if err != nil {
+		return nil, err
+	}
+	return storer, nil
+}
@@ -0,0 +1,64 @@
+// storage/runs.go
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Run is a finished run's counts as kept in run history.
+type Run struct {
+	// Profile is the profile that ran, empty for the unnamed profile.
+	Profile string `json:"profile,omitempty"`
+	// Trigger is what started the run: "run" for a one-shot run, "lambda" for a Lambda invocation, "api"
+	// or "grpc" for a search requested through an API, or "queue" for a job taken from a queue by a worker.
+	Trigger   string        `json:"trigger"`
+	Started   time.Time     `json:"started"`
+	Duration  float64       `json:"duration_seconds"`
+	Failed    bool          `json:"failed"`
+	Totals    RunPlatform   `json:"totals"`
+	Platforms []RunPlatform `json:"platforms"`
+}
+
+// RunPlatform counts the searches of one platform during a run, or of every platform in a run's totals.
+type RunPlatform struct {
+	Platform string   `json:"platform,omitempty"`
+	Searches int      `json:"searches"`
+	Failed   int      `json:"failed"`
+	Found    int      `json:"found"`
+	New      int      `json:"new"`
+	Skipped  int      `json:"skipped"`
+	Duration float64  `json:"duration_seconds"`
+	Errors   []string `json:"errors"`
+}
+
+// RunRecorder is implemented by storers that can keep a history of runs, so it can be reviewed through the
+// API.
+type RunRecorder interface {
+	// RecordRun adds a run to the history.
+	RecordRun(ctx context.Context, run Run) error
+	// Runs returns up to limit runs from the history, most recently started first. A zero limit returns
+	// every run.
+	Runs(ctx context.Context, limit int) ([]Run, error)
+}
+
+// AsRunRecorder returns the storer's run recorder if its backend has one. A MultiStorer records runs in its
+// primary.
+func AsRunRecorder(s Storer) (RunRecorder, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	recorder, ok := s.(RunRecorder)
+	return recorder, ok
+}
+
+// latestRuns sorts runs most recently started first and returns up to limit of them, or all of them for a
+// zero limit, for backends that keep runs unordered.
+func latestRuns(runs []Run, limit int) []Run {
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Started.After(runs[j].Started) })
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs
+}
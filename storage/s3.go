@@ -0,0 +1,113 @@
+// storage/s3.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// S3Storer persists results and last search times as one JSON object per platform in an S3 bucket.
+type S3Storer struct {
+	*objectStorer
+}
+
+type s3Backend struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewS3Storer creates a storer writing objects under prefix in the bucket named by S3_BUCKET, sending
+// requests with httpClient unless it is nil.
+func NewS3Storer(ctx context.Context, prefix string, httpClient *http.Client) (*S3Storer, error) {
+	bucket := os.Getenv("S3_BUCKET")
+	if bucket == "" {
+		return nil, errors.New("missing S3 configuration: S3_BUCKET is required")
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, awsHTTPClient(httpClient)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	backend := &s3Backend{client: s3.NewFromConfig(cfg), bucket: bucket}
+	return &S3Storer{objectStorer: newObjectStorer(backend, prefix)}, nil
+}
+
+func (s *s3Backend) get(ctx context.Context, key string) ([]byte, string, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, "", errObjectNotFound
+		}
+		return nil, "", fmt.Errorf("failed to get object %s from S3: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read object %s from S3: %w", key, err)
+	}
+
+	return data, aws.ToString(out.ETag), nil
+}
+
+func (s *s3Backend) put(ctx context.Context, key string, data []byte, etag string) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}
+	if etag == "" {
+		input.IfNoneMatch = aws.String("*")
+	} else {
+		input.IfMatch = aws.String(etag)
+	}
+
+	_, err := s.client.PutObject(ctx, input)
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.ErrorCode() {
+			case "PreconditionFailed", "ConditionalRequestConflict":
+				return errPreconditionFailed
+			}
+		}
+		return fmt.Errorf("failed to put object %s into S3: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *s3Backend) list(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects in S3: %w", err)
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+	return keys, nil
+}
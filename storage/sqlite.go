@@ -2,8 +2,13 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/jaxxstorm/grass/search"
 	_ "github.com/mattn/go-sqlite3"
@@ -19,21 +24,11 @@ func NewSQLiteStorer(dbPath string) (*SQLiteStorer, error) {
 		return nil, err
 	}
 
-	// Create tables if they do not exist
-	createTables := `
-	CREATE TABLE IF NOT EXISTS search_results (
-		Platform TEXT,
-		Keyword TEXT,
-		Title TEXT,
-		URL TEXT PRIMARY KEY,
-		Timestamp INTEGER
-	);
-	CREATE TABLE IF NOT EXISTS last_search_time (
-		Platform TEXT PRIMARY KEY,
-		LastSearchTime INTEGER
-	);`
-	_, err = db.Exec(createTables)
-	if err != nil {
+	// SQLite allows a single writer; serialize access so parallel searches wait instead of failing with
+	// "database is locked".
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSQLite(db); err != nil {
 		return nil, err
 	}
 
@@ -41,28 +36,127 @@ func NewSQLiteStorer(dbPath string) (*SQLiteStorer, error) {
 }
 
 // Exists checks if a specific item already exists in SQLite.
-func (s *SQLiteStorer) Exists(platform, url string) (bool, error) {
+func (s *SQLiteStorer) Exists(ctx context.Context, platform, url string) (bool, error) {
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM search_results WHERE Platform = ? AND URL = ?);`
-	err := s.db.QueryRow(query, platform, url).Scan(&exists)
+	err := s.db.QueryRowContext(ctx, query, platform, url).Scan(&exists)
 	return exists, err
 }
 
-// Save stores a new search result in SQLite.
-func (s *SQLiteStorer) Save(result search.SearchResult) error {
-	query := `
-	INSERT INTO search_results (Platform, Keyword, Title, URL, Timestamp)
-	VALUES (?, ?, ?, ?, ?)
+const sqliteInsertResult = `
+	INSERT INTO search_results (Platform, Keyword, Title, URL, Timestamp, Content, Author, Score, Comments, Reposts, Views, Tags, Metadata, Priority, ContentHash)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(URL) DO NOTHING;
 	`
-	_, err := s.db.Exec(query, result.Platform, result.Keyword, result.Title, result.URL, result.Timestamp)
-	return err
+
+const sqliteInsertKeyword = `INSERT OR IGNORE INTO result_keywords (Platform, URL, Keyword) VALUES (?, ?, ?);`
+
+// Save stores a new search result in SQLite.
+func (s *SQLiteStorer) Save(ctx context.Context, result search.SearchResult) error {
+	return s.SaveBatch(ctx, []search.SearchResult{result})
+}
+
+// SaveBatch stores several results in a single SQLite transaction.
+func (s *SQLiteStorer) SaveBatch(ctx context.Context, results []search.SearchResult) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, sqliteInsertResult)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	keywordStmt, err := tx.PrepareContext(ctx, sqliteInsertKeyword)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer keywordStmt.Close()
+
+	for _, result := range results {
+		_, err := stmt.ExecContext(ctx, result.Platform, result.Keyword, result.Title, result.URL, result.Timestamp,
+			result.Content, result.Author, result.Score, result.Comments, result.Reposts, result.Views, encodeTags(result.Tags),
+			encodeMetadata(result.Metadata), string(result.Priority), result.ContentHash)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, keyword := range resultKeywords(result.Keyword, result.Keywords) {
+			if _, err := keywordStmt.ExecContext(ctx, result.Platform, result.URL, keyword); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AddKeywords records further keywords matched by stored results in a single transaction.
+func (s *SQLiteStorer) AddKeywords(ctx context.Context, results []search.SearchResult) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+	INSERT OR IGNORE INTO result_keywords (Platform, URL, Keyword)
+	SELECT Platform, URL, ? FROM search_results WHERE Platform = ? AND URL = ?;`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, result := range results {
+		for _, keyword := range result.Keywords {
+			if _, err := stmt.ExecContext(ctx, keyword, result.Platform, result.URL); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Archive stores snapshots in a single transaction. Prune never deletes them.
+func (s *SQLiteStorer) Archive(ctx context.Context, snapshots []Snapshot) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+	INSERT OR IGNORE INTO snapshots (Platform, URL, ArchivedAt, Result, Page)
+	VALUES (?, ?, ?, ?, ?);`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, snapshot := range snapshots {
+		result, err := json.Marshal(snapshot.Result)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, snapshot.Result.Platform, snapshot.Result.URL, snapshot.ArchivedAt, string(result), snapshot.Page); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 // GetLastSearchTime retrieves the last search time for a given platform from SQLite.
-func (s *SQLiteStorer) GetLastSearchTime(platform string) (int64, error) {
+func (s *SQLiteStorer) GetLastSearchTime(ctx context.Context, platform string) (int64, error) {
 	var lastSearchTime int64
-	err := s.db.QueryRow(`SELECT LastSearchTime FROM last_search_time WHERE Platform = ?;`, platform).Scan(&lastSearchTime)
+	err := s.db.QueryRowContext(ctx, `SELECT LastSearchTime FROM last_search_time WHERE Platform = ?;`, platform).Scan(&lastSearchTime)
 	if err == sql.ErrNoRows {
 		// Default to epoch start if no record exists
 		return 0, nil
@@ -73,13 +167,162 @@ func (s *SQLiteStorer) GetLastSearchTime(platform string) (int64, error) {
 }
 
 // SetLastSearchTime updates the last search time for a given platform in SQLite.
-func (s *SQLiteStorer) SetLastSearchTime(platform string, epochTime int64) error {
+func (s *SQLiteStorer) SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error {
 	query := `
 	INSERT INTO last_search_time (Platform, LastSearchTime)
 	VALUES (?, ?)
 	ON CONFLICT(Platform) DO UPDATE SET LastSearchTime = excluded.LastSearchTime;
 	`
-	_, err := s.db.Exec(query, platform, epochTime)
+	_, err := s.db.ExecContext(ctx, query, platform, epochTime)
+	return err
+}
+
+// LastSearchTimes returns every last search time stored in SQLite.
+func (s *SQLiteStorer) LastSearchTimes(ctx context.Context) (map[string]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT Platform, LastSearchTime FROM last_search_time;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	times := make(map[string]int64)
+	for rows.Next() {
+		var (
+			platform       string
+			lastSearchTime int64
+		)
+		if err := rows.Scan(&platform, &lastSearchTime); err != nil {
+			return nil, err
+		}
+		times[platform] = lastSearchTime
+	}
+	return times, rows.Err()
+}
+
+// ManagedKeywords returns every managed keyword stored in SQLite, sorted.
+func (s *SQLiteStorer) ManagedKeywords(ctx context.Context) ([]ManagedKeyword, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT Keyword, Paused, PausedUntil, AddedAt FROM managed_keywords ORDER BY Keyword;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keywords []ManagedKeyword
+	for rows.Next() {
+		var keyword ManagedKeyword
+		if err := rows.Scan(&keyword.Keyword, &keyword.Paused, &keyword.PausedUntil, &keyword.AddedAt); err != nil {
+			return nil, err
+		}
+		keywords = append(keywords, keyword)
+	}
+	return keywords, rows.Err()
+}
+
+// PutKeyword adds or replaces a managed keyword in SQLite.
+func (s *SQLiteStorer) PutKeyword(ctx context.Context, keyword ManagedKeyword) error {
+	_, err := s.db.ExecContext(ctx, `
+	INSERT INTO managed_keywords (Keyword, Paused, PausedUntil, AddedAt)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT(Keyword) DO UPDATE SET Paused = excluded.Paused, PausedUntil = excluded.PausedUntil, AddedAt = excluded.AddedAt;
+	`, keyword.Keyword, keyword.Paused, keyword.PausedUntil, keyword.AddedAt)
+	return err
+}
+
+// DeleteKeyword removes a managed keyword from SQLite.
+func (s *SQLiteStorer) DeleteKeyword(ctx context.Context, keyword string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM managed_keywords WHERE Keyword = ?;`, keyword)
+	return err
+}
+
+// RecordRun adds a run to the history in SQLite.
+func (s *SQLiteStorer) RecordRun(ctx context.Context, run Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO run_history (Started, Run) VALUES (?, ?);`, run.Started.UnixNano(), string(data))
+	return err
+}
+
+// Runs returns up to limit runs from the history in SQLite, most recently started first.
+func (s *SQLiteStorer) Runs(ctx context.Context, limit int) ([]Run, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT Run FROM run_history ORDER BY Started DESC LIMIT ?;`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []Run
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var run Run
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			return nil, fmt.Errorf("failed to decode run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// Token returns the token stored under key in SQLite, or nil if there is none.
+func (s *SQLiteStorer) Token(ctx context.Context, key string) (*search.Token, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT Token FROM tokens WHERE Key = ?;`, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var token search.Token
+	if err := json.Unmarshal([]byte(data), &token); err != nil {
+		return nil, fmt.Errorf("failed to decode token: %w", err)
+	}
+	return &token, nil
+}
+
+// PutToken stores a token under key in SQLite, replacing any stored before.
+func (s *SQLiteStorer) PutToken(ctx context.Context, key string, token search.Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+	INSERT INTO tokens (Key, Token)
+	VALUES (?, ?)
+	ON CONFLICT(Key) DO UPDATE SET Token = excluded.Token;
+	`, key, string(data))
+	return err
+}
+
+// AcquireLease takes or renews the named lease in SQLite, replacing the row unless another holder's lease,
+// which expires at a Unix time in milliseconds, is still live.
+func (s *SQLiteStorer) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res, err := s.db.ExecContext(ctx, `
+	INSERT INTO leases (Name, Holder, ExpiresAt)
+	VALUES (?, ?, ?)
+	ON CONFLICT(Name) DO UPDATE SET Holder = excluded.Holder, ExpiresAt = excluded.ExpiresAt
+	WHERE leases.Holder = excluded.Holder OR leases.ExpiresAt <= ?;
+	`, name, holder, now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ReleaseLease deletes the named lease from SQLite if holder has it.
+func (s *SQLiteStorer) ReleaseLease(ctx context.Context, name, holder string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM leases WHERE Name = ? AND Holder = ?;`, name, holder)
 	return err
 }
 
@@ -87,3 +330,224 @@ func (s *SQLiteStorer) SetLastSearchTime(platform string, epochTime int64) error
 func (s *SQLiteStorer) Close() error {
 	return s.db.Close()
 }
+
+// Prune deletes results older than the given time from SQLite.
+func (s *SQLiteStorer) Prune(ctx context.Context, olderThan time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+	DELETE FROM search_results WHERE Timestamp < ?;
+	DELETE FROM result_keywords WHERE NOT EXISTS (
+		SELECT 1 FROM search_results WHERE search_results.Platform = result_keywords.Platform AND search_results.URL = result_keywords.URL
+	);`, olderThan.Unix())
+	return err
+}
+
+// Get returns the stored result at url.
+func (s *SQLiteStorer) Get(ctx context.Context, platform, url string) (search.SearchResult, bool, error) {
+	result := search.SearchResult{Platform: platform, URL: url}
+	var tags, metadata, priority string
+	err := s.db.QueryRowContext(ctx, `
+	SELECT COALESCE(Keyword, ''), COALESCE(Title, ''), Timestamp, COALESCE(Content, ''), COALESCE(Author, ''), COALESCE(Score, 0),
+		COALESCE(Comments, 0), COALESCE(Reposts, 0), COALESCE(Views, 0), COALESCE(Tags, ''), COALESCE(Metadata, ''),
+		COALESCE(Priority, ''), COALESCE(ContentHash, ''), COALESCE(DeletedAt, 0)
+	FROM search_results WHERE Platform = ? AND URL = ?;`, platform, url).Scan(&result.Keyword, &result.Title, &result.Timestamp,
+		&result.Content, &result.Author, &result.Score, &result.Comments, &result.Reposts, &result.Views,
+		&tags, &metadata, &priority, &result.ContentHash, &result.DeletedAt)
+	if err == sql.ErrNoRows {
+		return search.SearchResult{}, false, nil
+	} else if err != nil {
+		return search.SearchResult{}, false, err
+	}
+	result.Tags = decodeTags(tags)
+	result.Metadata = decodeMetadata(metadata)
+	result.Priority = search.Priority(priority)
+	return result, true, nil
+}
+
+// Update replaces the mutable fields of a stored result.
+func (s *SQLiteStorer) Update(ctx context.Context, result search.SearchResult) error {
+	_, err := s.db.ExecContext(ctx, `
+	UPDATE search_results SET Title = ?, Content = ?, Score = ?, Comments = ?, Reposts = ?, Views = ?, Tags = ?, Metadata = ?,
+		ContentHash = ?, DeletedAt = NULLIF(?, 0)
+	WHERE Platform = ? AND URL = ?;`, result.Title, result.Content, result.Score, result.Comments, result.Reposts, result.Views,
+		encodeTags(result.Tags), encodeMetadata(result.Metadata), result.ContentHash, result.DeletedAt, result.Platform, result.URL)
+	return err
+}
+
+// FindByContentHash returns results with a matching content hash saved at or after since.
+func (s *SQLiteStorer) FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+sqliteResultColumns+`
+	FROM search_results WHERE ContentHash = ? AND Timestamp >= ? ORDER BY Timestamp;`, hash, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	return scanSQLiteResults(rows)
+}
+
+// Query returns the stored results matching q, newest first. Keywords match any keyword recorded for a
+// result, not only the one it was first found by.
+func (s *SQLiteStorer) Query(ctx context.Context, q Query) ([]search.SearchResult, error) {
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+	if !q.Since.IsZero() {
+		from = q.Since.Unix()
+	}
+	if !q.Until.IsZero() {
+		to = q.Until.Unix()
+	}
+
+	where := "Timestamp >= ? AND Timestamp < ?"
+	args := []any{from, to}
+	if len(q.Platforms) > 0 {
+		where += " AND Platform IN (" + sqlitePlaceholders(len(q.Platforms)) + ")"
+		for _, platform := range q.Platforms {
+			args = append(args, platform)
+		}
+	}
+	if len(q.Keywords) > 0 {
+		placeholders := sqlitePlaceholders(len(q.Keywords))
+		where += " AND (Keyword IN (" + placeholders + ") OR EXISTS (SELECT 1 FROM result_keywords k WHERE k.Platform = search_results.Platform AND k.URL = search_results.URL AND k.Keyword IN (" + placeholders + ")))"
+		for range 2 {
+			for _, keyword := range q.Keywords {
+				args = append(args, keyword)
+			}
+		}
+	}
+	if len(q.States) > 0 {
+		where += " AND COALESCE(CASE WHEN json_valid(Metadata) THEN json_extract(Metadata, '$.state') END, ?) IN (" + sqlitePlaceholders(len(q.States)) + ")"
+		args = append(args, StateNew)
+		for _, state := range q.States {
+			args = append(args, state)
+		}
+	}
+	// A negative limit is unlimited in SQLite
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	args = append(args, limit, max(q.Offset, 0))
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+sqliteResultColumns+`
+	FROM search_results WHERE `+where+` ORDER BY Timestamp DESC, URL LIMIT ? OFFSET ?;`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanSQLiteResults(rows)
+}
+
+// sqliteResultColumns are the search_results columns read by scanSQLiteResults.
+const sqliteResultColumns = `Platform, COALESCE(Keyword, ''), COALESCE(Title, ''), URL, Timestamp, COALESCE(Content, ''), COALESCE(Author, ''), COALESCE(Score, 0),
+		COALESCE(Comments, 0), COALESCE(Reposts, 0), COALESCE(Views, 0), COALESCE(Tags, ''), COALESCE(Metadata, ''),
+		COALESCE(Priority, ''), COALESCE(ContentHash, ''), COALESCE(DeletedAt, 0)`
+
+// scanSQLiteResults reads and closes rows selecting sqliteResultColumns.
+func scanSQLiteResults(rows *sql.Rows) ([]search.SearchResult, error) {
+	defer rows.Close()
+
+	var results []search.SearchResult
+	for rows.Next() {
+		var result search.SearchResult
+		var tags, metadata, priority string
+		if err := rows.Scan(&result.Platform, &result.Keyword, &result.Title, &result.URL, &result.Timestamp,
+			&result.Content, &result.Author, &result.Score, &result.Comments, &result.Reposts, &result.Views,
+			&tags, &metadata, &priority, &result.ContentHash, &result.DeletedAt); err != nil {
+			return nil, err
+		}
+		result.Tags = decodeTags(tags)
+		result.Metadata = decodeMetadata(metadata)
+		result.Priority = search.Priority(priority)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// sqlitePlaceholders returns n comma-separated query placeholders.
+func sqlitePlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// Enqueue adds notifications to the SQLite outbox in a single transaction.
+func (s *SQLiteStorer) Enqueue(ctx context.Context, entries []OutboxEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		_, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox (ID, Notifier, Payload, Attempts, NextAttempt, CreatedAt, LastError)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(ID) DO NOTHING;`,
+			entry.ID, entry.Notifier, entry.Payload, entry.Attempts, entry.NextAttempt, entry.CreatedAt, entry.LastError)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DueOutbox returns queued notifications that are due for delivery, oldest first.
+func (s *SQLiteStorer) DueOutbox(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT ID, Notifier, Payload, Attempts, NextAttempt, CreatedAt, COALESCE(LastError, '')
+	FROM outbox WHERE NextAttempt <= ? ORDER BY CreatedAt LIMIT ?;`, now.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var entry OutboxEntry
+		if err := rows.Scan(&entry.ID, &entry.Notifier, &entry.Payload, &entry.Attempts, &entry.NextAttempt, &entry.CreatedAt, &entry.LastError); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// UpdateOutbox records a failed delivery attempt.
+func (s *SQLiteStorer) UpdateOutbox(ctx context.Context, entry OutboxEntry) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE outbox SET Attempts = ?, NextAttempt = ?, LastError = ? WHERE ID = ?;`,
+		entry.Attempts, entry.NextAttempt, entry.LastError, entry.ID)
+	return err
+}
+
+// DeleteOutbox removes a notification from the outbox.
+func (s *SQLiteStorer) DeleteOutbox(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM outbox WHERE ID = ?;`, id)
+	return err
+}
+
+// CountResults counts stored results by platform and keyword.
+func (s *SQLiteStorer) CountResults(ctx context.Context, since, until time.Time) ([]ResultCount, error) {
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+	if !since.IsZero() {
+		from = since.Unix()
+	}
+	if !until.IsZero() {
+		to = until.Unix()
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT Platform, COALESCE(Keyword, ''), COUNT(*) FROM search_results
+	WHERE Timestamp >= ? AND Timestamp < ?
+	GROUP BY Platform, Keyword ORDER BY Platform, Keyword;`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []ResultCount
+	for rows.Next() {
+		var count ResultCount
+		if err := rows.Scan(&count.Platform, &count.Keyword, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,273 @@
+// storage/sqlite_migrations.go
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+)
+
+// sqliteMigration is a single, ordered schema change. Migrations must be idempotent, because databases
+// created before schema versioning existed start at version 0 even though their tables already exist.
+type sqliteMigration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// sqliteMigrations are applied in order. Never edit or reorder a released migration; append a new one.
+var sqliteMigrations = []sqliteMigration{
+	{
+		version:     1,
+		description: "create search_results and last_search_time tables",
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS search_results (
+			Platform TEXT,
+			Keyword TEXT,
+			Title TEXT,
+			URL TEXT PRIMARY KEY,
+			Timestamp INTEGER
+		);
+		CREATE TABLE IF NOT EXISTS last_search_time (
+			Platform TEXT PRIMARY KEY,
+			LastSearchTime INTEGER
+		);`),
+	},
+	{
+		version:     2,
+		description: "add content and metadata columns to search_results",
+		up: func(tx *sql.Tx) error {
+			return addMissingColumns(tx, "search_results", []sqliteColumn{
+				{"Content", "TEXT"},
+				{"Author", "TEXT"},
+				{"Score", "INTEGER"},
+				{"Priority", "TEXT"},
+			})
+		},
+	},
+	{
+		version:     3,
+		description: "add content hash column and index to search_results",
+		up: func(tx *sql.Tx) error {
+			if err := addMissingColumns(tx, "search_results", []sqliteColumn{{"ContentHash", "TEXT"}}); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS search_results_content_hash ON search_results (ContentHash, Timestamp);`)
+			return err
+		},
+	},
+	{
+		version:     4,
+		description: "create notification outbox table",
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS outbox (
+			ID TEXT PRIMARY KEY,
+			Notifier TEXT,
+			Payload BLOB,
+			Attempts INTEGER,
+			NextAttempt INTEGER,
+			CreatedAt INTEGER,
+			LastError TEXT
+		);
+		CREATE INDEX IF NOT EXISTS outbox_next_attempt ON outbox (NextAttempt);`),
+	},
+	{
+		version:     5,
+		description: "add engagement columns to search_results",
+		up: func(tx *sql.Tx) error {
+			return addMissingColumns(tx, "search_results", []sqliteColumn{
+				{"Comments", "INTEGER"},
+				{"Reposts", "INTEGER"},
+				{"Views", "INTEGER"},
+			})
+		},
+	},
+	{
+		version:     6,
+		description: "create result_keywords table",
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS result_keywords (
+			Platform TEXT,
+			URL TEXT,
+			Keyword TEXT,
+			PRIMARY KEY (Platform, URL, Keyword)
+		);
+		INSERT OR IGNORE INTO result_keywords (Platform, URL, Keyword)
+		SELECT Platform, URL, Keyword FROM search_results WHERE COALESCE(Keyword, '') != '';`),
+	},
+	{
+		version:     7,
+		description: "add tags and metadata columns to search_results",
+		up: func(tx *sql.Tx) error {
+			return addMissingColumns(tx, "search_results", []sqliteColumn{
+				{"Tags", "TEXT"},
+				{"Metadata", "TEXT"},
+			})
+		},
+	},
+	{
+		version:     8,
+		description: "add DeletedAt column to search_results",
+		up: func(tx *sql.Tx) error {
+			return addMissingColumns(tx, "search_results", []sqliteColumn{
+				{"DeletedAt", "INTEGER"},
+			})
+		},
+	},
+	{
+		version:     9,
+		description: "create snapshots table",
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS snapshots (
+			Platform TEXT,
+			URL TEXT,
+			ArchivedAt INTEGER,
+			Result TEXT,
+			Page TEXT,
+			PRIMARY KEY (Platform, URL, ArchivedAt)
+		);`),
+	},
+	{
+		version:     10,
+		description: "create managed_keywords table",
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS managed_keywords (
+			Keyword TEXT PRIMARY KEY,
+			Paused INTEGER NOT NULL DEFAULT 0,
+			AddedAt INTEGER
+		);`),
+	},
+	{
+		version:     11,
+		description: "create run_history table",
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS run_history (
+			Started INTEGER NOT NULL,
+			Run TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS run_history_started ON run_history (Started);`),
+	},
+	{
+		version:     12,
+		description: "create tokens table",
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS tokens (
+			Key TEXT PRIMARY KEY,
+			Token TEXT NOT NULL
+		);`),
+	},
+	{
+		version:     13,
+		description: "add PausedUntil column to managed_keywords",
+		up: func(tx *sql.Tx) error {
+			return addMissingColumns(tx, "managed_keywords", []sqliteColumn{
+				{"PausedUntil", "INTEGER NOT NULL DEFAULT 0"},
+			})
+		},
+	},
+	{
+		version:     14,
+		description: "create leases table",
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS leases (
+			Name TEXT PRIMARY KEY,
+			Holder TEXT NOT NULL,
+			ExpiresAt INTEGER NOT NULL
+		);`),
+	},
+}
+
+// execMigration builds a migration step from plain SQL.
+func execMigration(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// migrateSQLite brings the database schema up to the latest version, one transaction per migration.
+func migrateSQLite(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (Version INTEGER NOT NULL);`); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(Version), 0) FROM schema_version;`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, migration := range sqliteMigrations {
+		if migration.version <= current {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := migration.up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.version, migration.description, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM schema_version;`); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record schema version %d: %w", migration.version, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_version (Version) VALUES (?);`, migration.version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record schema version %d: %w", migration.version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", migration.version, err)
+		}
+
+		log.Info("Applied SQLite migration", "version", migration.version, "description", migration.description)
+	}
+
+	return nil
+}
+
+type sqliteColumn struct {
+	name    string
+	colType string
+}
+
+// addMissingColumns adds any of the given columns that the table does not have yet.
+func addMissingColumns(tx *sql.Tx, table string, columns []sqliteColumn) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s);", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for _, column := range columns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column.name, column.colType)); err != nil {
+			return fmt.Errorf("failed to add column %s to %s: %w", column.name, table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// storage/state.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Result states track how a stored result has been handled. They are kept in its metadata, so setting
+// them needs a storer that can update results. Saving a new version of an edited result replaces its
+// metadata, so edited results are new again.
+const (
+	StateNew          = "new"
+	StateAcknowledged = "acknowledged"
+	StateEscalated    = "escalated"
+	StateDismissed    = "dismissed"
+	StateActioned     = "actioned"
+
+	// StateMetadata is the metadata key holding a result's state. Results without one are new.
+	StateMetadata = "state"
+	// StateChangedMetadata is the metadata key holding when a result's state was last set, as an RFC 3339
+	// time.
+	StateChangedMetadata = "state_changed_at"
+)
+
+// States lists the result states in the order results usually move through them.
+var States = []string{StateNew, StateAcknowledged, StateEscalated, StateDismissed, StateActioned}
+
+// ResultState returns a result's state.
+func ResultState(result search.SearchResult) string {
+	if state := result.Metadata[StateMetadata]; state != "" {
+		return state
+	}
+	return StateNew
+}
+
+// CheckStates returns an error naming the first of states that isn't one of States.
+func CheckStates(states ...string) error {
+	for _, state := range states {
+		if !slices.Contains(States, state) {
+			return fmt.Errorf("unknown state %q; use one of %v", state, States)
+		}
+	}
+	return nil
+}
+
+// SetState sets the state of the stored result at url and returns the updated result, reporting false if
+// there is none.
+func SetState(ctx context.Context, updater Updater, platform, url, state string) (search.SearchResult, bool, error) {
+	if err := CheckStates(state); err != nil {
+		return search.SearchResult{}, false, err
+	}
+	result, found, err := updater.Get(ctx, platform, url)
+	if err != nil || !found {
+		return result, found, err
+	}
+	result.Metadata = maps.Clone(result.Metadata)
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]string)
+	}
+	result.Metadata[StateMetadata] = state
+	result.Metadata[StateChangedMetadata] = time.Now().UTC().Format(time.RFC3339)
+	return result, true, updater.Update(ctx, result)
+}
@@ -1,12 +1,24 @@
 // storage/storage.go
 package storage
 
-import "github.com/jaxxstorm/grass/search"
+import (
+	"context"
+	"time"
+
+	"github.com/jaxxstorm/grass/search"
+)
 
 // Storer defines the methods required for storing search results.
 type Storer interface {
-	Exists(platform, url string) (bool, error)
-	Save(result search.SearchResult) error
-	GetLastSearchTime(platform string) (int64, error)
-	SetLastSearchTime(platform string, epochTime int64) error
+	Exists(ctx context.Context, platform, url string) (bool, error)
+	Save(ctx context.Context, result search.SearchResult) error
+	// SaveBatch stores several results at once, using a transaction or batch API where the backend has one.
+	SaveBatch(ctx context.Context, results []search.SearchResult) error
+	GetLastSearchTime(ctx context.Context, platform string) (int64, error)
+	SetLastSearchTime(ctx context.Context, platform string, epochTime int64) error
+	// Prune deletes stored results with a timestamp before olderThan. Last search times are kept.
+	Prune(ctx context.Context, olderThan time.Time) error
+	// FindByContentHash returns stored results with the given content hash and a timestamp at or after since,
+	// for detecting the same story posted to several platforms.
+	FindByContentHash(ctx context.Context, hash string, since time.Time) ([]search.SearchResult, error)
 }
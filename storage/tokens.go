@@ -0,0 +1,14 @@
+// storage/tokens.go
+package storage
+
+import "github.com/jaxxstorm/grass/search"
+
+// AsTokenStore returns the storer's token store if its backend can keep the tokens searchers authenticate
+// with, so they outlive the process. A MultiStorer keeps tokens in its primary.
+func AsTokenStore(s Storer) (search.TokenStore, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	store, ok := s.(search.TokenStore)
+	return store, ok
+}
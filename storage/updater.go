@@ -0,0 +1,28 @@
+// storage/updater.go
+package storage
+
+import (
+	"context"
+
+	"github.com/jaxxstorm/grass/search"
+)
+
+// Updater is implemented by storers that can read back and update stored results, so edits made to posts
+// after they were saved can be detected and recorded.
+type Updater interface {
+	// Get returns the stored result at url, reporting false if there is none.
+	Get(ctx context.Context, platform, url string) (search.SearchResult, bool, error)
+	// Update replaces the title, content, engagement, tags, metadata, content hash, and deletion time of a
+	// stored result. Results that aren't stored are ignored.
+	Update(ctx context.Context, result search.SearchResult) error
+}
+
+// AsUpdater returns the storer's updater if its backend has one. A MultiStorer reads and updates its
+// primary.
+func AsUpdater(s Storer) (Updater, bool) {
+	if m, ok := s.(*MultiStorer); ok {
+		s = m.primary
+	}
+	updater, ok := s.(Updater)
+	return updater, ok
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jaxxstorm/grass/storage"
+)
+
+// storageBackends lists every value accepted by --db and --secondary-db.
+var storageBackends = storage.Backends()
+
+// newStorer initializes a single storage backend by name, using table as its table, file, or key prefix.
+func newStorer(ctx context.Context, dbType, table string) (storage.Storer, error) {
+	storer, err := storage.New(ctx, dbType, table,
+		storage.WithHTTPClient(sharedHTTPClient),
+		storage.WithCreateTable(*dynamoCreateTable),
+		storage.WithRetention(*retention),
+		storage.WithTTL(*redisTTL),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s storage: %w", dbType, err)
+	}
+	return storer, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/internal/systemd"
+)
+
+// activatedListeners returns the sockets systemd passed to grass, read once since reading them clears the
+// activation variables.
+var activatedListeners = sync.OnceValues(systemd.Listeners)
+
+// listen returns the socket systemd passed for name, a FileDescriptorName such as api or grpc, or
+// otherwise listens on addr.
+func listen(name, addr string) (net.Listener, error) {
+	listeners, err := activatedListeners()
+	if err != nil {
+		return nil, err
+	}
+	if listener, ok := listeners[name]; ok {
+		log.Info("Using socket passed by systemd", "name", name, "addr", listener.Addr())
+		return listener, nil
+	}
+	return net.Listen("tcp", addr)
+}
+
+// stuckJobFactor is how many times the search and notify timeouts together a scheduled job may run before
+// grass is taken to have wedged. A job makes several bounded calls, so it can take longer than one of each.
+const stuckJobFactor = 10
+
+// stuckJobThreshold returns how long a scheduled job may run before grass is taken to have wedged, or zero
+// when a disabled timeout leaves jobs unbounded.
+func stuckJobThreshold() time.Duration {
+	if *searchTimeout <= 0 || *notifyTimeout <= 0 {
+		return 0
+	}
+	return stuckJobFactor * (*searchTimeout + *notifyTimeout)
+}
+
+// jobTracker reports the job running now, as a scheduler does.
+type jobTracker interface {
+	Current() (string, time.Time, bool)
+}
+
+// notifySystemd tells systemd grass is ready, when it runs as a Type=notify service, and keeps the
+// service's watchdog fed until ctx is cancelled. While sched runs a job for longer than stuckJobThreshold
+// allows, grass is taken to have wedged and the watchdog is left to expire, so systemd restarts it.
+// The returned function tells systemd grass is stopping.
+func notifySystemd(ctx context.Context, sched jobTracker) func() {
+	notified, err := systemd.Notify("READY=1")
+	if err != nil {
+		log.Warn("Failed to notify systemd", "error", err)
+	}
+	if !notified {
+		return func() {}
+	}
+	log.Debug("Notified systemd")
+
+	done := make(chan struct{})
+	if interval, ok := systemd.WatchdogInterval(); ok {
+		stuckAfter := stuckJobThreshold()
+		go func() {
+			ticker := time.NewTicker(interval / 2)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-done:
+					return
+				case <-ticker.C:
+				}
+				if sched != nil && stuckAfter > 0 {
+					if job, started, running := sched.Current(); running && time.Since(started) > stuckAfter {
+						log.Warn("Scheduled job is stuck; letting the systemd watchdog expire", "job", job, "running", time.Since(started).Round(time.Second))
+						continue
+					}
+				}
+				if _, err := systemd.Notify("WATCHDOG=1"); err != nil {
+					log.Warn("Failed to notify the systemd watchdog", "error", err)
+				}
+			}
+		}()
+	}
+	return func() {
+		close(done)
+		if _, err := systemd.Notify("STOPPING=1"); err != nil {
+			log.Warn("Failed to notify systemd", "error", err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// tenantNames returns the tenants in dir, one per YAML file named after the tenant, sorted by name.
+func tenantNames(dir string) (map[string]string, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := make(map[string]string)
+	var names []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if _, ok := paths[name]; ok {
+			return nil, nil, &os.PathError{Op: "load tenant", Path: filepath.Join(dir, entry.Name()), Err: os.ErrExist}
+		}
+		paths[name] = filepath.Join(dir, entry.Name())
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return paths, names, nil
+}
+
+// newTenantProfiles initializes a tenant's profiles and campaigns from its config file, exiting on invalid
+// configuration. Tenants are isolated from each other: their profiles are named "<tenant>/<profile>",
+// their storage tables default to "<tenant>-<profile>" (and "<tenant>-campaign-<campaign>"), and their
+// searchers are created with the tenant's env so credentials are never shared.
+func newTenantProfiles(ctx context.Context, tenant, path string) []*profile {
+	logger := log.With("tenant", tenant)
+	cfg, err := config.Load(path)
+	if err != nil {
+		logger.Fatalf("Failed to load tenant configuration: %v", err)
+	}
+	if err := resolveSecrets(cfg.Env); err != nil {
+		logger.Fatalf("Failed to resolve secret in tenant configuration: %v", err)
+	}
+	if len(cfg.Profiles) == 0 && len(cfg.Campaigns) == 0 {
+		logger.Fatalf("Tenant %q defines no profiles or campaigns", tenant)
+	}
+
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var profiles []*profile
+	withEnv(cfg.Env, func() {
+		searcherCache := make(map[string]search.Searcher)
+		for _, name := range names {
+			p := cfg.Profiles[name]
+			if p.TableName == "" {
+				p.TableName = tenant + "-" + name
+			}
+			profiles = append(profiles, newProfile(ctx, cfg, tenant+"/"+name, p, searcherCache))
+		}
+		for _, name := range campaignNames(cfg) {
+			if _, ok := cfg.Profiles[name]; ok {
+				logger.Fatalf("Campaign %q has the same name as a profile", name)
+			}
+			c := cfg.Campaigns[name]
+			if c.TableName == "" {
+				c.TableName = tenant + "-campaign-" + name
+			}
+			profiles = append(profiles, newCampaign(ctx, cfg, tenant+"/"+name, c, searcherCache))
+		}
+	})
+
+	logger.Info("Loaded tenant", "profiles", len(profiles))
+	return profiles
+}
+
+// withEnv runs fn with the given environment variables set, restoring the previous environment afterwards.
+// Searchers, notifiers, and storage read their credentials from the environment when they are created, so
+// this scopes credentials to the profiles created in fn. It must not run concurrently with other code
+// that reads the environment.
+func withEnv(env map[string]string, fn func()) {
+	type previous struct {
+		value string
+		set   bool
+	}
+	saved := make(map[string]previous, len(env))
+	for key, value := range env {
+		old, set := os.LookupEnv(key)
+		saved[key] = previous{value: old, set: set}
+		os.Setenv(key, value)
+	}
+	defer func() {
+		for key, old := range saved {
+			if old.set {
+				os.Setenv(key, old.value)
+			} else {
+				os.Unsetenv(key)
+			}
+		}
+	}()
+
+	fn()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+// timeValue is a flag holding a time given as a date (2006-01-02, midnight UTC) or an RFC 3339 time.
+type timeValue struct {
+	t *time.Time
+}
+
+// timeFlag registers clause as a time flag, returning where its value is stored. Unset flags leave the
+// zero time.
+func timeFlag(clause *kingpin.FlagClause) *time.Time {
+	var t time.Time
+	clause.SetValue(&timeValue{t: &t})
+	return &t
+}
+
+func (v *timeValue) Set(value string) error {
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			*v.t = t
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time %q: use a date like 2024-01-31 or an RFC 3339 time", value)
+}
+
+func (v *timeValue) String() string {
+	if v.t.IsZero() {
+		return ""
+	}
+	return v.t.Format(time.RFC3339)
+}
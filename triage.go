@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	triageCommand  = kingpin.Command("triage", "Set the state of stored results, to track which have been handled")
+	triageState    = triageCommand.Arg("state", "State to set: new, acknowledged, escalated, dismissed, or actioned").Required().Enum(storage.States...)
+	triageURLs     = triageCommand.Arg("url", "URLs of the results, as listed by the query command").Required().Strings()
+	triagePlatform = triageCommand.Flag("platform", "Platform of the results, e.g. HackerNews").Required().String()
+	triageProfile  = triageCommand.Flag("profile", "Triage the results of this profile from the config file instead of those in --db and --table-name").String()
+)
+
+// runTriage sets the state of the results named by the triage arguments in the profile's primary storage,
+// writing what it did to w.
+func runTriage(ctx context.Context, cfg *config.Config, w io.Writer) error {
+	storer, db, err := openStorage(ctx, cfg, *triageProfile)
+	if err != nil {
+		return err
+	}
+	if closer, ok := storer.(io.Closer); ok {
+		defer closer.Close()
+	}
+	updater, ok := storage.AsUpdater(storer)
+	if !ok {
+		return fmt.Errorf("%s storage can't keep result states; use sqlite or bolt", db)
+	}
+
+	for _, url := range *triageURLs {
+		_, found, err := storage.SetState(ctx, updater, *triagePlatform, url, *triageState)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("no stored %s result at %s", *triagePlatform, url)
+		}
+		fmt.Fprintf(w, "Marked %s %s\n", url, *triageState)
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+var (
+	tuiCommand = kingpin.Command("tui", "Browse stored results in an interactive terminal dashboard")
+	tuiProfile = tuiCommand.Flag("profile", "Browse the storage of this profile from the config file instead of --db and --table-name").String()
+	tuiLimit   = tuiCommand.Flag("limit", "Number of recent results to load (0 loads every result)").Default("500").Int()
+	tuiRefresh = tuiCommand.Flag("refresh", "Reload results this often, to follow results stored by a running daemon (0 disables)").Default("10s").Duration()
+)
+
+// tuiDetailLines is how many lines the selected result's details take below the list.
+const tuiDetailLines = 6
+
+var (
+	tuiHeaderStyle   = lipgloss.NewStyle().Bold(true)
+	tuiSelectedStyle = lipgloss.NewStyle().Reverse(true)
+	tuiDimStyle      = lipgloss.NewStyle().Faint(true)
+)
+
+// runTUI shows the results stored in the profile's primary storage until the user quits.
+func runTUI(ctx context.Context, cfg *config.Config, location *time.Location) error {
+	storer, db, err := openStorage(ctx, cfg, *tuiProfile)
+	if err != nil {
+		return err
+	}
+	if closer, ok := storer.(io.Closer); ok {
+		defer closer.Close()
+	}
+	querier, ok := storage.AsQuerier(storer)
+	if !ok {
+		return fmt.Errorf("%s storage can't be browsed", db)
+	}
+
+	// Results can only be triaged in storage that can update them
+	updater, _ := storage.AsUpdater(storer)
+	model := &tuiModel{ctx: ctx, querier: querier, updater: updater, location: location}
+	_, err = tea.NewProgram(model, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	return err
+}
+
+// tuiResultsMsg carries freshly loaded results.
+type tuiResultsMsg struct {
+	results []search.SearchResult
+	err     error
+}
+
+// tuiTickMsg asks for the results to be reloaded.
+type tuiTickMsg struct{}
+
+// tuiStateMsg carries a result whose state was set.
+type tuiStateMsg struct {
+	result search.SearchResult
+	err    error
+}
+
+// tuiStateKeys are the keys that set the selected result's state.
+var tuiStateKeys = map[string]string{
+	"n": storage.StateNew,
+	"a": storage.StateAcknowledged,
+	"e": storage.StateEscalated,
+	"x": storage.StateDismissed,
+	"d": storage.StateActioned,
+}
+
+// tuiModel is the dashboard's state: every loaded result, the filters applied to them, and the position of
+// the selection in the filtered list.
+type tuiModel struct {
+	ctx      context.Context
+	querier  storage.Querier
+	updater  storage.Updater
+	location *time.Location
+
+	results []search.SearchResult
+	visible []search.SearchResult
+	// platform and keyword filter the list when set, cycling through the values of the loaded results.
+	// state filters it when set, cycling through every state.
+	platform, keyword, state string
+
+	cursor, offset int
+	width, height  int
+	loaded         time.Time
+	status         string
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return m.load
+}
+
+// load queries the most recent results.
+func (m *tuiModel) load() tea.Msg {
+	results, err := m.querier.Query(m.ctx, storage.Query{
+		Keywords: *keywords,
+		Since:    *since,
+		Until:    *until,
+		Limit:    *tuiLimit,
+	})
+	return tuiResultsMsg{results: results, err: err}
+}
+
+// tick schedules the next reload, if results are refreshed.
+func (m *tuiModel) tick() tea.Cmd {
+	if *tuiRefresh <= 0 {
+		return nil
+	}
+	return tea.Tick(*tuiRefresh, func(time.Time) tea.Msg { return tuiTickMsg{} })
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.scroll()
+	case tuiTickMsg:
+		return m, m.load
+	case tuiResultsMsg:
+		if msg.err != nil {
+			m.status = "Loading failed: " + msg.err.Error()
+		} else {
+			m.results = msg.results
+			m.loaded = time.Now()
+			m.filter()
+		}
+		return m, m.tick()
+	case tuiStateMsg:
+		if msg.err != nil {
+			m.status = "Setting state failed: " + msg.err.Error()
+			return m, nil
+		}
+		for i, result := range m.results {
+			if result.Platform == msg.result.Platform && result.URL == msg.result.URL {
+				m.results[i] = msg.result
+			}
+		}
+		m.status = "Marked " + storage.ResultState(msg.result)
+		m.filter()
+	case tea.KeyMsg:
+		return m, m.key(msg)
+	}
+	return m, nil
+}
+
+// key handles a key press.
+func (m *tuiModel) key(msg tea.KeyMsg) tea.Cmd {
+	m.status = ""
+	switch msg.String() {
+	case "q", "ctrl+c", "esc":
+		return tea.Quit
+	case "up", "k":
+		m.cursor--
+	case "down", "j":
+		m.cursor++
+	case "pgup":
+		m.cursor -= m.listHeight()
+	case "pgdown", " ":
+		m.cursor += m.listHeight()
+	case "home", "g":
+		m.cursor = 0
+	case "end", "G":
+		m.cursor = len(m.visible) - 1
+	case "p":
+		m.platform = nextFilter(m.platform, m.values(func(r search.SearchResult) string { return r.Platform }))
+		m.filter()
+	case "w":
+		m.keyword = nextFilter(m.keyword, m.values(func(r search.SearchResult) string { return r.Keyword }))
+		m.filter()
+	case "s":
+		m.state = nextFilter(m.state, storage.States)
+		m.filter()
+	case "c":
+		m.platform, m.keyword, m.state = "", "", ""
+		m.filter()
+	case "n", "a", "e", "x", "d":
+		if result, ok := m.selected(); ok {
+			return m.setState(result, tuiStateKeys[msg.String()])
+		}
+	case "r":
+		return m.load
+	case "enter", "o":
+		if result, ok := m.selected(); ok {
+			if err := openBrowser(result.URL); err != nil {
+				m.status = "Opening failed: " + err.Error()
+			} else {
+				m.status = "Opened " + result.URL
+			}
+		}
+	}
+	m.scroll()
+	return nil
+}
+
+// setState sets a result's state in storage.
+func (m *tuiModel) setState(result search.SearchResult, state string) tea.Cmd {
+	if m.updater == nil {
+		m.status = "This storage can't keep result states"
+		return nil
+	}
+	return func() tea.Msg {
+		updated, found, err := storage.SetState(m.ctx, m.updater, result.Platform, result.URL, state)
+		if err == nil && !found {
+			err = fmt.Errorf("result no longer stored")
+		}
+		return tuiStateMsg{result: updated, err: err}
+	}
+}
+
+// filter rebuilds the visible list from the loaded results, keeping the selected result selected.
+func (m *tuiModel) filter() {
+	selected, hadSelection := m.selected()
+	m.visible = m.visible[:0]
+	for _, result := range m.results {
+		if (m.platform == "" || result.Platform == m.platform) && (m.keyword == "" || result.Keyword == m.keyword) &&
+			(m.state == "" || storage.ResultState(result) == m.state) {
+			m.visible = append(m.visible, result)
+		}
+	}
+	if hadSelection && m.cursor > 0 {
+		m.cursor = slices.IndexFunc(m.visible, func(r search.SearchResult) bool {
+			return r.Platform == selected.Platform && r.URL == selected.URL
+		})
+	}
+	m.scroll()
+}
+
+// values returns the distinct values of a field across the loaded results, sorted.
+func (m *tuiModel) values(field func(search.SearchResult) string) []string {
+	var values []string
+	for _, result := range m.results {
+		if value := field(result); !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	slices.Sort(values)
+	return values
+}
+
+// nextFilter returns the value after current in values, cycling back to "" (no filter) after the last.
+func nextFilter(current string, values []string) string {
+	i := slices.Index(values, current)
+	if i+1 < len(values) {
+		return values[i+1]
+	}
+	return ""
+}
+
+// selected returns the result under the cursor.
+func (m *tuiModel) selected() (search.SearchResult, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return search.SearchResult{}, false
+	}
+	return m.visible[m.cursor], true
+}
+
+// listHeight is how many results fit on screen.
+func (m *tuiModel) listHeight() int {
+	return max(m.height-tuiDetailLines-4, 1)
+}
+
+// scroll keeps the cursor within the list and the list scrolled to show it.
+func (m *tuiModel) scroll() {
+	m.cursor = max(min(m.cursor, len(m.visible)-1), 0)
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.listHeight() {
+		m.offset = m.cursor - m.listHeight() + 1
+	}
+}
+
+func (m *tuiModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	line := lipgloss.NewStyle().MaxWidth(m.width)
+	var b strings.Builder
+
+	filters := fmt.Sprintf("platform: %s  keyword: %s  state: %s", orAll(m.platform), orAll(m.keyword), orAll(m.state))
+	header := fmt.Sprintf("grass  %d of %d results  %s", len(m.visible), len(m.results), filters)
+	if !m.loaded.IsZero() {
+		header += "  loaded " + m.loaded.In(m.location).Format(time.Kitchen)
+	}
+	b.WriteString(line.Render(tuiHeaderStyle.Render(header)) + "\n\n")
+
+	end := min(m.offset+m.listHeight(), len(m.visible))
+	for i := m.offset; i < end; i++ {
+		result := m.visible[i]
+		row := fmt.Sprintf("%s  %-10s  %-15s  %-12s  %s",
+			time.Unix(result.Timestamp, 0).In(m.location).Format("Jan 02 15:04"),
+			result.Platform, queryTitle(result.Keyword), storage.ResultState(result), queryTitle(result.Title))
+		row = line.Render(row)
+		if i == m.cursor {
+			row = tuiSelectedStyle.Render(row)
+		}
+		b.WriteString(row + "\n")
+	}
+	b.WriteString(strings.Repeat("\n", m.listHeight()-(end-m.offset)))
+
+	b.WriteString(tuiDimStyle.Render(line.Render(strings.Repeat("─", m.width))) + "\n")
+	detail := make([]string, 0, tuiDetailLines)
+	if result, ok := m.selected(); ok {
+		detail = append(detail, tuiHeaderStyle.Render(line.Render(queryTitle(result.Title))))
+		detail = append(detail, line.Render(fmt.Sprintf("%s · %d points · %d comments · %s", result.Author, result.Score, result.Comments, result.URL)))
+		content := lipgloss.NewStyle().Width(m.width).Render(strings.Join(strings.Fields(result.Content), " "))
+		detail = append(detail, strings.Split(content, "\n")...)
+	}
+	for len(detail) < tuiDetailLines {
+		detail = append(detail, "")
+	}
+	b.WriteString(strings.Join(detail[:tuiDetailLines], "\n") + "\n")
+
+	footer := "↑/↓ move  enter open  a/e/x/d/n acknowledge/escalate/dismiss/done/new  p platform  w keyword  s state  c clear  r reload  q quit"
+	if m.status != "" {
+		footer = m.status
+	}
+	b.WriteString(tuiDimStyle.Render(line.Render(footer)))
+	return b.String()
+}
+
+// orAll names an unset filter.
+func orAll(value string) string {
+	if value == "" {
+		return "all"
+	}
+	return value
+}
+
+// openBrowser opens a URL in the default browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/config"
+	"github.com/jaxxstorm/grass/internal/scheduler"
+	"github.com/jaxxstorm/grass/plugin"
+	"github.com/jaxxstorm/grass/search"
+)
+
+var (
+	configCommand   = kingpin.Command("config", "Work with the config file")
+	validateCommand = configCommand.Command("validate", "Check the config file for unknown keys, invalid settings, conflicts, and missing credentials without connecting to anything, exiting non-zero on problems")
+	validateFile    = validateCommand.Arg("file", "Config file to check (default: --config)").String()
+)
+
+// credentialChecks report what the environment is missing for each built-in searcher, notifier, and
+// storage backend that needs credentials, as they would fail when created. Notifiers' channels are those
+// set in the config file, if any.
+var credentialChecks = map[string]func(channels []string) error{
+	"reddit": func([]string) error {
+		if os.Getenv("REDDIT_REFRESH_TOKEN") != "" {
+			return missingEnv("REDDIT_CLIENT_ID")
+		}
+		return missingEnv("REDDIT_CLIENT_ID", "REDDIT_CLIENT_SECRET", "REDDIT_USERNAME", "REDDIT_PASSWORD")
+	},
+	"bluesky": func([]string) error {
+		return missingEnv("BSKY_USERNAME", "BSKY_PASSWORD")
+	},
+	"fediverse": func([]string) error {
+		instances := os.Getenv("FEDIVERSE_INSTANCES")
+		if instances == "" {
+			return missingEnv("FEDIVERSE_INSTANCES")
+		}
+		var errs []error
+		for _, instance := range strings.Split(instances, ",") {
+			prefix := search.FediverseEnvPrefix(strings.TrimSpace(instance))
+			if os.Getenv(prefix+"_ACCESS_TOKEN") != "" {
+				continue
+			}
+			if err := missingEnv(prefix+"_CLIENT_ID", prefix+"_CLIENT_SECRET"); err != nil {
+				errs = append(errs, fmt.Errorf("%w, or %s_ACCESS_TOKEN", err, prefix))
+			}
+		}
+		return errors.Join(errs...)
+	},
+	"youtube": func([]string) error {
+		if os.Getenv("YOUTUBE_REFRESH_TOKEN") != "" {
+			return missingEnv("YOUTUBE_CLIENT_ID", "YOUTUBE_CLIENT_SECRET")
+		}
+		return missingEnv("YOUTUBE_API_KEY")
+	},
+	"slack": func(channels []string) error {
+		if len(channels) > 0 {
+			return missingEnv("SLACK_BOT_TOKEN")
+		}
+		return missingEnv("SLACK_BOT_TOKEN", "SLACK_CHANNEL_ID")
+	},
+	"discord": func(channels []string) error {
+		if len(channels) > 0 {
+			return missingEnv("DISCORD_BOT_TOKEN")
+		}
+		return missingEnv("DISCORD_BOT_TOKEN", "DISCORD_CHANNEL_ID")
+	},
+	"elasticsearch": func([]string) error {
+		return missingEnv("ELASTICSEARCH_URL")
+	},
+	"s3": func([]string) error {
+		return missingEnv("S3_BUCKET")
+	},
+	"gcs": func([]string) error {
+		return missingEnv("GCS_BUCKET")
+	},
+}
+
+// missingEnv returns an error naming those of the environment variables that aren't set, if any.
+func missingEnv(names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if os.Getenv(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+}
+
+// runValidate checks the config file given to the validate command, or --config, writing each problem
+// found to w. Profiles are checked after falling back to flags, as a run would, and credentials are checked
+// in the environment with the file's env settings applied, but nothing is connected to. It returns an
+// error if there are any problems.
+func runValidate(w io.Writer) error {
+	path := firstNonEmpty(*validateFile, *configFile)
+	if path == "" {
+		return fmt.Errorf("no config file to check; pass one or set --config")
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return fmt.Errorf("%s is invalid", path)
+	}
+
+	var problems []string
+	withEnv(cfg.Env, func() {
+		problems = validateConfig(cfg)
+	})
+	for _, problem := range problems {
+		fmt.Fprintln(w, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s has %d problem(s)", path, len(problems))
+	}
+	fmt.Fprintf(w, "%s is valid\n", path)
+	return nil
+}
+
+// validateConfig returns the problems with every profile and campaign in cfg, and its logging settings.
+func validateConfig(cfg *config.Config) []string {
+	var problems []string
+	problem := func(where, format string, args ...any) {
+		problems = append(problems, where+": "+fmt.Sprintf(format, args...))
+	}
+
+	if cfg.Log.Level != "" {
+		if _, err := log.ParseLevel(cfg.Log.Level); err != nil {
+			problem("log", "%v", err)
+		}
+	}
+	if _, ok := logFormats[cfg.Log.Format]; cfg.Log.Format != "" && !ok {
+		problem("log", "invalid format %q: use text, json, or logfmt", cfg.Log.Format)
+	}
+
+	// Profiles sharing storage would share results and last search times
+	tables := make(map[string]string)
+	checkProfile := func(where, name string, p config.Profile) {
+		p = profileDefaults(cfg, name, p)
+		for _, err := range validateProfile(cfg, p) {
+			problem(where, "%v", err)
+		}
+		table := p.DB + "/" + p.TableName
+		if other, ok := tables[table]; ok {
+			problem(where, "shares %s storage table %q with %s; set a different table_name", p.DB, p.TableName, other)
+		} else {
+			tables[table] = where
+		}
+	}
+
+	names, profileCfgs := profileConfigs(cfg)
+	for _, name := range names {
+		where := "profile " + name
+		if name == "" {
+			where = "default profile"
+		}
+		checkProfile(where, name, profileCfgs[name])
+	}
+	for _, name := range campaignNames(cfg) {
+		c := cfg.Campaigns[name]
+		where := "campaign " + name
+		if _, ok := cfg.Profiles[name]; ok {
+			problem(where, "has the same name as a profile")
+		}
+		if len(c.Keywords) == 0 {
+			problem(where, "has no keywords")
+		}
+		start, err := parseCampaignTime(c.Start, false)
+		if err != nil {
+			problem(where, "invalid start: %v", err)
+		}
+		end, err := parseCampaignTime(c.End, true)
+		if err != nil {
+			problem(where, "invalid end: %v", err)
+		}
+		if !start.IsZero() && !end.IsZero() && !end.After(start) {
+			problem(where, "ends before it starts")
+		}
+		if c.TableName == "" {
+			c.TableName = "campaign-" + name
+		}
+		checkProfile(where, name, c.Profile)
+	}
+	return problems
+}
+
+// validateProfile returns the problems with a profile whose unset settings have been filled in from flags
+// and the top-level configuration.
+func validateProfile(cfg *config.Config, p config.Profile) []error {
+	var errs []error
+
+	for _, keyword := range p.Keywords {
+		if _, err := search.ParseQuery(keyword); err != nil {
+			errs = append(errs, fmt.Errorf("invalid keyword: %w", err))
+		}
+	}
+	for _, account := range p.Accounts {
+		if _, ok := search.ParseAccount(search.AccountPrefix + account); !ok {
+			errs = append(errs, fmt.Errorf("invalid account %q: expected <searcher>/<account>", account))
+		}
+	}
+
+	for _, db := range append([]string{p.DB}, p.SecondaryDBs...) {
+		if !slices.Contains(storageBackends, db) {
+			errs = append(errs, fmt.Errorf("unknown storage backend %q: use %s", db, strings.Join(storageBackends, ", ")))
+			continue
+		}
+		if check, ok := credentialChecks[db]; ok {
+			if err := check(nil); err != nil {
+				errs = append(errs, fmt.Errorf("%s storage: %w", db, err))
+			}
+		}
+	}
+
+	for _, name := range p.Searchers {
+		if !slices.Contains(search.Registered(), name) {
+			if _, err := plugin.Find(pluginDirectory(), plugin.KindSearcher, name); err != nil {
+				errs = append(errs, fmt.Errorf("unknown searcher %s: %w", name, err))
+			}
+			continue
+		}
+		if check, ok := credentialChecks[name]; ok {
+			if err := check(nil); err != nil {
+				errs = append(errs, fmt.Errorf("%s searcher: %w", name, err))
+			}
+		}
+	}
+
+	for _, botType := range p.Bots {
+		notifierCfg, ok := p.Notifiers[botType]
+		if !ok {
+			notifierCfg = cfg.Notifiers[botType]
+		}
+		for _, err := range validateNotifier(botType, notifierCfg) {
+			errs = append(errs, fmt.Errorf("%s notifier: %w", botType, err))
+		}
+	}
+	for _, botType := range sortedKeys(p.Notifiers) {
+		if !slices.Contains(p.Bots, botType) {
+			errs = append(errs, fmt.Errorf("notifiers sets up %s, which isn't one of the bots", botType))
+		}
+	}
+
+	if _, err := bot.NewRouter(p.Routing, p.Bots); err != nil {
+		errs = append(errs, fmt.Errorf("invalid routing: %w", err))
+	}
+	if _, err := bot.NewPrioritizer(p.Priorities); err != nil {
+		errs = append(errs, fmt.Errorf("invalid priority rules: %w", err))
+	}
+	if _, err := bot.NewFilter(p.Filters); err != nil {
+		errs = append(errs, fmt.Errorf("invalid filters: %w", err))
+	}
+
+	checkSchedule := func(what, expr string) {
+		if _, err := scheduler.Parse(expr); expr != "" && err != nil {
+			errs = append(errs, fmt.Errorf("invalid schedule for %s: %w", what, err))
+		}
+	}
+	checkSchedule("default", p.Schedule.Default)
+	for _, searcher := range sortedKeys(p.Schedule.Searchers) {
+		if !slices.Contains(p.Searchers, searcher) {
+			errs = append(errs, fmt.Errorf("schedule is set for searcher %s, which isn't one of the searchers", searcher))
+		}
+		checkSchedule("searcher "+searcher, p.Schedule.Searchers[searcher])
+	}
+	for _, keyword := range sortedKeys(p.Schedule.Keywords) {
+		checkSchedule("keyword "+keyword, p.Schedule.Keywords[keyword])
+	}
+	return errs
+}
+
+// validateNotifier returns the problems with a notifier's settings and credentials. Notifier plugins only
+// need to be installed.
+func validateNotifier(botType string, notifierCfg config.Notifier) []error {
+	if !slices.Contains(bot.RegisteredNotifiers(), botType) {
+		if _, err := plugin.Find(pluginDirectory(), plugin.KindNotifier, botType); err != nil {
+			return []error{fmt.Errorf("unknown bot type: %w", err)}
+		}
+		return nil
+	}
+
+	var errs []error
+	if check, ok := credentialChecks[botType]; ok {
+		if err := check(notifierCfg.Channels); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, err := bot.ParseTemplate(botType, notifierCfg.Template, ""); notifierCfg.Template != "" && err != nil {
+		errs = append(errs, fmt.Errorf("invalid template: %w", err))
+	}
+	if _, err := bot.ParseTemplate(botType+" digest", notifierCfg.DigestTemplate, ""); notifierCfg.DigestTemplate != "" && err != nil {
+		errs = append(errs, fmt.Errorf("invalid digest_template: %w", err))
+	}
+	if _, err := bot.ParseMentions(notifierCfg.Mentions); err != nil {
+		errs = append(errs, fmt.Errorf("invalid mentions: %w", err))
+	}
+	if _, err := time.ParseDuration(notifierCfg.DigestWindow); notifierCfg.DigestWindow != "" && err != nil {
+		errs = append(errs, fmt.Errorf("invalid digest_window: %w", err))
+	}
+	if _, err := time.ParseDuration(notifierCfg.RateWindow); notifierCfg.RateWindow != "" && err != nil {
+		errs = append(errs, fmt.Errorf("invalid rate_window: %w", err))
+	}
+	if overflow := notifierCfg.RateOverflow; overflow != "" && !slices.Contains(bot.ThrottleOverflows, overflow) {
+		errs = append(errs, fmt.Errorf("invalid rate_overflow %q: use %s", overflow, strings.Join(bot.ThrottleOverflows, " or ")))
+	}
+	return errs
+}
+
+// sortedKeys returns the keys of m in order, so problems are reported in the same order every time.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jaxxstorm/grass/search"
+	"github.com/mattn/go-isatty"
+)
+
+var (
+	watchCommand = kingpin.Command("watch", "Run the daemon, streaming new results to the terminal as they are found")
+	watchContent = watchCommand.Flag("content", "Show up to this many characters of each result's content (0 hides it)").Default("200").Int()
+)
+
+// watchPlatformColors are the ANSI colors platforms are shown in, chosen by hashing the platform's name so
+// each keeps its color between runs.
+var watchPlatformColors = []lipgloss.Color{"6", "5", "4", "3", "2", "14", "13", "12", "11", "10"}
+
+// watchResults writes every result saved through feed to w as it arrives, until ctx is cancelled. The
+// returned function waits for the last result to be written.
+func watchResults(ctx context.Context, feed *resultFeed, w io.Writer, location *time.Location) func() {
+	saved, unsubscribe := feed.subscribe()
+	// Terminals make URLs clickable through OSC 8 hyperlinks, which other outputs would show as noise
+	links := false
+	if f, ok := w.(*os.File); ok {
+		links = isatty.IsTerminal(f.Fd())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case r := <-saved:
+				fmt.Fprint(w, watchEntry(r, location, links))
+			}
+		}
+	}()
+	return func() { <-done }
+}
+
+// watchEntry formats a saved result for the live feed.
+func watchEntry(r savedResult, location *time.Location, links bool) string {
+	result := r.result
+	bold := lipgloss.NewStyle().Bold(true)
+	dim := lipgloss.NewStyle().Faint(true)
+
+	header := []string{
+		dim.Render(time.Unix(result.Timestamp, 0).In(location).Format("15:04:05")),
+		lipgloss.NewStyle().Bold(true).Foreground(watchPlatformColor(result.Platform)).Render(result.Platform),
+	}
+	if r.profile != "" {
+		header = append(header, dim.Render(r.profile))
+	}
+	header = append(header, strings.Join(resultKeywordList(result), ", "))
+	switch result.Priority {
+	case search.PriorityCritical:
+		header = append(header, lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("1")).Render("CRITICAL"))
+	case search.PriorityWarn:
+		header = append(header, lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("WARN"))
+	}
+	if result.Edited {
+		header = append(header, dim.Render("(updated)"))
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(header, "  ") + "\n")
+	b.WriteString("  " + bold.Render(queryTitle(result.Title)) + "\n")
+	if content := strings.Join(strings.Fields(result.Content), " "); content != "" && *watchContent > 0 {
+		if len([]rune(content)) > *watchContent {
+			content = string([]rune(content)[:*watchContent]) + "…"
+		}
+		b.WriteString("  " + content + "\n")
+	}
+	url := lipgloss.NewStyle().Foreground(lipgloss.Color("4")).Render(result.URL)
+	if links {
+		url = "\x1b]8;;" + result.URL + "\x1b\\" + url + "\x1b]8;;\x1b\\"
+	}
+	details := fmt.Sprintf("%d points · %d comments", result.Score, result.Comments)
+	if result.Author != "" {
+		details = result.Author + " · " + details
+	}
+	b.WriteString("  " + url + "  " + dim.Render(details) + "\n\n")
+	return b.String()
+}
+
+// watchPlatformColor returns the color a platform is shown in.
+func watchPlatformColor(platform string) lipgloss.Color {
+	h := fnv.New32a()
+	h.Write([]byte(platform))
+	return watchPlatformColors[h.Sum32()%uint32(len(watchPlatformColors))]
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/search"
+)
+
+// webhookMaxBody caps the size of a webhook request body.
+const webhookMaxBody = 1 << 20
+
+// webhookPlatform is the platform recorded for pushed results that don't name one.
+const webhookPlatform = "webhook"
+
+// webhookRequest is the JSON body accepted by the webhook server. The top-level keyword applies to results
+// that don't set their own.
+type webhookRequest struct {
+	Keyword string          `json:"keyword"`
+	Results []webhookResult `json:"results"`
+}
+
+// webhookResult is a pushed result. Timestamp is in Unix seconds and defaults to the time it is received.
+type webhookResult struct {
+	Platform  string            `json:"platform"`
+	Keyword   string            `json:"keyword"`
+	Title     string            `json:"title"`
+	URL       string            `json:"url"`
+	Timestamp int64             `json:"timestamp"`
+	Content   string            `json:"content"`
+	Author    string            `json:"author"`
+	Score     int64             `json:"score"`
+	Comments  int64             `json:"comments"`
+	Reposts   int64             `json:"reposts"`
+	Views     int64             `json:"views"`
+	Tags      []string          `json:"tags"`
+	Metadata  map[string]string `json:"metadata"`
+	Link      string            `json:"link"`
+}
+
+// webhookResponse reports how many results were received and how many of them were new.
+type webhookResponse struct {
+	Received int    `json:"received"`
+	New      int    `json:"new"`
+	Error    string `json:"error,omitempty"`
+}
+
+// webhookHandler feeds results pushed to /webhook into the unnamed profile, and those pushed to
+// /webhook/<profile> into the named profile.
+type webhookHandler struct {
+	profiles map[string]*profile
+	token    string
+}
+
+// newWebhookHandler creates a handler for the profiles. Requests must carry token as a bearer token unless
+// it is empty.
+func newWebhookHandler(profiles []*profile, token string) *webhookHandler {
+	h := &webhookHandler{profiles: make(map[string]*profile, len(profiles)), token: token}
+	for _, p := range profiles {
+		h.profiles[p.name] = p
+	}
+	return h
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutPrefix(r.URL.Path, "/webhook")
+	if !ok || (name != "" && !strings.HasPrefix(name, "/")) {
+		http.NotFound(w, r)
+		return
+	}
+	name = strings.TrimPrefix(name, "/")
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeWebhookResponse(w, http.StatusMethodNotAllowed, webhookResponse{Error: "method not allowed"})
+		return
+	}
+	if !h.authorized(r) {
+		writeWebhookResponse(w, http.StatusUnauthorized, webhookResponse{Error: "unauthorized"})
+		return
+	}
+	p, ok := h.profiles[name]
+	if !ok {
+		writeWebhookResponse(w, http.StatusNotFound, webhookResponse{Error: fmt.Sprintf("unknown profile %q", name)})
+		return
+	}
+	if !p.active(time.Now()) {
+		writeWebhookResponse(w, http.StatusConflict, webhookResponse{Error: fmt.Sprintf("campaign %q is not running", name)})
+		return
+	}
+
+	results, err := decodeWebhook(w, r)
+	if err != nil {
+		writeWebhookResponse(w, http.StatusBadRequest, webhookResponse{Error: err.Error()})
+		return
+	}
+
+	log.Info("Received webhook results", "profile", p.name, "count", len(results))
+	created, err := p.bot.Ingest(r.Context(), results)
+	resp := webhookResponse{Received: len(results), New: created}
+	if err != nil {
+		resp.Error = err.Error()
+		writeWebhookResponse(w, http.StatusInternalServerError, resp)
+		return
+	}
+	writeWebhookResponse(w, http.StatusOK, resp)
+}
+
+// authorized checks the request's bearer token.
+func (h *webhookHandler) authorized(r *http.Request) bool {
+	return h.token == "" || hasBearerToken(r, h.token)
+}
+
+// decodeWebhook parses and validates a webhook body into search results.
+func decodeWebhook(w http.ResponseWriter, r *http.Request) ([]search.SearchResult, error) {
+	var req webhookRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, webhookMaxBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	if len(req.Results) == 0 {
+		return nil, errors.New("no results in request")
+	}
+
+	now := time.Now().Unix()
+	results := make([]search.SearchResult, 0, len(req.Results))
+	for i, pushed := range req.Results {
+		if pushed.URL == "" {
+			return nil, fmt.Errorf("result %d: url is required", i)
+		}
+		if pushed.Title == "" && pushed.Content == "" {
+			return nil, fmt.Errorf("result %d: title or content is required", i)
+		}
+		result := search.SearchResult{
+			Platform:  pushed.Platform,
+			Keyword:   pushed.Keyword,
+			Title:     pushed.Title,
+			URL:       pushed.URL,
+			Timestamp: pushed.Timestamp,
+			Content:   pushed.Content,
+			Author:    pushed.Author,
+			Score:     pushed.Score,
+			Comments:  pushed.Comments,
+			Reposts:   pushed.Reposts,
+			Views:     pushed.Views,
+			Tags:      pushed.Tags,
+			Metadata:  pushed.Metadata,
+			Link:      pushed.Link,
+		}
+		if result.Platform == "" {
+			result.Platform = webhookPlatform
+		}
+		if result.Keyword == "" {
+			result.Keyword = req.Keyword
+		}
+		if result.Keyword != "" {
+			if _, err := search.ParseQuery(result.Keyword); err != nil {
+				return nil, fmt.Errorf("result %d: invalid keyword: %w", i, err)
+			}
+		}
+		if result.Timestamp == 0 {
+			result.Timestamp = now
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func writeWebhookResponse(w http.ResponseWriter, status int, resp webhookResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Debug("Failed to write webhook response", "error", err)
+	}
+}
+
+// serveWebhooks accepts webhooks on addr until ctx is cancelled, then waits for in-flight requests to finish.
+func serveWebhooks(ctx context.Context, addr string, profiles []*profile, token string) error {
+	if token == "" {
+		log.Warn("Webhook server accepts unauthenticated requests; set --webhook-token to require a bearer token")
+	}
+
+	log.Info("Serving webhooks", "addr", addr)
+	return listenAndServe(ctx, &http.Server{
+		Addr:              addr,
+		Handler:           newWebhookHandler(profiles, token),
+		ReadHeaderTimeout: 10 * time.Second,
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/storage"
+)
+
+func TestWebhookKeywordlessResult(t *testing.T) {
+	storer, err := storage.NewNDJSONStorer(filepath.Join(t.TempDir(), "results"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storer.Close()
+	p := &profile{bot: bot.NewBot(nil, storer, nil, nil), storer: storer}
+	handler := newWebhookHandler([]*profile{p}, "")
+
+	body := `{"results": [{"title": "Pushed without a keyword", "url": "https://example.com/pushed"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var resp webhookResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Received != 1 || resp.New != 1 {
+		t.Errorf("response = %+v, want 1 received and 1 new", resp)
+	}
+
+	results, err := storer.Query(context.Background(), storage.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Keyword != "" || results[0].Platform != webhookPlatform {
+		t.Errorf("stored results = %+v, want one keyword-less %s result", results, webhookPlatform)
+	}
+}
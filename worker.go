@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/charmbracelet/log"
+	"github.com/jaxxstorm/grass/bot"
+	"github.com/jaxxstorm/grass/internal/queue"
+	"github.com/jaxxstorm/grass/search"
+)
+
+var (
+	workerCommand    = kingpin.Command("worker", "Run search jobs taken from an SQS queue or NATS subject until interrupted")
+	workerQueue      = workerCommand.Flag("queue", "Queue to take jobs from: an SQS queue URL, or nats://<host>/<subject>[?group=<group>]").Envar("GRASS_QUEUE").Required().String()
+	workerJobs       = workerCommand.Flag("jobs", "Number of jobs to run at once").Envar("GRASS_WORKER_JOBS").Default("1").Int()
+	workerJobTimeout = workerCommand.Flag("job-timeout", "Abandon a job that takes longer than this; SQS jobs are hidden from other workers for as long").Envar("GRASS_WORKER_JOB_TIMEOUT").Default("10m").Duration()
+)
+
+const (
+	// workerRetryDelay is how long a worker waits after failing to receive a job before trying again.
+	workerRetryDelay = 5 * time.Second
+	// workerAckTimeout bounds acknowledging a job, which happens even once the worker is stopping.
+	workerAckTimeout = 30 * time.Second
+)
+
+// errInvalidJob marks a job that can never run, such as one naming an unknown profile. Retrying it can't
+// help, so it is removed from the queue.
+var errInvalidJob = errors.New("invalid job")
+
+// workerJob is the JSON body of a queued job. Keyword and Keywords are combined, Platforms defaults to
+// every platform the profile searches, and Since and Until are RFC 3339 times.
+type workerJob struct {
+	Profile   string    `json:"profile"`
+	Keyword   string    `json:"keyword"`
+	Keywords  []string  `json:"keywords"`
+	Platforms []string  `json:"platforms"`
+	Since     time.Time `json:"since"`
+	Until     time.Time `json:"until"`
+}
+
+// openQueue connects to the queue named by --queue.
+func openQueue(ctx context.Context) (queue.Queue, error) {
+	return queue.Open(ctx, *workerQueue,
+		queue.WithHTTPClient(sharedHTTPClient),
+		queue.WithVisibilityTimeout(*workerJobTimeout),
+	)
+}
+
+// runWorker runs jobs taken from q on the profiles, --jobs at a time, until ctx is cancelled. Jobs running
+// when ctx is cancelled are abandoned, to be delivered again where the queue redelivers.
+func runWorker(ctx context.Context, q queue.Queue, profiles []*profile) {
+	byName := make(map[string]*profile, len(profiles))
+	for _, p := range profiles {
+		byName[p.name] = p
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(*workerJobs, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				msg, err := q.Receive(ctx)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					log.Error("Failed to receive a job", "error", err, "retry_in", workerRetryDelay)
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(workerRetryDelay):
+					}
+					continue
+				}
+				runQueuedJob(ctx, msg, byName)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// runQueuedJob runs the job in msg and acknowledges it, replying with the run's report. Invalid jobs are
+// acknowledged too, replied to with the error. Jobs that time out, whose campaign isn't running, or in which
+// every search failed are given up on, to be retried where the queue redelivers, and replied to with the error.
+func runQueuedJob(ctx context.Context, msg queue.Message, profiles map[string]*profile) {
+	ackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), workerAckTimeout)
+	defer cancel()
+
+	p, report, err := runJob(ctx, msg.Body(), profiles)
+	if err != nil {
+		log.Error("Job failed", "error", err)
+		reply, _ := json.Marshal(apiError{Error: err.Error()})
+		if errors.Is(err, errInvalidJob) {
+			err = msg.Ack(ackCtx, reply)
+		} else {
+			err = msg.Nack(ackCtx, reply)
+		}
+		if err != nil {
+			log.Error("Failed to acknowledge the job", "error", err)
+		}
+		return
+	}
+
+	reply, err := json.Marshal(newRunReport(report, p.name, "queue"))
+	if err != nil {
+		log.Error("Failed to encode the job's report", "error", err)
+	}
+	if report.AllFailed() {
+		log.Error("Every search of the job failed", "profile", p.name, "summary", report.String())
+		err = msg.Nack(ackCtx, reply)
+	} else {
+		log.Info("Job finished", "profile", p.name, "summary", report.String())
+		err = msg.Ack(ackCtx, reply)
+	}
+	if err != nil {
+		log.Error("Failed to acknowledge the job", "error", err)
+	}
+}
+
+// runJob decodes and runs a queued job, recording it in the profile's run history, and returns the profile
+// it ran on and its report.
+func runJob(ctx context.Context, body []byte, profiles map[string]*profile) (*profile, *bot.RunReport, error) {
+	var job workerJob
+	if err := json.Unmarshal(body, &job); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errInvalidJob, err)
+	}
+	keywords := job.Keywords
+	if job.Keyword != "" {
+		keywords = append([]string{job.Keyword}, keywords...)
+	}
+	for _, keyword := range keywords {
+		if _, err := search.ParseQuery(keyword); err != nil || keyword == "" {
+			return nil, nil, fmt.Errorf("%w: invalid keyword %q", errInvalidJob, keyword)
+		}
+	}
+
+	p, ok := profiles[job.Profile]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: unknown profile %q", errInvalidJob, job.Profile)
+	}
+	if !p.active(time.Now()) {
+		return nil, nil, fmt.Errorf("campaign %q is not running", p.name)
+	}
+
+	log.Info("Running job", "profile", p.name, "keywords", keywords, "platforms", job.Platforms)
+	jobCtx, cancel := withTimeout(ctx, *workerJobTimeout)
+	defer cancel()
+	report, err := p.bot.RunJob(jobCtx, bot.Job{
+		Keywords:  keywords,
+		Platforms: job.Platforms,
+		Since:     job.Since,
+		Until:     job.Until,
+	}, *concurrency)
+	if err != nil {
+		// The job itself is at fault, e.g. naming a platform the profile doesn't search
+		return nil, nil, fmt.Errorf("%w: %w", errInvalidJob, err)
+	}
+	recordRun(ctx, p, report, "queue")
+	if errors.Is(jobCtx.Err(), context.DeadlineExceeded) {
+		return nil, nil, fmt.Errorf("job took longer than %s", *workerJobTimeout)
+	}
+	return p, report, nil
+}